@@ -24,6 +24,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/google/go-tika/tika"
@@ -31,7 +32,7 @@ import (
 
 func usage() {
 	fmt.Printf("Usage: %s [OPTIONS] ACTION\n\n", os.Args[0])
-	fmt.Printf("ACTIONS: parse, detect, language, meta, version, parsers, mimetypes, detectors\n\n")
+	fmt.Printf("ACTIONS: parse, detect, language, meta, version, parsers, mimetypes, detectors, manifest\n\n")
 	fmt.Println("OPTIONS:")
 	flag.PrintDefaults()
 }
@@ -52,8 +53,15 @@ const (
 	detectors = "detectors"
 )
 
+// manifest writes the manifest of a directory, with the hash and MIME type of
+// every file, as JSON lines.
+const manifest = "manifest"
+
 // Command line flags.
 var (
+	dir             = flag.String("dir", "", `Directory to list when using the "manifest" action.`)
+	localDetect     = flag.Bool("local", false, `Whether to detect MIME types locally rather than with the server when using the "manifest" action.`)
+	workers         = flag.Int("workers", 8, `Number of files processed concurrently when using the "manifest" action.`)
 	downloadVersion = flag.String("download_version", "", "Tika Server JAR version to download. If -serverJAR is specified, it will be downloaded to that location, otherwise it will be downloaded to your working directory. If the JAR has already been downloaded and has the correct MD5, this will do nothing. Valid versions: 1.14.")
 	filename        = flag.String("filename", "", "Path to file to parse.")
 	metaField       = flag.String("field", "", `Specific field to get when using the "meta" action. Undefined when using the -recursive flag.`)
@@ -71,6 +79,13 @@ func main() {
 	}
 	action := flag.Arg(0)
 
+	if action == manifest && *localDetect {
+		if err := writeManifest(nil); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if *downloadVersion != "" {
 		if *serverJAR == "" {
 			*serverJAR = "tika-server-" + *downloadVersion + ".jar"
@@ -102,6 +117,7 @@ func main() {
 	}
 
 	var file io.Reader
+	var opts []tika.RequestOption
 
 	// Check actions requiring input have an input and get it.
 	switch action {
@@ -110,16 +126,30 @@ func main() {
 			cancel()
 			log.Fatalf("error: you must provide an input filename")
 		}
-		var err error
-		file, err = os.Open(*filename)
+		f, err := os.Open(*filename)
 		if err != nil {
 			cancel()
 			log.Fatalf("error opening file: %v", err)
 		}
+		file = f
+		// Pass the file name and modification time as detection hints.
+		opts = append(opts, tika.WithResourceName(filepath.Base(*filename)))
+		if fi, err := f.Stat(); err == nil {
+			opts = append(opts, tika.WithLastModified(fi.ModTime()))
+		}
 	}
 
 	c := tika.NewClient(nil, *serverURL)
-	b, err := process(c, action, file)
+	if action == manifest {
+		if err := writeManifest(c); err != nil {
+			if cancel != nil {
+				cancel()
+			}
+			log.Fatal(err)
+		}
+		return
+	}
+	b, err := process(c, action, file, opts)
 	if err != nil {
 		cancel()
 		log.Fatalf("tika error: %v", err)
@@ -127,30 +157,30 @@ func main() {
 	fmt.Println(b)
 }
 
-func process(c *tika.Client, action string, file io.Reader) (string, error) {
+func process(c *tika.Client, action string, file io.Reader, opts []tika.RequestOption) (string, error) {
 	switch action {
 	default:
 		flag.Usage()
 		return "", fmt.Errorf("error: invalid action %q", action)
 	case parse:
 		if *recursive {
-			bs, err := c.ParseRecursive(context.Background(), file)
+			bs, err := c.ParseRecursive(context.Background(), file, opts...)
 			if err != nil {
 				return "", err
 			}
 			return strings.Join(bs, "\n"), nil
 		}
-		return c.Parse(context.Background(), file)
+		return c.Parse(context.Background(), file, opts...)
 	case detect:
-		return c.Detect(context.Background(), file)
+		return c.Detect(context.Background(), file, opts...)
 	case language:
 		return c.Language(context.Background(), file)
 	case meta:
 		if *metaField != "" {
-			return c.MetaField(context.Background(), file, *metaField)
+			return c.MetaField(context.Background(), file, *metaField, opts...)
 		}
 		if *recursive {
-			mr, err := c.MetaRecursive(context.Background(), file)
+			mr, err := c.MetaRecursive(context.Background(), file, opts...)
 			if err != nil {
 				return "", err
 			}
@@ -160,7 +190,7 @@ func process(c *tika.Client, action string, file io.Reader) (string, error) {
 			}
 			return string(bytes), nil
 		}
-		return c.Meta(context.Background(), file)
+		return c.Meta(context.Background(), file, opts...)
 	case version:
 		return c.Version(context.Background())
 	case parsers:
@@ -195,3 +225,17 @@ func process(c *tika.Client, action string, file io.Reader) (string, error) {
 		return string(bytes), nil
 	}
 }
+
+// writeManifest writes the manifest of -dir to stdout, detecting the MIME types
+// with c, or locally if c is nil.
+func writeManifest(c *tika.Client) error {
+	if *dir == "" {
+		return fmt.Errorf("error: you must provide a directory")
+	}
+	p := &tika.Prepass{Source: tika.NewFSSource(os.DirFS(*dir)), Client: c, Workers: *workers}
+	m, err := p.Manifest(context.Background())
+	if err != nil {
+		return err
+	}
+	return m.WriteJSON(os.Stdout)
+}
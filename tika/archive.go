@@ -0,0 +1,455 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ArchiveSeparator separates the ID of an archive from the name of an entry
+// in the IDs of the Inputs of an ArchiveSource, as in "docs.zip!/a/b.pdf".
+const ArchiveSeparator = "!/"
+
+// SkipTooLarge is the SkipReason of archive entries exceeding the size limits
+// of an ArchiveSource.
+const SkipTooLarge SkipReason = "too large"
+
+// archiveTypes are the archive formats known by name, and how to read them
+// locally. Formats without a kind, such as 7z, can only be expanded by the
+// Tika Server; see ArchiveSource.Client.
+var archiveTypes = []struct {
+	ext, mimeType, kind string
+}{
+	{".tar.gz", "application/gzip", "tgz"},
+	{".tgz", "application/gzip", "tgz"},
+	{".tar", "application/x-tar", "tar"},
+	{".zip", "application/zip", "zip"},
+	{".jar", "application/java-archive", "zip"},
+	{".7z", "application/x-7z-compressed", ""},
+}
+
+// archiveType returns the MIME type and kind of the archive named name, or
+// empty strings if name is not an archive.
+func archiveType(name string) (mimeType, kind string) {
+	name = strings.ToLower(name)
+	for _, t := range archiveTypes {
+		if strings.HasSuffix(name, t.ext) {
+			return t.mimeType, t.kind
+		}
+	}
+	return "", ""
+}
+
+// ArchiveSource is a Source which treats some archives of another Source as
+// directories: their entries are listed as Inputs, while other archives are
+// listed as single documents, as by the other Source. Archives are selected by
+// MIME type, detected from their name. The zip (including jar) and tar
+// (including gzip compressed) formats are expanded locally, without the Tika
+// Server, and other formats, such as 7z, by the Tika Server if the
+// ArchiveSource has a Client.
+//
+// Entries are listed with the ID of the archive, ArchiveSeparator and their
+// name, recursively for archives in archives.
+type ArchiveSource struct {
+	source   Source
+	patterns []string
+
+	// Client, if not nil, expands the archives which cannot be read locally
+	// with Client.Unpack. Each such archive is sent to the Tika Server when
+	// it is listed, and again when each of its entries is opened.
+	Client *Client
+	// MaxDepth is the depth of nested archives expanded. Archives deeper than
+	// MaxDepth are listed as single documents.
+	MaxDepth int
+	// MaxEntrySize is the uncompressed size past which entries are skipped.
+	MaxEntrySize int64
+	// MaxTotalSize is the total uncompressed size of the entries listed per
+	// archive, past which the next entries are skipped, to defuse archive
+	// bombs.
+	MaxTotalSize int64
+	// TempDir is where archives and entries are spooled when they cannot be
+	// streamed. If empty, os.TempDir is used.
+	TempDir string
+	// OnSkip, if not nil, is called with the IDs of the skipped entries.
+	OnSkip func(id string, reason SkipReason)
+	// NameCharset is the charset of the entry names which are not UTF-8, as
+	// in zip files from older tools (default "ibm437", the charset of the zip
+	// format). Archives made on Japanese or Chinese systems often use
+	// "shift_jis" or "gbk", which need a NameDecoder. Zip entries with an
+	// Info-ZIP Unicode Path extra field use it instead.
+	NameCharset string
+	// NameDecoder decodes the names which are not UTF-8 from NameCharset
+	// (default DecodeText). Bytes it leaves invalid are replaced with U+FFFD.
+	NameDecoder TextDecoder
+}
+
+// NewArchiveSource creates a Source expanding the archives of source whose
+// MIME type matches one of patterns, as defined by path.Match, such as
+// "application/zip" or "application/*". By default, MaxDepth is 3,
+// MaxEntrySize is 100 MiB and MaxTotalSize is 1 GiB.
+func NewArchiveSource(source Source, patterns ...string) *ArchiveSource {
+	return &ArchiveSource{
+		source:       source,
+		patterns:     patterns,
+		MaxDepth:     3,
+		MaxEntrySize: 100 << 20,
+		MaxTotalSize: 1 << 30,
+	}
+}
+
+// unpackKind is the kind of the archives expanded by the Tika Server.
+const unpackKind = "unpack"
+
+// explodes returns the kind of the archive named name if s expands it.
+func (s *ArchiveSource) explodes(name string) (string, bool) {
+	mimeType, kind := archiveType(name)
+	if kind == "" && s.Client != nil && mimeType != "" {
+		kind = unpackKind
+	}
+	if kind == "" {
+		return "", false
+	}
+	for _, p := range s.patterns {
+		if ok, _ := path.Match(p, mimeType); ok {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// An archiveEntry is a regular file of an archive. open is only valid until
+// the function walking the entries returns.
+type archiveEntry struct {
+	name    string
+	size    int64
+	modTime time.Time
+	open    func() (io.Reader, error)
+}
+
+// walkArchive calls fn for each regular file of the archive of the kind read
+// from r.
+func (s *ArchiveSource) walkArchive(ctx context.Context, r io.Reader, kind string, fn func(archiveEntry) error) error {
+	switch kind {
+	case unpackKind:
+		it, err := s.Client.Unpack(ctx, r)
+		if err != nil {
+			return err
+		}
+		defer it.Close()
+		for it.Next() {
+			doc := it.Document()
+			e := archiveEntry{name: path.Clean(doc.Name), size: doc.Size}
+			e.open = func() (io.Reader, error) { return doc.Content, nil }
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return it.Err()
+	case "tgz":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+		fallthrough
+	case "tar":
+		tr := tar.NewReader(r)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if h.Typeflag != tar.TypeReg {
+				continue
+			}
+			e := archiveEntry{name: path.Clean(s.entryName(h.Name)), size: h.Size, modTime: h.ModTime}
+			e.open = func() (io.Reader, error) { return tr, nil }
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	case "zip":
+		f, err := ioutil.TempFile(s.TempDir, "archive-*.zip")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		size, err := io.Copy(f, r)
+		if err != nil {
+			return err
+		}
+		zr, err := zip.NewReader(f, size)
+		if err != nil {
+			return err
+		}
+		for _, zf := range zr.File {
+			if !zf.Mode().IsRegular() {
+				continue
+			}
+			zf := zf
+			name, ok := unicodePath(zf)
+			if !ok {
+				name = s.entryName(zf.Name)
+			}
+			e := archiveEntry{name: path.Clean(name), size: int64(zf.UncompressedSize64), modTime: zf.Modified}
+			var rc io.ReadCloser
+			e.open = func() (io.Reader, error) {
+				var err error
+				rc, err = zf.Open()
+				return rc, err
+			}
+			err := fn(e)
+			if rc != nil {
+				rc.Close()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported archive kind %q", kind)
+}
+
+// entryName returns the entry name raw in UTF-8, decoded from NameCharset if
+// it is not valid UTF-8.
+func (s *ArchiveSource) entryName(raw string) string {
+	if utf8.ValidString(raw) {
+		return raw
+	}
+	charset, decode := s.NameCharset, s.NameDecoder
+	if charset == "" {
+		charset = "ibm437"
+	}
+	if decode == nil {
+		decode = DecodeText
+	}
+	name, err := decode(charset, []byte(raw))
+	if err != nil {
+		name = raw
+	}
+	return strings.ToValidUTF8(name, "\uFFFD")
+}
+
+// unicodePathID is the ID of the Info-ZIP Unicode Path extra field, holding
+// the UTF-8 name of zip entries whose name is in another charset.
+const unicodePathID = 0x7075
+
+// unicodePath returns the name in the Unicode Path extra field of zf, if it
+// has one for its current name.
+func unicodePath(zf *zip.File) (string, bool) {
+	extra := zf.Extra
+	for len(extra) >= 4 {
+		id, size := binary.LittleEndian.Uint16(extra), int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			return "", false
+		}
+		field := extra[4 : 4+size]
+		extra = extra[4+size:]
+		// The field has a version, 1, and the CRC-32 of the name it
+		// replaces, which differs if the entry was renamed by a tool
+		// unaware of the field.
+		if id != unicodePathID || len(field) < 5 || field[0] != 1 {
+			continue
+		}
+		if binary.LittleEndian.Uint32(field[1:]) != crc32.ChecksumIEEE([]byte(zf.Name)) || !utf8.Valid(field[5:]) {
+			return "", false
+		}
+		return string(field[5:]), true
+	}
+	return "", false
+}
+
+// Walk implements Source. Archives which cannot be read are listed as single
+// documents.
+func (s *ArchiveSource) Walk(ctx context.Context, fn func(Input) error) error {
+	return s.source.Walk(ctx, func(in Input) error {
+		kind, ok := s.explodes(in.Name)
+		if !ok || s.MaxDepth < 1 {
+			return fn(in)
+		}
+		r, err := s.source.Open(ctx, in.ID)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		err = s.walk(ctx, r, kind, in.ID, 1, fn)
+		if _, ok := err.(archiveError); ok {
+			return fn(in)
+		}
+		return err
+	})
+}
+
+// archiveError is an error reading an archive before any of its entries was
+// listed, which is then listed as a single document instead.
+type archiveError struct{ error }
+
+// walk lists the entries of the archive with the given ID, of the kind read
+// from r, at depth. An error reading the archive once entries were listed
+// fails, since the archive can neither be listed as a single document nor its
+// remaining entries be found.
+func (s *ArchiveSource) walk(ctx context.Context, r io.Reader, kind, id string, depth int, fn func(Input) error) error {
+	var total int64
+	var fnErr error
+	listed := false
+	err := s.walkArchive(ctx, r, kind, func(e archiveEntry) error {
+		if err := ctx.Err(); err != nil {
+			fnErr = err
+			return err
+		}
+		eid := id + ArchiveSeparator + e.name
+		if e.size > s.MaxEntrySize || total+e.size > s.MaxTotalSize {
+			if s.OnSkip != nil {
+				s.OnSkip(eid, SkipTooLarge)
+			}
+			return nil
+		}
+		total += e.size
+		if kind, ok := s.explodes(e.name); ok && depth < s.MaxDepth {
+			er, err := e.open()
+			if err == nil {
+				err = s.walk(ctx, io.LimitReader(er, s.MaxEntrySize), kind, eid, depth+1, fn)
+			}
+			if err == nil {
+				listed = true
+				return nil
+			}
+			if _, ok := err.(archiveError); !ok {
+				fnErr = err
+				return err
+			}
+			// An unreadable archive is listed as a single document.
+		}
+		if err := fn(Input{ID: eid, Name: path.Base(e.name), Size: e.size, ModTime: e.modTime}); err != nil {
+			fnErr = err
+			return err
+		}
+		listed = true
+		return nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil && listed {
+		return fmt.Errorf("error reading archive %s after listing some of its entries: %w", id, err)
+	}
+	if err != nil {
+		return archiveError{err}
+	}
+	return nil
+}
+
+var errEntryFound = errors.New("entry found")
+
+// Open implements Source. Entries are spooled to a temporary file, removed
+// when the entry is closed.
+func (s *ArchiveSource) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	parts := strings.Split(id, ArchiveSeparator)
+	rc, err := s.source.Open(ctx, parts[0])
+	if err != nil || len(parts) == 1 {
+		return rc, err
+	}
+	defer rc.Close()
+	f, err := s.openEntry(ctx, rc, parts[0], parts[1:])
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", id, err)
+	}
+	return &tempFile{f}, nil
+}
+
+// openEntry returns a temporary file with the content of the entry named by
+// names in the archive named name, read from r.
+func (s *ArchiveSource) openEntry(ctx context.Context, r io.Reader, name string, names []string) (*os.File, error) {
+	kind, ok := s.explodes(name)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an expanded archive", name)
+	}
+	var f *os.File
+	err := s.walkArchive(ctx, r, kind, func(e archiveEntry) error {
+		if e.name != names[0] {
+			return nil
+		}
+		er, err := e.open()
+		if err != nil {
+			return err
+		}
+		er = io.LimitReader(er, s.MaxEntrySize)
+		if len(names) > 1 {
+			f, err = s.openEntry(ctx, er, e.name, names[1:])
+		} else {
+			f, err = s.spool(er)
+		}
+		if err != nil {
+			return err
+		}
+		return errEntryFound
+	})
+	if err == errEntryFound {
+		return f, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("no entry %s in %s", names[0], name)
+	}
+	return nil, err
+}
+
+func (s *ArchiveSource) spool(r io.Reader) (*os.File, error) {
+	f, err := ioutil.TempFile(s.TempDir, "entry-*")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(f, r); err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// tempFile is a temporary file removed when closed.
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func zipOf(t *testing.T, files map[string][]byte) []byte {
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for name, data := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(data)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tgzOf(t *testing.T, files map[string][]byte) []byte {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	w := tar.NewWriter(zw)
+	for name, data := range files {
+		if err := w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func archiveFS(t *testing.T) fstest.MapFS {
+	inner := zipOf(t, map[string][]byte{"c.txt": []byte("c")})
+	return fstest.MapFS{
+		"a.zip": {Data: zipOf(t, map[string][]byte{
+			"b.txt":     []byte("b"),
+			"big.bin":   make([]byte, 1000),
+			"inner.zip": inner,
+		})},
+		"d.tar.gz": {Data: tgzOf(t, map[string][]byte{"e/f.txt": []byte("f")})},
+		"g.7z":     {Data: []byte("7z")},
+		"bad.zip":  {Data: []byte("not a zip")},
+	}
+}
+
+func TestArchiveSource(t *testing.T) {
+	tests := []struct {
+		name      string
+		patterns  []string
+		depth     int
+		want      []string
+		wantSkips map[string]SkipReason
+	}{
+		{
+			name: "no patterns",
+			want: []string{"a.zip", "bad.zip", "d.tar.gz", "g.7z"},
+		},
+		{
+			name:      "zip",
+			patterns:  []string{"application/zip"},
+			depth:     3,
+			want:      []string{"a.zip!/b.txt", "a.zip!/inner.zip!/c.txt", "bad.zip", "d.tar.gz", "g.7z"},
+			wantSkips: map[string]SkipReason{"a.zip!/big.bin": SkipTooLarge},
+		},
+		{
+			name:      "all, depth 1",
+			patterns:  []string{"application/*"},
+			depth:     1,
+			want:      []string{"a.zip!/b.txt", "a.zip!/inner.zip", "bad.zip", "d.tar.gz!/e/f.txt", "g.7z"},
+			wantSkips: map[string]SkipReason{"a.zip!/big.bin": SkipTooLarge},
+		},
+	}
+	for _, test := range tests {
+		s := NewArchiveSource(NewFSSource(archiveFS(t)), test.patterns...)
+		s.MaxDepth = test.depth
+		s.MaxEntrySize = 500
+		skips := map[string]SkipReason{}
+		s.OnSkip = func(id string, reason SkipReason) { skips[id] = reason }
+		got := walkIDs(t, s)
+		// Zip entries are listed in the order they were written.
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Walk(%s) got %v, want %v", test.name, got, test.want)
+		}
+		if len(test.wantSkips) == 0 {
+			test.wantSkips = map[string]SkipReason{}
+		}
+		if !reflect.DeepEqual(skips, test.wantSkips) {
+			t.Errorf("Walk(%s) skipped %v, want %v", test.name, skips, test.wantSkips)
+		}
+	}
+}
+
+func TestArchiveSourceOpen(t *testing.T) {
+	s := NewArchiveSource(NewFSSource(archiveFS(t)), "application/*")
+	tests := []struct {
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{id: "a.zip!/b.txt", want: "b"},
+		{id: "a.zip!/inner.zip!/c.txt", want: "c"},
+		{id: "d.tar.gz!/e/f.txt", want: "f"},
+		{id: "g.7z", want: "7z"},
+		{id: "a.zip!/missing.txt", wantErr: true},
+		{id: "g.7z!/x", wantErr: true},
+	}
+	for _, test := range tests {
+		rc, err := s.Open(context.Background(), test.id)
+		if err != nil {
+			if !test.wantErr {
+				t.Errorf("Open(%q) got error: %v", test.id, err)
+			}
+			continue
+		}
+		b, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if test.wantErr {
+			t.Errorf("Open(%q) got no error", test.id)
+			continue
+		}
+		if string(b) != test.want {
+			t.Errorf("Open(%q) read %q, want %q", test.id, b, test.want)
+		}
+	}
+}
+
+func TestArchiveSourceNames(t *testing.T) {
+	sjis := string([]byte{0x93, 0xFA, 0x96, 0x7B}) + ".txt" // 日本.txt
+	unicodePathExtra := func(raw, name string) []byte {
+		b := make([]byte, 9, 9+len(name))
+		binary.LittleEndian.PutUint16(b, unicodePathID)
+		binary.LittleEndian.PutUint16(b[2:], uint16(5+len(name)))
+		b[4] = 1
+		binary.LittleEndian.PutUint32(b[5:], crc32.ChecksumIEEE([]byte(raw)))
+		return append(b, name...)
+	}
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for _, h := range []*zip.FileHeader{
+		{Name: "文書/😀.txt"},
+		{Name: "caf\x82.txt", NonUTF8: true},
+		{Name: sjis, NonUTF8: true, Extra: unicodePathExtra(sjis, "日本.txt")},
+		{Name: "x" + sjis, NonUTF8: true, Extra: unicodePathExtra(sjis, "stale.txt")},
+	} {
+		f, err := w.CreateHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(h.Name))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"a.zip":    {Data: buf.Bytes()},
+		"b.tar.gz": {Data: tgzOf(t, map[string][]byte{"한국어 🎉.txt": []byte("b"), "caf\x82.txt": []byte("c")})},
+	}
+
+	tests := []struct {
+		name    string
+		charset string
+		decoder TextDecoder
+		want    []string
+	}{
+		{
+			name: "default",
+			want: []string{
+				"a.zip!/文書/😀.txt", "a.zip!/café.txt", "a.zip!/日本.txt", "a.zip!/xô·û{.txt",
+				"b.tar.gz!/café.txt", "b.tar.gz!/한국어 🎉.txt",
+			},
+		},
+		{
+			name:    "unknown charset",
+			charset: "shift_jis",
+			want: []string{
+				"a.zip!/文書/😀.txt", "a.zip!/caf�.txt", "a.zip!/日本.txt", "a.zip!/x�{.txt",
+				"b.tar.gz!/caf�.txt", "b.tar.gz!/한국어 🎉.txt",
+			},
+		},
+		{
+			name:    "decoder",
+			charset: "shift_jis",
+			decoder: func(charset string, body []byte) (string, error) {
+				return strings.Replace(string(body), sjis, "日本.txt", 1), nil
+			},
+			want: []string{
+				"a.zip!/文書/😀.txt", "a.zip!/caf�.txt", "a.zip!/日本.txt", "a.zip!/x日本.txt",
+				"b.tar.gz!/caf�.txt", "b.tar.gz!/한국어 🎉.txt",
+			},
+		},
+	}
+	for _, test := range tests {
+		s := NewArchiveSource(NewFSSource(fsys), "application/*")
+		s.NameCharset, s.NameDecoder = test.charset, test.decoder
+		got := walkIDs(t, s)
+		// Tar entries are written in random order.
+		sort.Strings(got[4:])
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Walk(%s) got %q, want %q", test.name, got, test.want)
+		}
+		for _, id := range got {
+			rc, err := s.Open(context.Background(), id)
+			if err != nil {
+				t.Errorf("Open(%s, %q) got error: %v", test.name, id, err)
+				continue
+			}
+			rc.Close()
+		}
+	}
+}
+
+func TestArchiveSourceTruncated(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := tar.NewWriter(buf)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 1000})
+		w.Write(make([]byte, 1000))
+	}
+	w.Close()
+	// Cut the archive in the middle of the header of b.txt.
+	truncated := buf.Bytes()[:1800]
+	s := NewArchiveSource(NewFSSource(fstest.MapFS{"t.tar": {Data: truncated}}), "application/x-tar")
+	var got []string
+	err := s.Walk(context.Background(), func(in Input) error {
+		got = append(got, in.ID)
+		return nil
+	})
+	if err == nil || !reflect.DeepEqual(got, []string{"t.tar!/a.txt"}) {
+		t.Errorf("Walk of a truncated archive listed %v with error %v, want t.tar!/a.txt and an error", got, err)
+	}
+}
+
+func TestArchiveSourceUnpack(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path != "/unpack" || string(b) != "7z" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		tw := tar.NewWriter(w)
+		tw.WriteHeader(&tar.Header{Name: "x/y.txt", Mode: 0644, Size: 1})
+		tw.Write([]byte("y"))
+		tw.Close()
+	}))
+	defer ts.Close()
+	s := NewArchiveSource(NewFSSource(archiveFS(t)), "application/x-7z-compressed", "application/zip")
+	s.Client = NewClient(nil, ts.URL)
+	got := walkIDs(t, s)
+	sort.Strings(got)
+	want := []string{"a.zip!/b.txt", "a.zip!/big.bin", "a.zip!/inner.zip!/c.txt", "bad.zip", "d.tar.gz", "g.7z!/x/y.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk got %v, want %v", got, want)
+	}
+	rc, err := s.Open(context.Background(), "g.7z!/x/y.txt")
+	if err != nil {
+		t.Fatalf("Open got error: %v", err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != "y" {
+		t.Errorf("Open read %q, want y", b)
+	}
+}
@@ -0,0 +1,521 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+)
+
+// The Arrow IPC file format, as specified by
+// https://arrow.apache.org/docs/format/Columnar.html. Its metadata are
+// FlatBuffers, written by fbBuilder, so the package needs no Arrow library.
+const (
+	arrowMagic = "ARROW1"
+	// arrowV5 is the MetadataVersion of the format.
+	arrowV5 = 4
+	// Message header types.
+	arrowSchemaHeader      = 1
+	arrowRecordBatchHeader = 3
+	// Field types.
+	arrowInt  = 2
+	arrowUtf8 = 5
+)
+
+// DefaultArrowBatchSize is the number of Documents of the record batches of
+// an ArrowWriter with no BatchSize.
+const DefaultArrowBatchSize = 4096
+
+// maxArrowBatchBytes bounds the strings of a record batch, whose offsets are
+// 32-bit.
+const maxArrowBatchBytes = 1 << 30
+
+// An ArrowWriter writes Documents to an Apache Arrow IPC file, also known as
+// Feather V2, for analytics tools such as DuckDB, Polars, pandas or Spark. The
+// file has the string columns id, content_type and content, the int64 column
+// size, the metadata as a JSON object in the string column metadata, and a
+// string column for each of the metadata keys given to NewArrowWriter, with
+// the first value of the key or null. Documents are buffered and written in
+// record batches of BatchSize rows.
+//
+// The file is only complete, and readable, once the ArrowWriter is closed.
+// An ArrowWriter is safe for concurrent use.
+type ArrowWriter struct {
+	// BatchSize is the number of Documents of a record batch.
+	// DefaultArrowBatchSize is used if it is 0.
+	BatchSize int
+
+	mu      sync.Mutex
+	w       io.Writer
+	keys    []string
+	pos     int64
+	started bool
+	rows    []arrowRow
+	size    int
+	blocks  []arrowBlock
+	closed  bool
+	err     error
+}
+
+// arrowRow is a buffered Document, with its metadata as JSON.
+type arrowRow struct {
+	doc      Document
+	metadata []byte
+}
+
+// arrowBlock locates a record batch in the file.
+type arrowBlock struct {
+	offset     int64
+	metaLength int32
+	bodyLength int64
+}
+
+// NewArrowWriter returns an ArrowWriter writing to w, with a column for each
+// of the metadata keys, such as "dc:title" or "Content-Length".
+func NewArrowWriter(w io.Writer, metadataKeys ...string) *ArrowWriter {
+	return &ArrowWriter{w: w, keys: append([]string(nil), metadataKeys...)}
+}
+
+// Write adds doc to the file, and writes a record batch if BatchSize
+// Documents are buffered.
+func (a *ArrowWriter) Write(doc Document) error {
+	var metadata []byte
+	if doc.Metadata != nil {
+		var err error
+		if metadata, err = json.Marshal(doc.Metadata); err != nil {
+			return err
+		}
+	}
+	n := len(doc.ID) + len(doc.ContentType) + len(doc.Content) + len(metadata)
+	if n > maxArrowBatchBytes {
+		return fmt.Errorf("document %q is too large for Arrow: %d bytes", doc.ID, n)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return errors.New("ArrowWriter is closed")
+	}
+	if a.size+n > maxArrowBatchBytes {
+		if err := a.flush(); err != nil {
+			return err
+		}
+	}
+	a.rows = append(a.rows, arrowRow{doc: doc, metadata: metadata})
+	a.size += n
+	batch := a.BatchSize
+	if batch <= 0 {
+		batch = DefaultArrowBatchSize
+	}
+	if len(a.rows) >= batch {
+		return a.flush()
+	}
+	return nil
+}
+
+// Emit adds doc to the file like Write. It can be used as Job.Emit.
+func (a *ArrowWriter) Emit(ctx context.Context, doc Document) error {
+	return a.Write(doc)
+}
+
+// Flush writes the buffered Documents as a record batch.
+func (a *ArrowWriter) Flush() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return errors.New("ArrowWriter is closed")
+	}
+	return a.flush()
+}
+
+// Close writes the buffered Documents and the footer of the file. It does
+// not close the underlying io.Writer.
+func (a *ArrowWriter) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return a.err
+	}
+	if err := a.flush(); err != nil {
+		return err
+	}
+	a.closed = true
+	// End of stream.
+	var eos [8]byte
+	binary.LittleEndian.PutUint32(eos[:], 0xFFFFFFFF)
+	if a.err = a.write(eos[:]); a.err != nil {
+		return a.err
+	}
+	footer := a.footer()
+	var trailer [4]byte
+	binary.LittleEndian.PutUint32(trailer[:], uint32(len(footer)))
+	footer = append(append(footer, trailer[:]...), arrowMagic...)
+	a.err = a.write(footer)
+	return a.err
+}
+
+// write writes p and advances the position of a.
+func (a *ArrowWriter) write(p []byte) error {
+	n, err := a.w.Write(p)
+	a.pos += int64(n)
+	return err
+}
+
+// start writes the header of the file and the schema, once.
+func (a *ArrowWriter) start() error {
+	if a.started {
+		return nil
+	}
+	a.started = true
+	if err := a.write([]byte(arrowMagic + "\x00\x00")); err != nil {
+		return err
+	}
+	b := &fbBuilder{}
+	_, err := a.writeMessage(b, arrowSchemaHeader, a.schema(b), nil)
+	return err
+}
+
+// flush writes the buffered rows as a record batch. a.mu must be held.
+func (a *ArrowWriter) flush() error {
+	if a.err != nil {
+		return a.err
+	}
+	if a.err = a.start(); a.err != nil {
+		return a.err
+	}
+	if len(a.rows) == 0 {
+		return nil
+	}
+	var c arrowColumns
+	n := len(a.rows)
+	c.utf8(n, func(i int) (string, bool) { return a.rows[i].doc.ID, true })
+	c.utf8(n, func(i int) (string, bool) { return a.rows[i].doc.ContentType, true })
+	c.int64(n, func(i int) int64 { return a.rows[i].doc.Size })
+	c.utf8(n, func(i int) (string, bool) { return a.rows[i].doc.Content, true })
+	c.utf8(n, func(i int) (string, bool) {
+		return string(a.rows[i].metadata), a.rows[i].metadata != nil
+	})
+	for _, k := range a.keys {
+		k := k
+		c.utf8(n, func(i int) (string, bool) {
+			vs := a.rows[i].doc.Metadata[k]
+			if len(vs) == 0 {
+				return "", false
+			}
+			return vs[0], true
+		})
+	}
+
+	b := &fbBuilder{}
+	nodes := make([]byte, 0, 16*len(c.nodes))
+	for _, nd := range c.nodes {
+		nodes = appendInt64(appendInt64(nodes, nd[0]), nd[1])
+	}
+	buffers := make([]byte, 0, 16*len(c.buffers))
+	for _, bf := range c.buffers {
+		buffers = appendInt64(appendInt64(buffers, bf[0]), bf[1])
+	}
+	nodesVec := b.createStructVector(nodes, len(c.nodes), 8)
+	buffersVec := b.createStructVector(buffers, len(c.buffers), 8)
+	b.startTable(5)
+	b.addInt64(0, int64(n))
+	b.addOffset(1, nodesVec)
+	b.addOffset(2, buffersVec)
+	batch := b.endTable()
+
+	block, err := a.writeMessage(b, arrowRecordBatchHeader, batch, c.body)
+	if err != nil {
+		a.err = err
+		return err
+	}
+	a.blocks = append(a.blocks, block)
+	a.rows, a.size = nil, 0
+	return nil
+}
+
+// writeMessage writes an encapsulated message, with the header built by b and
+// the given body.
+func (a *ArrowWriter) writeMessage(b *fbBuilder, headerType byte, header int, body []byte) (arrowBlock, error) {
+	b.startTable(5)
+	b.addInt16(0, arrowV5)
+	b.addByte(1, headerType)
+	b.addOffset(2, header)
+	b.addInt64(3, int64(len(body)))
+	meta := b.finish(b.endTable())
+	meta = append(meta, make([]byte, pad8(len(meta)))...)
+
+	block := arrowBlock{offset: a.pos, metaLength: int32(8 + len(meta)), bodyLength: int64(len(body))}
+	var prefix [8]byte
+	binary.LittleEndian.PutUint32(prefix[:], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint32(prefix[4:], uint32(len(meta)))
+	for _, p := range [][]byte{prefix[:], meta, body} {
+		if err := a.write(p); err != nil {
+			return arrowBlock{}, err
+		}
+	}
+	return block, nil
+}
+
+// schema builds the Schema of the file with b.
+func (a *ArrowWriter) schema(b *fbBuilder) int {
+	type column struct {
+		name     string
+		typ      byte
+		nullable bool
+	}
+	columns := []column{
+		{"id", arrowUtf8, false},
+		{"content_type", arrowUtf8, false},
+		{"size", arrowInt, false},
+		{"content", arrowUtf8, false},
+		{"metadata", arrowUtf8, true},
+	}
+	for _, k := range a.keys {
+		columns = append(columns, column{k, arrowUtf8, true})
+	}
+	fields := make([]int, len(columns))
+	for i, col := range columns {
+		name := b.createString(col.name)
+		b.startTable(2)
+		if col.typ == arrowInt {
+			b.addInt32(0, 64)
+			b.addByte(1, 1)
+		}
+		typ := b.endTable()
+		children := b.createOffsetVector(nil)
+		b.startTable(7)
+		b.addOffset(0, name)
+		if col.nullable {
+			b.addByte(1, 1)
+		} else {
+			b.addByte(1, 0)
+		}
+		b.addByte(2, col.typ)
+		b.addOffset(3, typ)
+		b.addOffset(5, children)
+		fields[i] = b.endTable()
+	}
+	fieldsVec := b.createOffsetVector(fields)
+	b.startTable(4)
+	b.addOffset(1, fieldsVec)
+	return b.endTable()
+}
+
+// footer returns the footer of the file.
+func (a *ArrowWriter) footer() []byte {
+	b := &fbBuilder{}
+	blocks := make([]byte, 0, 24*len(a.blocks))
+	for _, bl := range a.blocks {
+		blocks = appendInt64(blocks, bl.offset)
+		blocks = appendInt64(blocks, int64(uint32(bl.metaLength)))
+		blocks = appendInt64(blocks, bl.bodyLength)
+	}
+	batches := b.createStructVector(blocks, len(a.blocks), 8)
+	dictionaries := b.createStructVector(nil, 0, 8)
+	schema := a.schema(b)
+	b.startTable(5)
+	b.addInt16(0, arrowV5)
+	b.addOffset(1, schema)
+	b.addOffset(2, dictionaries)
+	b.addOffset(3, batches)
+	return b.finish(b.endTable())
+}
+
+// arrowColumns is the body of a record batch being built, with its field
+// nodes and buffers as (length, null count) and (offset, length) pairs.
+type arrowColumns struct {
+	body    []byte
+	nodes   [][2]int64
+	buffers [][2]int64
+}
+
+// buffer appends a buffer to the body, padded to 8 bytes.
+func (c *arrowColumns) buffer(p []byte) {
+	c.buffers = append(c.buffers, [2]int64{int64(len(c.body)), int64(len(p))})
+	c.body = append(c.body, p...)
+	c.body = append(c.body, make([]byte, pad8(len(p)))...)
+}
+
+// utf8 appends a string column of n values.
+func (c *arrowColumns) utf8(n int, value func(int) (string, bool)) {
+	validity := make([]byte, (n+7)/8)
+	offsets := make([]byte, 4, 4*(n+1))
+	var data []byte
+	nulls := 0
+	for i := 0; i < n; i++ {
+		v, ok := value(i)
+		if ok {
+			validity[i/8] |= 1 << (i % 8)
+			data = append(data, v...)
+		} else {
+			nulls++
+		}
+		offsets = appendInt32(offsets, int32(len(data)))
+	}
+	if nulls == 0 {
+		validity = nil
+	}
+	c.nodes = append(c.nodes, [2]int64{int64(n), int64(nulls)})
+	c.buffer(validity)
+	c.buffer(offsets)
+	c.buffer(data)
+}
+
+// int64 appends an int64 column of n values.
+func (c *arrowColumns) int64(n int, value func(int) int64) {
+	data := make([]byte, 0, 8*n)
+	for i := 0; i < n; i++ {
+		data = appendInt64(data, value(i))
+	}
+	c.nodes = append(c.nodes, [2]int64{int64(n), 0})
+	c.buffer(nil)
+	c.buffer(data)
+}
+
+func pad8(n int) int {
+	return (8 - n%8) % 8
+}
+
+func appendInt32(b []byte, v int32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendInt64(b []byte, v int64) []byte {
+	return appendInt32(appendInt32(b, int32(v)), int32(v>>32))
+}
+
+// fbBuilder builds a FlatBuffer back to front, like the FlatBuffers library:
+// objects are prepended to buf, and referenced by their position from the end
+// of buf, so that objects are built before the objects referencing them.
+type fbBuilder struct {
+	buf      []byte
+	minAlign int
+	// fields are the positions of the fields of the current table, or 0.
+	fields []int
+	// tableEnd is the position where the current table ends.
+	tableEnd int
+}
+
+// prepend prepends data to buf, so that it is aligned to align, and returns
+// its position.
+func (b *fbBuilder) prepend(data []byte, align int) int {
+	if align > b.minAlign {
+		b.minAlign = align
+	}
+	pad := (align - (len(b.buf)+len(data))%align) % align
+	buf := make([]byte, len(data)+pad+len(b.buf))
+	copy(buf, data)
+	copy(buf[len(data)+pad:], b.buf)
+	b.buf = buf
+	return len(b.buf)
+}
+
+func (b *fbBuilder) createString(s string) int {
+	data := make([]byte, 4+len(s)+1)
+	binary.LittleEndian.PutUint32(data, uint32(len(s)))
+	copy(data[4:], s)
+	return b.prepend(data, 4)
+}
+
+// createStructVector creates a vector of n structs, encoded in elems.
+func (b *fbBuilder) createStructVector(elems []byte, n, align int) int {
+	b.prepend(elems, align)
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(n))
+	return b.prepend(length[:], 4)
+}
+
+// createOffsetVector creates a vector of the objects at the given positions.
+func (b *fbBuilder) createOffsetVector(objects []int) int {
+	n := len(objects)
+	start := b.prepend(nil, 4)
+	data := make([]byte, 4+4*n)
+	binary.LittleEndian.PutUint32(data, uint32(n))
+	for i, o := range objects {
+		// Offsets are relative to their own position.
+		binary.LittleEndian.PutUint32(data[4+4*i:], uint32(start+4*(n-i)-o))
+	}
+	return b.prepend(data, 4)
+}
+
+func (b *fbBuilder) startTable(numFields int) {
+	b.fields = make([]int, numFields)
+	b.tableEnd = len(b.buf)
+}
+
+func (b *fbBuilder) addByte(field int, v byte) {
+	b.fields[field] = b.prepend([]byte{v}, 1)
+}
+
+func (b *fbBuilder) addInt16(field int, v int16) {
+	b.fields[field] = b.prepend([]byte{byte(v), byte(v >> 8)}, 2)
+}
+
+func (b *fbBuilder) addInt32(field int, v int32) {
+	b.fields[field] = b.prepend(appendInt32(nil, v), 4)
+}
+
+func (b *fbBuilder) addInt64(field int, v int64) {
+	b.fields[field] = b.prepend(appendInt64(nil, v), 8)
+}
+
+// addOffset adds a field referencing the object at position o.
+func (b *fbBuilder) addOffset(field, o int) {
+	pos := b.prepend(nil, 4) + 4
+	b.fields[field] = b.prepend(appendInt32(nil, int32(pos-o)), 4)
+}
+
+// endTable ends the current table, prepending its vtable, and returns its
+// position.
+func (b *fbBuilder) endTable() int {
+	t := b.prepend(make([]byte, 4), 4)
+	if t-b.tableEnd > math.MaxUint16 {
+		panic("tika: FlatBuffers table too large")
+	}
+	vtable := make([]byte, 4+2*len(b.fields))
+	binary.LittleEndian.PutUint16(vtable, uint16(len(vtable)))
+	binary.LittleEndian.PutUint16(vtable[2:], uint16(t-b.tableEnd))
+	for i, f := range b.fields {
+		if f != 0 {
+			binary.LittleEndian.PutUint16(vtable[4+2*i:], uint16(t-f))
+		}
+	}
+	v := b.prepend(vtable, 2)
+	// The table starts with the offset back to its vtable, which precedes
+	// it.
+	binary.LittleEndian.PutUint32(b.buf[len(b.buf)-t:], uint32(int32(v-t)))
+	b.fields = nil
+	return t
+}
+
+// finish prepends the offset of the root table at position root, and returns
+// the buffer.
+func (b *fbBuilder) finish(root int) []byte {
+	align := b.minAlign
+	if align < 4 {
+		align = 4
+	}
+	b.prepend(make([]byte, (align-(len(b.buf)+4)%align)%align), 1)
+	pos := len(b.buf) + 4
+	b.prepend(appendInt32(nil, int32(pos-root)), 4)
+	return b.buf
+}
@@ -0,0 +1,335 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fbTable reads a FlatBuffers table, checking the alignment of what it reads
+// as the FlatBuffers verifier does.
+type fbTable struct {
+	t   *testing.T
+	buf []byte
+	pos int
+}
+
+func fbRoot(t *testing.T, buf []byte) fbTable {
+	r := fbTable{t: t, buf: buf}
+	return fbTable{t: t, buf: buf, pos: int(r.u32(0))}
+}
+
+func (f fbTable) check(pos, size int) {
+	f.t.Helper()
+	if pos%size != 0 || pos+size > len(f.buf) {
+		f.t.Fatalf("misaligned or out of bounds read of %d bytes at %d in %d bytes", size, pos, len(f.buf))
+	}
+}
+
+func (f fbTable) u16(pos int) uint16 {
+	f.check(pos, 2)
+	return binary.LittleEndian.Uint16(f.buf[pos:])
+}
+
+func (f fbTable) u32(pos int) uint32 {
+	f.check(pos, 4)
+	return binary.LittleEndian.Uint32(f.buf[pos:])
+}
+
+func (f fbTable) i64(pos int) int64 {
+	f.check(pos, 8)
+	return int64(binary.LittleEndian.Uint64(f.buf[pos:]))
+}
+
+// field returns the position of field i, or 0 if it is not set.
+func (f fbTable) field(i int) int {
+	vt := f.pos - int(int32(f.u32(f.pos)))
+	if 4+2*i >= int(f.u16(vt)) {
+		return 0
+	}
+	if off := f.u16(vt + 4 + 2*i); off != 0 {
+		return f.pos + int(off)
+	}
+	return 0
+}
+
+func (f fbTable) ref(i int) int {
+	p := f.field(i)
+	if p == 0 {
+		f.t.Fatalf("field %d is not set", i)
+	}
+	return p + int(f.u32(p))
+}
+
+func (f fbTable) table(i int) fbTable {
+	return fbTable{t: f.t, buf: f.buf, pos: f.ref(i)}
+}
+
+func (f fbTable) byteField(i int) byte {
+	if p := f.field(i); p != 0 {
+		return f.buf[p]
+	}
+	return 0
+}
+
+func (f fbTable) int16Field(i int) int16 {
+	if p := f.field(i); p != 0 {
+		return int16(f.u16(p))
+	}
+	return 0
+}
+
+func (f fbTable) int64Field(i int) int64 {
+	if p := f.field(i); p != 0 {
+		return f.i64(p)
+	}
+	return 0
+}
+
+func (f fbTable) str(i int) string {
+	p := f.ref(i)
+	n := int(f.u32(p))
+	return string(f.buf[p+4 : p+4+n])
+}
+
+// vector returns the position of the first element and the length of vector i.
+func (f fbTable) vector(i int) (int, int) {
+	p := f.ref(i)
+	return p + 4, int(f.u32(p))
+}
+
+func (f fbTable) tables(i int) []fbTable {
+	start, n := f.vector(i)
+	var r []fbTable
+	for j := 0; j < n; j++ {
+		p := start + 4*j
+		r = append(r, fbTable{t: f.t, buf: f.buf, pos: p + int(f.u32(p))})
+	}
+	return r
+}
+
+type arrowTestField struct {
+	Name     string
+	Type     byte
+	Nullable bool
+}
+
+func readArrowSchema(schema fbTable) []arrowTestField {
+	var fields []arrowTestField
+	for _, f := range schema.tables(1) {
+		if _, n := f.vector(5); n != 0 {
+			f.t.Errorf("field %s has %d children", f.str(0), n)
+		}
+		field := arrowTestField{Name: f.str(0), Type: f.byteField(2), Nullable: f.byteField(1) == 1}
+		if field.Type == arrowInt {
+			typ := f.table(3)
+			if bits, signed := int32(typ.u32(typ.field(0))), typ.byteField(1); bits != 64 || signed != 1 {
+				f.t.Errorf("field %s is Int(%d, %v), want Int(64, true)", field.Name, bits, signed)
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// readArrowFile reads the schema and rows of an Arrow IPC file written by
+// ArrowWriter, with null values as nil.
+func readArrowFile(t *testing.T, file []byte) ([]arrowTestField, [][]interface{}) {
+	t.Helper()
+	if !bytes.HasPrefix(file, []byte("ARROW1\x00\x00")) || !bytes.HasSuffix(file, []byte("ARROW1")) {
+		t.Fatalf("file has no Arrow magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(file[len(file)-10:]))
+	footer := fbRoot(t, append([]byte(nil), file[len(file)-10-footerLen:len(file)-10]...))
+	if v := footer.int16Field(0); v != arrowV5 {
+		t.Errorf("footer version got %d, want %d", v, arrowV5)
+	}
+	fields := readArrowSchema(footer.table(1))
+
+	message := func(offset int) (fbTable, []byte) {
+		if c := binary.LittleEndian.Uint32(file[offset:]); c != 0xFFFFFFFF {
+			t.Fatalf("message at %d has no continuation marker", offset)
+		}
+		n := int(binary.LittleEndian.Uint32(file[offset+4:]))
+		if (8+n)%8 != 0 {
+			t.Errorf("message at %d has metadata of %d bytes, not padded", offset, n)
+		}
+		m := fbRoot(t, append([]byte(nil), file[offset+8:offset+8+n]...))
+		bodyStart := offset + 8 + n
+		return m, file[bodyStart : bodyStart+int(m.int64Field(3))]
+	}
+	schemaMsg, _ := message(8)
+	if typ := schemaMsg.byteField(1); typ != arrowSchemaHeader {
+		t.Fatalf("first message has type %d, want Schema", typ)
+	}
+	if got := readArrowSchema(schemaMsg.table(2)); !reflect.DeepEqual(got, fields) {
+		t.Errorf("stream schema %v differs from footer schema %v", got, fields)
+	}
+
+	var rows [][]interface{}
+	start, n := footer.vector(3)
+	for i := 0; i < n; i++ {
+		block := start + 24*i
+		offset := int(footer.i64(block))
+		m, body := message(offset)
+		if got, want := int(footer.u32(block+8)), 8+int(binary.LittleEndian.Uint32(file[offset+4:])); got != want {
+			t.Errorf("block %d has metadata length %d, want %d", i, got, want)
+		}
+		if m.byteField(1) != arrowRecordBatchHeader {
+			t.Fatalf("block %d is not a record batch", i)
+		}
+		batch := m.table(2)
+		length := int(batch.int64Field(0))
+		bufStart, _ := batch.vector(2)
+		buffer := func(j int) []byte {
+			p := bufStart + 16*j
+			off, n := batch.i64(p), batch.i64(p+8)
+			if off%8 != 0 {
+				t.Errorf("buffer %d at misaligned offset %d", j, off)
+			}
+			return body[off : off+n]
+		}
+		batchRows := make([][]interface{}, length)
+		b := 0
+		for _, f := range fields {
+			var validity []byte
+			if f.Type == arrowUtf8 {
+				validity = buffer(b)
+				offsets, data := buffer(b+1), buffer(b+2)
+				for r := 0; r < length; r++ {
+					var v interface{}
+					if len(validity) == 0 || validity[r/8]&(1<<(r%8)) != 0 {
+						lo, hi := binary.LittleEndian.Uint32(offsets[4*r:]), binary.LittleEndian.Uint32(offsets[4*r+4:])
+						v = string(data[lo:hi])
+					}
+					batchRows[r] = append(batchRows[r], v)
+				}
+				b += 3
+			} else {
+				data := buffer(b + 1)
+				for r := 0; r < length; r++ {
+					batchRows[r] = append(batchRows[r], int64(binary.LittleEndian.Uint64(data[8*r:])))
+				}
+				b += 2
+			}
+		}
+		rows = append(rows, batchRows...)
+	}
+	return fields, rows
+}
+
+func TestArrowWriter(t *testing.T) {
+	docs := []Document{
+		{ID: "a.pdf", ContentType: "application/pdf", Size: 1000, Content: "some text", Metadata: map[string][]string{"dc:title": {"A"}, "k": {"1", "2"}}},
+		{ID: "b.txt", ContentType: "text/plain", Size: 3, Content: "é€"},
+		{ID: "c.png", ContentType: "image/png", Size: 1 << 40, Metadata: map[string][]string{"k": {"v"}}},
+	}
+	var buf bytes.Buffer
+	w := NewArrowWriter(&buf, "dc:title", "k")
+	w.BatchSize = 2
+	for _, d := range docs {
+		if err := w.Write(d); err != nil {
+			t.Fatalf("Write got error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close got error: %v", err)
+	}
+	if err := w.Write(docs[0]); err == nil {
+		t.Error("Write after Close got no error")
+	}
+
+	fields, rows := readArrowFile(t, buf.Bytes())
+	wantFields := []arrowTestField{
+		{"id", arrowUtf8, false},
+		{"content_type", arrowUtf8, false},
+		{"size", arrowInt, false},
+		{"content", arrowUtf8, false},
+		{"metadata", arrowUtf8, true},
+		{"dc:title", arrowUtf8, true},
+		{"k", arrowUtf8, true},
+	}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("schema got %v, want %v", fields, wantFields)
+	}
+	metadata := func(m map[string][]string) interface{} {
+		b, _ := json.Marshal(m)
+		return string(b)
+	}
+	wantRows := [][]interface{}{
+		{"a.pdf", "application/pdf", int64(1000), "some text", metadata(docs[0].Metadata), "A", "1"},
+		{"b.txt", "text/plain", int64(3), "é€", nil, nil, nil},
+		{"c.png", "image/png", int64(1 << 40), "", metadata(docs[2].Metadata), nil, "v"},
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("rows got\n%v\nwant\n%v", rows, wantRows)
+	}
+}
+
+func TestArrowWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewArrowWriter(&buf).Close(); err != nil {
+		t.Fatalf("Close got error: %v", err)
+	}
+	fields, rows := readArrowFile(t, buf.Bytes())
+	if len(fields) != 5 || len(rows) != 0 {
+		t.Errorf("empty file has %d fields and %d rows, want 5 and 0", len(fields), len(rows))
+	}
+}
+
+// TestArrowWriterGolden compares the output of ArrowWriter with
+// testdata/docs.arrow, which was checked against the Arrow format
+// specification with testdata/arrow_verify.py, so the reader of this file is
+// not the only check of the format.
+func TestArrowWriterGolden(t *testing.T) {
+	golden, err := ioutil.ReadFile(filepath.Join("testdata", "docs.arrow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := NewArrowWriter(&buf, "dc:title")
+	docs := []Document{
+		{ID: "a.txt", ContentType: "text/plain", Size: 3, Content: "abc", Metadata: map[string][]string{"dc:title": {"T"}}},
+		{ID: "b", ContentType: "image/png", Size: 1 << 33},
+	}
+	for _, d := range docs {
+		if err := w.Write(d); err != nil {
+			t.Fatalf("Write got error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close got error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Errorf("ArrowWriter wrote %d bytes differing from testdata/docs.arrow", buf.Len())
+	}
+
+	_, rows := readArrowFile(t, golden)
+	wantRows := [][]interface{}{
+		{"a.txt", "text/plain", int64(3), "abc", `{"dc:title":["T"]}`, "T"},
+		{"b", "image/png", int64(1 << 33), "", nil, nil},
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("rows of testdata/docs.arrow got\n%v\nwant\n%v", rows, wantRows)
+	}
+}
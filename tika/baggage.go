@@ -0,0 +1,92 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// contextHeader is a header whose value is taken from the Context of calls.
+type contextHeader struct {
+	name  string
+	value func(context.Context) (string, bool)
+}
+
+// WithContextHeader returns a ClientOption to send the header name, such as
+// "X-Tenant-ID", with every call whose Context has a value for it, as
+// returned by value. This lets the Tika Server logs, or a proxy in front of
+// it, record identifiers of the caller for cross-system debugging. Headers
+// set with RequestOptions take precedence.
+func WithContextHeader(name string, value func(ctx context.Context) (string, bool)) ClientOption {
+	return func(c *Client) {
+		c.contextHeaders = append(c.contextHeaders, contextHeader{name: http.CanonicalHeaderKey(name), value: value})
+	}
+}
+
+// WithBaggageHeaders returns a ClientOption to send entries of the baggage of
+// the Context of calls as headers. baggage returns the baggage of a Context,
+// and headers maps baggage keys to header names. For example, with
+// OpenTelemetry:
+//
+//	tika.WithBaggageHeaders(func(ctx context.Context) map[string]string {
+//		m := map[string]string{}
+//		for _, member := range baggage.FromContext(ctx).Members() {
+//			m[member.Key()] = member.Value()
+//		}
+//		return m
+//	}, map[string]string{"tenant.id": "X-Tenant-ID", "job.id": "X-Job-ID"})
+//
+// Only the mapped keys are sent, since baggage may hold values which must not
+// leave the process.
+func WithBaggageHeaders(baggage func(ctx context.Context) map[string]string, headers map[string]string) ClientOption {
+	return func(c *Client) {
+		for key, name := range headers {
+			key := key
+			WithContextHeader(name, func(ctx context.Context) (string, bool) {
+				v, ok := baggage(ctx)[key]
+				return v, ok
+			})(c)
+		}
+	}
+}
+
+// setContextHeaders sets the context headers of c in h from ctx, and returns
+// h, which is created if nil and needed.
+func (c *Client) setContextHeaders(ctx context.Context, h http.Header) http.Header {
+	for _, ch := range c.contextHeaders {
+		if _, ok := h[ch.name]; ok {
+			continue
+		}
+		v, ok := ch.value(ctx)
+		if !ok {
+			continue
+		}
+		if h == nil {
+			h = make(http.Header)
+		}
+		// Line breaks would end the header early; drop them.
+		h[ch.name] = []string{strings.Map(func(r rune) rune {
+			if r == '\r' || r == '\n' {
+				return -1
+			}
+			return r
+		}, v)}
+	}
+	return h
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type baggageKey struct{}
+
+func TestContextHeaders(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer ts.Close()
+	baggage := func(ctx context.Context) map[string]string {
+		m, _ := ctx.Value(baggageKey{}).(map[string]string)
+		return m
+	}
+	c := NewClient(nil, ts.URL,
+		WithBaggageHeaders(baggage, map[string]string{"tenant.id": "X-Tenant-ID", "job.id": "x-job-id"}),
+		WithContextHeader("X-Request", func(context.Context) (string, bool) { return "req\r\nX-Evil: 1", true }))
+
+	tests := []struct {
+		name    string
+		baggage map[string]string
+		opts    []RequestOption
+		want    map[string]string
+	}{
+		{
+			name: "no baggage",
+			want: map[string]string{"X-Tenant-Id": "", "X-Job-Id": "", "X-Request": "reqX-Evil: 1", "X-Evil": ""},
+		},
+		{
+			name:    "baggage",
+			baggage: map[string]string{"tenant.id": "acme", "job.id": "nightly", "secret": "s3cret"},
+			want:    map[string]string{"X-Tenant-Id": "acme", "X-Job-Id": "nightly", "Secret": ""},
+		},
+		{
+			name:    "request option wins",
+			baggage: map[string]string{"tenant.id": "acme"},
+			opts:    []RequestOption{func(cfg *callConfig) { cfg.setHeader("X-Tenant-ID", "explicit") }},
+			want:    map[string]string{"X-Tenant-Id": "explicit"},
+		},
+	}
+	for _, test := range tests {
+		ctx := context.WithValue(context.Background(), baggageKey{}, test.baggage)
+		if _, err := c.Parse(ctx, strings.NewReader("input"), test.opts...); err != nil {
+			t.Fatalf("Parse(%s) got error: %v", test.name, err)
+		}
+		for k, v := range test.want {
+			if got.Get(k) != v {
+				t.Errorf("Parse(%s) sent %s: %q, want %q", test.name, k, got.Get(k), v)
+			}
+		}
+	}
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A BlobRef references content in a BlobStore. It has the form
+// "sha256:<hex digest>", so identical content has the same BlobRef.
+type BlobRef string
+
+const blobRefPrefix = "sha256:"
+
+// digest returns the hex digest of r, and an error if r is malformed.
+func (r BlobRef) digest() (string, error) {
+	d := strings.TrimPrefix(string(r), blobRefPrefix)
+	if len(d) != 2*sha256.Size || len(d) == len(r) {
+		return "", fmt.Errorf("invalid blob reference %q", string(r))
+	}
+	if _, err := hex.DecodeString(d); err != nil {
+		return "", fmt.Errorf("invalid blob reference %q", string(r))
+	}
+	return d, nil
+}
+
+// A BlobStore stores content, such as extracted text and attachments, under
+// the hash of the content. Storing content already in the store does not
+// store it again.
+type BlobStore interface {
+	// Put stores the content of r and returns its reference.
+	Put(ctx context.Context, r io.Reader) (BlobRef, error)
+	// Open opens the content referenced by ref.
+	Open(ctx context.Context, ref BlobRef) (io.ReadCloser, error)
+}
+
+// StoreContent moves the Content of doc to store, and records its reference
+// in doc.ContentRef.
+func StoreContent(ctx context.Context, store BlobStore, doc *Document) error {
+	ref, err := store.Put(ctx, strings.NewReader(doc.Content))
+	if err != nil {
+		return fmt.Errorf("error storing content of %s: %w", doc.ID, err)
+	}
+	doc.Content, doc.ContentRef = "", ref
+	return nil
+}
+
+// spool copies r to a temporary file in dir, and returns the file, rewound,
+// and the reference of its content. The caller must close and remove the file.
+func spool(dir string, r io.Reader) (*os.File, BlobRef, error) {
+	f, err := ioutil.TempFile(dir, "blob-*.tmp")
+	if err != nil {
+		return nil, "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, "", err
+	}
+	return f, BlobRef(blobRefPrefix + hex.EncodeToString(h.Sum(nil))), nil
+}
+
+// spoolCompressed compresses r with c to a temporary file in dir, and returns
+// the file, rewound. The caller must close and remove the file.
+func spoolCompressed(dir string, c Compressor, r io.Reader) (*os.File, error) {
+	f, err := ioutil.TempFile(dir, "blob-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err := compress(c, f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("error compressing content: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// compressedSuffix is added to the name of compressed content in a BlobStore,
+// so content stored uncompressed is never mistaken for compressed content.
+const compressedSuffix = ".tkz"
+
+// DirBlobStore is a BlobStore in a local directory. Content is stored at
+// <dir>/<first 2 digits of the digest>/<digest>, with compressedSuffix if it
+// is compressed.
+type DirBlobStore struct {
+	dir string
+	// Keys, if not nil, encrypts the stored content at rest. Content is still
+	// addressed by the hash of its plaintext.
+	Keys KeyProvider
+	// Compressor, if not nil, compresses the stored content, before it is
+	// encrypted. Content is still addressed by the hash of its uncompressed
+	// bytes, and content stored before compression was enabled is read as
+	// is. Content compressed with a DictCompressor can only be read with the
+	// same Compressor; gzip content is read even without one.
+	Compressor Compressor
+}
+
+// NewDirBlobStore creates a BlobStore in dir, creating it if needed.
+func NewDirBlobStore(dir string) (*DirBlobStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating blob store: %w", err)
+	}
+	return &DirBlobStore{dir: dir}, nil
+}
+
+func (s *DirBlobStore) path(digest string) string {
+	return filepath.Join(s.dir, digest[:2], digest)
+}
+
+// Put implements BlobStore.
+func (s *DirBlobStore) Put(ctx context.Context, r io.Reader) (BlobRef, error) {
+	f, ref, err := spool(s.dir, r)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name()) // No-op once renamed.
+	defer f.Close()
+	digest, _ := ref.digest()
+	p := s.path(digest)
+	for _, p := range []string{p, p + compressedSuffix} {
+		if _, err := os.Stat(p); err == nil {
+			return ref, nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", err
+	}
+	if s.Compressor != nil {
+		p += compressedSuffix
+		zf, err := spoolCompressed(s.dir, s.Compressor, f)
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(zf.Name())
+		defer zf.Close()
+		f = zf
+	}
+	if s.Keys != nil {
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			return "", err
+		}
+		return ref, WriteFileEncrypted(ctx, s.Keys, p, data, 0644)
+	}
+	f.Close()
+	if err := os.Rename(f.Name(), p); err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
+// Open implements BlobStore.
+func (s *DirBlobStore) Open(ctx context.Context, ref BlobRef) (io.ReadCloser, error) {
+	digest, err := ref.digest()
+	if err != nil {
+		return nil, err
+	}
+	p := s.path(digest)
+	rc, err := s.open(ctx, p+compressedSuffix)
+	if err == nil {
+		return decompress(s.Compressor, rc)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return s.open(ctx, p)
+}
+
+// open opens the file at p, decrypted.
+func (s *DirBlobStore) open(ctx context.Context, p string) (io.ReadCloser, error) {
+	if s.Keys == nil {
+		return os.Open(p)
+	}
+	data, err := ReadFileEncrypted(ctx, s.Keys, p)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// ObjectStore is a bucket of an object storage service, such as Amazon S3 or
+// Google Cloud Storage.
+//
+// The tika package does not depend on the client libraries of these services,
+// so ObjectStore is meant to be a thin adapter of one.
+type ObjectStore interface {
+	// Exists reports whether there is an object with the key.
+	Exists(ctx context.Context, key string) (bool, error)
+	// Put writes the object with the key, of size bytes read from r.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get opens the object with the key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// ObjectBlobStore is a BlobStore in an ObjectStore. Content is stored with the
+// key <prefix>/<first 2 digits of the digest>/<digest>, with compressedSuffix
+// if it is compressed.
+type ObjectBlobStore struct {
+	objects ObjectStore
+	prefix  string
+	// TempDir is the directory content is spooled to, to hash it before
+	// uploading it. If empty, os.TempDir is used.
+	TempDir string
+	// Compressor, if not nil, compresses the uploaded content, like the
+	// Compressor of a DirBlobStore.
+	Compressor Compressor
+}
+
+// NewObjectBlobStore creates a BlobStore in objects, under prefix.
+func NewObjectBlobStore(objects ObjectStore, prefix string) *ObjectBlobStore {
+	return &ObjectBlobStore{objects: objects, prefix: prefix}
+}
+
+func (s *ObjectBlobStore) key(digest string) string {
+	return path.Join(s.prefix, digest[:2], digest)
+}
+
+// Put implements BlobStore.
+func (s *ObjectBlobStore) Put(ctx context.Context, r io.Reader) (BlobRef, error) {
+	f, ref, err := spool(s.TempDir, r)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	digest, _ := ref.digest()
+	key := s.key(digest)
+	for _, key := range []string{key, key + compressedSuffix} {
+		ok, err := s.objects.Exists(ctx, key)
+		if err != nil {
+			return "", fmt.Errorf("error checking %s: %w", key, err)
+		}
+		if ok {
+			return ref, nil
+		}
+	}
+	if s.Compressor != nil {
+		key += compressedSuffix
+		zf, err := spoolCompressed(s.TempDir, s.Compressor, f)
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(zf.Name())
+		defer zf.Close()
+		f = zf
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if err := s.objects.Put(ctx, key, f, fi.Size()); err != nil {
+		return "", fmt.Errorf("error writing %s: %w", key, err)
+	}
+	return ref, nil
+}
+
+// Open implements BlobStore.
+func (s *ObjectBlobStore) Open(ctx context.Context, ref BlobRef) (io.ReadCloser, error) {
+	digest, err := ref.digest()
+	if err != nil {
+		return nil, err
+	}
+	key := s.key(digest)
+	ok, err := s.objects.Exists(ctx, key+compressedSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("error checking %s: %w", key+compressedSuffix, err)
+	}
+	if !ok {
+		return s.objects.Get(ctx, key)
+	}
+	rc, err := s.objects.Get(ctx, key+compressedSuffix)
+	if err != nil {
+		return nil, err
+	}
+	return decompress(s.Compressor, rc)
+}
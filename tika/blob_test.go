@@ -0,0 +1,173 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memObjects is an in-memory ObjectStore.
+type memObjects struct {
+	objects map[string][]byte
+	puts    int
+}
+
+func (m *memObjects) Exists(_ context.Context, key string) (bool, error) {
+	_, ok := m.objects[key]
+	return ok, nil
+}
+
+func (m *memObjects) Put(_ context.Context, key string, r io.Reader, size int64) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if int64(len(b)) != size {
+		return io.ErrUnexpectedEOF
+	}
+	m.objects[key] = b
+	m.puts++
+	return nil
+}
+
+func (m *memObjects) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	b, ok := m.objects[key]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// SHA-256 of "hello".
+const helloRef = BlobRef("sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+
+func testBlobStore(t *testing.T, name string, s BlobStore) {
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		ref, err := s.Put(ctx, strings.NewReader("hello"))
+		if err != nil {
+			t.Fatalf("%s: Put got error: %v", name, err)
+		}
+		if ref != helloRef {
+			t.Errorf("%s: Put returned %q, want %q", name, ref, helloRef)
+		}
+	}
+	rc, err := s.Open(ctx, helloRef)
+	if err != nil {
+		t.Fatalf("%s: Open got error: %v", name, err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != "hello" {
+		t.Errorf("%s: Open read %q, want %q", name, b, "hello")
+	}
+	for _, ref := range []BlobRef{"", "hello", "sha256:zz", helloRef[len(blobRefPrefix):]} {
+		if _, err := s.Open(ctx, ref); err == nil {
+			t.Errorf("%s: Open(%q) got no error", name, ref)
+		}
+	}
+}
+
+func TestDirBlobStore(t *testing.T) {
+	dir := tempDir(t)
+	s, err := NewDirBlobStore(dir)
+	if err != nil {
+		t.Fatalf("NewDirBlobStore got error: %v", err)
+	}
+	testBlobStore(t, "DirBlobStore", s)
+	if got, want := remaining(t, dir), []string{"2c", "2c/" + string(helloRef[len(blobRefPrefix):])}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("DirBlobStore left %v, want %v", got, want)
+	}
+}
+
+func TestDirBlobStoreEncrypted(t *testing.T) {
+	dir := tempDir(t)
+	s, err := NewDirBlobStore(dir)
+	if err != nil {
+		t.Fatalf("NewDirBlobStore got error: %v", err)
+	}
+	s.Keys = testKey
+	testBlobStore(t, "encrypted DirBlobStore", s)
+	raw, err := ioutil.ReadFile(filepath.Join(dir, "2c", string(helloRef[len(blobRefPrefix):])))
+	if err != nil || bytes.Contains(raw, []byte("hello")) {
+		t.Errorf("encrypted DirBlobStore stored %q, %v, want encrypted content", raw, err)
+	}
+}
+
+func TestDirBlobStoreCompressed(t *testing.T) {
+	for _, keys := range []KeyProvider{nil, testKey} {
+		dir := tempDir(t)
+		s, err := NewDirBlobStore(dir)
+		if err != nil {
+			t.Fatalf("NewDirBlobStore got error: %v", err)
+		}
+		s.Keys = keys
+		s.Compressor = GzipCompressor(0)
+		testBlobStore(t, "compressed DirBlobStore", s)
+		if got, want := remaining(t, dir), []string{"2c", "2c/" + string(helloRef[len(blobRefPrefix):]) + compressedSuffix}; strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("compressed DirBlobStore left %v, want %v", got, want)
+		}
+		raw, err := ReadFileEncrypted(context.Background(), keys, filepath.Join(dir, "2c", string(helloRef[len(blobRefPrefix):])+compressedSuffix))
+		if err != nil || !bytes.HasPrefix(raw, compressedMagic) {
+			t.Errorf("compressed DirBlobStore stored %q, %v, want compressed content", raw, err)
+		}
+	}
+}
+
+func TestObjectBlobStoreCompressed(t *testing.T) {
+	objects := &memObjects{objects: map[string][]byte{}}
+	s := NewObjectBlobStore(objects, "blobs")
+	s.TempDir = tempDir(t)
+	s.Compressor = GzipCompressor(0)
+	testBlobStore(t, "compressed ObjectBlobStore", s)
+	if raw := objects.objects["blobs/2c/"+string(helloRef[len(blobRefPrefix):])+compressedSuffix]; !bytes.HasPrefix(raw, compressedMagic) {
+		t.Errorf("compressed ObjectBlobStore wrote %q, want compressed content", raw)
+	}
+}
+
+func TestObjectBlobStore(t *testing.T) {
+	objects := &memObjects{objects: map[string][]byte{}}
+	s := NewObjectBlobStore(objects, "blobs")
+	s.TempDir = tempDir(t)
+	testBlobStore(t, "ObjectBlobStore", s)
+	if objects.puts != 1 {
+		t.Errorf("ObjectBlobStore wrote %d objects, want 1", objects.puts)
+	}
+	if _, ok := objects.objects["blobs/2c/"+string(helloRef[len(blobRefPrefix):])]; !ok {
+		t.Errorf("ObjectBlobStore wrote %v, want the content under its hash", objects.objects)
+	}
+}
+
+func TestStoreContent(t *testing.T) {
+	s, err := NewDirBlobStore(tempDir(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := &Document{ID: "a", Content: "hello"}
+	if err := StoreContent(context.Background(), s, doc); err != nil {
+		t.Fatalf("StoreContent got error: %v", err)
+	}
+	if doc.Content != "" || doc.ContentRef != helloRef {
+		t.Errorf("StoreContent left %+v, want the content replaced by %q", doc, helloRef)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen is the error of the calls refused by an open CircuitBreaker,
+// wrapped in a CanceledError with the CancelBreakerOpen reason.
+var ErrBreakerOpen = errors.New("circuit breaker open")
+
+// A CircuitBreaker stops the requests of a Client to a server which is down,
+// so that bulk jobs fail fast instead of sending every document to a dead
+// endpoint. After Threshold consecutive failures, network errors or 5xx
+// responses, the breaker opens and refuses requests for Cooldown. Then it lets
+// one request through: the breaker closes if it succeeds, and opens again for
+// Cooldown if it fails. The requests sent before the breaker opened do not
+// count once it did.
+//
+// A CircuitBreaker is safe for concurrent use, and may be shared by the
+// Clients of a server. Its zero value is not usable; use NewCircuitBreaker.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int       // failures is the number of consecutive failures.
+	until    time.Time // until is when an open breaker lets a probe through.
+	probing  bool      // probing is whether the probe is in flight.
+	// gen is the number of times the breaker opened. The outcome of requests
+	// allowed before the breaker last opened does not count, so a slow
+	// request succeeding does not close it without a probe.
+	gen int
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker opening after threshold
+// consecutive failures, at least 1, for cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// WithCircuitBreaker returns a ClientOption to stop the requests of the Client
+// with b, as the Middleware of b. Add it after WithRetry, so that every
+// attempt counts, or before it, so that calls failing after their retries
+// count once. The state of b is reported by the Stats of the Client.
+func WithCircuitBreaker(b *CircuitBreaker) ClientOption {
+	return func(c *Client) {
+		c.breakers = append(c.breakers, b)
+		WithMiddleware(b.Middleware())(c)
+	}
+}
+
+// Open reports whether b refuses requests.
+func (b *CircuitBreaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold && (b.probing || time.Now().Before(b.until))
+}
+
+// allow returns whether a request may be sent, whether it is the probe of an
+// open breaker, and the generation of b it was allowed in.
+func (b *CircuitBreaker) allow() (ok, probe bool, gen int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true, false, b.gen
+	}
+	if b.probing || time.Now().Before(b.until) {
+		return false, false, b.gen
+	}
+	b.probing = true
+	return true, true, b.gen
+}
+
+// done records the outcome of a request allowed by b in generation gen,
+// unless it was canceled or b opened since.
+func (b *CircuitBreaker) done(gen int, probe, canceled, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	if canceled || gen != b.gen {
+		return
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.until = time.Now().Add(b.cooldown)
+		b.gen++
+	}
+}
+
+// Middleware returns a Middleware refusing requests while b is open, with a
+// CanceledError wrapping ErrBreakerOpen, and recording the outcome of the
+// others. Requests whose Context is done do not count.
+func (b *CircuitBreaker) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ok, probe, gen := b.allow()
+			if !ok {
+				if req.Body != nil {
+					req.Body.Close()
+				}
+				statsOf(req).reject()
+				return nil, &CanceledError{Reason: CancelBreakerOpen, Err: ErrBreakerOpen}
+			}
+			resp, err := next.RoundTrip(req)
+			b.done(gen, probe, err != nil && req.Context().Err() != nil, err != nil || resp.StatusCode >= http.StatusInternalServerError)
+			return resp, err
+		})
+	}
+}
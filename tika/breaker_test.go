@@ -0,0 +1,151 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var down int32 = 1
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+	b := NewCircuitBreaker(2, 50*time.Millisecond)
+	c := NewClient(nil, ts.URL, WithCircuitBreaker(b))
+	parse := func() error {
+		_, err := c.Parse(context.Background(), strings.NewReader("doc"))
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := parse(); err == nil || errors.Is(err, ErrBreakerOpen) {
+			t.Fatalf("Parse %d of a down server got error %v, want a TikaError", i, err)
+		}
+	}
+	if !b.Open() {
+		t.Fatalf("breaker not open after 2 failures")
+	}
+	err := parse()
+	if reason, _ := CancelReasonOf(err); !errors.Is(err, ErrBreakerOpen) || reason != CancelBreakerOpen {
+		t.Errorf("Parse with an open breaker got error %v, want ErrBreakerOpen canceled for %q", err, CancelBreakerOpen)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("server got %d requests, want 2, none while the breaker is open", n)
+	}
+
+	// The probe after the cooldown fails, so the breaker opens again.
+	time.Sleep(60 * time.Millisecond)
+	if err := parse(); err == nil || errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("probe of a down server got error %v, want a TikaError", err)
+	}
+	if err := parse(); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("Parse after a failed probe got error %v, want ErrBreakerOpen", err)
+	}
+	if s := c.Stats(); !s.BreakerOpen || s.BreakerRejections != 2 {
+		t.Errorf("Stats() = %+v, want an open breaker and 2 rejections", s)
+	}
+
+	// The probe succeeds, closing the breaker.
+	atomic.StoreInt32(&down, 0)
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if err := parse(); err != nil {
+			t.Errorf("Parse %d of a server back up got error %v", i, err)
+		}
+	}
+	if b.Open() || c.Stats().BreakerOpen {
+		t.Errorf("breaker open after the server came back up")
+	}
+}
+
+func TestCircuitBreakerCounts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tika":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case "/version":
+			time.Sleep(50 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+	b := NewCircuitBreaker(1, time.Hour)
+	c := NewClient(nil, ts.URL, WithCircuitBreaker(b))
+	for i := 0; i < 3; i++ {
+		c.Parse(context.Background(), nil)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	c.Version(ctx)
+	if b.Open() {
+		t.Fatalf("breaker opened after 422 responses and a canceled call")
+	}
+	if _, err := c.Detect(context.Background(), nil); errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("Detect got error %v, want the 500 response", err)
+	}
+	if _, err := c.Version(context.Background()); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("Version after a 500 response got error %v, want ErrBreakerOpen", err)
+	}
+}
+
+func TestCircuitBreakerStaleSuccess(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/version" {
+			close(started)
+			<-release
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+	b := NewCircuitBreaker(1, time.Hour)
+	c := NewClient(nil, ts.URL, WithCircuitBreaker(b))
+
+	// A slow call sent before the breaker opened succeeds after it did.
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.Version(context.Background())
+		errc <- err
+	}()
+	<-started
+	c.Parse(context.Background(), nil)
+	if !b.Open() {
+		t.Fatalf("breaker not open after a 500 response")
+	}
+	close(release)
+	if err := <-errc; err != nil {
+		t.Fatalf("slow Version got error: %v", err)
+	}
+	if !b.Open() {
+		t.Errorf("breaker closed by a call sent before it opened")
+	}
+}
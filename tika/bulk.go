@@ -0,0 +1,208 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// A BulkResult is the result of parsing one of the inputs of ParseAll.
+type BulkResult struct {
+	// Content is the extracted text, undefined if Err is not nil.
+	Content string
+	// Err is the error parsing the input, or the error of the context if the
+	// input was not parsed before it was done.
+	Err error
+}
+
+// ParseAll parses inputs with c, at most concurrency at a time, and returns
+// their results in the order of inputs. 1 is used if concurrency is less than
+// 1. Once ctx is done, no more inputs are parsed: the results of the inputs
+// not parsed yet hold the error of ctx, which is also returned. ParseAll
+// returns once every started parse has returned.
+func ParseAll(ctx context.Context, c Service, inputs []io.Reader, concurrency int, opts ...RequestOption) ([]BulkResult, error) {
+	results := make([]BulkResult, len(inputs))
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(inputs) {
+		concurrency = len(inputs)
+	}
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[i].Content, results[i].Err = c.Parse(ctx, inputs[i], opts...)
+			}
+		}()
+	}
+	next := 0
+loop:
+	for ; next < len(inputs); next++ {
+		select {
+		case queue <- next:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(queue)
+	wg.Wait()
+	for ; next < len(inputs); next++ {
+		results[next].Err = ctx.Err()
+	}
+	return results, ctx.Err()
+}
+
+// A FileResult is the result of parsing a file with ParseFS.
+type FileResult struct {
+	// Path is the slash-separated path of the file, relative to the root of
+	// the fs.FS or directory.
+	Path string
+	// Content is the extracted text, undefined if Err is not nil.
+	Content string
+	// Err is the error opening or parsing the file.
+	Err error
+}
+
+// matchFile reports whether the file at p matches one of patterns. Patterns
+// with a "/" match the whole path, and the others match the file name, so
+// "*.pdf" matches PDF files in every directory. Every file matches no
+// patterns.
+func matchFile(patterns []string, p string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		name := path.Base(p)
+		if strings.Contains(pattern, "/") {
+			name = p
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFS parses the regular files of fsys matching one of patterns, as
+// defined by path.Match, with c, at most concurrency at a time, and calls fn
+// with their results, one call at a time, in no particular order. Patterns
+// with a "/" match the path of the files, and the others match their name, so
+// "*.pdf" matches PDF files in every directory. Every file is parsed if there
+// are no patterns. The name of each file is sent with WithResourceName.
+//
+// Errors opening or parsing a file are in its FileResult. ParseFS stops at the
+// first error listing the files or returned by fn, or once ctx is done.
+func ParseFS(ctx context.Context, c Service, fsys fs.FS, patterns []string, concurrency int, fn func(FileResult) error, opts ...RequestOption) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	src := NewFSSource(fsys)
+	accept := func(in Input) bool { return matchFile(patterns, in.ID) }
+	listErr, err := fanOut(ctx, src, concurrency, accept, func(ctx context.Context, in Input) func() error {
+		r := FileResult{Path: in.ID}
+		if f, err := src.Open(ctx, in.ID); err != nil {
+			r.Err = err
+		} else {
+			r.Content, r.Err = c.Parse(ctx, f, append(opts[:len(opts):len(opts)], WithResourceName(in.Name))...)
+			f.Close()
+		}
+		return func() error { return fn(r) }
+	})
+	if listErr != nil {
+		return fmt.Errorf("error listing files: %w", listErr)
+	}
+	return err
+}
+
+// fanOut walks src and processes the Inputs accepted by accept, at most
+// workers at a time. The function returned by process for each Input is
+// then called, one call at a time, unless ctx is done.
+//
+// fanOut stops at the first error returned by such a function, which is
+// returned as err, or once ctx is done. An error walking src is returned as
+// listErr, unless ctx is done.
+func fanOut(ctx context.Context, src Source, workers int, accept func(Input) bool, process func(context.Context, Input) func() error) (listErr, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if workers < 1 {
+		workers = 1
+	}
+	queue := make(chan Input)
+	var mu sync.Mutex
+	var fnErr error
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for in := range queue {
+				done := process(ctx, in)
+				if ctx.Err() != nil {
+					continue
+				}
+				mu.Lock()
+				if fnErr == nil {
+					if fnErr = done(); fnErr != nil {
+						cancel()
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	walkErr := src.Walk(ctx, func(in Input) error {
+		if !accept(in) {
+			return nil
+		}
+		select {
+		case queue <- in:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(queue)
+	wg.Wait()
+	if fnErr != nil {
+		return nil, fnErr
+	}
+	if walkErr != nil && ctx.Err() == nil {
+		return walkErr, nil
+	}
+	return nil, ctx.Err()
+}
+
+// ParseDir is ParseFS of the files in the directory dir.
+func ParseDir(ctx context.Context, c Service, dir string, patterns []string, concurrency int, fn func(FileResult) error, opts ...RequestOption) error {
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	return ParseFS(ctx, c, os.DirFS(dir), patterns, concurrency, fn, opts...)
+}
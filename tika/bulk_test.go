@@ -0,0 +1,274 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseAll(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if string(b) == "bad" {
+			http.Error(w, "bad input", http.StatusUnprocessableEntity)
+			return
+		}
+		fmt.Fprintf(w, "parsed %s", b)
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+
+	var inputs []io.Reader
+	for i := 0; i < 10; i++ {
+		inputs = append(inputs, strings.NewReader(fmt.Sprint(i)))
+	}
+	inputs[3] = strings.NewReader("bad")
+	got, err := ParseAll(context.Background(), c, inputs, 3)
+	if err != nil {
+		t.Fatalf("ParseAll returned an error: %v", err)
+	}
+	if len(got) != len(inputs) {
+		t.Fatalf("ParseAll returned %d results, want %d", len(got), len(inputs))
+	}
+	for i, r := range got {
+		if i == 3 {
+			var terr *TikaError
+			if !errors.As(r.Err, &terr) || terr.StatusCode != http.StatusUnprocessableEntity {
+				t.Errorf("result %d: got error %v, want status %d", i, r.Err, http.StatusUnprocessableEntity)
+			}
+			continue
+		}
+		if want := fmt.Sprintf("parsed %d", i); r.Err != nil || r.Content != want {
+			t.Errorf("result %d: got (%q, %v), want %q", i, r.Content, r.Err, want)
+		}
+	}
+}
+
+func TestParseAllConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		started <- struct{}{}
+		<-release
+		mu.Lock()
+		active--
+		mu.Unlock()
+		fmt.Fprint(w, "ok")
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	inputs := make([]io.Reader, 6)
+	for i := range inputs {
+		inputs[i] = strings.NewReader("x")
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := ParseAll(context.Background(), c, inputs, 2); err != nil {
+			t.Errorf("ParseAll returned an error: %v", err)
+		}
+	}()
+	<-started
+	<-started
+	close(release)
+	<-done
+	if maxActive != 2 {
+		t.Errorf("ParseAll ran %d parses at once, want 2", maxActive)
+	}
+}
+
+func TestParseAllCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	inputs := []io.Reader{strings.NewReader("a"), strings.NewReader("b"), strings.NewReader("c")}
+	got, err := ParseAll(ctx, c, inputs, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ParseAll returned error %v, want %v", err, context.Canceled)
+	}
+	for i, r := range got {
+		if r.Err == nil {
+			t.Errorf("result %d: got no error, want an error", i)
+		}
+	}
+	if !errors.Is(got[2].Err, context.Canceled) {
+		t.Errorf("result 2: got error %v, want %v", got[2].Err, context.Canceled)
+	}
+}
+
+func TestParseAllEmpty(t *testing.T) {
+	got, err := ParseAll(context.Background(), NewClient(nil, "http://unused"), nil, 4)
+	if err != nil || len(got) != 0 {
+		t.Errorf("ParseAll(nil) = %v, %v, want no results and no error", got, err)
+	}
+}
+
+func TestMatchFile(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{nil, "a/b.txt", true},
+		{[]string{"*.pdf"}, "a/b.pdf", true},
+		{[]string{"*.pdf"}, "a/b.txt", false},
+		{[]string{"*.doc", "*.txt"}, "b.txt", true},
+		{[]string{"a/*.txt"}, "a/b.txt", true},
+		{[]string{"a/*.txt"}, "c/a/b.txt", false},
+	}
+	for _, test := range tests {
+		if got := matchFile(test.patterns, test.path); got != test.want {
+			t.Errorf("matchFile(%q, %q) = %v, want %v", test.patterns, test.path, got, test.want)
+		}
+	}
+}
+
+// nameServer returns the content sent to it, after the file name of its
+// Content-Disposition. Content "bad" is rejected.
+func nameServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if string(b) == "bad" {
+			http.Error(w, "bad input", http.StatusUnprocessableEntity)
+			return
+		}
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Disposition"))
+		fmt.Fprintf(w, "%s:%s", params["filename"], b)
+	}))
+}
+
+func TestParseFS(t *testing.T) {
+	ts := nameServer()
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	fsys := fstest.MapFS{
+		"a.txt":       {Data: []byte("A")},
+		"b.pdf":       {Data: []byte("B")},
+		"sub/c.txt":   {Data: []byte("C")},
+		"sub/bad.txt": {Data: []byte("bad")},
+		"sub/d.doc":   {Data: []byte("D")},
+	}
+	tests := []struct {
+		patterns []string
+		want     []string
+	}{
+		{nil, []string{"a.txt=a.txt:A", "b.pdf=b.pdf:B", "sub/bad.txt=error", "sub/c.txt=c.txt:C", "sub/d.doc=d.doc:D"}},
+		{[]string{"*.txt"}, []string{"a.txt=a.txt:A", "sub/bad.txt=error", "sub/c.txt=c.txt:C"}},
+		{[]string{"sub/*.doc", "*.pdf"}, []string{"b.pdf=b.pdf:B", "sub/d.doc=d.doc:D"}},
+		{[]string{"*.xls"}, nil},
+	}
+	for _, test := range tests {
+		var got []string
+		err := ParseFS(context.Background(), c, fsys, test.patterns, 3, func(r FileResult) error {
+			if r.Err != nil {
+				got = append(got, r.Path+"=error")
+			} else {
+				got = append(got, r.Path+"="+r.Content)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ParseFS(%q) got error: %v", test.patterns, err)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseFS(%q) got %q, want %q", test.patterns, got, test.want)
+		}
+	}
+	if err := ParseFS(context.Background(), c, fsys, []string{"["}, 1, func(FileResult) error { return nil }); err == nil {
+		t.Errorf("ParseFS with an invalid pattern got no error")
+	}
+}
+
+func TestParseFSStop(t *testing.T) {
+	ts := nameServer()
+	defer ts.Close()
+	fsys := fstest.MapFS{}
+	for i := 0; i < 20; i++ {
+		fsys[fmt.Sprintf("%02d.txt", i)] = &fstest.MapFile{Data: []byte("x")}
+	}
+	stop := errors.New("stop")
+	calls := 0
+	err := ParseFS(context.Background(), NewClient(nil, ts.URL), fsys, nil, 4, func(FileResult) error {
+		calls++
+		return stop
+	})
+	if err != stop {
+		t.Errorf("ParseFS got error %v, want %v", err, stop)
+	}
+	if calls != 1 {
+		t.Errorf("ParseFS called fn %d times after an error, want 1", calls)
+	}
+}
+
+func TestParseDir(t *testing.T) {
+	ts := nameServer()
+	defer ts.Close()
+	dir := tempDir(t)
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"a.txt": "A", "sub/b.txt": "B", "sub/c.bin": "C"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	err := ParseDir(context.Background(), NewClient(nil, ts.URL), dir, []string{"*.txt"}, 2, func(r FileResult) error {
+		got = append(got, r.Path+"="+r.Content)
+		return r.Err
+	})
+	if err != nil {
+		t.Fatalf("ParseDir got error: %v", err)
+	}
+	sort.Strings(got)
+	if want := []string{"a.txt=a.txt:A", "sub/b.txt=b.txt:B"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDir got %q, want %q", got, want)
+	}
+	if err := ParseDir(context.Background(), NewClient(nil, ts.URL), filepath.Join(dir, "missing"), nil, 1, func(FileResult) error { return nil }); err == nil {
+		t.Errorf("ParseDir of a missing directory got no error")
+	}
+}
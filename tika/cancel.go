@@ -0,0 +1,96 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CancelReason is why work was given up, as opposed to failed.
+type CancelReason string
+
+// CancelReasons.
+const (
+	// CancelDeadline is a context deadline, including call timeouts.
+	CancelDeadline CancelReason = "deadline exceeded"
+	// CancelShutdown is a context canceled without a reason, usually because
+	// the program is stopping.
+	CancelShutdown CancelReason = "shutdown"
+	// CancelBudget is a budget of time, documents or cost being exhausted.
+	CancelBudget CancelReason = "budget exceeded"
+	// CancelBreakerOpen is a circuit breaker refusing calls.
+	CancelBreakerOpen CancelReason = "breaker open"
+	// CancelAborted is a Job stopping after an error, such as an Emit error,
+	// canceling the inputs in flight.
+	CancelAborted CancelReason = "aborted"
+)
+
+// A CanceledError is the error of work given up for Reason. Err is the
+// underlying error, such as context.Canceled.
+type CanceledError struct {
+	Reason CancelReason
+	Err    error
+}
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("canceled (%s): %v", e.Reason, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// CancelReasonOf returns the reason err was canceled for, if it is or wraps a
+// CanceledError.
+func CancelReasonOf(err error) (CancelReason, bool) {
+	var ce *CanceledError
+	if errors.As(err, &ce) {
+		return ce.Reason, true
+	}
+	return "", false
+}
+
+// WithCancelReason returns a copy of parent and a function canceling it for
+// the given reason. Errors caused by the cancellation, such as those of the
+// Client and Job, are CanceledErrors with that reason.
+func WithCancelReason(parent context.Context) (context.Context, func(CancelReason)) {
+	ctx, cancel := context.WithCancelCause(parent)
+	return ctx, func(reason CancelReason) {
+		cancel(&CanceledError{Reason: reason, Err: context.Canceled})
+	}
+}
+
+// canceled returns err as a CanceledError if ctx is done, and err unchanged
+// otherwise.
+func canceled(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	if _, ok := CancelReasonOf(err); ok {
+		return err
+	}
+	reason := CancelShutdown
+	if r, ok := CancelReasonOf(context.Cause(ctx)); ok {
+		reason = r
+	} else if ctx.Err() == context.DeadlineExceeded {
+		reason = CancelDeadline
+	}
+	return &CanceledError{Reason: reason, Err: err}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCanceledErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tika" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL, WithEndpointTimeout(EndpointDetect, 10*time.Millisecond))
+
+	budget, cancel := WithCancelReason(context.Background())
+	cancel(CancelBudget)
+	shutdown, stop := context.WithCancel(context.Background())
+	stop()
+
+	tests := []struct {
+		name string
+		call func() error
+		want CancelReason
+	}{
+		{
+			name: "server failed",
+			call: func() error { _, err := c.Parse(context.Background(), nil); return err },
+		},
+		{
+			name: "timeout",
+			call: func() error { _, err := c.Detect(context.Background(), nil); return err },
+			want: CancelDeadline,
+		},
+		{
+			name: "budget",
+			call: func() error { _, err := c.Parse(budget, nil); return err },
+			want: CancelBudget,
+		},
+		{
+			name: "shutdown",
+			call: func() error { _, err := c.Parse(shutdown, nil); return err },
+			want: CancelShutdown,
+		},
+	}
+	for _, test := range tests {
+		err := test.call()
+		if err == nil {
+			t.Errorf("%s: got no error", test.name)
+			continue
+		}
+		reason, ok := CancelReasonOf(err)
+		if reason != test.want || ok != (test.want != "") {
+			t.Errorf("%s: CancelReasonOf(%v) = %q, %v, want %q", test.name, err, reason, ok, test.want)
+		}
+	}
+	if _, err := c.Parse(shutdown, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Parse with a canceled context got %v, want it to wrap context.Canceled", err)
+	}
+}
+
+func TestJobCanceled(t *testing.T) {
+	ts := rmetaServer()
+	defer ts.Close()
+	j, _ := testJob(ts, map[string]string{"a.txt": "a", "b.txt": "fail"})
+	j.Workers = 1
+	ctx, cancel := WithCancelReason(context.Background())
+	j.Emit = func(context.Context, Document) error {
+		cancel(CancelBreakerOpen)
+		return nil
+	}
+	err := j.Run(ctx)
+	if reason, _ := CancelReasonOf(err); reason != CancelBreakerOpen {
+		t.Errorf("Run got error %v, want a %q cancellation", err, CancelBreakerOpen)
+	}
+	s := j.Status()
+	if s.Canceled != s.Failed {
+		t.Errorf("Status got %d failed and %d canceled inputs, want all failures canceled", s.Failed, s.Canceled)
+	}
+	for _, f := range s.RecentFailures {
+		if f.Canceled != CancelBreakerOpen {
+			t.Errorf("failure %s canceled for %q, want %q", f.ID, f.Canceled, CancelBreakerOpen)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode/utf16"
+)
+
+// A TextDecoder converts a text response body in the given charset to a
+// string. charset is the lower case charset parameter of the Content-Type of
+// the response, or "" if there is none. body is reused once the TextDecoder
+// returns, so it must not be retained.
+type TextDecoder func(charset string, body []byte) (string, error)
+
+// WithTextDecoder returns a ClientOption to set the TextDecoder of text
+// responses, such as the result of Parse (default DecodeText). Use it to
+// support more charsets, for example with golang.org/x/text:
+//
+//	tika.WithTextDecoder(func(charset string, body []byte) (string, error) {
+//		e, err := htmlindex.Get(charset)
+//		if err != nil {
+//			return tika.DecodeText(charset, body)
+//		}
+//		b, err := e.NewDecoder().Bytes(body)
+//		return string(b), err
+//	})
+func WithTextDecoder(d TextDecoder) ClientOption {
+	return func(c *Client) {
+		c.textDecoder = d
+	}
+}
+
+// decodeText decodes the text response resp.
+func (c *Client) decodeText(resp *response) (string, error) {
+	var charset string
+	if _, params, err := mime.ParseMediaType(resp.header.Get("Content-Type")); err == nil {
+		charset = strings.ToLower(params["charset"])
+	}
+	d := c.textDecoder
+	if d == nil {
+		d = DecodeText
+	}
+	return d(charset, resp.body)
+}
+
+// DecodeText is the default TextDecoder. It supports UTF-8, US-ASCII,
+// ISO-8859-1, windows-1252, IBM437 and UTF-16. UTF-16 without a byte order mark is
+// assumed to be big endian. Bodies in other charsets, or without a charset,
+// are assumed to be UTF-8 and returned unchanged.
+func DecodeText(charset string, body []byte) (string, error) {
+	switch charset {
+	case "iso-8859-1", "iso_8859-1", "latin1", "l1":
+		return decodeLatin1(body, nil), nil
+	case "windows-1252", "cp1252":
+		return decodeLatin1(body, &windows1252), nil
+	case "ibm437", "cp437", "437":
+		return decodeCP437(body), nil
+	case "utf-16", "utf-16be", "utf-16le":
+		return decodeUTF16(charset, body)
+	}
+	return string(body), nil
+}
+
+// windows1252 maps the bytes 0x80 to 0x9F of windows-1252 to runes. The other
+// bytes are the same as in ISO-8859-1.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// decodeLatin1 decodes body as ISO-8859-1, using high for the bytes 0x80 to
+// 0x9F if it is not nil.
+func decodeLatin1(body []byte, high *[32]rune) string {
+	var b strings.Builder
+	b.Grow(len(body))
+	for _, c := range body {
+		if high != nil && c >= 0x80 && c < 0xA0 {
+			b.WriteRune(high[c-0x80])
+			continue
+		}
+		b.WriteRune(rune(c))
+	}
+	return b.String()
+}
+
+// cp437 are the runes of the bytes 0x80 to 0xFF of IBM437, the charset of the
+// names in legacy ZIP files. The other bytes are the same as in US-ASCII.
+var cp437 = []rune("ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒáíóúñÑªº¿⌐¬½¼¡«»" +
+	"░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" +
+	"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0")
+
+// decodeCP437 decodes body as IBM437.
+func decodeCP437(body []byte) string {
+	var b strings.Builder
+	b.Grow(len(body))
+	for _, c := range body {
+		if c >= 0x80 {
+			b.WriteRune(cp437[c-0x80])
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// decodeUTF16 decodes body as UTF-16. A byte order mark overrides the
+// endianness of charset.
+func decodeUTF16(charset string, body []byte) (string, error) {
+	if len(body)%2 != 0 {
+		return "", fmt.Errorf("invalid %s body: odd length %d", charset, len(body))
+	}
+	bigEndian := charset != "utf-16le"
+	switch {
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+		bigEndian, body = true, body[2:]
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		bigEndian, body = false, body[2:]
+	}
+	u := make([]uint16, len(body)/2)
+	for i := range u {
+		if bigEndian {
+			u[i] = uint16(body[2*i])<<8 | uint16(body[2*i+1])
+		} else {
+			u[i] = uint16(body[2*i+1])<<8 | uint16(body[2*i])
+		}
+	}
+	return string(utf16.Decode(u)), nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		charset string
+		body    []byte
+		want    string
+	}{
+		{"", []byte("café"), "café"},
+		{"utf-8", []byte("café"), "café"},
+		{"unknown", []byte("café"), "café"},
+		{"iso-8859-1", []byte{'c', 'a', 'f', 0xE9}, "café"},
+		{"windows-1252", []byte{0x93, 'h', 'i', 0x94, ' ', 0x80}, "“hi” €"},
+		{"utf-16be", []byte{0, 'h', 0, 0xE9}, "hé"},
+		{"utf-16le", []byte{'h', 0, 0xE9, 0}, "hé"},
+		{"utf-16", []byte{0, 'h'}, "h"},
+		{"utf-16", []byte{0xFF, 0xFE, 'h', 0}, "h"},
+		{"utf-16le", []byte{0xFE, 0xFF, 0, 'h'}, "h"},
+		{"utf-16", []byte{0xD8, 0x3D, 0xDE, 0x00}, "😀"},
+		{"ibm437", []byte{'c', 'a', 'f', 0x82, ' ', 0xE1, 0xFF}, "café ß\u00a0"},
+	}
+	for _, test := range tests {
+		got, err := DecodeText(test.charset, test.body)
+		if err != nil {
+			t.Errorf("DecodeText(%q, %v) got error: %v", test.charset, test.body, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("DecodeText(%q, %v) = %q, want %q", test.charset, test.body, got, test.want)
+		}
+	}
+	if len(cp437) != 0x80 {
+		t.Errorf("cp437 has %d runes, want 128", len(cp437))
+	}
+	if _, err := DecodeText("utf-16", []byte{0}); err == nil {
+		t.Errorf("DecodeText of odd length UTF-16 got no error, want an error")
+	}
+}
+
+func TestParseCharset(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=ISO-8859-1")
+		w.Write([]byte{'c', 'a', 'f', 0xE9})
+	}))
+	defer ts.Close()
+
+	got, err := NewClient(nil, ts.URL).Parse(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+	if want := "café"; got != want {
+		t.Errorf("Parse got %q, want %q", got, want)
+	}
+
+	upper := func(charset string, body []byte) (string, error) {
+		return strings.ToUpper(charset), nil
+	}
+	got, err = NewClient(nil, ts.URL, WithTextDecoder(upper)).Parse(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Parse with a TextDecoder returned an error: %v", err)
+	}
+	if want := "ISO-8859-1"; got != want {
+		t.Errorf("Parse with a TextDecoder got %q, want %q", got, want)
+	}
+}
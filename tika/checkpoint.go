@@ -0,0 +1,216 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Checkpoint records the state of an input when it was last extracted.
+type Checkpoint struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	// Hash is the hex encoded SHA-256 of the content of the input.
+	Hash string `json:"hash"`
+	// ContentType is the MIME type Tika detected for the input.
+	ContentType string `json:"contentType,omitempty"`
+	// Extracted is when the input was extracted.
+	Extracted time.Time `json:"extracted,omitempty"`
+	// ErrorClass lists the kinds of errors Tika reported in the metadata of
+	// an input it only extracted partially, separated by commas, such as
+	// "embedded_exception" for a field X-TIKA:EXCEPTION:embedded_exception.
+	ErrorClass string `json:"errorClass,omitempty"`
+	// Reprocess marks the input to be extracted again by the next run even
+	// if it did not change. See MarkForReprocessing.
+	Reprocess bool `json:"reprocess,omitempty"`
+}
+
+// tikaExceptionPrefix prefixes the metadata fields of the errors Tika
+// encountered while parsing a document.
+const tikaExceptionPrefix = "X-TIKA:EXCEPTION:"
+
+// errorClass returns the ErrorClass of the metadata m.
+func errorClass(m map[string][]string) string {
+	var classes []string
+	for k := range m {
+		if strings.HasPrefix(k, tikaExceptionPrefix) {
+			classes = append(classes, strings.TrimPrefix(k, tikaExceptionPrefix))
+		}
+	}
+	sort.Strings(classes)
+	return strings.Join(classes, ",")
+}
+
+// A ReprocessFilter selects the Checkpoints to mark for reprocessing. A
+// Checkpoint must match every field which is set; the zero ReprocessFilter
+// matches all of them.
+type ReprocessFilter struct {
+	// ContentTypes are MIME types or patterns, as defined by path.Match, such
+	// as "image/*".
+	ContentTypes []string
+	// ErrorClasses are error classes, as in Checkpoint.ErrorClass, or "*" for
+	// any error. A Checkpoint matches if it has one of them.
+	ErrorClasses []string
+	// ExtractedAfter and ExtractedBefore bound the time the inputs were
+	// extracted. Checkpoints recorded without an extraction time never match
+	// a time range.
+	ExtractedAfter, ExtractedBefore time.Time
+}
+
+func (f ReprocessFilter) match(c Checkpoint) bool {
+	if len(f.ContentTypes) > 0 && !matchAny(f.ContentTypes, c.ContentType) {
+		return false
+	}
+	if len(f.ErrorClasses) > 0 {
+		found := false
+		for _, class := range strings.Split(c.ErrorClass, ",") {
+			if class != "" && matchAny(f.ErrorClasses, class) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if (!f.ExtractedAfter.IsZero() || !f.ExtractedBefore.IsZero()) && c.Extracted.IsZero() {
+		return false
+	}
+	if !f.ExtractedAfter.IsZero() && !c.Extracted.After(f.ExtractedAfter) {
+		return false
+	}
+	if !f.ExtractedBefore.IsZero() && !c.Extracted.Before(f.ExtractedBefore) {
+		return false
+	}
+	return true
+}
+
+// matchAny reports whether s matches one of the path.Match patterns.
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// A CheckpointStore records the Checkpoints of the inputs extracted by a Job,
+// by input ID, so that later runs only extract the inputs which changed. It is
+// kept in memory and saved to a JSON file.
+//
+// A CheckpointStore is safe for concurrent use.
+type CheckpointStore struct {
+	path string
+	keys KeyProvider
+
+	mu          sync.Mutex
+	checkpoints map[string]Checkpoint
+}
+
+// OpenCheckpointStore opens the CheckpointStore saved at path, or creates an
+// empty one if there is no file at path. If keys is not nil, the file is
+// encrypted with it, as by WriteFileEncrypted.
+func OpenCheckpointStore(ctx context.Context, path string, keys KeyProvider) (*CheckpointStore, error) {
+	s := &CheckpointStore{path: path, keys: keys, checkpoints: map[string]Checkpoint{}}
+	data, err := ReadFileEncrypted(ctx, keys, path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoints: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.checkpoints); err != nil {
+		return nil, fmt.Errorf("error reading checkpoints: %w", err)
+	}
+	return s, nil
+}
+
+// Get returns the Checkpoint of the input with the ID, and whether there is
+// one.
+func (s *CheckpointStore) Get(id string) (Checkpoint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.checkpoints[id]
+	return c, ok
+}
+
+// Put records the Checkpoint of the input with the ID.
+func (s *CheckpointStore) Put(id string, c Checkpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[id] = c
+}
+
+// MarkForReprocessing marks the Checkpoints matching f for reprocessing, for
+// example after upgrading Tika, and returns how many it marked. The next run
+// of a Job extracts the marked inputs again, and only them among the
+// unchanged inputs; their Checkpoints are kept until then. The marks are
+// saved with the Checkpoints.
+func (s *CheckpointStore) MarkForReprocessing(f ReprocessFilter) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, c := range s.checkpoints {
+		if !c.Reprocess && f.match(c) {
+			c.Reprocess = true
+			s.checkpoints[id] = c
+			n++
+		}
+	}
+	return n
+}
+
+// Len returns the number of Checkpoints.
+func (s *CheckpointStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.checkpoints)
+}
+
+// Retain removes the Checkpoints of the inputs whose ID is not in ids, such as
+// deleted files.
+func (s *CheckpointStore) Retain(ids map[string]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.checkpoints {
+		if !ids[id] {
+			delete(s.checkpoints, id)
+		}
+	}
+}
+
+// Save writes the Checkpoints to the file of the store.
+func (s *CheckpointStore) Save(ctx context.Context) error {
+	s.mu.Lock()
+	data, err := json.Marshal(s.checkpoints)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := WriteFileEncrypted(ctx, s.keys, s.path, data, 0600); err != nil {
+		return fmt.Errorf("error saving checkpoints: %w", err)
+	}
+	return nil
+}
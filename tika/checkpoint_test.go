@@ -0,0 +1,111 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckpointStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(tempDir(t), "checkpoints.json")
+	// The plaintext store is migrated by AllowPlaintext, then read sealed.
+	for _, keys := range []KeyProvider{nil, AllowPlaintext{testKey}, testKey} {
+		s, err := OpenCheckpointStore(ctx, path, keys)
+		if err != nil {
+			t.Fatalf("OpenCheckpointStore(%v) of a missing file got error: %v", keys, err)
+		}
+		c := Checkpoint{Size: 3, ModTime: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), Hash: "abc"}
+		s.Put("a", c)
+		s.Put("b", c)
+		s.Retain(map[string]bool{"a": true})
+		if err := s.Save(ctx); err != nil {
+			t.Fatalf("Save got error: %v", err)
+		}
+		if raw, _ := ioutil.ReadFile(path); keys != nil && bytes.Contains(raw, []byte("abc")) {
+			t.Errorf("Save with keys wrote plaintext %q", raw)
+		}
+
+		s, err = OpenCheckpointStore(ctx, path, keys)
+		if err != nil {
+			t.Fatalf("OpenCheckpointStore(%v) got error: %v", keys, err)
+		}
+		if got, ok := s.Get("a"); !ok || got != c {
+			t.Errorf("Get(a) = %+v, %v, want %+v", got, ok, c)
+		}
+		if _, ok := s.Get("b"); ok || s.Len() != 1 {
+			t.Errorf("Retain kept b, want only a")
+		}
+	}
+}
+
+func TestMarkForReprocessing(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2017, 1, d, 0, 0, 0, 0, time.UTC) }
+	checkpoints := map[string]Checkpoint{
+		"pdf":     {ContentType: "application/pdf", Extracted: day(1)},
+		"png":     {ContentType: "image/png", Extracted: day(2)},
+		"broken":  {ContentType: "application/pdf", Extracted: day(3), ErrorClass: "embedded_exception,warn"},
+		"unknown": {ContentType: "text/plain"},
+	}
+	tests := []struct {
+		name   string
+		filter ReprocessFilter
+		want   []string
+	}{
+		{name: "all", want: []string{"broken", "pdf", "png", "unknown"}},
+		{name: "type", filter: ReprocessFilter{ContentTypes: []string{"image/*", "text/plain"}}, want: []string{"png", "unknown"}},
+		{name: "any error", filter: ReprocessFilter{ErrorClasses: []string{"*"}}, want: []string{"broken"}},
+		{name: "error class", filter: ReprocessFilter{ErrorClasses: []string{"warn"}}, want: []string{"broken"}},
+		{name: "other error class", filter: ReprocessFilter{ErrorClasses: []string{"runtime"}}},
+		{name: "time range", filter: ReprocessFilter{ExtractedAfter: day(1), ExtractedBefore: day(3)}, want: []string{"png"}},
+		{
+			name:   "type and time",
+			filter: ReprocessFilter{ContentTypes: []string{"application/pdf"}, ExtractedBefore: day(2)},
+			want:   []string{"pdf"},
+		},
+	}
+	for _, test := range tests {
+		s, err := OpenCheckpointStore(context.Background(), filepath.Join(tempDir(t), "missing.json"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for id, c := range checkpoints {
+			s.Put(id, c)
+		}
+		if n := s.MarkForReprocessing(test.filter); n != len(test.want) {
+			t.Errorf("MarkForReprocessing(%s) = %d, want %d", test.name, n, len(test.want))
+		}
+		var got []string
+		for _, id := range []string{"broken", "pdf", "png", "unknown"} {
+			if c, _ := s.Get(id); c.Reprocess {
+				got = append(got, id)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("MarkForReprocessing(%s) marked %v, want %v", test.name, got, test.want)
+		}
+		if n := s.MarkForReprocessing(test.filter); n != 0 {
+			t.Errorf("MarkForReprocessing(%s) again = %d, want 0", test.name, n)
+		}
+	}
+}
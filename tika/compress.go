@@ -0,0 +1,282 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A Compressor compresses stored content, such as the extracted text in a
+// BlobStore, which typically compresses 5 to 10 times. GzipCompressor and
+// DictCompressor are provided; other formats, such as zstd, can be used by
+// implementing Compressor with their library.
+type Compressor interface {
+	// Name identifies the format, and its parameters needed to decompress,
+	// such as a dictionary. It is recorded with the compressed content, which
+	// is only decompressed by a Compressor of the same Name.
+	Name() string
+	// NewWriter returns a writer compressing to w. The content is complete
+	// once the writer is closed, which does not close w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader decompressing r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// GzipCompressor is a Compressor in the gzip format, with the compression
+// level, such as gzip.BestCompression. 0 means gzip.DefaultCompression.
+type GzipCompressor int
+
+// Name implements Compressor.
+func (GzipCompressor) Name() string {
+	return "gzip"
+}
+
+// NewWriter implements Compressor.
+func (c GzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if c == 0 {
+		return gzip.NewWriter(w), nil
+	}
+	return gzip.NewWriterLevel(w, int(c))
+}
+
+// NewReader implements Compressor.
+func (GzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// DictCompressor is a Compressor in the DEFLATE format with a preset
+// dictionary, which compresses much better than GzipCompressor when the
+// content is small or shares a vocabulary, such as documents of the same
+// corpus. Use TrainDictionary to build the dictionary from a sample of the
+// corpus. Content compressed with a dictionary can only be decompressed with
+// the same dictionary, so it must be kept as long as the content.
+type DictCompressor struct {
+	dict  []byte
+	level int
+	name  string
+}
+
+// NewDictCompressor returns a DictCompressor with dict, of which only the last
+// 32 KiB are used, and the compression level, such as flate.BestCompression.
+// 0 means flate.DefaultCompression.
+func NewDictCompressor(dict []byte, level int) (*DictCompressor, error) {
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return nil, fmt.Errorf("invalid compression level %d", level)
+	}
+	if len(dict) > maxDictSize {
+		dict = dict[len(dict)-maxDictSize:]
+	}
+	sum := sha256.Sum256(dict)
+	return &DictCompressor{
+		dict:  append([]byte{}, dict...),
+		level: level,
+		name:  "deflate-dict:" + hex.EncodeToString(sum[:8]),
+	}, nil
+}
+
+// maxDictSize is the size of the DEFLATE window, past which a dictionary is
+// not used.
+const maxDictSize = 32 << 10
+
+// Name implements Compressor. It includes the hash of the dictionary.
+func (c *DictCompressor) Name() string {
+	return c.name
+}
+
+// NewWriter implements Compressor.
+func (c *DictCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriterDict(w, c.level, c.dict)
+}
+
+// NewReader implements Compressor.
+func (c *DictCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReaderDict(r, c.dict), nil
+}
+
+// TrainDictionary returns a dictionary of at most size bytes for
+// NewDictCompressor, made of the passages most shared by samples, such as the
+// extracted text of a few hundred documents of a corpus. Passages found in a
+// single sample are not used, so the dictionary may be smaller than size, or
+// empty. size is capped at 32 KiB.
+func TrainDictionary(samples []string, size int) []byte {
+	if size > maxDictSize {
+		size = maxDictSize
+	}
+	const segLen = 32
+	type segment struct {
+		s       string
+		samples int
+		last    int
+	}
+	segs := map[string]*segment{}
+	for i, sample := range samples {
+		for j := 0; j < len(sample); j++ {
+			// Passages start at words, which keeps the number of
+			// candidates down.
+			if j > 0 && sample[j-1] != ' ' && sample[j-1] != '\n' {
+				continue
+			}
+			end := j + segLen
+			if end > len(sample) {
+				end = len(sample)
+			}
+			if end-j < 8 {
+				break
+			}
+			s := sample[j:end]
+			seg := segs[s]
+			if seg == nil {
+				seg = &segment{s: s, last: -1}
+				segs[s] = seg
+			}
+			if seg.last != i {
+				seg.samples++
+				seg.last = i
+			}
+		}
+	}
+	var ranked []*segment
+	for _, seg := range segs {
+		if seg.samples > 1 {
+			ranked = append(ranked, seg)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.samples*len(a.s) != b.samples*len(b.s) {
+			return a.samples*len(a.s) > b.samples*len(b.s)
+		}
+		return a.s < b.s
+	})
+	var picked []string
+	n := 0
+	for _, seg := range ranked {
+		if n+len(seg.s) > size {
+			continue
+		}
+		picked = append(picked, seg.s)
+		n += len(seg.s)
+	}
+	// DEFLATE encodes close matches in fewer bits, so the most shared
+	// passages go at the end, next to the content.
+	var dict bytes.Buffer
+	for i := len(picked) - 1; i >= 0; i-- {
+		dict.WriteString(picked[i])
+	}
+	return dict.Bytes()
+}
+
+// compressedMagic starts compressed content, followed by the length of the
+// Name of its Compressor and the Name. Stores keep compressed content apart
+// from uncompressed content, which may start with the same bytes.
+var compressedMagic = []byte("TKZ1")
+
+// compress writes the content of r to w compressed with c, after its header.
+func compress(c Compressor, w io.Writer, r io.Reader) error {
+	name := c.Name()
+	if len(name) > 255 {
+		return fmt.Errorf("compressor name %.20q... is too long", name)
+	}
+	hdr := append(append([]byte{}, compressedMagic...), byte(len(name)))
+	if _, err := w.Write(append(hdr, name...)); err != nil {
+		return err
+	}
+	zw, err := c.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(zw, r); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// errOtherCompressor is the error decompressing content compressed with
+// another Compressor.
+var errOtherCompressor = errors.New("wrong compressor")
+
+// decompress returns a reader of the content of rc, written by compress, which
+// closes rc. The content is decompressed with c, which must have the Name
+// recorded by compress, except that gzip content needs no Compressor, so it
+// stays readable by a store opened without one.
+func decompress(c Compressor, rc io.ReadCloser) (io.ReadCloser, error) {
+	br := bufio.NewReader(rc)
+	hdr, err := br.Peek(len(compressedMagic) + 1)
+	if err != nil || !bytes.Equal(hdr[:len(compressedMagic)], compressedMagic) {
+		rc.Close()
+		return nil, errors.New("content is not compressed")
+	}
+	hdr, err = br.Peek(len(hdr) + int(hdr[len(compressedMagic)]))
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("compressed content is truncated: %w", err)
+	}
+	name := string(hdr[len(compressedMagic)+1:])
+	if c == nil && name == (GzipCompressor(0)).Name() {
+		c = GzipCompressor(0)
+	}
+	if c == nil || c.Name() != name {
+		rc.Close()
+		return nil, fmt.Errorf("%w: content is compressed with %q, not %q", errOtherCompressor, name, compressorName(c))
+	}
+	br.Discard(len(hdr))
+	zr, err := c.NewReader(br)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("error decompressing: %w", err)
+	}
+	return readCloser{zr, closers{zr, rc}}, nil
+}
+
+func compressorName(c Compressor) string {
+	if c == nil {
+		return "no compressor"
+	}
+	return c.Name()
+}
+
+// readCloser reads from a Reader and closes a Closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// closers closes all its Closers, and returns the first error.
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var err error
+	for _, c := range cs {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
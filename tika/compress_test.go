@@ -0,0 +1,240 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// invoice returns the text of an invoice, sharing most of its words with the
+// other invoices.
+func invoice(n int) string {
+	return fmt.Sprintf("INVOICE %d\nAcme Corporation, 1600 Example Avenue, Springfield\n"+
+		"Payment is due within 30 days of the invoice date. Please include the invoice number with your payment.\n"+
+		"Item %d: consulting services, %d hours at the standard hourly rate\n"+
+		"Thank you for your business. Questions about this invoice can be sent to billing@example.com.\n", n, n*7, n%40)
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	dict := TrainDictionary([]string{invoice(1), invoice(2), invoice(3)}, 4096)
+	dc, err := NewDictCompressor(dict, flate.BestCompression)
+	if err != nil {
+		t.Fatalf("NewDictCompressor got error: %v", err)
+	}
+	for _, c := range []Compressor{GzipCompressor(0), GzipCompressor(9), dc} {
+		for _, content := range []string{"", "hello", invoice(4), strings.Repeat(invoice(5), 100)} {
+			var buf bytes.Buffer
+			if err := compress(c, &buf, strings.NewReader(content)); err != nil {
+				t.Fatalf("%s: compress got error: %v", c.Name(), err)
+			}
+			rc, err := decompress(c, ioutil.NopCloser(&buf))
+			if err != nil {
+				t.Fatalf("%s: decompress got error: %v", c.Name(), err)
+			}
+			got, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil || string(got) != content {
+				t.Errorf("%s: round trip of %d bytes got %d bytes, %v", c.Name(), len(content), len(got), err)
+			}
+		}
+	}
+}
+
+func TestDecompressUncompressed(t *testing.T) {
+	for _, content := range []string{"", "TK", "hello world", "TKZ"} {
+		if _, err := decompress(GzipCompressor(0), ioutil.NopCloser(strings.NewReader(content))); err == nil {
+			t.Errorf("decompress(%q) got no error", content)
+		}
+	}
+}
+
+func TestDecompressGzipWithoutCompressor(t *testing.T) {
+	var buf bytes.Buffer
+	if err := compress(GzipCompressor(9), &buf, strings.NewReader("hello")); err != nil {
+		t.Fatalf("compress got error: %v", err)
+	}
+	rc, err := decompress(nil, ioutil.NopCloser(&buf))
+	if err != nil {
+		t.Fatalf("decompress got error: %v", err)
+	}
+	defer rc.Close()
+	if got, err := ioutil.ReadAll(rc); err != nil || string(got) != "hello" {
+		t.Errorf("decompress read %q, %v, want hello", got, err)
+	}
+}
+
+func TestDecompressOtherCompressor(t *testing.T) {
+	a, _ := NewDictCompressor([]byte("one dictionary"), 0)
+	b, _ := NewDictCompressor([]byte("another dictionary"), 0)
+	var buf bytes.Buffer
+	if err := compress(a, &buf, strings.NewReader("hello")); err != nil {
+		t.Fatalf("compress got error: %v", err)
+	}
+	for _, c := range []Compressor{b, GzipCompressor(0), nil} {
+		if _, err := decompress(c, ioutil.NopCloser(bytes.NewReader(buf.Bytes()))); err == nil {
+			t.Errorf("decompress with %s got no error", compressorName(c))
+		}
+	}
+	if _, err := decompress(a, ioutil.NopCloser(bytes.NewReader(buf.Bytes()[:len(compressedMagic)+3]))); err == nil {
+		t.Errorf("decompress of a truncated header got no error")
+	}
+}
+
+func TestNewDictCompressor(t *testing.T) {
+	if _, err := NewDictCompressor(nil, 10); err == nil {
+		t.Errorf("NewDictCompressor with level 10 got no error")
+	}
+	long := bytes.Repeat([]byte("x"), maxDictSize+10)
+	c, err := NewDictCompressor(long, 0)
+	if err != nil {
+		t.Fatalf("NewDictCompressor got error: %v", err)
+	}
+	if len(c.dict) != maxDictSize {
+		t.Errorf("NewDictCompressor kept %d bytes of the dictionary, want %d", len(c.dict), maxDictSize)
+	}
+	d, _ := NewDictCompressor(long[:maxDictSize], 0)
+	if c.Name() != d.Name() {
+		t.Errorf("Name is %q and %q for the same dictionary", c.Name(), d.Name())
+	}
+}
+
+func TestTrainDictionary(t *testing.T) {
+	var samples []string
+	for i := 0; i < 50; i++ {
+		samples = append(samples, invoice(i))
+	}
+	dict := TrainDictionary(samples, 2048)
+	if len(dict) == 0 || len(dict) > 2048 {
+		t.Fatalf("TrainDictionary returned %d bytes, want 1 to 2048", len(dict))
+	}
+	if !bytes.Contains(dict, []byte("Payment is due")) {
+		t.Errorf("TrainDictionary returned %q, want the shared passages", dict)
+	}
+	if got := TrainDictionary([]string{"only one sample of text"}, 2048); len(got) != 0 {
+		t.Errorf("TrainDictionary of one sample returned %q, want empty", got)
+	}
+
+	dc, err := NewDictCompressor(dict, flate.BestCompression)
+	if err != nil {
+		t.Fatalf("NewDictCompressor got error: %v", err)
+	}
+	size := func(c Compressor) int {
+		var buf bytes.Buffer
+		if err := compress(c, &buf, strings.NewReader(invoice(1000))); err != nil {
+			t.Fatalf("%s: compress got error: %v", c.Name(), err)
+		}
+		return buf.Len()
+	}
+	if d, g := size(dc), size(GzipCompressor(9)); d*2 > g {
+		t.Errorf("invoice compressed to %d bytes with the dictionary and %d without, want at most half", d, g)
+	}
+}
+
+func TestDirBlobStoreDictionary(t *testing.T) {
+	dir := tempDir(t)
+	s, err := NewDirBlobStore(dir)
+	if err != nil {
+		t.Fatalf("NewDirBlobStore got error: %v", err)
+	}
+	ctx := context.Background()
+	// Content stored before compression is enabled is still readable.
+	old, err := s.Put(ctx, strings.NewReader(invoice(1)))
+	if err != nil {
+		t.Fatalf("Put got error: %v", err)
+	}
+	s.Compressor, _ = NewDictCompressor(TrainDictionary([]string{invoice(2), invoice(3)}, 4096), 0)
+	ref, err := s.Put(ctx, strings.NewReader(invoice(4)))
+	if err != nil {
+		t.Fatalf("Put got error: %v", err)
+	}
+	for ref, want := range map[BlobRef]string{old: invoice(1), ref: invoice(4)} {
+		rc, err := s.Open(ctx, ref)
+		if err != nil {
+			t.Fatalf("Open got error: %v", err)
+		}
+		got, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if string(got) != want {
+			t.Errorf("Open(%s) read %q, want %q", ref, got, want)
+		}
+	}
+	digest, _ := ref.digest()
+	fi, err := os.Stat(filepath.Join(dir, digest[:2], digest+compressedSuffix))
+	if err != nil {
+		t.Fatalf("Stat got error: %v", err)
+	}
+	if fi.Size() >= int64(len(invoice(4))) {
+		t.Errorf("stored %d bytes, want less than %d", fi.Size(), len(invoice(4)))
+	}
+}
+
+func TestBlobStoreCompressedReopened(t *testing.T) {
+	ctx := context.Background()
+	dir := tempDir(t)
+	s, err := NewDirBlobStore(dir)
+	if err != nil {
+		t.Fatalf("NewDirBlobStore got error: %v", err)
+	}
+	// Content stored uncompressed which looks like compressed content is
+	// read as is.
+	legacy := string(compressedMagic) + "\x04gzip not compressed"
+	old, err := s.Put(ctx, strings.NewReader(legacy))
+	if err != nil {
+		t.Fatalf("Put got error: %v", err)
+	}
+	s.Compressor = GzipCompressor(0)
+	ref, err := s.Put(ctx, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("Put got error: %v", err)
+	}
+
+	// The store is reopened without its Compressor.
+	s, err = NewDirBlobStore(dir)
+	if err != nil {
+		t.Fatalf("NewDirBlobStore got error: %v", err)
+	}
+	for ref, want := range map[BlobRef]string{old: legacy, ref: "hello"} {
+		rc, err := s.Open(ctx, ref)
+		if err != nil {
+			t.Fatalf("Open(%s) got error: %v", ref, err)
+		}
+		got, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if string(got) != want {
+			t.Errorf("Open(%s) read %q, want %q", ref, got, want)
+		}
+	}
+
+	dc, _ := NewDictCompressor([]byte("hello world"), 0)
+	s.Compressor = dc
+	ref, err = s.Put(ctx, strings.NewReader("hello world"))
+	if err != nil {
+		t.Fatalf("Put got error: %v", err)
+	}
+	s.Compressor = nil
+	if _, err := s.Open(ctx, ref); err == nil {
+		t.Errorf("Open of content compressed with a dictionary got no error without the Compressor")
+	}
+}
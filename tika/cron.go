@@ -0,0 +1,303 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A CronSchedule is a parsed cron expression.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64 // Bit sets of the matching values.
+	// domStar and dowStar report whether the day fields are "*", since a day
+	// matches either day field when both are restricted.
+	domStar, dowStar bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	cronMonths = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	cronDays   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// ParseCron parses a standard cron expression of five fields: minute, hour,
+// day of month, month and day of week. Fields can be "*", values, ranges
+// ("1-5"), steps ("*/15", "0-30/10") and lists of those ("1,15"). Months and
+// days of week can also be written with their first three letters, and
+// Sunday is 0 or 7. The descriptors @yearly, @monthly, @weekly, @daily and
+// @hourly are also accepted.
+func ParseCron(expr string) (*CronSchedule, error) {
+	if d, ok := cronDescriptors[strings.ToLower(strings.TrimSpace(expr))]; ok {
+		expr = d
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: want 5 fields, got %d", expr, len(fields))
+	}
+	s := &CronSchedule{domStar: fields[2] == "*", dowStar: fields[4] == "*"}
+	var err error
+	for _, f := range []struct {
+		bits     *uint64
+		min, max int
+		names    []string
+		nameBase int
+	}{
+		{&s.minute, 0, 59, nil, 0},
+		{&s.hour, 0, 23, nil, 0},
+		{&s.dom, 1, 31, nil, 0},
+		{&s.month, 1, 12, cronMonths, 1},
+		{&s.dow, 0, 7, cronDays, 0},
+	} {
+		if *f.bits, err = parseCronField(fields[0], f.min, f.max, f.names, f.nameBase); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+		fields = fields[1:]
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 // 7 is also Sunday.
+	}
+	return s, nil
+}
+
+func parseCronField(field string, min, max int, names []string, nameBase int) (uint64, error) {
+	value := func(v string) (int, error) {
+		for i, n := range names {
+			if strings.EqualFold(v, n) {
+				return i + nameBase, nil
+			}
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("invalid value %q, want %d-%d", v, min, max)
+		}
+		return n, nil
+	}
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+		}
+		lo, hi := min, max
+		if part != "*" {
+			var err error
+			r := strings.SplitN(part, "-", 2)
+			if lo, err = value(r[0]); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(r) == 2 {
+				if hi, err = value(r[1]); err != nil {
+					return 0, err
+				}
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first time matching s after t, or the zero time if there is
+// none within five years, as for "0 0 30 2 *".
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// OverlapPolicy is what a Cron does when a job is due while its previous run
+// is still running.
+type OverlapPolicy int
+
+// OverlapPolicies.
+const (
+	// OverlapSkip skips the run.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue starts the run when the previous one finishes. At most one
+	// run is queued.
+	OverlapQueue
+)
+
+// A Runner is a job run by a Cron, such as a Job.
+type Runner interface {
+	Run(ctx context.Context) error
+}
+
+type cronEntry struct {
+	name     string
+	schedule *CronSchedule
+	runner   Runner
+	overlap  OverlapPolicy
+	next     time.Time
+
+	mu      sync.Mutex
+	running bool
+	queued  bool
+}
+
+// A Cron runs jobs on cron schedules, turning recurring crawls and
+// extractions into a single long-running process.
+type Cron struct {
+	// OnError, if not nil, is called with the errors of the runs.
+	OnError func(name string, err error)
+	// OnSkip, if not nil, is called when a run is skipped by OverlapSkip.
+	OnSkip func(name string)
+
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+
+	mu      sync.Mutex
+	entries []*cronEntry
+	wg      sync.WaitGroup
+	// added wakes up Run when an entry is added.
+	added chan struct{}
+}
+
+// NewCron creates a Cron without jobs.
+func NewCron() *Cron {
+	return &Cron{now: time.Now, after: time.After, added: make(chan struct{}, 1)}
+}
+
+// Add schedules r to run at the times matching the cron expression spec, as
+// parsed by ParseCron. name identifies r in OnError and OnSkip.
+func (c *Cron) Add(name, spec string, r Runner, overlap OverlapPolicy) error {
+	s, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, &cronEntry{name: name, schedule: s, runner: r, overlap: overlap})
+	select {
+	case c.added <- struct{}{}:
+	default: // Run is already woken up.
+	}
+	return nil
+}
+
+// Run runs the jobs when they are due, until ctx is done. It then waits for
+// the running jobs, which are canceled with ctx, and returns ctx.Err().
+func (c *Cron) Run(ctx context.Context) error {
+	defer c.wg.Wait()
+	for {
+		now := c.now()
+		var next time.Time
+		c.mu.Lock()
+		for _, e := range c.entries {
+			if e.next.IsZero() {
+				e.next = e.schedule.Next(now)
+			}
+			if !e.next.IsZero() && !e.next.After(now) {
+				c.fire(ctx, e)
+				e.next = e.schedule.Next(now)
+			}
+			if !e.next.IsZero() && (next.IsZero() || e.next.Before(next)) {
+				next = e.next
+			}
+		}
+		c.mu.Unlock()
+		// Wake up at least hourly, in case the clock jumps.
+		wait := time.Hour
+		if !next.IsZero() && next.Sub(now) < wait {
+			wait = next.Sub(now)
+		}
+		select {
+		case <-c.after(wait):
+		case <-c.added:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// fire runs e, unless its previous run is still running.
+func (c *Cron) fire(ctx context.Context, e *cronEntry) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.running {
+		if e.overlap == OverlapQueue {
+			e.queued = true
+		} else if c.OnSkip != nil {
+			c.OnSkip(e.name)
+		}
+		return
+	}
+	e.running = true
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			if err := e.runner.Run(ctx); err != nil && c.OnError != nil {
+				c.OnError(e.name, err)
+			}
+			e.mu.Lock()
+			if !e.queued || ctx.Err() != nil {
+				e.running, e.queued = false, false
+				e.mu.Unlock()
+				return
+			}
+			e.queued = false
+			e.mu.Unlock()
+		}
+	}()
+}
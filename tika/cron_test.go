@@ -0,0 +1,215 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// A Sunday.
+	from := time.Date(2017, time.January, 1, 10, 30, 15, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2017, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2017, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{"0 9-17 * * *", time.Date(2017, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 2 * * mon-fri", time.Date(2017, 1, 2, 2, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2017, 1, 8, 0, 0, 0, 0, time.UTC)},
+		{"30 4 1,15 * *", time.Date(2017, 1, 15, 4, 30, 0, 0, time.UTC)},
+		{"0 0 1 mar *", time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches.
+		{"0 0 13 * fri", time.Date(2017, 1, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, test := range tests {
+		s, err := ParseCron(test.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q) got error: %v", test.expr, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(test.want) {
+			t.Errorf("ParseCron(%q).Next(%v) = %v, want %v", test.expr, from, got, test.want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *", "@often"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) got no error", expr)
+		}
+	}
+}
+
+// blockingRunner counts its runs, each of which waits for release.
+type blockingRunner struct {
+	mu      sync.Mutex
+	runs    int
+	release chan struct{}
+}
+
+func (r *blockingRunner) Run(ctx context.Context) error {
+	r.mu.Lock()
+	r.runs++
+	r.mu.Unlock()
+	select {
+	case <-r.release:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func (r *blockingRunner) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.runs
+}
+
+func TestCronOverlap(t *testing.T) {
+	for _, test := range []struct {
+		policy    OverlapPolicy
+		wantRuns  int
+		wantSkips int
+	}{
+		{OverlapSkip, 1, 2},
+		{OverlapQueue, 2, 0},
+	} {
+		c := NewCron()
+		skips := 0
+		c.OnSkip = func(string) { skips++ }
+		r := &blockingRunner{release: make(chan struct{})}
+		e := &cronEntry{name: "job", runner: r, overlap: test.policy}
+		for i := 0; i < 3; i++ {
+			c.fire(context.Background(), e)
+		}
+		close(r.release)
+		c.wg.Wait()
+		if r.count() != test.wantRuns || skips != test.wantSkips {
+			t.Errorf("policy %v: got %d runs and %d skips, want %d and %d", test.policy, r.count(), skips, test.wantRuns, test.wantSkips)
+		}
+	}
+}
+
+type countingRunner struct {
+	mu   sync.Mutex
+	runs int
+}
+
+func (r *countingRunner) Run(context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs++
+	return nil
+}
+
+func TestCronRun(t *testing.T) {
+	clock := time.Date(2017, 1, 1, 0, 0, 30, 0, time.UTC)
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	waits := 0
+	c := NewCron()
+	c.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return clock
+	}
+	c.after = func(d time.Duration) <-chan time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		ch := make(chan time.Time, 1)
+		if waits++; waits == 10 {
+			cancel()
+			return ch
+		}
+		clock = clock.Add(d)
+		ch <- clock
+		return ch
+	}
+	skips := 0
+	c.OnSkip = func(string) { skips++ }
+	r := &countingRunner{}
+	if err := c.Add("every 2 minutes", "*/2 * * * *", r, OverlapSkip); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Run(ctx); err != context.Canceled {
+		t.Errorf("Run got error %v, want context.Canceled", err)
+	}
+	// Each of the 9 waits before the canceled one ends on a due time, followed
+	// by a run, or a skip if the previous run is still running.
+	if r.runs+skips != 9 || r.runs == 0 {
+		t.Errorf("Run ran the job %d times and skipped it %d times, want 9 in all", r.runs, skips)
+	}
+}
+
+func TestCronRunAdd(t *testing.T) {
+	clock := time.Date(2017, 1, 1, 0, 0, 30, 0, time.UTC)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	waiting := make(chan bool, 1)
+	c := NewCron()
+	c.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return clock
+	}
+	c.after = func(d time.Duration) <-chan time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		ch := make(chan time.Time, 1)
+		if d == time.Hour {
+			// Without entries, Run waits until it is woken up.
+			select {
+			case waiting <- true:
+			default:
+			}
+			return ch
+		}
+		clock = clock.Add(d)
+		ch <- clock
+		return ch
+	}
+	ran := make(chan bool, 1)
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+	<-waiting
+	err := c.Add("every minute", "* * * * *", runnerFunc(func(context.Context) error {
+		select {
+		case ran <- true:
+		default:
+		}
+		return nil
+	}), OverlapSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Run did not run a job added while waiting")
+	}
+	cancel()
+	<-done
+}
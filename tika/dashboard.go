@@ -0,0 +1,87 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// A Dashboard is an http.Handler showing the status of Jobs, so their
+// progress can be followed while they run. It serves an HTML page refreshing
+// itself, or the JobStatus of each Job as JSON if the request has
+// "format=json" in its query or accepts application/json.
+//
+// For example:
+//
+//	d := tika.NewDashboard(job)
+//	http.Handle("/jobs", d)
+type Dashboard struct {
+	mu   sync.Mutex
+	jobs []*Job
+}
+
+// NewDashboard creates a Dashboard of jobs.
+func NewDashboard(jobs ...*Job) *Dashboard {
+	return &Dashboard{jobs: jobs}
+}
+
+// Add adds j to the Dashboard.
+func (d *Dashboard) Add(j *Job) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.jobs = append(d.jobs, j)
+}
+
+// Statuses returns the status of the Jobs of the Dashboard.
+func (d *Dashboard) Statuses() []JobStatus {
+	d.mu.Lock()
+	jobs := append([]*Job(nil), d.jobs...)
+	d.mu.Unlock()
+	statuses := make([]JobStatus, len(jobs))
+	for i, j := range jobs {
+		statuses[i] = j.Status()
+	}
+	return statuses
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta http-equiv="refresh" content="5"><title>Tika jobs</title>
+<style>body{font-family:sans-serif}td,th{padding:2px 8px;text-align:right}td:first-child,th:first-child{text-align:left}</style>
+</head><body><h1>Tika jobs</h1>
+<table><tr><th>Job</th><th>State</th><th>Total</th><th>Pending</th><th>In flight</th><th>Succeeded</th><th>Failed</th><th>Skipped</th><th>Docs/s</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td>{{.State}}{{if .Paused}} (paused: {{.Paused}}){{end}}</td><td>{{.Total}}</td><td>{{.Pending}}</td><td>{{.InFlight}}</td><td>{{.Succeeded}}</td><td>{{.Failed}}{{if .Canceled}} ({{.Canceled}} canceled){{end}}</td><td>{{.Skipped}}</td><td>{{printf "%.2f" .Throughput}}</td></tr>
+{{end}}</table>
+{{range .}}{{if or .Err .RecentFailures}}<h2>{{.Name}}</h2>{{if .Err}}<p>Error: {{.Err}}</p>{{end}}
+<ul>{{range .RecentFailures}}<li>{{.Time.Format "15:04:05"}} {{.ID}}: {{.Err}}</li>{{end}}</ul>{{end}}{{end}}
+</body></html>
+`))
+
+// ServeHTTP implements http.Handler.
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	statuses := d.Statuses()
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statuses)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	dashboardTemplate.Execute(w, statuses)
+}
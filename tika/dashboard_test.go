@@ -0,0 +1,67 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDashboard(t *testing.T) {
+	ts := rmetaServer()
+	defer ts.Close()
+	j, _ := testJob(ts, map[string]string{"a.txt": "a", "<b>.txt": "fail"})
+	if err := j.Run(context.Background()); err != nil {
+		t.Fatalf("Run got error: %v", err)
+	}
+	d := NewDashboard()
+	d.Add(j)
+	d.Add(&Job{Name: "idle"})
+
+	tests := []struct {
+		target, accept string
+		wantJSON       bool
+	}{
+		{"/jobs", "", false},
+		{"/jobs?format=json", "", true},
+		{"/jobs", "application/json", true},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", test.target, nil)
+		r.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, r)
+		body := w.Body.String()
+		if !test.wantJSON {
+			if !strings.Contains(body, "<td>test</td><td>succeeded</td><td>2</td>") || !strings.Contains(body, "&lt;b&gt;.txt") {
+				t.Errorf("GET %s returned:\n%s\nwant both jobs and the escaped failure", test.target, body)
+			}
+			continue
+		}
+		var got []JobStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Errorf("GET %s (Accept: %s) returned invalid JSON %q: %v", test.target, test.accept, body, err)
+			continue
+		}
+		if len(got) != 2 || got[0].Succeeded != 1 || got[0].Failed != 1 || got[1].State != JobIdle {
+			t.Errorf("GET %s (Accept: %s) returned %+v", test.target, test.accept, got)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sync/atomic"
+)
+
+// clientStats holds the counters behind Client.Stats. All fields are accessed
+// atomically.
+type clientStats struct {
+	active   int64
+	requests int64
+	failures int64
+	retries  int64
+	rejected int64
+}
+
+func (s *clientStats) start() {
+	atomic.AddInt64(&s.active, 1)
+	atomic.AddInt64(&s.requests, 1)
+}
+
+func (s *clientStats) finish(err error) {
+	atomic.AddInt64(&s.active, -1)
+	if err != nil {
+		atomic.AddInt64(&s.failures, 1)
+	}
+}
+
+// statsOf returns the stats of the Client making req, or nil if req is not
+// made by a Client.
+func statsOf(req *http.Request) *clientStats {
+	if cl, ok := req.Context().Value(callKey{}).(*call); ok {
+		return cl.stats
+	}
+	return nil
+}
+
+// retry counts a retried request, if s is not nil.
+func (s *clientStats) retry() {
+	if s != nil {
+		atomic.AddInt64(&s.retries, 1)
+	}
+}
+
+// reject counts a request refused by a CircuitBreaker, if s is not nil.
+func (s *clientStats) reject() {
+	if s != nil {
+		atomic.AddInt64(&s.rejected, 1)
+	}
+}
+
+// Stats is a snapshot of the counters of a Client. Stats implements
+// expvar.Var, so it can be published with expvar.Publish; see
+// Client.PublishExpvar.
+type Stats struct {
+	// Active is the number of calls in progress.
+	Active int64 `json:"active"`
+	// Requests is the number of calls made.
+	Requests int64 `json:"requests"`
+	// Failures is the number of calls which returned an error.
+	Failures int64 `json:"failures"`
+	// Retries is the number of requests retried by a RetryPolicy.
+	Retries int64 `json:"retries"`
+	// BreakerRejections is the number of requests refused by an open
+	// CircuitBreaker.
+	BreakerRejections int64 `json:"breakerRejections"`
+	// BreakerOpen is whether a CircuitBreaker added with WithCircuitBreaker
+	// is open.
+	BreakerOpen bool `json:"breakerOpen"`
+}
+
+// String returns s as JSON.
+func (s Stats) String() string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// Stats returns the current counters of c.
+func (c *Client) Stats() Stats {
+	s := Stats{
+		Active:            atomic.LoadInt64(&c.stats.active),
+		Requests:          atomic.LoadInt64(&c.stats.requests),
+		Failures:          atomic.LoadInt64(&c.stats.failures),
+		Retries:           atomic.LoadInt64(&c.stats.retries),
+		BreakerRejections: atomic.LoadInt64(&c.stats.rejected),
+	}
+	for _, b := range c.breakers {
+		s.BreakerOpen = s.BreakerOpen || b.Open()
+	}
+	return s
+}
+
+// PublishExpvar publishes the Stats of c as the expvar with the given name,
+// so they are served by the /debug/vars handler of the expvar package. Like
+// expvar.Publish, PublishExpvar panics if the name is already in use.
+func (c *Client) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} { return c.Stats() }))
+}
+
+// DebugHandler returns an http.Handler serving the Stats of c as JSON,
+// including the retries and the state of its CircuitBreakers. Mount it on an
+// internal endpoint, for example:
+//
+//	http.Handle("/debug/tika", client.DebugHandler())
+func (c *Client) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(c.Stats().String()))
+	})
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	// block holds requests until the test has checked the active count.
+	block := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/detect/stream" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		<-block
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+
+	done := make(chan struct{})
+	go func() {
+		c.Parse(context.Background(), nil)
+		close(done)
+	}()
+	for c.Stats().Active != 1 {
+		runtime.Gosched()
+	}
+	close(block)
+	<-done
+	c.Detect(context.Background(), nil)
+
+	want := Stats{Active: 0, Requests: 2, Failures: 1}
+	if got := c.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	rec := httptest.NewRecorder()
+	c.DebugHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/tika", nil))
+	var got Stats
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("DebugHandler served invalid JSON %q: %v", rec.Body.String(), err)
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &fields)
+	for _, f := range []string{"retries", "breakerRejections", "breakerOpen"} {
+		if _, ok := fields[f]; !ok {
+			t.Errorf("DebugHandler served %s, without %q", rec.Body.String(), f)
+		}
+	}
+	if got != want {
+		t.Errorf("DebugHandler served %+v, want %+v", got, want)
+	}
+
+	c.PublishExpvar("test-tika-client")
+	if got, want := expvar.Get("test-tika-client").String(), want.String(); got != want {
+		t.Errorf("expvar = %s, want %s", got, want)
+	}
+}
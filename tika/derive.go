@@ -0,0 +1,97 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"net/http"
+	"time"
+)
+
+// WithRequestDefaults returns a ClientOption to apply opts to every call of
+// the Client, before the RequestOptions of the call, which override them. Use
+// it to set headers, such as WithOCR, or timeouts for all the calls of a
+// Client.
+func WithRequestDefaults(opts ...RequestOption) ClientOption {
+	return func(c *Client) {
+		c.requestDefaults = append(c.requestDefaults, opts...)
+	}
+}
+
+// withDefaults returns the configuration of a call with cfg applied over the
+// request defaults of c.
+func (c *Client) withDefaults(cfg *callConfig) *callConfig {
+	d := newCallConfig(c.requestDefaults)
+	for k, v := range cfg.header {
+		if d.header == nil {
+			d.header = make(http.Header)
+		}
+		d.header[k] = v
+	}
+	if cfg.timeout != 0 {
+		d.timeout = cfg.timeout
+	}
+	if cfg.url != "" {
+		d.url = cfg.url
+	}
+	if cfg.handler != "" {
+		d.handler = cfg.handler
+	}
+	return d
+}
+
+// With returns a new Client configured like c, with options applied on top,
+// for example to give a tenant or a kind of document its own headers and
+// timeouts. The new Client shares the http.Client of c, and so its pool of
+// connections, unless options change how the server is resolved, as by
+// WithResolver. It has its own Stats.
+//
+// With is safe to call concurrently with calls of c. Options adding to a
+// setting of c, such as WithRequestDefaults or WithMiddleware, add to a copy
+// of it.
+func (c *Client) With(options ...ClientOption) *Client {
+	// Copy the fields one by one, since the stats of c may be updated
+	// concurrently.
+	d := &Client{
+		url:             c.url,
+		httpClient:      c.httpClient,
+		timeout:         c.timeout,
+		textDecoder:     c.textDecoder,
+		label:           c.label,
+		contextHeaders:  append([]contextHeader(nil), c.contextHeaders...),
+		middlewares:     append([]Middleware(nil), c.middlewares...),
+		errorBodyLimit:  c.errorBodyLimit,
+		requestDefaults: append([]RequestOption(nil), c.requestDefaults...),
+		breakers:        append([]*CircuitBreaker(nil), c.breakers...),
+	}
+	if c.timeouts != nil {
+		d.timeouts = make(map[Endpoint]time.Duration, len(c.timeouts))
+		for e, t := range c.timeouts {
+			d.timeouts[e] = t
+		}
+	}
+	for _, o := range options {
+		o(d)
+	}
+	if d.dnsConfig != nil {
+		// The http.Client of c already dials as configured by c, if it
+		// has DNS options, for the addresses this one does not resolve.
+		d.httpClient = d.dnsConfig.wrap(d.httpClient, d.url)
+	}
+	// The middlewares of d count its own Stats.
+	d.chain()
+	return d
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientWith(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s", r.Header.Get("X-Tenant"), r.Header.Get("X-Tika-OCRLanguage"))
+	}))
+	defer ts.Close()
+	base := NewClient(nil, ts.URL, WithRequestDefaults(WithHeader("X-Tenant", "none")), WithEndpointTimeout(EndpointParse, time.Minute))
+	var seen int
+	count := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			seen++
+			return next.RoundTrip(req)
+		})
+	}
+	derived := base.With(
+		WithRequestDefaults(WithHeader("X-Tenant", "acme"), WithOCR(OCROptions{Language: "deu"})),
+		WithEndpointTimeout(EndpointParse, time.Second),
+		WithMiddleware(count),
+	)
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		c    *Client
+		opts []RequestOption
+		want string
+	}{
+		{name: "base", c: base, want: "none|"},
+		{name: "derived", c: derived, want: "acme|deu"},
+		{name: "derived with override", c: derived, opts: []RequestOption{WithHeader("X-Tenant", "other")}, want: "other|deu"},
+	}
+	for _, test := range tests {
+		got, err := test.c.Parse(ctx, nil, test.opts...)
+		if err != nil || got != test.want {
+			t.Errorf("Parse(%s) = %q, %v, want %q", test.name, got, err, test.want)
+		}
+	}
+	if seen != 2 {
+		t.Errorf("middleware of the derived Client saw %d requests, want 2", seen)
+	}
+	if base.timeouts[EndpointParse] != time.Minute || derived.timeouts[EndpointParse] != time.Second {
+		t.Errorf("Parse timeouts are %v and %v, want 1m for the base Client and 1s for the derived one", base.timeouts[EndpointParse], derived.timeouts[EndpointParse])
+	}
+	if base.Stats().Requests != 1 || derived.Stats().Requests != 2 {
+		t.Errorf("Stats of the base and derived Clients = %+v and %+v, want 1 and 2 requests", base.Stats(), derived.Stats())
+	}
+	// Neither Client has an http.Client of its own: their requests go
+	// through copies of the http.DefaultClient.
+	if plain := base.With(WithLabel("x")); plain.httpClient != nil || base.httpClient != nil || plain.Label() != "x" {
+		t.Errorf("With(WithLabel) has an http.Client %v, the base Client %v, and label %q, want none, none and \"x\"", plain.httpClient, base.httpClient, plain.Label())
+	}
+}
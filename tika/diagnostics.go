@@ -0,0 +1,147 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// stderrTailLines is the number of lines of stderr kept in
+// StartupDiagnostics.
+const stderrTailLines = 50
+
+// StartupDiagnostics describe a Server which failed to start.
+type StartupDiagnostics struct {
+	// Command is the command line of the Java process.
+	Command []string
+	// JavaVersion is the first line of the output of java -version, or why
+	// it could not be run.
+	JavaVersion string
+	// Port is whether something listened on the address of the Server when
+	// Start gave up, such as "listening" or "not listening: connection
+	// refused". A listening port after a startup timeout may be held by
+	// another process.
+	Port string
+	// Probes are the failed probes of the Server. See StartupError.
+	Probes []StartupProbe
+	// Stderr holds the last lines the Java process wrote to stderr, which
+	// often say why it failed.
+	Stderr []string
+}
+
+// A StartError is returned by Server.Start when the Java process was run but
+// the Server did not start. It wraps ErrServerNotStarted and the cause of the
+// failure, such as a StartupError. Test for it with errors.As.
+type StartError struct {
+	Err         error
+	Diagnostics *StartupDiagnostics
+}
+
+func (e *StartError) Error() string {
+	d := e.Diagnostics
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v\ncommand: %s\njava version: %s\nport: %s\n", e.Err, strings.Join(d.Command, " "), d.JavaVersion, d.Port)
+	if n := len(d.Probes); n > 0 {
+		p := d.Probes[n-1]
+		fmt.Fprintf(&b, "probes: %d, last after %v: %v\n", n, p.Elapsed.Round(time.Millisecond), p.Err)
+	}
+	fmt.Fprintf(&b, "server stderr:\n\n%s", strings.Join(d.Stderr, "\n"))
+	return b.String()
+}
+
+func (e *StartError) Unwrap() error {
+	return e.Err
+}
+
+// startError shuts down the server of cmd with cancel and returns the error
+// of Start for err, with diagnostics. stderr is the stderr of cmd, or nil if
+// it did not run.
+func (s *Server) startError(cancel func(), cmd *exec.Cmd, stderr io.Reader, err error) error {
+	d := &StartupDiagnostics{
+		Command: append([]string{}, cmd.Args...),
+		Port:    s.portStatus(),
+	}
+	cancel()
+	if stderr != nil {
+		d.Stderr = tailLines(stderr, stderrTailLines)
+	}
+	d.JavaVersion = javaVersion(s)
+	var se *StartupError
+	if errors.As(err, &se) {
+		d.Probes = se.Probes
+	}
+	return &StartError{
+		Err:         fmt.Errorf("%w: %w", ErrServerNotStarted, err),
+		Diagnostics: d,
+	}
+}
+
+// portStatus reports whether something listens on the address of s.
+func (s *Server) portStatus() string {
+	addr := net.JoinHostPort(s.hostname, s.listenPort())
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		return fmt.Sprintf("%s not listening: %v", addr, err)
+	}
+	conn.Close()
+	return addr + " listening"
+}
+
+// tailLines returns the last n lines of r.
+func tailLines(r io.Reader, n int) []string {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		lines = append(lines, fmt.Sprintf("(error reading stderr: %v)", err))
+	}
+	return lines
+}
+
+// javaVersion returns the first line of the output of java -version, run
+// with the environment of s. It is a variable for testing.
+var javaVersion = func(s *Server) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cmd := cmder(ctx, "java", "-version")
+	if s.env != nil {
+		cmd.Env = s.env
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Sprintf("(error running java -version: %v)", err)
+	}
+	line := strings.TrimSpace(string(out))
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+	return line
+}
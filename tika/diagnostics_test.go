@@ -0,0 +1,108 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// helperCommand returns a commander running TestHelperProcess with args.
+func helperCommand(args ...string) commander {
+	return func(context.Context, string, ...string) *exec.Cmd {
+		c := exec.Command(os.Args[0], append([]string{"-test.run=TestHelperProcess", "--"}, args...)...)
+		c.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return c
+	}
+}
+
+func TestStartDiagnostics(t *testing.T) {
+	oldCmder, oldVersion := cmder, javaVersion
+	defer func() { cmder, javaVersion = oldCmder, oldVersion }()
+	cmder = helperCommand("stderr", "60")
+	javaVersion = func(*Server) string { return "test java" }
+
+	ts := bouncyServer(1000)
+	defer ts.Close()
+	s := serverFor(t, ts, WithStartupTimeout(300*time.Millisecond), WithStartupBackoff(10*time.Millisecond, 50*time.Millisecond))
+	cancel, err := s.Start(context.Background())
+	if err == nil {
+		cancel()
+		t.Fatalf("Start got no error, want an error")
+	}
+	if !errors.Is(err, ErrServerNotStarted) || !errors.Is(err, ErrStartupTimeout) {
+		t.Errorf("Start got error %v, want ErrServerNotStarted and ErrStartupTimeout", err)
+	}
+	var se *StartError
+	if !errors.As(err, &se) {
+		t.Fatalf("Start got error %v, want a *StartError", err)
+	}
+	d := se.Diagnostics
+	var want []string
+	for i := 60 - stderrTailLines + 1; i <= 60; i++ {
+		want = append(want, fmt.Sprintf("line %d", i))
+	}
+	if !reflect.DeepEqual(d.Stderr, want) {
+		t.Errorf("Start got stderr %q, want %q", d.Stderr, want)
+	}
+	if d.JavaVersion != "test java" {
+		t.Errorf("Start got Java version %q, want %q", d.JavaVersion, "test java")
+	}
+	if !strings.HasSuffix(d.Port, " listening") {
+		t.Errorf("Start got port %q, want listening", d.Port)
+	}
+	if len(d.Probes) == 0 || d.Probes[0].StatusCode != 500 {
+		t.Errorf("Start got probes %+v, want failed probes", d.Probes)
+	}
+	if len(d.Command) == 0 || !strings.Contains(err.Error(), "line 60") || !strings.Contains(err.Error(), "probes: ") {
+		t.Errorf("Start got error %q, want the command, probes and stderr", err)
+	}
+}
+
+func TestJavaVersion(t *testing.T) {
+	old := cmder
+	defer func() { cmder = old }()
+	cmder = helperCommand("java-version")
+	want := `openjdk version "17.0.2" 2022-01-18`
+	if got := javaVersion(&Server{}); got != want {
+		t.Errorf("javaVersion() = %q, want %q", got, want)
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want []string
+	}{
+		{in: "", n: 2},
+		{in: "a\nb\n", n: 2, want: []string{"a", "b"}},
+		{in: "a\nb\nc", n: 2, want: []string{"b", "c"}},
+	}
+	for _, test := range tests {
+		if got := tailLines(strings.NewReader(test.in), test.n); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("tailLines(%q, %d) = %q, want %q", test.in, test.n, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DiffOp is the kind of change a LineDiff represents.
+type DiffOp int
+
+// Kinds of LineDiff.
+const (
+	DiffEqual  DiffOp = iota // The line is in both documents.
+	DiffInsert               // The line is only in the second document.
+	DiffDelete               // The line is only in the first document.
+)
+
+// A LineDiff is a single line of a text diff.
+type LineDiff struct {
+	Op   DiffOp
+	Text string
+}
+
+// A MetadataChange is a metadata field present in both documents with
+// different values.
+type MetadataChange struct {
+	Key string
+	Old []string
+	New []string
+}
+
+// A DocumentDiff is the difference between two documents, as returned by
+// CompareDocuments.
+type DocumentDiff struct {
+	// Text is the line-based diff of the content of the documents.
+	Text []LineDiff
+	// Added holds the metadata fields only present in the second document.
+	Added map[string][]string
+	// Removed holds the metadata fields only present in the first document.
+	Removed map[string][]string
+	// Changed holds the metadata fields with different values, sorted by Key.
+	Changed []MetadataChange
+}
+
+// Equal reports whether the documents have the same content and metadata.
+func (d *DocumentDiff) Equal() bool {
+	for _, l := range d.Text {
+		if l.Op != DiffEqual {
+			return false
+		}
+	}
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// volatileFields are metadata fields that differ between two parses of the
+// same document, so they are left out of metadata diffs.
+var volatileFields = map[string]bool{
+	XTIKAContent:               true,
+	"X-TIKA:parse_time_millis": true,
+}
+
+// CompareDocuments parses a and b and returns the difference between their
+// content and metadata. Only the container documents are compared, not their
+// embedded documents. The given RequestOptions are used for both calls. If
+// the error is not nil, the diff is undefined.
+func (c *Client) CompareDocuments(ctx context.Context, a, b io.Reader, opts ...RequestOption) (*DocumentDiff, error) {
+	am, err := c.containerMeta(ctx, a, opts)
+	if err != nil {
+		return nil, err
+	}
+	bm, err := c.containerMeta(ctx, b, opts)
+	if err != nil {
+		return nil, err
+	}
+	return diffDocuments(am, bm), nil
+}
+
+// diffDocuments returns the difference between the documents with metadata
+// a and b.
+func diffDocuments(a, b map[string][]string) *DocumentDiff {
+	d := diffMetadata(a, b)
+	d.Text = diffLines(splitLines(firstValue(a, XTIKAContent)), splitLines(firstValue(b, XTIKAContent)))
+	return d
+}
+
+// containerMeta returns the metadata of the container document of input.
+func (c *Client) containerMeta(ctx context.Context, input io.Reader, opts []RequestOption) (map[string][]string, error) {
+	m, err := c.MetaRecursive(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(m) == 0 {
+		return map[string][]string{}, nil
+	}
+	return m[0], nil
+}
+
+func firstValue(m map[string][]string, key string) string {
+	if v := m[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// metaBool returns whether the boolean metadata key is "true" in m, such as
+// a flag set when a limit was reached.
+func metaBool(m map[string][]string, key string) bool {
+	return strings.EqualFold(firstValue(m, key), "true")
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffMetadata returns a DocumentDiff with the metadata changes from a to b.
+func diffMetadata(a, b map[string][]string) *DocumentDiff {
+	d := &DocumentDiff{
+		Added:   make(map[string][]string),
+		Removed: make(map[string][]string),
+	}
+	for k, av := range a {
+		if volatileFields[k] {
+			continue
+		}
+		bv, ok := b[k]
+		switch {
+		case !ok:
+			d.Removed[k] = av
+		case !reflect.DeepEqual(av, bv):
+			d.Changed = append(d.Changed, MetadataChange{Key: k, Old: av, New: bv})
+		}
+	}
+	for k, bv := range b {
+		if _, ok := a[k]; !ok && !volatileFields[k] {
+			d.Added[k] = bv
+		}
+	}
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].Key < d.Changed[j].Key })
+	return d
+}
+
+// diffLines returns the shortest edit script from a to b, using the linear
+// space variant of Myers' algorithm, so long and very different contents do
+// not need memory quadratic in their length.
+func diffLines(a, b []string) []LineDiff {
+	var r []LineDiff
+	diffRange(a, b, &r)
+	return r
+}
+
+// diffRange appends the edit script from a to b to r. It strips the common
+// prefix and suffix of a and b, then splits them in two at the middle of a
+// shortest edit script, and diffs both halves.
+func diffRange(a, b []string, r *[]LineDiff) {
+	p := 0
+	for p < len(a) && p < len(b) && a[p] == b[p] {
+		*r = append(*r, LineDiff{Op: DiffEqual, Text: a[p]})
+		p++
+	}
+	a, b = a[p:], b[p:]
+	s := 0
+	for s < len(a) && s < len(b) && a[len(a)-1-s] == b[len(b)-1-s] {
+		s++
+	}
+	suffix := a[len(a)-s:]
+	a, b = a[:len(a)-s], b[:len(b)-s]
+	// A split at an end of a and b would not make the problem smaller.
+	if x, y, ok := bisect(a, b); ok && x+y > 0 && x+y < len(a)+len(b) {
+		diffRange(a[:x], b[:y], r)
+		diffRange(a[x:], b[y:], r)
+	} else {
+		for _, l := range a {
+			*r = append(*r, LineDiff{Op: DiffDelete, Text: l})
+		}
+		for _, l := range b {
+			*r = append(*r, LineDiff{Op: DiffInsert, Text: l})
+		}
+	}
+	for _, l := range suffix {
+		*r = append(*r, LineDiff{Op: DiffEqual, Text: l})
+	}
+}
+
+// bisect returns the point (x, y) where a shortest edit script from a to b,
+// found from both ends at once, crosses its middle, and whether there is one
+// splitting a and b in smaller problems. There is none if a or b is empty.
+func bisect(a, b []string) (x, y int, ok bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	maxD := (n + m + 1) / 2
+	off := maxD
+	vf := make([]int, 2*maxD+2) // vf are the furthest x of forward paths.
+	vr := make([]int, 2*maxD+2) // vr are the furthest x of reverse paths.
+	for i := range vf {
+		vf[i], vr[i] = -1, -1
+	}
+	vf[off+1], vr[off+1] = 0, 0
+	delta := n - m
+	// The paths meet on a forward step if delta is odd, else a reverse one.
+	front := delta%2 != 0
+	// The diagonals leaving the edit graph are trimmed from the search.
+	fstart, fend, rstart, rend := 0, 0, 0, 0
+	for d := 0; d < maxD; d++ {
+		for k := -d + fstart; k <= d-fend; k += 2 {
+			i := off + k
+			var x1 int
+			if k == -d || (k != d && vf[i-1] < vf[i+1]) {
+				x1 = vf[i+1]
+			} else {
+				x1 = vf[i-1] + 1
+			}
+			y1 := x1 - k
+			for x1 < n && y1 < m && a[x1] == b[y1] {
+				x1++
+				y1++
+			}
+			vf[i] = x1
+			switch {
+			case x1 > n:
+				fend += 2
+			case y1 > m:
+				fstart += 2
+			case front:
+				if j := off + delta - k; j >= 0 && j < len(vr) && vr[j] != -1 && x1 >= n-vr[j] {
+					return x1, y1, true
+				}
+			}
+		}
+		for k := -d + rstart; k <= d-rend; k += 2 {
+			i := off + k
+			var x2 int
+			if k == -d || (k != d && vr[i-1] < vr[i+1]) {
+				x2 = vr[i+1]
+			} else {
+				x2 = vr[i-1] + 1
+			}
+			y2 := x2 - k
+			for x2 < n && y2 < m && a[n-x2-1] == b[m-y2-1] {
+				x2++
+				y2++
+			}
+			vr[i] = x2
+			switch {
+			case x2 > n:
+				rend += 2
+			case y2 > m:
+				rstart += 2
+			case !front:
+				if j := off + delta - k; j >= 0 && j < len(vf) && vf[j] != -1 && vf[j] >= n-x2 {
+					x1 := vf[j]
+					return x1, x1 - (j - off), true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
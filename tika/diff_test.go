@@ -0,0 +1,181 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want []LineDiff
+	}{
+		{},
+		{
+			a:    []string{"a", "b"},
+			b:    []string{"a", "b"},
+			want: []LineDiff{{DiffEqual, "a"}, {DiffEqual, "b"}},
+		},
+		{
+			b:    []string{"a"},
+			want: []LineDiff{{DiffInsert, "a"}},
+		},
+		{
+			a:    []string{"a"},
+			want: []LineDiff{{DiffDelete, "a"}},
+		},
+		{
+			a: []string{"a", "b", "c"},
+			b: []string{"a", "x", "c", "d"},
+			want: []LineDiff{
+				{DiffEqual, "a"},
+				{DiffDelete, "b"},
+				{DiffInsert, "x"},
+				{DiffEqual, "c"},
+				{DiffInsert, "d"},
+			},
+		},
+	}
+	for _, test := range tests {
+		if got := diffLines(test.a, test.b); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("diffLines(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+// lcsLen returns the length of the longest common subsequence of a and b.
+func lcsLen(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func TestDiffLinesShortest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		l := make([]string, r.Intn(30))
+		for i := range l {
+			l[i] = string(rune('a' + r.Intn(4)))
+		}
+		return l
+	}
+	for i := 0; i < 500; i++ {
+		a, b := lines(), lines()
+		var gotA, gotB []string
+		edits := 0
+		for _, d := range diffLines(a, b) {
+			if d.Op != DiffInsert {
+				gotA = append(gotA, d.Text)
+			}
+			if d.Op != DiffDelete {
+				gotB = append(gotB, d.Text)
+			}
+			if d.Op != DiffEqual {
+				edits++
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("diffLines(%q, %q) is not an edit script from a to b", a, b)
+		}
+		if want := len(a) + len(b) - 2*lcsLen(a, b); edits != want {
+			t.Fatalf("diffLines(%q, %q) has %d edits, want %d", a, b, edits, want)
+		}
+	}
+
+	// Long contents with nothing in common take linear memory.
+	a, b := make([]string, 5000), make([]string, 5000)
+	for i := range a {
+		a[i], b[i] = fmt.Sprint("a", i), fmt.Sprint("b", i)
+	}
+	if got := len(diffLines(a, b)); got != 10000 {
+		t.Errorf("diffLines of distinct contents has %d lines, want 10000", got)
+	}
+}
+
+func TestCompareDocuments(t *testing.T) {
+	// The server answers with the metadata for the document named by the body.
+	responses := map[string]string{
+		"a": `[{"X-TIKA:content":"title\nold line\n","author":"ann","kept":"yes","X-TIKA:parse_time_millis":"3"}]`,
+		"b": `[{"X-TIKA:content":"title\nnew line\n","author":"bob","kept":"yes","pages":"2","X-TIKA:parse_time_millis":"5"}]`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, responses[string(body)])
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+
+	got, err := c.CompareDocuments(context.Background(), strings.NewReader("a"), strings.NewReader("b"))
+	if err != nil {
+		t.Fatalf("CompareDocuments returned an error: %v", err)
+	}
+	want := &DocumentDiff{
+		Text: []LineDiff{
+			{DiffEqual, "title"},
+			{DiffDelete, "old line"},
+			{DiffInsert, "new line"},
+		},
+		Added:   map[string][]string{"pages": {"2"}},
+		Removed: map[string][]string{},
+		Changed: []MetadataChange{{Key: "author", Old: []string{"ann"}, New: []string{"bob"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareDocuments got %+v, want %+v", got, want)
+	}
+	if got.Equal() {
+		t.Errorf("CompareDocuments got Equal() = true, want false")
+	}
+
+	same, err := c.CompareDocuments(context.Background(), strings.NewReader("a"), strings.NewReader("a"))
+	if err != nil {
+		t.Fatalf("CompareDocuments returned an error: %v", err)
+	}
+	if !same.Equal() {
+		t.Errorf("CompareDocuments of the same document got %+v, want no differences", same)
+	}
+}
+
+func TestCompareDocumentsError(t *testing.T) {
+	if _, err := errorClient.CompareDocuments(context.Background(), nil, nil); err == nil {
+		t.Error("CompareDocuments got no error, want an error")
+	}
+}
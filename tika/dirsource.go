@@ -0,0 +1,193 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SkipReason is why a DirSource or an ArchiveSource skipped a file.
+type SkipReason string
+
+// SkipReasons.
+const (
+	SkipSymlink  SkipReason = "symlink"       // A symlink, with SymlinkSkip.
+	SkipCycle    SkipReason = "symlink cycle" // A directory already walked.
+	SkipBroken   SkipReason = "broken symlink"
+	SkipHardlink SkipReason = "hardlink" // A file already listed, with DedupHardlinks.
+	SkipSpecial  SkipReason = "special file"
+	SkipSparse   SkipReason = "sparse file" // With SparseSkip.
+)
+
+// SymlinkPolicy is how a DirSource handles symlinks.
+type SymlinkPolicy int
+
+// SymlinkPolicies.
+const (
+	// SymlinkSkip skips symlinks.
+	SymlinkSkip SymlinkPolicy = iota
+	// SymlinkFollow follows symlinks, to files and directories. Directories
+	// reached more than once, such as through a symlink to a parent, are only
+	// walked the first time.
+	SymlinkFollow
+)
+
+// SparsePolicy is how a DirSource handles sparse files, whose allocated size
+// is smaller than their size, such as disk images.
+type SparsePolicy int
+
+// SparsePolicies.
+const (
+	// SparseInclude lists sparse files as any other file.
+	SparseInclude SparsePolicy = iota
+	// SparseSkip skips sparse files.
+	SparseSkip
+)
+
+// DirSource is a Source providing the files of a local directory, with
+// explicit policies for the file system features naive walks mishandle. The
+// ID of an Input is its slash-separated path relative to the directory.
+//
+// Special files, such as devices, FIFOs and sockets, are always skipped:
+// reading them may block forever or never end. Hardlinks and sparse files are
+// only detected on Unix.
+type DirSource struct {
+	root string
+
+	Symlinks SymlinkPolicy
+	Sparse   SparsePolicy
+	// DedupHardlinks lists files with several hardlinks once, by the first of
+	// their paths in lexical order.
+	DedupHardlinks bool
+	// OnSkip, if not nil, is called with the IDs of the skipped files and
+	// directories.
+	OnSkip func(id string, reason SkipReason)
+}
+
+// NewDirSource creates a Source of the files under dir. By default, symlinks
+// are skipped, and hardlinks and sparse files are listed.
+func NewDirSource(dir string) *DirSource {
+	return &DirSource{root: dir}
+}
+
+func (s *DirSource) skip(id string, reason SkipReason) {
+	if s.OnSkip != nil {
+		s.OnSkip(id, reason)
+	}
+}
+
+// Walk implements Source. Inputs are walked in lexical order.
+func (s *DirSource) Walk(ctx context.Context, fn func(Input) error) error {
+	fi, err := os.Stat(s.root)
+	if err != nil {
+		return err
+	}
+	dirs := map[interface{}]bool{s.key(s.root, fi): true}
+	files := map[fileID]bool{}
+	return s.walk(ctx, s.root, "", dirs, files, fn)
+}
+
+// key identifies the directory at path, to detect cycles.
+func (s *DirSource) key(path string, fi os.FileInfo) interface{} {
+	if id, ok := fileIDOf(fi); ok {
+		return id
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path
+}
+
+func (s *DirSource) walk(ctx context.Context, dir, prefix string, dirs map[interface{}]bool, files map[fileID]bool, fn func(Input) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		id := prefix + name
+		fi, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if s.Symlinks == SymlinkSkip {
+				s.skip(id, SkipSymlink)
+				continue
+			}
+			if fi, err = os.Stat(path); err != nil {
+				s.skip(id, SkipBroken)
+				continue
+			}
+		}
+		switch {
+		case fi.IsDir():
+			k := s.key(path, fi)
+			if dirs[k] {
+				s.skip(id, SkipCycle)
+				continue
+			}
+			dirs[k] = true
+			if err := s.walk(ctx, path, id+"/", dirs, files, fn); err != nil {
+				return err
+			}
+		case !fi.Mode().IsRegular():
+			s.skip(id, SkipSpecial)
+		case s.Sparse == SparseSkip && isSparse(fi):
+			s.skip(id, SkipSparse)
+		default:
+			if fid, ok := fileIDOf(fi); ok && s.DedupHardlinks {
+				if files[fid] {
+					s.skip(id, SkipHardlink)
+					continue
+				}
+				files[fid] = true
+			}
+			if err := fn(Input{ID: id, Name: name, Size: fi.Size(), ModTime: fi.ModTime()}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Open implements Source.
+func (s *DirSource) Open(_ context.Context, id string) (io.ReadCloser, error) {
+	p := filepath.FromSlash(id)
+	if filepath.IsAbs(p) || p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) || filepath.Clean(p) != p {
+		return nil, fmt.Errorf("invalid id %q", id)
+	}
+	return os.Open(filepath.Join(s.root, p))
+}
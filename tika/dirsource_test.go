@@ -0,0 +1,123 @@
+//go:build unix
+
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+// crawlTree creates a tree with the file system features DirSource handles.
+func crawlTree(t *testing.T) string {
+	dir := tempDir(t)
+	writeAged(t, dir, "a.txt", 1, 0)
+	writeAged(t, dir, "sub/b.txt", 1, 0)
+	for _, err := range []error{
+		os.Symlink("a.txt", filepath.Join(dir, "link.txt")),
+		os.Symlink("..", filepath.Join(dir, "sub", "parent")),
+		os.Symlink("missing", filepath.Join(dir, "broken")),
+		os.Link(filepath.Join(dir, "a.txt"), filepath.Join(dir, "hard.txt")),
+		syscall.Mkfifo(filepath.Join(dir, "fifo"), 0644),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Create(filepath.Join(dir, "sparse.img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := f.Truncate(1 << 30); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestDirSource(t *testing.T) {
+	dir := crawlTree(t)
+	if fi, err := os.Stat(filepath.Join(dir, "sparse.img")); err != nil || !isSparse(fi) {
+		t.Skipf("file system does not support sparse files")
+	}
+	tests := []struct {
+		name      string
+		configure func(*DirSource)
+		want      []string
+		wantSkips map[string]SkipReason
+	}{
+		{
+			name: "defaults",
+			want: []string{"a.txt", "hard.txt", "sparse.img", "sub/b.txt"},
+			wantSkips: map[string]SkipReason{
+				"broken": SkipSymlink, "fifo": SkipSpecial, "link.txt": SkipSymlink, "sub/parent": SkipSymlink,
+			},
+		},
+		{
+			name: "follow symlinks",
+			configure: func(s *DirSource) {
+				s.Symlinks = SymlinkFollow
+				s.DedupHardlinks = true
+				s.Sparse = SparseSkip
+			},
+			want: []string{"a.txt", "sub/b.txt"},
+			wantSkips: map[string]SkipReason{
+				"broken": SkipBroken, "fifo": SkipSpecial, "hard.txt": SkipHardlink, "link.txt": SkipHardlink,
+				"sparse.img": SkipSparse, "sub/parent": SkipCycle,
+			},
+		},
+	}
+	for _, test := range tests {
+		s := NewDirSource(dir)
+		skips := map[string]SkipReason{}
+		s.OnSkip = func(id string, reason SkipReason) { skips[id] = reason }
+		if test.configure != nil {
+			test.configure(s)
+		}
+		if got := walkIDs(t, s); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Walk(%s) got %v, want %v", test.name, got, test.want)
+		}
+		if !reflect.DeepEqual(skips, test.wantSkips) {
+			t.Errorf("Walk(%s) skipped %v, want %v", test.name, skips, test.wantSkips)
+		}
+	}
+}
+
+func TestDirSourceOpen(t *testing.T) {
+	dir := crawlTree(t)
+	s := NewDirSource(filepath.Join(dir, "sub"))
+	rc, err := s.Open(context.Background(), "b.txt")
+	if err != nil {
+		t.Fatalf("Open got error: %v", err)
+	}
+	b, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	if len(b) != 1 {
+		t.Errorf("Open read %q, want 1 byte", b)
+	}
+	for _, id := range []string{"../a.txt", "/etc/passwd", "x/../../a.txt"} {
+		if _, err := s.Open(context.Background(), id); err == nil {
+			t.Errorf("Open(%q) got no error", id)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// dnsConfig is how a Client resolves the host of the Tika Server.
+type dnsConfig struct {
+	// pinned is the address connected to instead of the host, if not empty.
+	pinned string
+	// once caches the addresses of the host after the first successful
+	// connection.
+	once   bool
+	lookup func(ctx context.Context, host string) ([]string, error)
+	dialer net.Dialer
+
+	mu     sync.Mutex
+	cached []string
+}
+
+// dns returns the dnsConfig of c, creating it if needed.
+func (c *Client) dns() *dnsConfig {
+	if c.dnsConfig == nil {
+		c.dnsConfig = &dnsConfig{lookup: net.DefaultResolver.LookupHost}
+	}
+	return c.dnsConfig
+}
+
+// WithResolver returns a ClientOption to resolve the host of the Tika Server
+// with r, for example to query the DNS server of a given zone where the
+// hostname resolves differently.
+//
+// Like the other DNS options, WithResolver has no effect if the Transport of
+// the http.Client of the Client is set and is not an *http.Transport. The
+// http.Client is not modified: the Client uses a copy.
+func WithResolver(r *net.Resolver) ClientOption {
+	return func(c *Client) {
+		c.dns().lookup = r.LookupHost
+	}
+}
+
+// WithPinnedAddress returns a ClientOption to connect to addr, an IP address
+// with an optional port, instead of resolving the host of the Tika Server.
+// The host is still sent in requests, so virtual hosts and TLS keep working.
+func WithPinnedAddress(addr string) ClientOption {
+	return func(c *Client) {
+		c.dns().pinned = addr
+	}
+}
+
+// WithResolveOnce returns a ClientOption to resolve the host of the Tika
+// Server once, and connect to the same addresses afterwards, avoiding a DNS
+// lookup per connection. The host is resolved again if none of the addresses
+// can be connected to.
+func WithResolveOnce() ClientOption {
+	return func(c *Client) {
+		c.dns().once = true
+	}
+}
+
+// wrap returns a copy of httpClient, or http.DefaultClient if it is nil,
+// dialing the host of rawurl as configured by d, or httpClient if its
+// Transport cannot be configured.
+func (d *dnsConfig) wrap(httpClient *http.Client, rawurl string) *http.Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return httpClient
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t, ok := base.(*http.Transport)
+	if !ok {
+		return httpClient
+	}
+	t = t.Clone()
+	next := t.DialContext
+	if next == nil {
+		next = d.dialer.DialContext
+	}
+	host := u.Hostname()
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		h, port, err := net.SplitHostPort(addr)
+		if err != nil || h != host {
+			// Not the Tika Server, such as a proxy.
+			return next(ctx, network, addr)
+		}
+		return d.dial(ctx, network, h, port, next)
+	}
+	hc := *httpClient
+	hc.Transport = t
+	return &hc
+}
+
+func (d *dnsConfig) dial(ctx context.Context, network, host, port string, next func(context.Context, string, string) (net.Conn, error)) (net.Conn, error) {
+	if d.pinned != "" {
+		addr := d.pinned
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, port)
+		}
+		return next(ctx, network, addr)
+	}
+	d.mu.Lock()
+	addrs := d.cached
+	d.mu.Unlock()
+	cached := addrs != nil
+	if !cached {
+		var err error
+		if addrs, err = d.lookup(ctx, host); err != nil {
+			return nil, err
+		}
+	}
+	var lastErr error
+	for _, a := range addrs {
+		conn, err := next(ctx, network, net.JoinHostPort(a, port))
+		if err == nil {
+			if d.once && !cached {
+				d.mu.Lock()
+				d.cached = addrs
+				d.mu.Unlock()
+			}
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if cached {
+		d.mu.Lock()
+		d.cached = nil
+		d.mu.Unlock()
+	}
+	if lastErr == nil {
+		lastErr = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	return nil, lastErr
+}
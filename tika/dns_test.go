@@ -0,0 +1,118 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// versionServer returns a server answering any request with a version, and
+// the URL of the server with the host tika.test.
+func versionServer(t *testing.T) (*httptest.Server, string) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "Apache Tika 1.14 on "+r.Host)
+	}))
+	u, _ := url.Parse(ts.URL)
+	return ts, "http://tika.test:" + u.Port()
+}
+
+func TestWithPinnedAddress(t *testing.T) {
+	ts, tikaURL := versionServer(t)
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	for _, addr := range []string{"127.0.0.1", u.Host} {
+		// A new http.Client, not to reuse the connections of other tests.
+		c := NewClient(&http.Client{}, tikaURL, WithPinnedAddress(addr))
+		got, err := c.Version(context.Background())
+		if err != nil {
+			t.Errorf("Version with address %s got error: %v", addr, err)
+			continue
+		}
+		if !strings.HasSuffix(got, "tika.test:"+u.Port()) {
+			t.Errorf("Version with address %s = %q, want the tika.test host", addr, got)
+		}
+	}
+	// The http.DefaultClient is copied when none is given.
+	c := NewClient(nil, tikaURL, WithPinnedAddress(u.Host))
+	if _, err := c.Version(context.Background()); err != nil {
+		t.Errorf("Version of a Client without http.Client got error: %v", err)
+	}
+	if c.httpClient == http.DefaultClient {
+		t.Errorf("NewClient with a pinned address uses the http.DefaultClient")
+	}
+}
+
+func TestWithResolveOnce(t *testing.T) {
+	ts, tikaURL := versionServer(t)
+	defer ts.Close()
+	tests := []struct {
+		name        string
+		options     []ClientOption
+		wantLookups int
+	}{
+		{name: "every connection", wantLookups: 3},
+		{name: "once", options: []ClientOption{WithResolveOnce()}, wantLookups: 1},
+	}
+	for _, test := range tests {
+		lookups := 0
+		// Disable keep-alives so each call connects.
+		hc := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+		c := NewClient(hc, tikaURL, append(test.options, func(c *Client) {
+			c.dns().lookup = func(_ context.Context, host string) ([]string, error) {
+				lookups++
+				if host != "tika.test" {
+					return nil, fmt.Errorf("unexpected lookup of %s", host)
+				}
+				return []string{"127.0.0.1"}, nil
+			}
+		})...)
+		for i := 0; i < 3; i++ {
+			if _, err := c.Version(context.Background()); err != nil {
+				t.Fatalf("%s: Version got error: %v", test.name, err)
+			}
+		}
+		if lookups != test.wantLookups {
+			t.Errorf("%s: got %d lookups, want %d", test.name, lookups, test.wantLookups)
+		}
+		if hc.Transport.(*http.Transport).DialContext != nil {
+			t.Errorf("%s: NewClient modified the given http.Client", test.name)
+		}
+	}
+}
+
+func TestWithResolver(t *testing.T) {
+	errNoDNS := errors.New("no DNS here")
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errNoDNS
+		},
+	}
+	c := NewClient(&http.Client{}, "http://tika.test:9998", WithResolver(r))
+	// The resolver does not wrap the errors of Dial.
+	if _, err := c.Version(context.Background()); err == nil || !strings.Contains(err.Error(), errNoDNS.Error()) {
+		t.Errorf("Version got error %v, want the error of the resolver", err)
+	}
+}
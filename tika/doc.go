@@ -58,6 +58,17 @@ create a client, and call client.Parse.
 	body, err := client.Parse(context.Background(), f)
 
 If you pass an *http.Client to tika.NewClient, it will be used for all requests.
+Pass tika.ClientOptions to NewClient to configure the Client's behavior, for
+example to give quick calls a shorter timeout than expensive ones:
+
+	client := tika.NewClient(nil, s.URL(),
+		tika.WithDefaultTimeout(2*time.Minute),
+		tika.WithEndpointTimeout(tika.EndpointDetect, 5*time.Second))
+
+Tika detects the type of a document more reliably when it knows the original
+file name. Pass tika.RequestOptions to give it hints:
+
+	body, err := client.Parse(context.Background(), f, tika.WithResourceName("report.key"))
 
 Some functions return a custom type, like Parsers(), Detectors(), and
 MIMETypes(). Use these to see what features are supported by the current
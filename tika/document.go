@@ -0,0 +1,44 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+// A Document is the result of extracting a single input. It is the unit of
+// work of the utilities processing many inputs, such as Sampler.
+type Document struct {
+	// ID identifies the input, for example by its path.
+	ID string `json:"id"`
+	// ContentType is the MIME type Tika detected for the input.
+	ContentType string `json:"contentType,omitempty"`
+	// Size is the size of the input in bytes, or UnknownSize.
+	Size int64 `json:"size"`
+	// Content is the extracted text.
+	Content string `json:"content,omitempty"`
+	// ContentRef references the extracted text in a BlobStore, when it is
+	// stored there rather than in Content.
+	ContentRef BlobRef `json:"contentRef,omitempty"`
+	// Attachments reference the attachments of the input in a BlobStore.
+	Attachments []BlobRef `json:"attachments,omitempty"`
+	// Links are the hyperlinks of the input, extracted by a Job with Links
+	// set. Their offsets refer to Content.
+	Links []Link `json:"links,omitempty"`
+	// Metadata is the extracted metadata.
+	Metadata map[string][]string `json:"metadata,omitempty"`
+	// IdempotencyKey is the key deduplicating the emission of the Document
+	// by a Job, so that consumers can deduplicate it too. See
+	// Job.Idempotency.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
@@ -0,0 +1,323 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// downloadConfig is the configuration of DownloadServer.
+type downloadConfig struct {
+	concurrency int
+}
+
+// A DownloadOption can be passed to DownloadServer to configure the download.
+type DownloadOption func(*downloadConfig)
+
+// WithDownloadConcurrency returns a DownloadOption to set how many ranges of
+// the JAR are downloaded in parallel (default 4). Servers which do not support
+// range requests are always downloaded with a single request.
+func WithDownloadConcurrency(n int) DownloadOption {
+	return func(c *downloadConfig) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// downloadChunk is a byte range of a download. End is inclusive.
+type downloadChunk struct {
+	Start   int64 `json:"start"`
+	End     int64 `json:"end"`
+	Written int64 `json:"written"`
+}
+
+func (c *downloadChunk) done() bool {
+	return c.Start+c.Written > c.End
+}
+
+// downloadSaveInterval is how many bytes are downloaded between saves of the
+// state of a ranged download.
+var downloadSaveInterval int64 = 4 << 20
+
+// downloadState is the progress of a ranged download. It is saved next to the
+// part file while downloading, so an interrupted download can be resumed even
+// if the process is killed.
+type downloadState struct {
+	// saveMu serializes saves, so an older state never replaces a newer one.
+	saveMu sync.Mutex
+	mu     sync.Mutex
+	URL    string           `json:"url"`
+	Size   int64            `json:"size"`
+	Chunks []*downloadChunk `json:"chunks"`
+	// ETag and LastModified are the validators of the file being downloaded,
+	// to tell whether it changed since.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// unsaved is the number of bytes written since the last save.
+	unsaved int64
+}
+
+// newDownloadState splits a download of size bytes into n chunks. h is the
+// header of the HEAD response, holding the validators of the file.
+func newDownloadState(url string, size int64, n int, h http.Header) *downloadState {
+	s := &downloadState{URL: url, Size: size, ETag: h.Get("ETag"), LastModified: h.Get("Last-Modified")}
+	chunkSize := (size + int64(n) - 1) / int64(n)
+	for start := int64(0); start < size; start += chunkSize {
+		end := start + chunkSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		s.Chunks = append(s.Chunks, &downloadChunk{Start: start, End: end})
+	}
+	return s
+}
+
+// loadDownloadState reads the state saved at path. It returns nil if there is
+// no usable state for downloading size bytes from url, with the validators in
+// h: the file changed if they differ from the saved ones.
+func loadDownloadState(path, url string, size int64, h http.Header) *downloadState {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	s := new(downloadState)
+	if err := json.Unmarshal(b, s); err != nil || s.URL != url || s.Size != size {
+		return nil
+	}
+	if s.ETag != h.Get("ETag") || s.LastModified != h.Get("Last-Modified") {
+		return nil
+	}
+	return s
+}
+
+// ifRange returns the If-Range header of the range requests of s: its ETag
+// if it is strong, else its Last-Modified date, so the server sends the whole
+// file rather than a range of another version of it.
+func (s *downloadState) ifRange() string {
+	if s.ETag != "" && !strings.HasPrefix(s.ETag, "W/") {
+		return s.ETag
+	}
+	return s.LastModified
+}
+
+// errDownloadChanged is returned by downloadRange if the file changed since
+// the download started.
+var errDownloadChanged = errors.New("file changed during download")
+
+// save saves s at path. If f, the part file, is not nil, it is synced first,
+// so the saved state never counts bytes which are not on disk. The state is
+// replaced atomically, so a crash while saving keeps the previous one.
+func (s *downloadState) save(f *os.File, path string) error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	s.mu.Lock()
+	b, err := json.Marshal(s)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if f != nil {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// chunkWriter writes to a chunk of a file, recording its progress, and saves
+// the state of the download at statePath every downloadSaveInterval bytes.
+type chunkWriter struct {
+	f         *os.File
+	state     *downloadState
+	chunk     *downloadChunk
+	statePath string
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.state.mu.Lock()
+	off := w.chunk.Start + w.chunk.Written
+	w.state.mu.Unlock()
+	if remaining := w.chunk.End - off + 1; int64(len(p)) > remaining {
+		return 0, fmt.Errorf("server sent more than the requested range")
+	}
+	n, err := w.f.WriteAt(p, off)
+	w.state.mu.Lock()
+	w.chunk.Written += int64(n)
+	w.state.unsaved += int64(n)
+	save := w.state.unsaved >= downloadSaveInterval
+	if save {
+		w.state.unsaved = 0
+	}
+	w.state.mu.Unlock()
+	if err == nil && save {
+		if err := w.state.save(w.f, w.statePath); err != nil {
+			return n, fmt.Errorf("error saving download state: %w", err)
+		}
+	}
+	return n, err
+}
+
+// download saves url at part. If the server supports range requests, the
+// download is split into cfg.concurrency parallel requests and its progress is
+// saved at part+".state" as it goes and when it fails or ctx is canceled, so
+// calling download again resumes it. A download is restarted from scratch if
+// the file changed since it started, as told by its ETag or Last-Modified
+// date.
+func download(ctx context.Context, url, part string, cfg *downloadConfig) error {
+	err := downloadRanges(ctx, url, part, cfg)
+	if errors.Is(err, errDownloadChanged) {
+		// The state was removed, so this starts over, against the new file.
+		err = downloadRanges(ctx, url, part, cfg)
+	}
+	return err
+}
+
+// downloadRanges downloads url at part, as described by download.
+func downloadRanges(ctx context.Context, url, part string, cfg *downloadConfig) error {
+	resp, err := ctxhttp.Head(ctx, nil, url)
+	if err != nil {
+		return fmt.Errorf("unable to download %q: %w", url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Accept-Ranges") != "bytes" || resp.ContentLength <= 0 {
+		return downloadWhole(ctx, url, part)
+	}
+	size := resp.ContentLength
+
+	statePath := part + ".state"
+	state := loadDownloadState(statePath, url, size, resp.Header)
+	if _, err := os.Stat(part); err != nil {
+		// The state is of a part file which is gone.
+		state = nil
+	}
+	flags := os.O_RDWR | os.O_CREATE
+	if state == nil {
+		state = newDownloadState(url, size, cfg.concurrency, resp.Header)
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(state.Chunks))
+	var wg sync.WaitGroup
+	for _, c := range state.Chunks {
+		if c.done() {
+			continue
+		}
+		wg.Add(1)
+		go func(c *downloadChunk) {
+			defer wg.Done()
+			if err := downloadRange(ctx, url, &chunkWriter{f: f, state: state, chunk: c, statePath: statePath}); err != nil {
+				errs <- err
+				cancel()
+			}
+		}(c)
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; errors.Is(err, errDownloadChanged) {
+		if rmErr := os.Remove(statePath); rmErr != nil && !os.IsNotExist(rmErr) {
+			return fmt.Errorf("%w: error removing download state: %w", err, rmErr)
+		}
+		return err
+	} else if err != nil {
+		if saveErr := state.save(f, statePath); saveErr != nil {
+			return fmt.Errorf("%w: error saving download state: %w", err, saveErr)
+		}
+		return err
+	}
+	if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing download state: %w", err)
+	}
+	return nil
+}
+
+// downloadRange downloads the remaining bytes of w.chunk from url.
+func downloadRange(ctx context.Context, url string, w *chunkWriter) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	w.state.mu.Lock()
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", w.chunk.Start+w.chunk.Written, w.chunk.End))
+	w.state.mu.Unlock()
+	if v := w.state.ifRange(); v != "" {
+		req.Header.Set("If-Range", v)
+	}
+	resp, err := ctxhttp.Do(ctx, nil, req)
+	if err != nil {
+		return fmt.Errorf("unable to download %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && req.Header.Get("If-Range") != "" {
+		return fmt.Errorf("unable to download %q: %w", url, errDownloadChanged)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("unable to download %q: range request got response code %v", url, resp.StatusCode)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("error saving download: %w", err)
+	}
+	if !w.chunk.done() {
+		return fmt.Errorf("error saving download: range %d-%d incomplete", w.chunk.Start, w.chunk.End)
+	}
+	return nil
+}
+
+// downloadWhole saves url at part with a single request.
+func downloadWhole(ctx context.Context, url, part string) error {
+	out, err := os.Create(part)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer out.Close()
+
+	resp, err := ctxhttp.Get(ctx, nil, url)
+	if err != nil {
+		return fmt.Errorf("unable to download %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to download %q: response code %v", url, resp.StatusCode)
+	}
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return fmt.Errorf("error saving download: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testVersion Version = "test"
+
+// jarServer serves jar, counting the bytes it sends. If ranges is false, it
+// ignores range requests. While stall is set, range requests get the first
+// half of their range and then hang until they are canceled. etag is the ETag
+// of jar; if headETag is set, the next HEAD request gets it instead, as if jar
+// changed right after.
+type jarServer struct {
+	jar    []byte
+	ranges bool
+
+	mu       sync.Mutex
+	sent     int
+	stall    bool
+	etag     string
+	headETag string
+}
+
+func (s *jarServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	stall, etag := s.stall, s.etag
+	if r.Method == "HEAD" && s.headETag != "" {
+		etag, s.headETag = s.headETag, ""
+	}
+	s.mu.Unlock()
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	var start, end int
+	if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); stall && err == nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(s.jar)))
+		w.WriteHeader(http.StatusPartialContent)
+		half := s.jar[start : start+(end-start+1)/2]
+		s.count(len(half))
+		w.Write(half)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		return
+	}
+	if !s.ranges {
+		r.Header.Del("Range")
+		w.Header().Set("Content-Length", fmt.Sprint(len(s.jar)))
+		if r.Method != "HEAD" {
+			s.count(len(s.jar))
+			w.Write(s.jar)
+		}
+		return
+	}
+	cw := &countingWriter{ResponseWriter: w, s: s}
+	http.ServeContent(cw, r, "tika-server.jar", time.Time{}, bytes.NewReader(s.jar))
+}
+
+func (s *jarServer) count(n int) {
+	s.mu.Lock()
+	s.sent += n
+	s.mu.Unlock()
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	s *jarServer
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.s.count(len(p))
+	return w.ResponseWriter.Write(p)
+}
+
+// withTestJAR registers jar as the server version testVersion, served by s,
+// for the duration of the test.
+func withTestJAR(t *testing.T, s *jarServer) {
+	ts := httptest.NewServer(s)
+	oldURL := serverJARURL
+	serverJARURL = func(Version) string { return ts.URL }
+	md5s[testVersion] = fmt.Sprintf("%x", md5.Sum(s.jar))
+	t.Cleanup(func() {
+		ts.Close()
+		serverJARURL = oldURL
+		delete(md5s, testVersion)
+	})
+}
+
+func testJAR() []byte {
+	return []byte(strings.Repeat("0123456789abcdef", 1000))
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "go-tika")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestDownloadServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranges  bool
+		options []DownloadOption
+	}{
+		{name: "ranges", ranges: true},
+		{name: "one range", ranges: true, options: []DownloadOption{WithDownloadConcurrency(1)}},
+		{name: "many ranges", ranges: true, options: []DownloadOption{WithDownloadConcurrency(7)}},
+		{name: "no ranges"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &jarServer{jar: testJAR(), ranges: test.ranges}
+			withTestJAR(t, s)
+			path := filepath.Join(tempDir(t), "tika-server.jar")
+
+			if err := DownloadServer(context.Background(), testVersion, path, test.options...); err != nil {
+				t.Fatalf("DownloadServer got error: %v", err)
+			}
+			got, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("error reading download: %v", err)
+			}
+			if !bytes.Equal(got, s.jar) {
+				t.Errorf("DownloadServer saved %d bytes, want the %d bytes served", len(got), len(s.jar))
+			}
+			for _, leftover := range []string{path + ".part", path + ".part.state"} {
+				if _, err := os.Stat(leftover); err == nil {
+					t.Errorf("DownloadServer left %s behind", leftover)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadServerResume(t *testing.T) {
+	s := &jarServer{jar: testJAR(), ranges: true, etag: `"v1"`}
+	withTestJAR(t, s)
+	path := filepath.Join(tempDir(t), "tika-server.jar")
+	part := path + ".part"
+
+	// Simulate an interrupted download: the first half of the first chunk was
+	// saved, and the second chunk is complete.
+	size := int64(len(s.jar))
+	state := newDownloadState(serverJARURL(testVersion), size, 2, http.Header{"Etag": {s.etag}})
+	state.Chunks[0].Written = state.Chunks[0].End / 2
+	state.Chunks[1].Written = state.Chunks[1].End - state.Chunks[1].Start + 1
+	partial := make([]byte, size)
+	copy(partial, s.jar[:state.Chunks[0].Written])
+	copy(partial[state.Chunks[1].Start:], s.jar[state.Chunks[1].Start:])
+	if err := ioutil.WriteFile(part, partial, 0644); err != nil {
+		t.Fatalf("error writing part file: %v", err)
+	}
+	if err := state.save(nil, part+".state"); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+
+	if err := DownloadServer(context.Background(), testVersion, path); err != nil {
+		t.Fatalf("DownloadServer got error: %v", err)
+	}
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading download: %v", err)
+	}
+	if !bytes.Equal(got, s.jar) {
+		t.Errorf("DownloadServer saved a different file than the one served")
+	}
+	if want := int(state.Chunks[0].End - state.Chunks[0].Written + 1); s.sent != want {
+		t.Errorf("DownloadServer downloaded %d bytes, want %d", s.sent, want)
+	}
+}
+
+func TestDownloadServerChanged(t *testing.T) {
+	s := &jarServer{jar: testJAR(), ranges: true, etag: `"v2"`}
+	withTestJAR(t, s)
+	path := filepath.Join(tempDir(t), "tika-server.jar")
+	part := path + ".part"
+	size := int64(len(s.jar))
+
+	// The part file is of a previous version of the JAR.
+	state := newDownloadState(serverJARURL(testVersion), size, 2, http.Header{"Etag": {`"v1"`}})
+	state.Chunks[1].Written = state.Chunks[1].End - state.Chunks[1].Start + 1
+	if err := ioutil.WriteFile(part, bytes.Repeat([]byte{'x'}, int(size)), 0644); err != nil {
+		t.Fatalf("error writing part file: %v", err)
+	}
+	if err := state.save(nil, part+".state"); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+	if err := DownloadServer(context.Background(), testVersion, path); err != nil {
+		t.Fatalf("DownloadServer of a changed JAR got error: %v", err)
+	}
+	if got, err := ioutil.ReadFile(path); err != nil || !bytes.Equal(got, s.jar) {
+		t.Errorf("DownloadServer of a changed JAR saved a different file than the one served, error %v", err)
+	}
+
+	// The JAR changes after the HEAD request: the range requests get it whole,
+	// and the download starts over.
+	os.Remove(path)
+	s.mu.Lock()
+	s.headETag = `"v1"`
+	s.mu.Unlock()
+	if err := DownloadServer(context.Background(), testVersion, path); err != nil {
+		t.Fatalf("DownloadServer of a JAR changing got error: %v", err)
+	}
+	if got, err := ioutil.ReadFile(path); err != nil || !bytes.Equal(got, s.jar) {
+		t.Errorf("DownloadServer of a JAR changing saved a different file than the one served, error %v", err)
+	}
+}
+
+func TestDownloadServerInterrupted(t *testing.T) {
+	old := downloadSaveInterval
+	downloadSaveInterval = 1
+	defer func() { downloadSaveInterval = old }()
+	s := &jarServer{jar: testJAR(), ranges: true, stall: true}
+	withTestJAR(t, s)
+	path := filepath.Join(tempDir(t), "tika-server.jar")
+	statePath := path + ".part.state"
+	size := int64(len(s.jar))
+
+	// The state is saved while the download hangs, before it fails, so it
+	// survives the process being killed.
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- DownloadServer(ctx, testVersion, path, WithDownloadConcurrency(2)) }()
+	written := func() int64 {
+		var n int64
+		if state := loadDownloadState(statePath, serverJARURL(testVersion), size, http.Header{}); state != nil {
+			for _, c := range state.Chunks {
+				n += c.Written
+			}
+		}
+		return n
+	}
+	for deadline := time.Now().Add(5 * time.Second); written() != size/2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("state of the hanging download has %d bytes written, want %d", written(), size/2)
+		}
+	}
+	cancel()
+	if err := <-errc; err == nil {
+		t.Fatal("canceled DownloadServer got no error")
+	}
+	if got := written(); got != size/2 {
+		t.Errorf("state of the canceled download has %d bytes written, want %d", got, size/2)
+	}
+
+	s.mu.Lock()
+	s.stall, s.sent = false, 0
+	s.mu.Unlock()
+	if err := DownloadServer(context.Background(), testVersion, path); err != nil {
+		t.Fatalf("resumed DownloadServer got error: %v", err)
+	}
+	if got, err := ioutil.ReadFile(path); err != nil || !bytes.Equal(got, s.jar) {
+		t.Errorf("resumed DownloadServer saved a different file than the one served, error %v", err)
+	}
+	if want := int(size - size/2); s.sent != want {
+		t.Errorf("resumed DownloadServer downloaded %d bytes, want %d", s.sent, want)
+	}
+}
+
+func TestDownloadServerInvalidMD5(t *testing.T) {
+	s := &jarServer{jar: testJAR(), ranges: true}
+	withTestJAR(t, s)
+	md5s[testVersion] = "does not match"
+	path := filepath.Join(tempDir(t), "tika-server.jar")
+
+	if err := DownloadServer(context.Background(), testVersion, path); err == nil {
+		t.Fatal("DownloadServer got no error, want an error")
+	}
+	for _, p := range []string{path, path + ".part"} {
+		if _, err := os.Stat(p); err == nil {
+			t.Errorf("DownloadServer left %s behind after an invalid download", p)
+		}
+	}
+}
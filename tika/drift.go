@@ -0,0 +1,188 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ErrSchemaDrift is wrapped by the MetadataDrift errors of strict
+// DriftCheckers.
+var ErrSchemaDrift = errors.New("metadata schema drift")
+
+// An ExpectedSchema lists the metadata keys expected for documents of a MIME
+// type.
+type ExpectedSchema struct {
+	// Required are the keys every document must have.
+	Required []string
+	// Optional are the keys documents may have.
+	Optional []string
+}
+
+// Expected returns the ExpectedSchema of the documents s was inferred from:
+// the fields every document had are required, and the others optional.
+// Infer a Schema per MIME type, from the output of the Tika version in use,
+// to detect changes after an upgrade.
+func (s *Schema) Expected() ExpectedSchema {
+	var e ExpectedSchema
+	for _, f := range s.Fields {
+		if f.Count == s.Documents {
+			e.Required = append(e.Required, f.Name)
+		} else {
+			e.Optional = append(e.Optional, f.Name)
+		}
+	}
+	return e
+}
+
+// A MetadataDrift is the difference between the metadata of a document and
+// its ExpectedSchema.
+type MetadataDrift struct {
+	// ContentType is the MIME type of the document, without parameters.
+	ContentType string
+	// Unknown are the keys which are not expected, sorted.
+	Unknown []string
+	// Missing are the required keys the document does not have, sorted.
+	Missing []string
+	// Strict is whether the drift is an error rather than a warning. A strict
+	// MetadataDrift wraps ErrSchemaDrift.
+	Strict bool
+}
+
+func (d *MetadataDrift) Error() string {
+	var parts []string
+	if len(d.Unknown) > 0 {
+		parts = append(parts, "unknown keys "+strings.Join(d.Unknown, ", "))
+	}
+	if len(d.Missing) > 0 {
+		parts = append(parts, "missing keys "+strings.Join(d.Missing, ", "))
+	}
+	return fmt.Sprintf("%v for %s: %s", ErrSchemaDrift, d.ContentType, strings.Join(parts, "; "))
+}
+
+func (d *MetadataDrift) Unwrap() error {
+	if d.Strict {
+		return ErrSchemaDrift
+	}
+	return nil
+}
+
+// A DriftChecker compares the metadata of documents with the ExpectedSchema
+// of their MIME type, to catch silent changes of behavior, for example after
+// a Tika upgrade.
+//
+// A DriftChecker is safe for concurrent use. Do not change its fields once
+// it is in use.
+type DriftChecker struct {
+	// Schemas maps MIME types or patterns, as defined by path.Match, to the
+	// ExpectedSchema of the matching documents. A document is checked against
+	// the matching key with the fewest wildcards. Documents of other types
+	// are not checked.
+	Schemas map[string]ExpectedSchema
+	// Ignore are patterns, as defined by path.Match, of keys never reported,
+	// such as "X-TIKA:EXCEPTION:*". The keys whose value changes on every
+	// parse, such as X-TIKA:parse_time_millis, are always ignored.
+	Ignore []string
+	// Strict makes drift an error: a Job fails the drifting documents.
+	// Otherwise drift is a warning, passed to OnDrift.
+	Strict bool
+	// OnDrift, if not nil, is called with the drift of documents when not
+	// Strict, concurrently from the workers of a Job.
+	OnDrift func(id string, drift *MetadataDrift)
+}
+
+// Check returns the drift of the metadata m from its ExpectedSchema, or nil if
+// there is none or its type has no ExpectedSchema.
+func (c *DriftChecker) Check(m map[string][]string) *MetadataDrift {
+	typ := firstValue(m, "Content-Type")
+	if t, _, err := mime.ParseMediaType(typ); err == nil {
+		typ = t
+	}
+	e, ok := c.schema(typ)
+	if !ok {
+		return nil
+	}
+	expected := make(map[string]bool)
+	for _, k := range e.Optional {
+		expected[k] = true
+	}
+	d := &MetadataDrift{ContentType: typ, Strict: c.Strict}
+	for _, k := range e.Required {
+		expected[k] = true
+		if _, ok := m[k]; !ok && !c.ignored(k) {
+			d.Missing = append(d.Missing, k)
+		}
+	}
+	for k := range m {
+		if !expected[k] && !c.ignored(k) {
+			d.Unknown = append(d.Unknown, k)
+		}
+	}
+	if len(d.Unknown) == 0 && len(d.Missing) == 0 {
+		return nil
+	}
+	sort.Strings(d.Unknown)
+	sort.Strings(d.Missing)
+	return d
+}
+
+// schema returns the ExpectedSchema of the MIME type typ.
+func (c *DriftChecker) schema(typ string) (ExpectedSchema, bool) {
+	if e, ok := c.Schemas[typ]; ok {
+		return e, true
+	}
+	var patterns []string
+	for p := range c.Schemas {
+		patterns = append(patterns, p)
+	}
+	sortBySpecificity(patterns)
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, typ); ok {
+			return c.Schemas[p], true
+		}
+	}
+	return ExpectedSchema{}, false
+}
+
+func (c *DriftChecker) ignored(key string) bool {
+	return volatileFields[key] || matchAny(c.Ignore, key)
+}
+
+// checkDrift checks the metadata of doc with j.Drift, and returns the drift
+// if it is an error.
+func (j *Job) checkDrift(doc Document) error {
+	if j.Drift == nil {
+		return nil
+	}
+	d := j.Drift.Check(doc.Metadata)
+	if d == nil {
+		return nil
+	}
+	j.update(func(s *JobStatus) { s.Drifted++ })
+	if d.Strict {
+		return d
+	}
+	if j.Drift.OnDrift != nil {
+		j.Drift.OnDrift(doc.ID, d)
+	}
+	return nil
+}
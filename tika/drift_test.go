@@ -0,0 +1,86 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaExpected(t *testing.T) {
+	s := &Schema{Documents: 2, Fields: []FieldSchema{
+		{Name: "Content-Type", Count: 2},
+		{Name: "dc:title", Count: 1},
+	}}
+	want := ExpectedSchema{Required: []string{"Content-Type"}, Optional: []string{"dc:title"}}
+	if got := s.Expected(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDriftChecker(t *testing.T) {
+	c := &DriftChecker{
+		Schemas: map[string]ExpectedSchema{
+			"application/pdf": {Required: []string{"Content-Type", "xmpTPg:NPages"}, Optional: []string{"dc:title"}},
+			"application/*":   {Required: []string{"Content-Type"}},
+		},
+		Ignore: []string{"X-TIKA:EXCEPTION:*"},
+	}
+	tests := []struct {
+		name string
+		m    map[string][]string
+		want *MetadataDrift
+	}{
+		{
+			name: "matching",
+			m:    map[string][]string{"Content-Type": {"application/pdf"}, "xmpTPg:NPages": {"1"}, "X-TIKA:parse_time_millis": {"3"}},
+		},
+		{
+			name: "unchecked type",
+			m:    map[string][]string{"Content-Type": {"text/plain"}, "new": {"x"}},
+		},
+		{
+			name: "ignored",
+			m:    map[string][]string{"Content-Type": {"application/pdf"}, "xmpTPg:NPages": {"1"}, "X-TIKA:EXCEPTION:warn": {"x"}},
+		},
+		{
+			name: "drift",
+			m:    map[string][]string{"Content-Type": {"application/pdf; version=1.7"}, "pdf:new": {"x"}, "dc:title": {"t"}},
+			want: &MetadataDrift{ContentType: "application/pdf", Unknown: []string{"pdf:new"}, Missing: []string{"xmpTPg:NPages"}},
+		},
+		{
+			name: "pattern",
+			m:    map[string][]string{"Content-Type": {"application/zip"}, "xmpTPg:NPages": {"1"}},
+			want: &MetadataDrift{ContentType: "application/zip", Unknown: []string{"xmpTPg:NPages"}},
+		},
+	}
+	for _, test := range tests {
+		if got := c.Check(test.m); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Check(%s) = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+
+	d := c.Check(tests[3].m)
+	if errors.Is(d, ErrSchemaDrift) {
+		t.Errorf("non-strict drift %v is ErrSchemaDrift", d)
+	}
+	c.Strict = true
+	if d := c.Check(tests[3].m); !errors.Is(d, ErrSchemaDrift) {
+		t.Errorf("strict drift %v is not ErrSchemaDrift", d)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io"
+	"strconv"
+)
+
+// Metadata keys Tika sets on documents when an extraction was limited.
+const (
+	// embeddedLimitKey is set on the container document when the server
+	// stopped extracting embedded documents at its maxEmbeddedResources.
+	embeddedLimitKey = tikaExceptionPrefix + "embedded_resource_limit_reached"
+	// writeLimitKey is set on the documents whose content was truncated at
+	// the write limit of the server.
+	writeLimitKey = tikaExceptionPrefix + "write_limit_reached"
+	// embeddedDepthKey is the depth of embedded documents, 1 for the
+	// documents embedded in the container.
+	embeddedDepthKey = "X-TIKA:embedded_depth"
+)
+
+// EmbeddedLimits limits the embedded documents returned by
+// MetaRecursiveLimited. Zero fields are unlimited.
+type EmbeddedLimits struct {
+	// MaxEmbedded is the maximum number of embedded documents. It is also
+	// sent to the server, so it stops extracting once it is reached.
+	MaxEmbedded int
+	// MaxDepth is the maximum depth of embedded documents: 1 keeps the
+	// documents embedded in the container only. Deeper documents are still
+	// extracted by the server, which has no such limit.
+	MaxDepth int
+}
+
+// A RecursiveResult is the result of MetaRecursiveLimited. Extractions cut
+// short by a limit are flagged, so they are never mistaken for complete ones.
+type RecursiveResult struct {
+	// Documents are the metadata of the container document, first, and of
+	// the embedded documents returned, as by MetaRecursive.
+	Documents []map[string][]string
+	// Embedded is the number of embedded documents in Documents.
+	Embedded int
+	// Omitted is the number of embedded documents returned by the server
+	// which were left out of Documents by the EmbeddedLimits. The server
+	// does not tell how many documents it did not extract.
+	Omitted int
+	// TruncatedEmbedded is whether embedded documents are missing, because
+	// the server or the client reached a limit.
+	TruncatedEmbedded bool
+	// TruncatedContent is whether the content of a document was truncated
+	// at the write limit of the server.
+	TruncatedContent bool
+}
+
+// WithMaxEmbeddedResources returns a RequestOption to limit the number of
+// embedded documents extracted by MetaRecursive and ParseRecursive to n.
+// Tika flags the container document when the limit is reached; see
+// MetaRecursiveLimited to check it.
+func WithMaxEmbeddedResources(n int) RequestOption {
+	return func(cfg *callConfig) {
+		cfg.setHeader("maxEmbeddedResources", strconv.Itoa(n))
+	}
+}
+
+// MetaRecursiveLimited is like MetaRecursive, but returns at most the embedded
+// documents allowed by limits, and reports whether the result is truncated,
+// by limits or by the limits of the server. If the error is not nil, the
+// result is undefined.
+func (c *Client) MetaRecursiveLimited(ctx context.Context, input io.Reader, limits EmbeddedLimits, opts ...RequestOption) (*RecursiveResult, error) {
+	if limits.MaxEmbedded > 0 {
+		opts = append([]RequestOption{WithMaxEmbeddedResources(limits.MaxEmbedded)}, opts...)
+	}
+	docs, err := c.MetaRecursive(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	r := &RecursiveResult{}
+	for i, d := range docs {
+		if metaBool(d, writeLimitKey) {
+			r.TruncatedContent = true
+		}
+		if i == 0 {
+			r.TruncatedEmbedded = metaBool(d, embeddedLimitKey)
+			r.Documents = append(r.Documents, d)
+			continue
+		}
+		depth, _ := strconv.Atoi(firstValue(d, embeddedDepthKey))
+		if (limits.MaxEmbedded > 0 && r.Embedded == limits.MaxEmbedded) || (limits.MaxDepth > 0 && depth > limits.MaxDepth) {
+			r.Omitted++
+			r.TruncatedEmbedded = true
+			continue
+		}
+		r.Embedded++
+		r.Documents = append(r.Documents, d)
+	}
+	return r, nil
+}
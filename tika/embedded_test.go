@@ -0,0 +1,125 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// embeddedServer is a Tika server whose /rmeta/text endpoint returns a
+// container with an embedded document at depth 1 and 2, alternately, up to
+// the maxEmbeddedResources header, if any, or 4.
+func embeddedServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, limited := 4, false
+		if max, err := strconv.Atoi(r.Header.Get("maxEmbeddedResources")); err == nil && max < n {
+			n, limited = max, true
+		}
+		container := `{"Content-Type": "application/zip"`
+		if limited {
+			container += `, "X-TIKA:EXCEPTION:embedded_resource_limit_reached": "true"`
+		}
+		fmt.Fprint(w, "["+container+"}")
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(w, `, {"resourceName": "%d.txt", "X-TIKA:embedded_depth": "%d"}`, i, i%2+1)
+		}
+		fmt.Fprint(w, "]")
+	}))
+}
+
+func TestMetaRecursiveLimited(t *testing.T) {
+	ts := embeddedServer()
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	tests := []struct {
+		name          string
+		limits        EmbeddedLimits
+		opts          []RequestOption
+		wantDocs      int
+		wantEmbedded  int
+		wantOmitted   int
+		wantTruncated bool
+	}{
+		{
+			name:         "unlimited",
+			wantDocs:     5,
+			wantEmbedded: 4,
+		},
+		{
+			name:          "server limit",
+			limits:        EmbeddedLimits{MaxEmbedded: 2},
+			wantDocs:      3,
+			wantEmbedded:  2,
+			wantTruncated: true,
+		},
+		{
+			name:          "client limit",
+			limits:        EmbeddedLimits{MaxEmbedded: 2},
+			opts:          []RequestOption{WithMaxEmbeddedResources(10)},
+			wantDocs:      3,
+			wantEmbedded:  2,
+			wantOmitted:   2,
+			wantTruncated: true,
+		},
+		{
+			name:          "depth",
+			limits:        EmbeddedLimits{MaxDepth: 1},
+			wantDocs:      3,
+			wantEmbedded:  2,
+			wantOmitted:   2,
+			wantTruncated: true,
+		},
+		{
+			name:         "limits not reached",
+			limits:       EmbeddedLimits{MaxEmbedded: 4, MaxDepth: 2},
+			wantDocs:     5,
+			wantEmbedded: 4,
+		},
+	}
+	for _, test := range tests {
+		r, err := c.MetaRecursiveLimited(context.Background(), nil, test.limits, test.opts...)
+		if err != nil {
+			t.Errorf("MetaRecursiveLimited(%s) got error: %v", test.name, err)
+			continue
+		}
+		if len(r.Documents) != test.wantDocs || r.Embedded != test.wantEmbedded || r.Omitted != test.wantOmitted || r.TruncatedEmbedded != test.wantTruncated || r.TruncatedContent {
+			t.Errorf("MetaRecursiveLimited(%s) = %d documents, %+v", test.name, len(r.Documents), *r)
+		}
+	}
+	if _, err := errorClient.MetaRecursiveLimited(context.Background(), nil, EmbeddedLimits{}); err == nil {
+		t.Errorf("MetaRecursiveLimited of an error response got no error")
+	}
+}
+
+func TestMetaRecursiveLimitedContent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"X-TIKA:content": "abc"}, {"X-TIKA:EXCEPTION:write_limit_reached": "true"}]`)
+	}))
+	defer ts.Close()
+	r, err := NewClient(nil, ts.URL).MetaRecursiveLimited(context.Background(), nil, EmbeddedLimits{})
+	if err != nil {
+		t.Fatalf("MetaRecursiveLimited got error: %v", err)
+	}
+	if !r.TruncatedContent || r.TruncatedEmbedded {
+		t.Errorf("MetaRecursiveLimited = %+v, want truncated content only", *r)
+	}
+}
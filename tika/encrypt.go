@@ -0,0 +1,198 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// A KeyProvider provides the AES key encrypting local state at rest, such as
+// cached results, checkpoints and dead letters, so keys can be kept in a
+// secret store rather than on the host.
+type KeyProvider interface {
+	// Key returns an AES key of 16, 24 or 32 bytes.
+	Key(ctx context.Context) ([]byte, error)
+}
+
+// StaticKey is a KeyProvider always providing the same key.
+type StaticKey []byte
+
+// Key implements KeyProvider.
+func (k StaticKey) Key(context.Context) ([]byte, error) {
+	return []byte(k), nil
+}
+
+// EnvKey is a KeyProvider reading a base64 encoded key from the environment
+// variable it names.
+type EnvKey string
+
+// Key implements KeyProvider.
+func (e EnvKey) Key(context.Context) ([]byte, error) {
+	v := os.Getenv(string(e))
+	if v == "" {
+		return nil, fmt.Errorf("%s is not set", string(e))
+	}
+	k, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key in %s: %w", string(e), err)
+	}
+	return k, nil
+}
+
+// AllowPlaintext is a KeyProvider reading the files written before encryption
+// was enabled as plaintext, to migrate existing state: ReadFileEncrypted and
+// the stores using it otherwise refuse files which are not sealed, since
+// anyone able to write them could plant unauthenticated content. Drop it once
+// the state was rewritten encrypted.
+type AllowPlaintext struct {
+	KeyProvider
+}
+
+// allowsPlaintext returns whether keys reads files which are not sealed.
+func allowsPlaintext(keys KeyProvider) bool {
+	_, ok := keys.(AllowPlaintext)
+	return ok
+}
+
+// sealedMagic starts sealed data, to tell it apart from plaintext.
+var sealedMagic = []byte("TKAGCM1\x00")
+
+// ErrNotSealed is returned by Unseal for data not sealed by Seal.
+var ErrNotSealed = errors.New("data is not encrypted")
+
+// errAuthentication is the error of sealed data failing authentication,
+// because it was sealed with another key or modified.
+var errAuthentication = errors.New("error decrypting")
+
+func newGCM(ctx context.Context, keys KeyProvider) (cipher.AEAD, error) {
+	key, err := keys.Key(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal encrypts and authenticates plaintext with AES-GCM, using the key of
+// keys and a random nonce.
+func Seal(ctx context.Context, keys KeyProvider, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	return seal(gcm, plaintext)
+}
+
+// seal seals plaintext with gcm, as by Seal.
+func seal(gcm cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	out := append(append([]byte{}, sealedMagic...), nonce...)
+	return gcm.Seal(out, nonce, plaintext, nil), nil
+}
+
+// Unseal decrypts data sealed by Seal. It returns ErrNotSealed if data was not
+// sealed, and an error if data was sealed with another key or modified.
+func Unseal(ctx context.Context, keys KeyProvider, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, sealedMagic) {
+		return nil, ErrNotSealed
+	}
+	gcm, err := newGCM(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	return unseal(gcm, data)
+}
+
+// unseal opens data sealed with gcm, as by Unseal.
+func unseal(gcm cipher.AEAD, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, sealedMagic) {
+		return nil, ErrNotSealed
+	}
+	data = data[len(sealedMagic):]
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errAuthentication, err)
+	}
+	return plaintext, nil
+}
+
+// WriteFileEncrypted writes data to path, sealed with keys if keys is not nil.
+// The file is written to a temporary file first, then renamed, so path is
+// never left partially written.
+func WriteFileEncrypted(ctx context.Context, keys KeyProvider, path string, data []byte, perm os.FileMode) error {
+	if keys != nil {
+		var err error
+		if data, err = Seal(ctx, keys, data); err != nil {
+			return fmt.Errorf("error encrypting %s: %w", path, err)
+		}
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // No-op once renamed.
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// ReadFileEncrypted reads a file written by WriteFileEncrypted with the same
+// keys. If keys is nil, the file is read as is. A file which is not sealed,
+// such as one written before encryption was enabled, is an error wrapping
+// ErrNotSealed, unless keys is AllowPlaintext.
+func ReadFileEncrypted(ctx context.Context, keys KeyProvider, path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil || keys == nil {
+		return data, err
+	}
+	plaintext, err := Unseal(ctx, keys, data)
+	if errors.Is(err, ErrNotSealed) && allowsPlaintext(keys) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s: %w", path, err)
+	}
+	return plaintext, nil
+}
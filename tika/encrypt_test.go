@@ -0,0 +1,105 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testKey = StaticKey(bytes.Repeat([]byte{1}, 32))
+
+func TestSeal(t *testing.T) {
+	ctx := context.Background()
+	plaintext := []byte("checkpoint")
+	sealed, err := Seal(ctx, testKey, plaintext)
+	if err != nil {
+		t.Fatalf("Seal got error: %v", err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Errorf("Seal left the plaintext in %q", sealed)
+	}
+	if got, err := Unseal(ctx, testKey, sealed); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Unseal = %q, %v, want %q", got, err, plaintext)
+	}
+
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-1] ^= 1
+	tests := []struct {
+		name string
+		keys KeyProvider
+		data []byte
+	}{
+		{"wrong key", StaticKey(bytes.Repeat([]byte{2}, 32)), sealed},
+		{"invalid key", StaticKey("short"), sealed},
+		{"tampered", testKey, tampered},
+		{"truncated", testKey, sealed[:len(sealedMagic)+2]},
+		{"plaintext", testKey, plaintext},
+	}
+	for _, test := range tests {
+		if _, err := Unseal(ctx, test.keys, test.data); err == nil {
+			t.Errorf("Unseal(%s) got no error", test.name)
+		}
+	}
+}
+
+func TestEncryptedFile(t *testing.T) {
+	ctx := context.Background()
+	dir := tempDir(t)
+	path := filepath.Join(dir, "state.json")
+	if err := WriteFileEncrypted(ctx, testKey, path, []byte(`{"a":1}`), 0600); err != nil {
+		t.Fatalf("WriteFileEncrypted got error: %v", err)
+	}
+	if raw, _ := ioutil.ReadFile(path); bytes.Contains(raw, []byte(`"a"`)) {
+		t.Errorf("WriteFileEncrypted wrote plaintext %q", raw)
+	}
+	if got, err := ReadFileEncrypted(ctx, testKey, path); err != nil || string(got) != `{"a":1}` {
+		t.Errorf("ReadFileEncrypted = %q, %v", got, err)
+	}
+	if got := remaining(t, dir); len(got) != 1 {
+		t.Errorf("WriteFileEncrypted left %v, want only %s", got, path)
+	}
+
+	// Files written without encryption are refused, unless migrating.
+	if err := WriteFileEncrypted(ctx, nil, path, []byte("plain"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadFileEncrypted(ctx, testKey, path); !errors.Is(err, ErrNotSealed) {
+		t.Errorf("ReadFileEncrypted of a plaintext file = %q, %v, want ErrNotSealed", got, err)
+	}
+	if got, err := ReadFileEncrypted(ctx, AllowPlaintext{testKey}, path); err != nil || string(got) != "plain" {
+		t.Errorf("ReadFileEncrypted of a plaintext file allowing it = %q, %v", got, err)
+	}
+}
+
+func TestEnvKey(t *testing.T) {
+	defer os.Unsetenv("TIKA_TEST_KEY")
+	os.Setenv("TIKA_TEST_KEY", base64.StdEncoding.EncodeToString(testKey))
+	if k, err := EnvKey("TIKA_TEST_KEY").Key(context.Background()); err != nil || !bytes.Equal(k, testKey) {
+		t.Errorf("EnvKey = %x, %v, want %x", k, err, []byte(testKey))
+	}
+	os.Unsetenv("TIKA_TEST_KEY")
+	if _, err := EnvKey("TIKA_TEST_KEY").Key(context.Background()); err == nil {
+		t.Errorf("EnvKey of an unset variable got no error")
+	}
+}
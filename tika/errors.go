@@ -0,0 +1,163 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Errors returned by the package, wrapped with details. Test for them with
+// errors.Is.
+var (
+	// ErrServerNotStarted is returned by Server.Start when the server did not
+	// start responding, or failed to warm up.
+	ErrServerNotStarted = errors.New("server not started")
+	// ErrStartupTimeout is returned by Server.Start, with ErrServerNotStarted,
+	// when the server did not respond within the startup timeout.
+	ErrStartupTimeout = errors.New("server startup timed out")
+	// ErrChecksumMismatch is returned by DownloadServer when the downloaded
+	// JAR does not have the expected checksum, and by NewServer and
+	// Server.Start when the JAR was modified; see WithJARChecksum.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrPortInUse is returned by Server.Start, with WithPortReservation,
+	// when the port of the server is already in use, possibly by another
+	// Tika Server a Client would silently talk to.
+	ErrPortInUse = errors.New("port already in use")
+	// ErrUnsupportedMediaType matches the TikaError of documents the server
+	// cannot process, 415 and 422 responses, such as documents of an unknown
+	// type or corrupt ones. Retrying them fails again.
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	// ErrEncryptedDocument matches the TikaError of password protected
+	// documents the server could not decrypt, which are skipped rather than
+	// retried unless a password is given. The server only tells them apart
+	// in the stack trace of its error responses, which it returns when
+	// started with -includeStack, as by WithIncludeStack: otherwise they
+	// match ErrUnsupportedMediaType.
+	ErrEncryptedDocument = errors.New("encrypted document")
+)
+
+// encryptedException is the exception of Tika for encrypted documents, in the
+// body of their error responses.
+const encryptedException = "EncryptedDocumentException"
+
+// A TikaError is an error response of the Tika server. Test for it with
+// errors.As.
+type TikaError struct {
+	// StatusCode is the HTTP status code of the response, such as 422 for
+	// documents Tika cannot parse.
+	StatusCode int
+	// Endpoint is the path of the call, without its query, such as /tika or
+	// /rmeta/text, telling the failing endpoint of workflows calling several.
+	Endpoint string
+	// RetryAfter is the delay requested by the Retry-After header of 429 and
+	// 503 responses, from Tika or a proxy in front of it, or zero.
+	RetryAfter time.Duration
+	// Body is the body of the response, usually the explanation of the
+	// server, such as a Java stack trace, up to the limit set by
+	// WithErrorBodyLimit.
+	Body string
+}
+
+// maxErrorSummary is the length past which the summary of the Body of a
+// TikaError in its message is cut.
+const maxErrorSummary = 200
+
+func (e *TikaError) Error() string {
+	msg := fmt.Sprintf("response code %v", e.StatusCode)
+	// The first line of a stack trace is the exception and its message.
+	summary := firstLine(e.Body)
+	if len(summary) > maxErrorSummary {
+		i := maxErrorSummary
+		for i > 0 && !utf8.RuneStart(summary[i]) {
+			i--
+		}
+		summary = summary[:i] + "..."
+	}
+	if summary != "" {
+		msg += ": " + summary
+	}
+	return msg
+}
+
+// Is reports whether e matches target, ErrUnsupportedMediaType or
+// ErrEncryptedDocument, so callers test for them with errors.Is. Encrypted
+// documents are only told apart by the stack trace in the body of the
+// response, which is only sent by servers started with -includeStack, and not
+// read with a negative WithErrorBodyLimit.
+func (e *TikaError) Is(target error) bool {
+	switch target {
+	case ErrEncryptedDocument:
+		return e.encrypted()
+	case ErrUnsupportedMediaType:
+		return (e.StatusCode == http.StatusUnsupportedMediaType || e.StatusCode == http.StatusUnprocessableEntity) && !e.encrypted()
+	}
+	return false
+}
+
+// encrypted returns whether e is the failure of an encrypted document.
+func (e *TikaError) encrypted() bool {
+	return e.StatusCode == http.StatusUnprocessableEntity && strings.Contains(e.Body, encryptedException)
+}
+
+// defaultErrorBodyLimit is the default limit of WithErrorBodyLimit.
+const defaultErrorBodyLimit = 64 << 10
+
+// WithErrorBodyLimit returns a ClientOption to read at most n bytes of the
+// body of error responses (default 64 KiB) into the Body of their TikaError.
+// Bodies are read before they are closed, which also lets the connection be
+// reused; the connections of longer bodies are closed. A negative n skips
+// reading them.
+func WithErrorBodyLimit(n int64) ClientOption {
+	return func(c *Client) {
+		c.errorBodyLimit = n
+	}
+}
+
+// WithIncludeStack returns an Option to start the Server with -includeStack,
+// so its error responses include the stack trace of the failure. The Client
+// reads it into TikaError.Body, and tells encrypted documents apart with it;
+// see ErrEncryptedDocument.
+func WithIncludeStack() Option {
+	return func(s *Server) {
+		s.serverFlags = append(s.serverFlags, "-includeStack")
+	}
+}
+
+// tikaError returns the TikaError of the error response resp to a call to path,
+// reading its body up to the limit of c. The caller closes the body.
+func (c *Client) tikaError(resp *http.Response, path string) *TikaError {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	e := &TikaError{StatusCode: resp.StatusCode, Endpoint: path, RetryAfter: retryAfter(resp, time.Now())}
+	limit := c.errorBodyLimit
+	if limit == 0 {
+		limit = defaultErrorBodyLimit
+	}
+	if limit > 0 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, limit))
+		e.Body = string(b)
+	}
+	return e
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTikaError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"Parse", func() error { _, err := c.Parse(context.Background(), nil); return err }},
+		{"MetaRecursive", func() error { _, err := c.MetaRecursive(context.Background(), nil); return err }},
+		{"CallExtension", func() error {
+			var n int
+			return c.CallExtension(context.Background(), "test-custom", nil, "in", &n)
+		}},
+	}
+	for _, test := range tests {
+		err := test.call()
+		var te *TikaError
+		if !errors.As(err, &te) || te.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("%s got error %v, want a TikaError with status %d", test.name, err, http.StatusUnprocessableEntity)
+		}
+	}
+}
+
+func TestTikaErrorBody(t *testing.T) {
+	trace := "org.apache.tika.exception.TikaException: Unexpected RuntimeException\n\tat org.apache.tika.parser.CompositeParser.parse\n"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(trace))
+	}))
+	defer ts.Close()
+	tests := []struct {
+		name     string
+		options  []ClientOption
+		wantBody string
+		wantErr  string
+	}{
+		{
+			name:     "default",
+			wantBody: trace,
+			wantErr:  "response code 422: org.apache.tika.exception.TikaException: Unexpected RuntimeException",
+		},
+		{
+			name:     "limit",
+			options:  []ClientOption{WithErrorBodyLimit(9)},
+			wantBody: "org.apach",
+			wantErr:  "response code 422: org.apach",
+		},
+		{
+			name:    "skipped",
+			options: []ClientOption{WithErrorBodyLimit(-1)},
+			wantErr: "response code 422",
+		},
+	}
+	for _, test := range tests {
+		c := NewClient(nil, ts.URL, test.options...)
+		for name, call := range map[string]func() error{
+			"Parse":       func() error { _, err := c.Parse(context.Background(), nil); return err },
+			"ParseReader": func() error { _, err := c.ParseReader(context.Background(), nil); return err },
+		} {
+			err := call()
+			var te *TikaError
+			if !errors.As(err, &te) {
+				t.Errorf("%s(%s) got error %v, want a TikaError", name, test.name, err)
+				continue
+			}
+			if te.Body != test.wantBody || err.Error() != test.wantErr {
+				t.Errorf("%s(%s) got error %v with body %q, want %q with body %q", name, test.name, err, te.Body, test.wantErr, test.wantBody)
+			}
+		}
+	}
+
+	long := &TikaError{StatusCode: 500, Body: strings.Repeat("é", maxErrorSummary)}
+	if got := long.Error(); len(got) > len("response code 500: ")+maxErrorSummary+3 || !utf8.ValidString(got) || !strings.HasSuffix(got, "é...") {
+		t.Errorf("Error() of a long body = %q, want a valid summary cut at %d bytes", got, maxErrorSummary)
+	}
+}
+
+func TestTikaErrorEndpoint(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tika":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case "/rmeta/text":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	tests := []struct {
+		name         string
+		call         func() error
+		wantStatus   int
+		wantEndpoint string
+	}{
+		{
+			name:         "Parse",
+			call:         func() error { _, err := c.Parse(context.Background(), nil); return err },
+			wantStatus:   http.StatusUnprocessableEntity,
+			wantEndpoint: "/tika",
+		},
+		{
+			name:         "MetaRecursive",
+			call:         func() error { _, err := c.MetaRecursive(context.Background(), nil); return err },
+			wantStatus:   http.StatusInternalServerError,
+			wantEndpoint: "/rmeta/text",
+		},
+		{
+			name:         "MetaField",
+			call:         func() error { _, err := c.MetaField(context.Background(), nil, "Content-Type"); return err },
+			wantStatus:   http.StatusServiceUnavailable,
+			wantEndpoint: "/meta/Content-Type",
+		},
+	}
+	for _, test := range tests {
+		var te *TikaError
+		if err := test.call(); !errors.As(err, &te) || te.StatusCode != test.wantStatus || te.Endpoint != test.wantEndpoint {
+			t.Errorf("%s got error %v (%+v), want status %d from %s", test.name, err, te, test.wantStatus, test.wantEndpoint)
+		}
+	}
+}
+
+func TestTikaErrorIs(t *testing.T) {
+	// The stack trace sent by a server started with -includeStack.
+	encrypted := "org.apache.tika.exception.EncryptedDocumentException: Unable to process: document is encrypted\n\tat org.apache.tika.parser.pdf.PDFParser.parse\n"
+	tests := []struct {
+		name            string
+		status          int
+		body            string
+		options         []ClientOption
+		wantUnsupported bool
+		wantEncrypted   bool
+	}{
+		{name: "unknown type", status: http.StatusUnsupportedMediaType, wantUnsupported: true},
+		{name: "corrupt", status: http.StatusUnprocessableEntity, body: "org.apache.tika.exception.TikaException: Unexpected RuntimeException", wantUnsupported: true},
+		{name: "encrypted", status: http.StatusUnprocessableEntity, body: encrypted, wantEncrypted: true},
+		{name: "encrypted without stack", status: http.StatusUnprocessableEntity, wantUnsupported: true},
+		{name: "encrypted unread", status: http.StatusUnprocessableEntity, body: encrypted, options: []ClientOption{WithErrorBodyLimit(-1)}, wantUnsupported: true},
+		{name: "server error", status: http.StatusInternalServerError, body: encrypted},
+		{name: "unavailable", status: http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+		_, err := NewClient(nil, ts.URL, test.options...).Parse(context.Background(), strings.NewReader("doc"))
+		ts.Close()
+		if got := errors.Is(err, ErrUnsupportedMediaType); got != test.wantUnsupported {
+			t.Errorf("%s: errors.Is(%v, ErrUnsupportedMediaType) = %v, want %v", test.name, err, got, test.wantUnsupported)
+		}
+		if got := errors.Is(err, ErrEncryptedDocument); got != test.wantEncrypted {
+			t.Errorf("%s: errors.Is(%v, ErrEncryptedDocument) = %v, want %v", test.name, err, got, test.wantEncrypted)
+		}
+	}
+}
+
+func TestWithIncludeStack(t *testing.T) {
+	s, err := NewServer("server_test.go", WithIncludeStack())
+	if err != nil {
+		t.Fatalf("NewServer got error: %v", err)
+	}
+	if args := s.args(s.jar, ""); args[len(args)-1] != "-includeStack" {
+		t.Errorf("NewServer with WithIncludeStack has arguments %q, want -includeStack", args)
+	}
+}
+
+func TestErrChecksumMismatch(t *testing.T) {
+	withTestJAR(t, &jarServer{jar: testJAR(), ranges: true})
+	md5s[testVersion] = "0123456789abcdef0123456789abcdef"
+	path := filepath.Join(tempDir(t), "tika-server.jar")
+	err := DownloadServer(context.Background(), testVersion, path)
+	if !errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), "MD5") {
+		t.Errorf("DownloadServer got error %v, want ErrChecksumMismatch of the MD5", err)
+	}
+	if _, err := os.Stat(path + ".part"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("DownloadServer left the invalid download behind")
+	}
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// An Extension describes a Tika Server resource which has no dedicated Client
+// method, for example one added by a Tika plugin. Register it with
+// RegisterExtension and call it with Client.CallExtension. Calls to an
+// Extension go through the same code as the built-in methods, so they honor
+// the options of the Client.
+type Extension struct {
+	// Name identifies the Extension in CallExtension.
+	Name string
+	// Method is the HTTP method of the resource (default "PUT").
+	Method string
+	// Path is the path of the resource. It may contain parameters in braces,
+	// which are replaced by the escaped value of the parameter of the same
+	// name given to CallExtension. For example, "/convert/{format}".
+	Path string
+	// Accept is the value of the Accept header, if any.
+	Accept string
+	// Encode converts the input of CallExtension to a request body. If nil,
+	// the input must be nil, an io.Reader, a string or a []byte.
+	Encode func(in interface{}) (io.Reader, error)
+	// Decode stores the response body in the output of CallExtension. If nil,
+	// the output must be nil, a *string or a *[]byte, or the body is decoded as
+	// JSON.
+	Decode func(body []byte, out interface{}) error
+}
+
+var (
+	extensionsMu sync.RWMutex
+	extensions   = make(map[string]Extension)
+)
+
+// RegisterExtension makes an Extension available to all Clients. It is meant
+// to be called from the init function of the package providing the Extension.
+// RegisterExtension panics if the Extension has no Name or Path, or if an
+// Extension with the same Name is already registered.
+func RegisterExtension(e Extension) {
+	if e.Name == "" || e.Path == "" {
+		panic("tika: RegisterExtension requires a Name and a Path")
+	}
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	if _, dup := extensions[e.Name]; dup {
+		panic("tika: RegisterExtension called twice for extension " + e.Name)
+	}
+	if e.Method == "" {
+		e.Method = "PUT"
+	}
+	extensions[e.Name] = e
+}
+
+// Extensions returns the sorted names of the registered Extensions.
+func Extensions() []string {
+	extensionsMu.RLock()
+	defer extensionsMu.RUnlock()
+	var names []string
+	for name := range extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var pathParam = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// expandPath replaces the parameters of the path template with their escaped
+// values in params.
+func expandPath(template string, params map[string]string) (string, error) {
+	var missing []string
+	path := pathParam.ReplaceAllStringFunc(template, func(p string) string {
+		name := p[1 : len(p)-1]
+		v, ok := params[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return url.PathEscape(v)
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing path parameters: %s", strings.Join(missing, ", "))
+	}
+	return path, nil
+}
+
+// CallExtension calls the registered Extension with the given name. params
+// holds the values of the parameters of the Path of the Extension. in is
+// encoded as the request body and the response body is decoded into out, as
+// described by the Extension. If the error is not nil, out is undefined.
+func (c *Client) CallExtension(ctx context.Context, name string, params map[string]string, in, out interface{}, opts ...RequestOption) error {
+	extensionsMu.RLock()
+	e, ok := extensions[name]
+	extensionsMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown extension %q", name)
+	}
+
+	path, err := expandPath(e.Path, params)
+	if err != nil {
+		return fmt.Errorf("extension %q: %w", name, err)
+	}
+	encode := e.Encode
+	if encode == nil {
+		encode = encodeInput
+	}
+	body, err := encode(in)
+	if err != nil {
+		return fmt.Errorf("extension %q: error encoding input: %w", name, err)
+	}
+
+	cfg := newCallConfig(opts)
+	if e.Accept != "" && cfg.header.Get("Accept") == "" {
+		cfg.setHeader("Accept", e.Accept)
+	}
+	resp, err := c.call(ctx, body, e.Method, path, cfg)
+	if err != nil {
+		return err
+	}
+
+	decode := e.Decode
+	if decode == nil {
+		decode = decodeOutput
+	}
+	if err := decode(resp, out); err != nil {
+		return fmt.Errorf("extension %q: error decoding response: %w", name, err)
+	}
+	return nil
+}
+
+// encodeInput is the default Encode of an Extension. A nil io.Reader of a
+// pointer type, such as a nil *os.File, is an error rather than a panic or an
+// empty body.
+func encodeInput(in interface{}) (io.Reader, error) {
+	switch v := in.(type) {
+	case nil:
+		return nil, nil
+	case io.Reader:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil, fmt.Errorf("nil input of type %T", in)
+		}
+		return v, nil
+	case string:
+		return strings.NewReader(v), nil
+	case []byte:
+		return bytes.NewReader(v), nil
+	}
+	return nil, fmt.Errorf("unsupported input type %T", in)
+}
+
+// decodeOutput is the default Decode of an Extension.
+func decodeOutput(body []byte, out interface{}) error {
+	switch v := out.(type) {
+	case nil:
+		return nil
+	case *string:
+		*v = string(body)
+		return nil
+	case *[]byte:
+		*v = body
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
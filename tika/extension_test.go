@@ -0,0 +1,146 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func init() {
+	RegisterExtension(Extension{
+		Name:   "test-echo",
+		Path:   "/echo/{name}",
+		Accept: "application/json",
+	})
+	RegisterExtension(Extension{
+		Name:   "test-custom",
+		Method: "POST",
+		Path:   "/custom",
+		Encode: func(in interface{}) (io.Reader, error) {
+			return strings.NewReader(strings.ToUpper(in.(string))), nil
+		},
+		Decode: func(body []byte, out interface{}) error {
+			*out.(*int) = len(body)
+			return nil
+		},
+	})
+}
+
+func TestExpandPath(t *testing.T) {
+	tests := []struct {
+		template string
+		params   map[string]string
+		want     string
+		wantErr  bool
+	}{
+		{template: "/plain", want: "/plain"},
+		{template: "/a/{x}/{y}", params: map[string]string{"x": "1", "y": "a b/c"}, want: "/a/1/a%20b%2Fc"},
+		{template: "/a/{x}", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := expandPath(test.template, test.params)
+		if (err != nil) != test.wantErr {
+			t.Errorf("expandPath(%q) got error %v, want error %t", test.template, err, test.wantErr)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("expandPath(%q) = %q, want %q", test.template, got, test.want)
+		}
+	}
+}
+
+func TestCallExtension(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"method":%q,"path":%q,"accept":%q,"body":%q}`, r.Method, r.URL.EscapedPath(), r.Header.Get("Accept"), body)
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+
+	var got map[string]string
+	if err := c.CallExtension(context.Background(), "test-echo", map[string]string{"name": "x y"}, "input", &got); err != nil {
+		t.Fatalf("CallExtension(test-echo) got error: %v", err)
+	}
+	want := map[string]string{"method": "PUT", "path": "/echo/x%20y", "accept": "application/json", "body": "input"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CallExtension(test-echo) = %v, want %v", got, want)
+	}
+
+	var n int
+	if err := c.CallExtension(context.Background(), "test-custom", nil, "abc", &n); err != nil {
+		t.Fatalf("CallExtension(test-custom) got error: %v", err)
+	}
+	if want := len(`{"method":"POST","path":"/custom","accept":"","body":"ABC"}`); n != want {
+		t.Errorf("CallExtension(test-custom) decoded %d bytes, want %d", n, want)
+	}
+
+	if c.Stats().Requests != 2 {
+		t.Errorf("Stats().Requests = %d, want extension calls counted", c.Stats().Requests)
+	}
+}
+
+func TestCallExtensionError(t *testing.T) {
+	c := NewClient(nil, "http://localhost")
+	tests := []struct {
+		name   string
+		params map[string]string
+		in     interface{}
+	}{
+		{name: "unknown"},
+		{name: "test-echo"},
+		{name: "test-echo", params: map[string]string{"name": "x"}, in: 42},
+	}
+	for _, test := range tests {
+		if err := c.CallExtension(context.Background(), test.name, test.params, test.in, nil); err == nil {
+			t.Errorf("CallExtension(%s, %v, %v) got no error, want an error", test.name, test.params, test.in)
+		}
+	}
+	for _, in := range []interface{}{(*os.File)(nil), (*bytes.Reader)(nil)} {
+		if _, err := encodeInput(in); err == nil {
+			t.Errorf("encodeInput(%T(nil)) got no error, want an error", in)
+		}
+	}
+	if err := errorClient.CallExtension(context.Background(), "test-echo", map[string]string{"name": "x"}, nil, nil); err == nil {
+		t.Errorf("CallExtension got no error from errorClient, want an error")
+	}
+}
+
+func TestRegisterExtensionPanics(t *testing.T) {
+	for _, e := range []Extension{{}, {Name: "test-echo", Path: "/dup"}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterExtension(%+v) did not panic", e)
+				}
+			}()
+			RegisterExtension(e)
+		}()
+	}
+	if got, want := Extensions(), []string{"test-custom", "test-echo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Extensions() = %v, want %v", got, want)
+	}
+}
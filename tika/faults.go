@@ -0,0 +1,211 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A Fault is a kind of failure injected by a FaultInjector.
+type Fault int
+
+// Faults a FaultInjector can inject.
+const (
+	// FaultTimeout makes the request hang for the Delay of the FaultInjector,
+	// or until its context is done, then fail with a timeout error.
+	FaultTimeout Fault = iota
+	// FaultServerError responds with 503 Service Unavailable without calling
+	// the server.
+	FaultServerError
+	// FaultCorruptBody calls the server and corrupts the response body.
+	FaultCorruptBody
+	// FaultSlowResponse calls the server after waiting for the Delay of the
+	// FaultInjector.
+	FaultSlowResponse
+)
+
+var allFaults = []Fault{FaultTimeout, FaultServerError, FaultCorruptBody, FaultSlowResponse}
+
+// FaultInjector is an http.RoundTripper that injects failures into a given
+// fraction of requests, to test how code using a Client copes with an
+// unreliable Tika Server. Use it as the Transport of the http.Client passed to
+// NewClient:
+//
+//	fi := &tika.FaultInjector{Rate: 0.1}
+//	client := tika.NewClient(&http.Client{Transport: fi}, url)
+//
+// A FaultInjector is safe for concurrent use. Do not change its fields once
+// it is in use.
+type FaultInjector struct {
+	// Transport makes the requests which are not failed outright. If nil,
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+	// Rate is the probability, between 0 and 1, of injecting a fault into a
+	// request.
+	Rate float64
+	// Faults are the faults to pick from, with equal probability. If empty,
+	// all faults are used.
+	Faults []Fault
+	// Delay is how long FaultTimeout and FaultSlowResponse wait (default 1s).
+	Delay time.Duration
+	// Rand is the source of randomness. If nil, a source seeded with the
+	// current time is used.
+	Rand *rand.Rand
+
+	once     sync.Once
+	mu       sync.Mutex // mu guards Rand.
+	injected int64
+}
+
+// Injected returns the number of faults injected so far.
+func (fi *FaultInjector) Injected() int64 {
+	return atomic.LoadInt64(&fi.injected)
+}
+
+// pick returns whether to inject a fault and which one.
+func (fi *FaultInjector) pick() (Fault, bool) {
+	fi.once.Do(func() {
+		if fi.Rand == nil {
+			fi.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+		}
+	})
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	if fi.Rand.Float64() >= fi.Rate {
+		return 0, false
+	}
+	faults := fi.Faults
+	if len(faults) == 0 {
+		faults = allFaults
+	}
+	atomic.AddInt64(&fi.injected, 1)
+	return faults[fi.Rand.Intn(len(faults))], true
+}
+
+func (fi *FaultInjector) transport() http.RoundTripper {
+	if fi.Transport == nil {
+		return http.DefaultTransport
+	}
+	return fi.Transport
+}
+
+func (fi *FaultInjector) delay() time.Duration {
+	if fi.Delay == 0 {
+		return time.Second
+	}
+	return fi.Delay
+}
+
+// RoundTrip implements http.RoundTripper.
+func (fi *FaultInjector) RoundTrip(req *http.Request) (*http.Response, error) {
+	f, ok := fi.pick()
+	if !ok {
+		return fi.transport().RoundTrip(req)
+	}
+	switch f {
+	case FaultTimeout:
+		closeBody(req)
+		if err := fi.wait(req); err != nil {
+			return nil, err
+		}
+		return nil, errInjectedTimeout
+	case FaultServerError:
+		closeBody(req)
+		return &http.Response{
+			Status:     "503 Service Unavailable",
+			StatusCode: http.StatusServiceUnavailable,
+			Proto:      req.Proto,
+			ProtoMajor: req.ProtoMajor,
+			ProtoMinor: req.ProtoMinor,
+			Header:     http.Header{"Content-Type": {"text/plain"}},
+			Body:       ioutil.NopCloser(strings.NewReader("injected fault")),
+			Request:    req,
+		}, nil
+	case FaultCorruptBody:
+		resp, err := fi.transport().RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = &corruptReader{rc: resp.Body}
+		resp.ContentLength = -1
+		return resp, nil
+	case FaultSlowResponse:
+		if err := fi.wait(req); err != nil {
+			closeBody(req)
+			return nil, err
+		}
+	}
+	return fi.transport().RoundTrip(req)
+}
+
+// closeBody closes the body of req, which is not sent, as a RoundTripper
+// must.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// wait waits for fi.Delay or until the context of req is done.
+func (fi *FaultInjector) wait(req *http.Request) error {
+	t := time.NewTimer(fi.delay())
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// injectedTimeout is the error of FaultTimeout. Like the errors of the
+// net package, it reports itself as a timeout.
+type injectedTimeout struct{}
+
+func (injectedTimeout) Error() string   { return "injected fault: timeout" }
+func (injectedTimeout) Timeout() bool   { return true }
+func (injectedTimeout) Temporary() bool { return true }
+
+var errInjectedTimeout error = injectedTimeout{}
+
+// corruptReader inverts every other byte read from rc.
+type corruptReader struct {
+	rc io.ReadCloser
+	n  int
+}
+
+func (r *corruptReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	for i := 0; i < n; i++ {
+		if (r.n+i)%2 == 0 {
+			p[i] = ^p[i]
+		}
+	}
+	r.n += n
+	return n, err
+}
+
+func (r *corruptReader) Close() error {
+	return r.rc.Close()
+}
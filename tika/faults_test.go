@@ -0,0 +1,140 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFaultInjector(t *testing.T) {
+	want := "test value"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, want)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name      string
+		fault     Fault
+		wantError bool
+		check     func(got string, err error) bool
+	}{
+		{
+			name:      "timeout",
+			fault:     FaultTimeout,
+			wantError: true,
+			check: func(_ string, err error) bool {
+				ne, ok := err.(net.Error)
+				return ok && ne.Timeout()
+			},
+		},
+		{name: "server error", fault: FaultServerError, wantError: true},
+		{
+			name:  "corrupt body",
+			fault: FaultCorruptBody,
+			check: func(got string, _ error) bool { return len(got) == len(want) && got != want },
+		},
+		{
+			name:  "slow response",
+			fault: FaultSlowResponse,
+			check: func(got string, _ error) bool { return got == want },
+		},
+	}
+	for _, test := range tests {
+		fi := &FaultInjector{
+			Rate:   1,
+			Faults: []Fault{test.fault},
+			Delay:  10 * time.Millisecond,
+		}
+		c := NewClient(&http.Client{Transport: fi}, ts.URL)
+		got, err := c.Parse(context.Background(), nil)
+		if test.wantError && err == nil {
+			t.Errorf("Parse(%s) got no error, want an error", test.name)
+		}
+		if !test.wantError && err != nil {
+			t.Errorf("Parse(%s) got error %v, want no error", test.name, err)
+		}
+		if err != nil {
+			// Errors from the transport are wrapped in a *url.Error.
+			if ue, ok := err.(interface{ Unwrap() error }); ok {
+				err = ue.Unwrap()
+			}
+		}
+		if test.check != nil && !test.check(got, err) {
+			t.Errorf("Parse(%s) = %q, %v: not the expected fault", test.name, got, err)
+		}
+		if fi.Injected() != 1 {
+			t.Errorf("Parse(%s) injected %d faults, want 1", test.name, fi.Injected())
+		}
+	}
+}
+
+func TestFaultInjectorRate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer ts.Close()
+	fi := &FaultInjector{
+		Rate:   0.25,
+		Faults: []Fault{FaultServerError},
+		Rand:   rand.New(rand.NewSource(1)),
+	}
+	c := NewClient(&http.Client{Transport: fi}, ts.URL)
+	const n = 400
+	failed := 0
+	for i := 0; i < n; i++ {
+		if _, err := c.Parse(context.Background(), nil); err != nil {
+			failed++
+		}
+	}
+	if int64(failed) != fi.Injected() {
+		t.Errorf("got %d failures, want the %d injected faults", failed, fi.Injected())
+	}
+	if failed < n/8 || failed > n/2 {
+		t.Errorf("got %d failures out of %d requests at rate 0.25", failed, n)
+	}
+}
+
+func TestFaultInjectorContext(t *testing.T) {
+	fi := &FaultInjector{Rate: 1, Faults: []Fault{FaultTimeout}, Delay: time.Hour}
+	c := NewClient(&http.Client{Transport: fi}, "http://localhost")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Parse(ctx, nil); err == nil {
+		t.Errorf("Parse got no error, want an error")
+	}
+}
+
+func TestFaultInjectorClosesBody(t *testing.T) {
+	for _, f := range []Fault{FaultTimeout, FaultServerError} {
+		fi := &FaultInjector{Rate: 1, Faults: []Fault{f}, Delay: time.Millisecond}
+		body := &closeRecorder{Reader: strings.NewReader("body")}
+		req, _ := http.NewRequest("PUT", "http://localhost", body)
+		if resp, err := fi.RoundTrip(req); err == nil {
+			resp.Body.Close()
+		}
+		if !body.closed {
+			t.Errorf("RoundTrip with %v did not close the request body", f)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package fixtures provides small sample documents of common types, along with
+what Tika Server is expected to extract from them. Use them to test code built
+on the tika package, or to check that a Tika Server works as expected.
+
+	for _, f := range fixtures.All {
+		r, err := f.Open()
+		if err != nil {
+			log.Fatal(err)
+		}
+		m, err := client.MetaRecursive(context.Background(), r)
+		r.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, problem := range f.Check(m[0]) {
+			log.Printf("%s: %s", f.Name, problem)
+		}
+	}
+
+The expected results are maintained against TikaVersion. Other versions may
+extract slightly different metadata.
+*/
+package fixtures
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-tika/tika"
+)
+
+// TikaVersion is the version of Tika Server the expected results are
+// maintained against.
+const TikaVersion = tika.Version116
+
+//go:embed files
+var files embed.FS
+
+// A Fixture is a sample document and what Tika is expected to extract from it.
+type Fixture struct {
+	// Name is the file name of the document.
+	Name string
+	// ContentType is the MIME type Tika detects for the document.
+	ContentType string
+	// Text holds phrases the extracted content contains.
+	Text []string
+	// Metadata holds metadata fields and the value Tika extracts for them.
+	Metadata map[string]string
+}
+
+// All is the list of available fixtures.
+var All = []Fixture{
+	{
+		Name:        "sample.pdf",
+		ContentType: "application/pdf",
+		Text:        []string{"Hello from go-tika"},
+		Metadata: map[string]string{
+			"Content-Type":  "application/pdf",
+			"dc:title":      "Sample PDF",
+			"dc:creator":    "go-tika",
+			"xmpTPg:NPages": "1",
+		},
+	},
+	{
+		Name:        "sample.docx",
+		ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		Text:        []string{"Sample Heading", "Hello from go-tika"},
+		Metadata: map[string]string{
+			"Content-Type":    "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			"dc:title":        "Sample DOCX",
+			"dc:creator":      "go-tika",
+			"dcterms:created": "2017-01-01T00:00:00Z",
+		},
+	},
+	{
+		// sample.eml is a plain text email.
+		Name:        "sample.eml",
+		ContentType: "message/rfc822",
+		Text:        []string{"Hello from go-tika"},
+		Metadata: map[string]string{
+			"Content-Type": "message/rfc822",
+			"dc:title":     "Sample EML",
+			"Message-From": "Sender <sender@example.com>",
+		},
+	},
+	{
+		// sample.png is a 1x1 image with an eXIf chunk setting the camera Make
+		// to "go-tika" and Model to "fixture".
+		Name:        "sample.png",
+		ContentType: "image/png",
+		Metadata: map[string]string{
+			"Content-Type":     "image/png",
+			"tiff:ImageWidth":  "1",
+			"tiff:ImageLength": "1",
+		},
+	},
+}
+
+// Get returns the fixture with the given name.
+func Get(name string) (Fixture, bool) {
+	for _, f := range All {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Fixture{}, false
+}
+
+// Open opens the document of f.
+func (f Fixture) Open() (io.ReadCloser, error) {
+	return files.Open("files/" + f.Name)
+}
+
+// Bytes returns the document of f.
+func (f Fixture) Bytes() ([]byte, error) {
+	return files.ReadFile("files/" + f.Name)
+}
+
+// Check compares the metadata Tika extracted from f, as returned for the
+// container document by Client.MetaRecursive, with the expected results. It
+// returns a description of each difference.
+func (f Fixture) Check(metadata map[string][]string) []string {
+	var problems []string
+	content := strings.Join(metadata[tika.XTIKAContent], "\n")
+	for _, want := range f.Text {
+		if !strings.Contains(content, want) {
+			problems = append(problems, fmt.Sprintf("content does not contain %q", want))
+		}
+	}
+	for k, want := range f.Metadata {
+		got := metadata[k]
+		if len(got) == 0 {
+			problems = append(problems, fmt.Sprintf("missing metadata field %q, want %q", k, want))
+			continue
+		}
+		// Tika appends parameters such as the charset to some types.
+		if k == "Content-Type" && strings.HasPrefix(got[0], want) {
+			continue
+		}
+		if got[0] != want {
+			problems = append(problems, fmt.Sprintf("metadata field %q = %q, want %q", k, got[0], want))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fixtures
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFixturesEmbedded(t *testing.T) {
+	for _, f := range All {
+		r, err := f.Open()
+		if err != nil {
+			t.Errorf("Open(%s) got error: %v", f.Name, err)
+			continue
+		}
+		b, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil || len(b) == 0 {
+			t.Errorf("reading %s got %d bytes and error %v, want content", f.Name, len(b), err)
+			continue
+		}
+		// Check the types the standard library can sniff.
+		switch sniffed := http.DetectContentType(b); {
+		case f.ContentType == "application/pdf", f.ContentType == "image/png":
+			if sniffed != f.ContentType {
+				t.Errorf("%s sniffed as %q, want %q", f.Name, sniffed, f.ContentType)
+			}
+		}
+		if _, ok := Get(f.Name); !ok {
+			t.Errorf("Get(%s) found nothing", f.Name)
+		}
+	}
+	if _, ok := Get("missing.txt"); ok {
+		t.Errorf("Get(missing.txt) found a fixture")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	f, _ := Get("sample.pdf")
+	good := map[string][]string{
+		"X-TIKA:content": {"\n\nHello from go-tika\n"},
+		"Content-Type":   {"application/pdf"},
+		"dc:title":       {"Sample PDF"},
+		"dc:creator":     {"go-tika"},
+		"xmpTPg:NPages":  {"1"},
+	}
+	if got := f.Check(good); len(got) != 0 {
+		t.Errorf("Check(good) = %q, want no problems", got)
+	}
+
+	bad := map[string][]string{
+		"X-TIKA:content": {"something else"},
+		"Content-Type":   {"application/pdf"},
+		"dc:title":       {"Other"},
+	}
+	got := f.Check(bad)
+	if len(got) != 4 {
+		t.Fatalf("Check(bad) = %q, want 4 problems", got)
+	}
+	if !strings.Contains(strings.Join(got, "\n"), `"dc:title" = "Other"`) {
+		t.Errorf("Check(bad) = %q, want the changed title reported", got)
+	}
+}
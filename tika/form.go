@@ -0,0 +1,68 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+)
+
+// ParseForm parses the given input like Parse, but uploads it as the file
+// named filename of a multipart/form-data request to the /tika/form endpoint,
+// as a browser does. Use it when proxying browser uploads, or when a proxy in
+// front of Tika only accepts form uploads: Tika detects the type of the input
+// from the file name of the form part too, as with WithResourceName. The
+// input is streamed, not read into memory.
+//
+// A type given with WithContentTypeHint is the type of the form part.
+func (c *Client) ParseForm(ctx context.Context, input io.Reader, filename string, opts ...RequestOption) (string, error) {
+	cfg := newCallConfig(opts)
+	part := make(textproto.MIMEHeader)
+	part.Set("Content-Disposition", disposition("form-data", filename, map[string]string{"name": "file"}))
+	part.Set("Content-Type", "application/octet-stream")
+	if t := cfg.header.Get("Content-Type"); t != "" {
+		part.Set("Content-Type", t)
+	}
+	// The file name is in the form part.
+	cfg.header.Del("Content-Disposition")
+
+	pr, pw := io.Pipe()
+	// Closing the reader stops the writer if the request fails before
+	// reading the body.
+	defer pr.Close()
+	mw := multipart.NewWriter(pw)
+	cfg.setHeader("Content-Type", mw.FormDataContentType())
+	go func() {
+		w, err := mw.CreatePart(part)
+		if err == nil {
+			_, err = io.Copy(w, input)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	resp, err := c.callResponse(ctx, pr, "POST", "/tika/form", cfg)
+	if err != nil {
+		return "", err
+	}
+	defer resp.release()
+	return c.decodeText(resp)
+}
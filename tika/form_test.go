@@ -0,0 +1,87 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseForm(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/tika/form" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Content-Disposition") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f, h, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		body, _ := ioutil.ReadAll(f)
+		fmt.Fprintf(w, "%s|%s|%s", h.Filename, h.Header.Get("Content-Type"), body)
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+
+	tests := []struct {
+		name     string
+		filename string
+		opts     []RequestOption
+		want     string
+	}{
+		{"plain", "report.pdf", nil, "report.pdf|application/octet-stream|input"},
+		{"non-ASCII", "résumé €.docx", nil, "résumé €.docx|application/octet-stream|input"},
+		{"quotes", `a "b".txt`, nil, `a "b".txt|application/octet-stream|input`},
+		{"type hint", "scan", []RequestOption{WithContentTypeHint("image/png")}, "scan|image/png|input"},
+		{"resource name", "form.txt", []RequestOption{WithResourceName("other.txt")}, "form.txt|application/octet-stream|input"},
+	}
+	for _, test := range tests {
+		got, err := c.ParseForm(context.Background(), strings.NewReader("input"), test.filename, test.opts...)
+		if err != nil {
+			t.Errorf("ParseForm(%s) got error: %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseForm(%s) got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseFormError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+	}))
+	defer ts.Close()
+	// A failed request does not leave the upload blocked.
+	if _, err := NewClient(nil, ts.URL).ParseForm(context.Background(), strings.NewReader(strings.Repeat("x", 1<<20)), "a.txt"); err == nil {
+		t.Error("ParseForm got no error")
+	}
+	if _, err := NewClient(nil, "://bad").ParseForm(context.Background(), strings.NewReader("input"), "a.txt"); err == nil {
+		t.Error("ParseForm with a bad URL got no error")
+	}
+}
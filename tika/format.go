@@ -0,0 +1,90 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io"
+	"net/url"
+)
+
+// A ParseFormat is the format of the body returned by Parse and the methods
+// streaming it, such as ParseReader.
+type ParseFormat string
+
+// ParseFormat values.
+const (
+	// FormatText is plain text, the default of Tika.
+	FormatText ParseFormat = "text/plain"
+	// FormatHTML is the XHTML of the document served as HTML, keeping its
+	// headings, tables and pages, for example <div class="page">.
+	FormatHTML ParseFormat = "text/html"
+	// FormatXML is the same XHTML served as XML.
+	FormatXML ParseFormat = "text/xml"
+)
+
+// WithFormat returns a RequestOption to choose the format of the body of
+// Parse, ParseReader and ParseTo.
+func WithFormat(f ParseFormat) RequestOption {
+	return WithAccept(string(f))
+}
+
+// ParseHTML parses the given input like Parse, returning the XHTML of the
+// input rather than its text.
+func (c *Client) ParseHTML(ctx context.Context, input io.Reader, opts ...RequestOption) (string, error) {
+	return c.Parse(ctx, input, append(opts[:len(opts):len(opts)], WithFormat(FormatHTML))...)
+}
+
+// A RecursiveHandler is the format of the content of each document returned by
+// MetaRecursive, in its XTIKAContent field.
+type RecursiveHandler string
+
+// RecursiveHandler values.
+const (
+	// HandlerText is plain text, the default.
+	HandlerText RecursiveHandler = "text"
+	// HandlerXML is the XHTML of each document.
+	HandlerXML RecursiveHandler = "xml"
+	// HandlerHTML is the XHTML of each document served as HTML.
+	HandlerHTML RecursiveHandler = "html"
+	// HandlerIgnore omits the content, returning the metadata of the
+	// documents only, which is the fastest.
+	HandlerIgnore RecursiveHandler = "ignore"
+)
+
+// WithRecursiveHandler returns a RequestOption to choose the format of the
+// content of the documents returned by MetaRecursive and the methods built on
+// it, such as MetaRecursiveLimited. ParseRecursive returns the content in that
+// format too, and no content with HandlerIgnore.
+func WithRecursiveHandler(h RecursiveHandler) RequestOption {
+	return func(cfg *callConfig) {
+		cfg.handler = h
+	}
+}
+
+// recursivePath returns the path of MetaRecursive for cfg, whose handler
+// may be set by the request defaults of c.
+func (c *Client) recursivePath(cfg *callConfig) string {
+	h := cfg.handler
+	if h == "" && len(c.requestDefaults) > 0 {
+		h = newCallConfig(c.requestDefaults).handler
+	}
+	if h == "" {
+		h = HandlerText
+	}
+	return "/rmeta/" + url.PathEscape(string(h))
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Accept") {
+		case "text/html":
+			w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+			w.Write([]byte("<html><body><h1>title</h1></body></html>"))
+		case "text/xml":
+			w.Header().Set("Content-Type", "text/xml; charset=UTF-8")
+			w.Write([]byte(`<?xml version="1.0"?><html><body><h1>title</h1></body></html>`))
+		default:
+			w.Write([]byte("title"))
+		}
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+
+	tests := []struct {
+		name string
+		opts []RequestOption
+		want string
+	}{
+		{"default", nil, "title"},
+		{"text", []RequestOption{WithFormat(FormatText)}, "title"},
+		{"html", []RequestOption{WithFormat(FormatHTML)}, "<html><body><h1>title</h1></body></html>"},
+		{"xml", []RequestOption{WithFormat(FormatXML)}, `<?xml version="1.0"?><html><body><h1>title</h1></body></html>`},
+	}
+	for _, test := range tests {
+		got, err := c.Parse(context.Background(), strings.NewReader("input"), test.opts...)
+		if err != nil {
+			t.Errorf("Parse(%s) got error: %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Parse(%s) got %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	got, err := c.ParseHTML(context.Background(), strings.NewReader("input"), WithFormat(FormatText))
+	if err != nil {
+		t.Fatalf("ParseHTML got error: %v", err)
+	}
+	if want := "<html><body><h1>title</h1></body></html>"; got != want {
+		t.Errorf("ParseHTML got %q, want %q", got, want)
+	}
+}
+
+func TestRecursiveHandler(t *testing.T) {
+	var gotPath string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Write([]byte(`[{"X-TIKA:content":"content"}]`))
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+
+	tests := []struct {
+		name   string
+		client *Client
+		opts   []RequestOption
+		want   string
+	}{
+		{"default", c, nil, "/rmeta/text"},
+		{"xml", c, []RequestOption{WithRecursiveHandler(HandlerXML)}, "/rmeta/xml"},
+		{"html", c, []RequestOption{WithRecursiveHandler(HandlerHTML)}, "/rmeta/html"},
+		{"ignore", c, []RequestOption{WithRecursiveHandler(HandlerIgnore)}, "/rmeta/ignore"},
+		{"request default", c.With(WithRequestDefaults(WithRecursiveHandler(HandlerIgnore))), nil, "/rmeta/ignore"},
+		{"request over default", c.With(WithRequestDefaults(WithRecursiveHandler(HandlerIgnore))), []RequestOption{WithRecursiveHandler(HandlerXML)}, "/rmeta/xml"},
+	}
+	for _, test := range tests {
+		if _, err := test.client.MetaRecursive(context.Background(), strings.NewReader("input"), test.opts...); err != nil {
+			t.Errorf("MetaRecursive(%s) got error: %v", test.name, err)
+			continue
+		}
+		if gotPath != test.want {
+			t.Errorf("MetaRecursive(%s) requested %q, want %q", test.name, gotPath, test.want)
+		}
+	}
+}
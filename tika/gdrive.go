@@ -0,0 +1,225 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// googleDriveURL is the base URL of the Google Drive API. It is a variable for
+// testing.
+var googleDriveURL = "https://www.googleapis.com/drive/v3"
+
+// An ExportFormat is the format a native Google document is exported to.
+type ExportFormat struct {
+	MIMEType  string
+	Extension string
+}
+
+// DefaultExportFormats are the formats native Google documents are exported
+// to, by MIME type, and the extension of the exported files.
+var DefaultExportFormats = map[string]ExportFormat{
+	"application/vnd.google-apps.document":     {"application/vnd.openxmlformats-officedocument.wordprocessingml.document", ".docx"},
+	"application/vnd.google-apps.spreadsheet":  {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", ".xlsx"},
+	"application/vnd.google-apps.presentation": {"application/vnd.openxmlformats-officedocument.presentationml.presentation", ".pptx"},
+	"application/vnd.google-apps.drawing":      {"application/pdf", ".pdf"},
+}
+
+// GoogleDriveSource is a ChangeSource providing the files of a Google Drive.
+// The ID of an Input is the ID of the file.
+//
+// Native Google documents, which have no content of their own, are exported
+// to the formats of the source, and their Name has the extension of the
+// format. Other native files, such as folders and forms, are skipped.
+//
+// Changes uses the changes API of Google Drive, so it reports deleted and
+// trashed files, and its tokens are page tokens of that API.
+type GoogleDriveSource struct {
+	httpClient *http.Client
+	formats    map[string]ExportFormat
+}
+
+// NewGoogleDriveSource creates a Source of the files of a Google Drive. If
+// formats is nil, DefaultExportFormats are used.
+//
+// httpClient must authenticate requests to the Google Drive API, for example
+// with a client from golang.org/x/oauth2.
+func NewGoogleDriveSource(httpClient *http.Client, formats map[string]ExportFormat) *GoogleDriveSource {
+	if formats == nil {
+		formats = DefaultExportFormats
+	}
+	return &GoogleDriveSource{httpClient: httpClient, formats: formats}
+}
+
+const driveFileFields = "id,name,mimeType,size,modifiedTime,trashed"
+
+// driveFile is a file resource of the Google Drive API.
+type driveFile struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	MIMEType     string    `json:"mimeType"`
+	Size         string    `json:"size"` // int64 encoded as a string.
+	ModifiedTime time.Time `json:"modifiedTime"`
+	Trashed      bool      `json:"trashed"`
+}
+
+func (s *GoogleDriveSource) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	req, err := http.NewRequest("GET", googleDriveURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := ctxhttp.Do(ctx, s.httpClient, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("response code %v", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// input returns the Input of f, and false if f has no content to extract.
+func (s *GoogleDriveSource) input(f driveFile) (Input, bool) {
+	in := Input{ID: f.ID, Name: f.Name, ModTime: f.ModifiedTime, Deleted: f.Trashed}
+	if strings.HasPrefix(f.MIMEType, "application/vnd.google-apps.") {
+		format, ok := s.formats[f.MIMEType]
+		if !ok {
+			return in, false
+		}
+		in.Name += format.Extension
+		// The size of an export is only known once it is made.
+		in.Size = UnknownSize
+		return in, true
+	}
+	in.Size, _ = strconv.ParseInt(f.Size, 10, 64)
+	return in, true
+}
+
+// Walk implements Source.
+func (s *GoogleDriveSource) Walk(ctx context.Context, fn func(Input) error) error {
+	q := url.Values{
+		"q":        {"trashed = false"},
+		"pageSize": {"1000"},
+		"fields":   {"nextPageToken,files(" + driveFileFields + ")"},
+	}
+	for {
+		var page struct {
+			NextPageToken string      `json:"nextPageToken"`
+			Files         []driveFile `json:"files"`
+		}
+		if err := s.get(ctx, "/files", q, &page); err != nil {
+			return fmt.Errorf("error listing files: %w", err)
+		}
+		for _, f := range page.Files {
+			if in, ok := s.input(f); ok {
+				if err := fn(in); err != nil {
+					return err
+				}
+			}
+		}
+		if page.NextPageToken == "" {
+			return nil
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// Changes implements ChangeSource. With an empty token, it walks all the
+// files, and returns a token from before the walk so that no change is missed.
+func (s *GoogleDriveSource) Changes(ctx context.Context, token string, fn func(Input) error) (string, error) {
+	if token == "" {
+		var start struct {
+			StartPageToken string `json:"startPageToken"`
+		}
+		if err := s.get(ctx, "/changes/startPageToken", nil, &start); err != nil {
+			return "", fmt.Errorf("error getting start page token: %w", err)
+		}
+		if err := s.Walk(ctx, fn); err != nil {
+			return "", err
+		}
+		return start.StartPageToken, nil
+	}
+	q := url.Values{
+		"pageToken": {token},
+		"pageSize":  {"1000"},
+		"fields":    {"nextPageToken,newStartPageToken,changes(fileId,removed,file(" + driveFileFields + "))"},
+	}
+	for {
+		var page struct {
+			NextPageToken     string `json:"nextPageToken"`
+			NewStartPageToken string `json:"newStartPageToken"`
+			Changes           []struct {
+				FileID  string     `json:"fileId"`
+				Removed bool       `json:"removed"`
+				File    *driveFile `json:"file"`
+			} `json:"changes"`
+		}
+		if err := s.get(ctx, "/changes", q, &page); err != nil {
+			return "", fmt.Errorf("error listing changes: %w", err)
+		}
+		for _, c := range page.Changes {
+			in, ok := Input{ID: c.FileID, Deleted: true}, true
+			if !c.Removed && c.File != nil {
+				in, ok = s.input(*c.File)
+			}
+			if !ok {
+				continue
+			}
+			if err := fn(in); err != nil {
+				return "", err
+			}
+		}
+		if page.NextPageToken == "" {
+			return page.NewStartPageToken, nil
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// Open implements Source. Native Google documents are exported.
+func (s *GoogleDriveSource) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	path := "/files/" + url.PathEscape(id)
+	var f driveFile
+	if err := s.get(ctx, path, url.Values{"fields": {"mimeType"}}, &f); err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", id, err)
+	}
+	q := url.Values{"alt": {"media"}}
+	if format, ok := s.formats[f.MIMEType]; ok {
+		path += "/export"
+		q = url.Values{"mimeType": {format.MIMEType}}
+	}
+	req, err := http.NewRequest("GET", googleDriveURL+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := openHTTP(ctx, s.httpClient, req)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", id, err)
+	}
+	return rc, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func googleDriveServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch r.URL.Path {
+		case "/files":
+			if q.Get("pageToken") == "" {
+				fmt.Fprint(w, `{"nextPageToken": "p2", "files": [
+					{"id": "f", "name": "folder", "mimeType": "application/vnd.google-apps.folder"},
+					{"id": "a", "name": "a.pdf", "mimeType": "application/pdf", "size": "12"}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"files": [{"id": "d", "name": "Notes", "mimeType": "application/vnd.google-apps.document"}]}`)
+		case "/changes/startPageToken":
+			fmt.Fprint(w, `{"startPageToken": "100"}`)
+		case "/changes":
+			if q.Get("pageToken") != "100" {
+				t.Errorf("changes requested with page token %q, want 100", q.Get("pageToken"))
+			}
+			fmt.Fprint(w, `{"newStartPageToken": "101", "changes": [
+				{"fileId": "a", "removed": true},
+				{"fileId": "d", "file": {"id": "d", "name": "Notes", "mimeType": "application/vnd.google-apps.document", "trashed": true}},
+				{"fileId": "b", "file": {"id": "b", "name": "b.txt", "mimeType": "text/plain", "size": "3"}}]}`)
+		case "/files/a":
+			if q.Get("alt") == "media" {
+				fmt.Fprint(w, "pdf")
+				return
+			}
+			fmt.Fprint(w, `{"mimeType": "application/pdf"}`)
+		case "/files/d":
+			fmt.Fprint(w, `{"mimeType": "application/vnd.google-apps.document"}`)
+		case "/files/d/export":
+			fmt.Fprint(w, "exported as "+q.Get("mimeType"))
+		default:
+			t.Errorf("unexpected request for %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestGoogleDriveSource(t *testing.T) {
+	ts := googleDriveServer(t)
+	defer ts.Close()
+	defer func(u string) { googleDriveURL = u }(googleDriveURL)
+	googleDriveURL = ts.URL
+	s := NewGoogleDriveSource(ts.Client(), nil)
+
+	var names []string
+	token, err := s.Changes(context.Background(), "", func(in Input) error {
+		names = append(names, in.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Changes got error: %v", err)
+	}
+	if want := []string{"a.pdf", "Notes.docx"}; !reflect.DeepEqual(names, want) || token != "100" {
+		t.Errorf("Changes = %v, %q, want %v, %q", names, token, want, "100")
+	}
+
+	var changes []Input
+	token, err = s.Changes(context.Background(), token, func(in Input) error {
+		changes = append(changes, in)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Changes got error: %v", err)
+	}
+	want := []Input{
+		{ID: "a", Deleted: true},
+		{ID: "d", Name: "Notes.docx", Size: UnknownSize, Deleted: true},
+		{ID: "b", Name: "b.txt", Size: 3},
+	}
+	if !reflect.DeepEqual(changes, want) || token != "101" {
+		t.Errorf("Changes = %+v, %q, want %+v, %q", changes, token, want, "101")
+	}
+
+	tests := map[string]string{
+		"a": "pdf",
+		"d": "exported as application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	}
+	for id, want := range tests {
+		rc, err := s.Open(context.Background(), id)
+		if err != nil {
+			t.Errorf("Open(%s) got error: %v", id, err)
+			continue
+		}
+		b, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if string(b) != want {
+			t.Errorf("Open(%s) read %q, want %q", id, b, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// A ResourceGuard pauses the intake of a Job while the process is short of
+// file descriptors or temporary space, and resumes it once they are released,
+// rather than letting every input fail with "too many open files" or "no space
+// left on device".
+//
+// Resources which cannot be measured on the platform are not guarded: open
+// files are counted on systems with /proc/self/fd or /dev/fd, and free space
+// is measured on Linux, macOS and FreeBSD.
+type ResourceGuard struct {
+	// MaxOpenFiles is the number of open file descriptors at which intake is
+	// paused. Zero means no limit.
+	MaxOpenFiles int
+	// TempDir is the directory whose free space is guarded. If empty,
+	// os.TempDir is used.
+	TempDir string
+	// MinFreeTemp is the free space of TempDir, in bytes, under which intake
+	// is paused. Zero means no limit.
+	MinFreeTemp int64
+	// Interval is how often the resources are checked while paused. Zero
+	// means 1 second.
+	Interval time.Duration
+
+	// openFiles and freeSpace are replaced in tests.
+	openFiles func() (int, bool)
+	freeSpace func(dir string) (int64, bool)
+}
+
+// check returns why intake should be paused, or "" if it should not.
+func (g *ResourceGuard) check() string {
+	if g.MaxOpenFiles > 0 {
+		count := openFiles
+		if g.openFiles != nil {
+			count = g.openFiles
+		}
+		if n, ok := count(); ok && n >= g.MaxOpenFiles {
+			return fmt.Sprintf("%d open files", n)
+		}
+	}
+	if g.MinFreeTemp > 0 {
+		free := freeSpace
+		if g.freeSpace != nil {
+			free = g.freeSpace
+		}
+		dir := g.TempDir
+		if dir == "" {
+			dir = os.TempDir()
+		}
+		if n, ok := free(dir); ok && n < g.MinFreeTemp {
+			return fmt.Sprintf("%d bytes free in %s", n, dir)
+		}
+	}
+	return ""
+}
+
+// Wait returns once the resources are above their thresholds, or ctx is
+// done. While waiting, pause is called with the reason, each time it changes.
+// Wait returns immediately if g is nil.
+func (g *ResourceGuard) Wait(ctx context.Context, pause func(reason string)) error {
+	if g == nil {
+		return nil
+	}
+	interval := g.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	last := ""
+	for {
+		reason := g.check()
+		if reason == "" {
+			return nil
+		}
+		if reason != last && pause != nil {
+			pause(reason)
+		}
+		last = reason
+		t := time.NewTimer(interval)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// openFiles returns the number of file descriptors open in the process.
+func openFiles() (int, bool) {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		f, err := os.Open(dir)
+		if err != nil {
+			continue
+		}
+		names, err := f.Readdirnames(-1)
+		f.Close()
+		if err != nil {
+			continue
+		}
+		// Do not count the descriptor used to read the directory.
+		return len(names) - 1, true
+	}
+	return 0, false
+}
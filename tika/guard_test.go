@@ -0,0 +1,124 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeResources returns a guard whose resources are read from the given
+// sequences, the last value repeating.
+func fakeResources(files []int, free []int64) *ResourceGuard {
+	return &ResourceGuard{
+		Interval: time.Millisecond,
+		openFiles: func() (int, bool) {
+			n := files[0]
+			if len(files) > 1 {
+				files = files[1:]
+			}
+			return n, true
+		},
+		freeSpace: func(string) (int64, bool) {
+			n := free[0]
+			if len(free) > 1 {
+				free = free[1:]
+			}
+			return n, true
+		},
+	}
+}
+
+func TestResourceGuardWait(t *testing.T) {
+	g := fakeResources([]int{100, 100, 100, 10}, []int64{0, 0, 1 << 20})
+	g.TempDir = "/tmp"
+	g.MaxOpenFiles = 50
+	g.MinFreeTemp = 1 << 10
+	var reasons []string
+	if err := g.Wait(context.Background(), func(reason string) { reasons = append(reasons, reason) }); err != nil {
+		t.Fatalf("Wait got error: %v", err)
+	}
+	want := []string{"100 open files", "0 bytes free in /tmp"}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("Wait paused with %q, want %q", reasons, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := g.Wait(ctx, nil); err != nil {
+		t.Errorf("Wait with enough resources got error: %v", err)
+	}
+	g.MaxOpenFiles = 5
+	if err := g.Wait(ctx, nil); err != context.Canceled {
+		t.Errorf("Wait with a canceled context got %v, want %v", err, context.Canceled)
+	}
+	var nilGuard *ResourceGuard
+	if err := nilGuard.Wait(ctx, nil); err != nil {
+		t.Errorf("nil Wait got error: %v", err)
+	}
+}
+
+func TestResources(t *testing.T) {
+	n, ok := openFiles()
+	if !ok {
+		t.Skip("open files cannot be counted")
+	}
+	f, err := os.Open(os.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if m, _ := openFiles(); m != n+1 {
+		t.Errorf("openFiles after opening a file = %d, want %d", m, n+1)
+	}
+	if free, ok := freeSpace(os.TempDir()); ok && free <= 0 {
+		t.Errorf("freeSpace(%s) = %d, want > 0", os.TempDir(), free)
+	}
+}
+
+func TestJobGuard(t *testing.T) {
+	ts := rmetaServer()
+	defer ts.Close()
+	j, docs := testJob(ts, map[string]string{"a.txt": "a", "b.txt": "b"})
+	j.Guard = fakeResources([]int{10, 10, 1}, []int64{0})
+	j.Guard.MaxOpenFiles = 5
+	var paused []string
+	j.Guard.openFiles = func(count func() (int, bool)) func() (int, bool) {
+		return func() (int, bool) {
+			if p := j.Status().Paused; p != "" {
+				paused = append(paused, p)
+			}
+			return count()
+		}
+	}(j.Guard.openFiles)
+	if err := j.Run(context.Background()); err != nil {
+		t.Fatalf("Run got error: %v", err)
+	}
+	if len(docs()) != 2 {
+		t.Errorf("Run emitted %d documents, want 2", len(docs()))
+	}
+	// The guard is checked twice more while paused.
+	if want := []string{"10 open files", "10 open files"}; !reflect.DeepEqual(paused, want) {
+		t.Errorf("Run paused with %q, want %q", paused, want)
+	}
+	if s := j.Status(); s.Paused != "" {
+		t.Errorf("Status after Run is paused: %q", s.Paused)
+	}
+}
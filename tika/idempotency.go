@@ -0,0 +1,124 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// An IdempotencyStore records the idempotency keys of the Documents a Job
+// emitted, so that a Job retried after a crash does not emit them again. Each
+// key is appended to a file and synced before Record returns, unlike the
+// CheckpointStore, which is only saved at the end of a run. Keys are stored
+// hashed.
+//
+// The Tika servers supported by this package have no asynchronous endpoint
+// accepting idempotency keys, so submissions are deduplicated by the Job.
+//
+// An IdempotencyStore is safe for concurrent use.
+type IdempotencyStore struct {
+	mu   sync.Mutex
+	f    *os.File
+	keys map[string]bool
+}
+
+// OpenIdempotencyStore opens the IdempotencyStore saved at path, or creates
+// an empty one if there is no file at path. The store must be closed with
+// Close.
+func OpenIdempotencyStore(path string) (*IdempotencyStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening idempotency keys: %w", err)
+	}
+	s := &IdempotencyStore{f: f, keys: map[string]bool{}}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// A line cut short by a crash is ignored: its Document was not
+		// acknowledged, so it is emitted again.
+		if line := sc.Text(); len(line) == 2*sha256.Size {
+			s.keys[line] = true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error reading idempotency keys: %w", err)
+	}
+	return s, nil
+}
+
+func hashKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// Seen reports whether key was recorded.
+func (s *IdempotencyStore) Seen(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[hashKey(key)]
+}
+
+// Record records key, and returns once it is written to disk.
+func (s *IdempotencyStore) Record(key string) error {
+	h := hashKey(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys[h] {
+		return nil
+	}
+	// Start a new line, in case the last write was cut short.
+	if _, err := s.f.WriteString("\n" + h + "\n"); err != nil {
+		return fmt.Errorf("error recording idempotency key: %w", err)
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("error recording idempotency key: %w", err)
+	}
+	s.keys[h] = true
+	return nil
+}
+
+// Len returns the number of recorded keys.
+func (s *IdempotencyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.keys)
+}
+
+// Close closes the file of the store.
+func (s *IdempotencyStore) Close() error {
+	return s.f.Close()
+}
+
+// defaultIdempotencyKey is the idempotency key of in if Job.IdempotencyKey
+// is nil: a changed input gets a new key.
+func defaultIdempotencyKey(in Input) string {
+	return in.ID + "\x00" + strconv.FormatInt(in.Size, 10) + "\x00" + in.ModTime.UTC().Format("2006-01-02T15:04:05.999999999Z")
+}
+
+// idempotencyKey returns the idempotency key of in.
+func (j *Job) idempotencyKey(in Input) string {
+	if j.IdempotencyKey != nil {
+		return j.IdempotencyKey(in)
+	}
+	return defaultIdempotencyKey(in)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	path := filepath.Join(tempDir(t), "keys")
+	s, err := OpenIdempotencyStore(path)
+	if err != nil {
+		t.Fatalf("OpenIdempotencyStore got error: %v", err)
+	}
+	for _, key := range []string{"a", "b", "a"} {
+		if err := s.Record(key); err != nil {
+			t.Fatalf("Record(%q) got error: %v", key, err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Simulate a crash in the middle of a write.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("\n" + hashKey("c")[:10])
+	f.Close()
+
+	s, err = OpenIdempotencyStore(path)
+	if err != nil {
+		t.Fatalf("OpenIdempotencyStore got error: %v", err)
+	}
+	defer s.Close()
+	if s.Len() != 2 || !s.Seen("a") || !s.Seen("b") || s.Seen("c") {
+		t.Errorf("reopened store has %d keys, want a and b", s.Len())
+	}
+	if err := s.Record("c"); err != nil {
+		t.Fatalf("Record(c) got error: %v", err)
+	}
+	if !s.Seen("c") {
+		t.Errorf("Seen(c) = false after Record(c)")
+	}
+}
+
+func TestJobIdempotency(t *testing.T) {
+	ts := rmetaServer()
+	defer ts.Close()
+	path := filepath.Join(tempDir(t), "keys")
+	files := map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"}
+
+	// The first run crashes while emitting b.txt.
+	store, err := OpenIdempotencyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, _ := testJob(ts, files)
+	j.Workers = 1
+	j.Idempotency = store
+	j.IdempotencyKey = func(in Input) string { return "key-" + in.ID }
+	var emitted []string
+	crash := errors.New("crash")
+	j.Emit = func(_ context.Context, d Document) error {
+		if d.ID == "b.txt" {
+			return crash
+		}
+		if d.IdempotencyKey != "key-"+d.ID {
+			t.Errorf("emitted %s with key %q, want %q", d.ID, d.IdempotencyKey, "key-"+d.ID)
+		}
+		emitted = append(emitted, d.ID)
+		return nil
+	}
+	if err := j.Run(context.Background()); !errors.Is(err, crash) {
+		t.Fatalf("Run got error %v, want %v", err, crash)
+	}
+	store.Close()
+
+	// The retry emits the remaining Documents only.
+	if store, err = OpenIdempotencyStore(path); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	j.Idempotency = store
+	j.Emit = func(_ context.Context, d Document) error {
+		emitted = append(emitted, d.ID)
+		return nil
+	}
+	if err := j.Run(context.Background()); err != nil {
+		t.Fatalf("Run got error: %v", err)
+	}
+	if want := []string{"a.txt", "b.txt", "c.txt"}; !reflect.DeepEqual(emitted, want) {
+		t.Errorf("Runs emitted %v, want %v", emitted, want)
+	}
+	if s := j.Status(); s.Skipped != 1 || s.Succeeded != 2 {
+		t.Errorf("retry has status %+v, want 1 skipped and 2 succeeded", s)
+	}
+}
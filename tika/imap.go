@@ -0,0 +1,162 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A MailMessage describes a message of a Mailbox.
+type MailMessage struct {
+	UID  uint32
+	Size int64
+	// Date is the internal date of the message, when the server received it.
+	Date time.Time
+}
+
+// A Mailbox is a connection to a selected IMAP mailbox.
+//
+// The tika package does not depend on an IMAP library, so
+// Mailbox is meant to be a thin adapter of one, such as
+// github.com/emersion/go-imap.
+type Mailbox interface {
+	// UIDValidity returns the UIDVALIDITY of the mailbox. UIDs from a previous
+	// UIDVALIDITY do not identify the same messages.
+	UIDValidity() uint32
+	// Messages returns the messages with a UID of at least minUID, by
+	// increasing UID.
+	Messages(minUID uint32) ([]MailMessage, error)
+	// Open returns the full RFC 822 content of the message with the UID,
+	// without marking it as seen.
+	Open(uid uint32) (io.ReadCloser, error)
+	Close() error
+}
+
+// A MailboxDialer connects to an IMAP server with the given Credentials and
+// selects a mailbox.
+type MailboxDialer func(ctx context.Context, creds Credentials) (Mailbox, error)
+
+// IMAPSource is a ChangeSource providing the messages of an IMAP mailbox. The
+// ID of an Input is the UID of the message, and its Name is the UID with an
+// .eml extension, so Tika parses it as an email.
+//
+// Changes fetches the messages with a UID greater than the last one seen,
+// which is the checkpoint saved in the token. If the UIDVALIDITY of the
+// mailbox changed, all messages are listed again. Deleted messages are not
+// reported.
+type IMAPSource struct {
+	conn remoteConn
+}
+
+// NewIMAPSource creates a Source of the messages of the mailbox selected by
+// dial.
+func NewIMAPSource(creds CredentialsProvider, dial MailboxDialer) *IMAPSource {
+	return &IMAPSource{conn: remoteConn{creds: creds, dial: func(ctx context.Context, c Credentials) (io.Closer, error) {
+		return dial(ctx, c)
+	}}}
+}
+
+// connect returns the current connection, connecting if needed.
+func (s *IMAPSource) connect(ctx context.Context) (Mailbox, error) {
+	conn, err := s.conn.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(Mailbox), nil
+}
+
+// reset drops conn after a failure, so the next operation reconnects.
+func (s *IMAPSource) reset(conn Mailbox) {
+	s.conn.reset(conn)
+}
+
+// Close closes the connection, if any.
+func (s *IMAPSource) Close() error {
+	return s.conn.Close()
+}
+
+// Walk implements Source.
+func (s *IMAPSource) Walk(ctx context.Context, fn func(Input) error) error {
+	_, err := s.Changes(ctx, "", fn)
+	return err
+}
+
+// Changes implements ChangeSource. Tokens have the form "uidvalidity:uid".
+func (s *IMAPSource) Changes(ctx context.Context, token string, fn func(Input) error) (string, error) {
+	var validity, last uint64
+	if token != "" {
+		i := strings.IndexByte(token, ':')
+		var err1, err2 error
+		if i >= 0 {
+			validity, err1 = strconv.ParseUint(token[:i], 10, 32)
+			last, err2 = strconv.ParseUint(token[i+1:], 10, 32)
+		}
+		if i < 0 || err1 != nil || err2 != nil {
+			return "", fmt.Errorf("invalid token %q", token)
+		}
+	}
+	conn, err := s.connect(ctx)
+	if err != nil {
+		return "", err
+	}
+	if uint64(conn.UIDValidity()) != validity {
+		validity, last = uint64(conn.UIDValidity()), 0
+	}
+	msgs, err := conn.Messages(uint32(last) + 1)
+	if err != nil {
+		s.reset(conn)
+		return "", fmt.Errorf("error listing messages: %w", err)
+	}
+	for _, m := range msgs {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		// Servers return the last message for a range past the end.
+		if uint64(m.UID) <= last {
+			continue
+		}
+		id := strconv.FormatUint(uint64(m.UID), 10)
+		if err := fn(Input{ID: id, Name: id + ".eml", Size: m.Size, ModTime: m.Date}); err != nil {
+			return "", err
+		}
+		last = uint64(m.UID)
+	}
+	return fmt.Sprintf("%d:%d", validity, last), nil
+}
+
+// Open implements Source.
+func (s *IMAPSource) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	uid, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id %q", id)
+	}
+	conn, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := conn.Open(uint32(uid))
+	if err != nil {
+		s.reset(conn)
+		return nil, fmt.Errorf("error opening message %s: %w", id, err)
+	}
+	return rc, nil
+}
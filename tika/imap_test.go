@@ -0,0 +1,108 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeMailbox is a Mailbox of messages whose content is their UID.
+type fakeMailbox struct {
+	validity uint32
+	uids     []uint32
+}
+
+func (m *fakeMailbox) UIDValidity() uint32 { return m.validity }
+
+func (m *fakeMailbox) Messages(minUID uint32) ([]MailMessage, error) {
+	var msgs []MailMessage
+	for _, uid := range m.uids {
+		if uid >= minUID {
+			msgs = append(msgs, MailMessage{UID: uid, Size: 1})
+		}
+	}
+	if len(msgs) == 0 && len(m.uids) > 0 {
+		// Like "UID FETCH n:*", which always returns the last message.
+		msgs = append(msgs, MailMessage{UID: m.uids[len(m.uids)-1]})
+	}
+	return msgs, nil
+}
+
+func (m *fakeMailbox) Open(uid uint32) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(fmt.Sprint(uid))), nil
+}
+
+func (m *fakeMailbox) Close() error { return nil }
+
+func TestIMAPSource(t *testing.T) {
+	mb := &fakeMailbox{validity: 7, uids: []uint32{3, 5}}
+	s := NewIMAPSource(StaticCredentials{Username: "u"}, func(context.Context, Credentials) (Mailbox, error) {
+		return mb, nil
+	})
+	defer s.Close()
+
+	tests := []struct {
+		name    string
+		token   string
+		uids    []uint32
+		want    []string
+		wantTok string
+	}{
+		{name: "first", want: []string{"3", "5"}, wantTok: "7:5"},
+		{name: "no new messages", token: "7:5", wantTok: "7:5"},
+		{name: "new messages", token: "7:5", uids: []uint32{3, 5, 6}, want: []string{"6"}, wantTok: "7:6"},
+		{name: "uid validity changed", token: "6:9", want: []string{"3", "5", "6"}, wantTok: "7:6"},
+	}
+	for _, test := range tests {
+		if test.uids != nil {
+			mb.uids = test.uids
+		}
+		var got []string
+		tok, err := s.Changes(context.Background(), test.token, func(in Input) error {
+			if in.Name != in.ID+".eml" {
+				t.Errorf("Changes(%s) got Input named %q", test.name, in.Name)
+			}
+			got = append(got, in.ID)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Changes(%s) got error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) || tok != test.wantTok {
+			t.Errorf("Changes(%s) = %v, %q, want %v, %q", test.name, got, tok, test.want, test.wantTok)
+		}
+	}
+
+	if _, err := s.Changes(context.Background(), "bad", func(Input) error { return nil }); err == nil {
+		t.Errorf("Changes with an invalid token got no error")
+	}
+	rc, err := s.Open(context.Background(), "5")
+	if err != nil {
+		t.Fatalf("Open got error: %v", err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != "5" {
+		t.Errorf("Open read %q, want %q", b, "5")
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Index is an in-memory inverted index over extracted text. It is meant for
+// checking the results of an extraction run locally, not as a replacement for
+// a search engine. An Index is safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]string              // docs maps document ID to text.
+	postings map[string]map[string]struct{} // postings maps term to document IDs.
+}
+
+// A SearchResult is a line of a document matching a query.
+type SearchResult struct {
+	ID   string // ID is the ID the document was added with.
+	Line int    // Line is the 1-based line number of the match.
+	Text string // Text is the matching line.
+}
+
+// NewIndex creates a new, empty Index.
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[string]string),
+		postings: make(map[string]map[string]struct{}),
+	}
+}
+
+// terms splits s into lower case words.
+func terms(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Add adds the text of the document with the given ID to the index, replacing
+// any document previously added with the same ID.
+func (x *Index) Add(id, text string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.docs[id]; ok {
+		x.remove(id)
+	}
+	x.docs[id] = text
+	for _, t := range terms(text) {
+		ids := x.postings[t]
+		if ids == nil {
+			ids = make(map[string]struct{})
+			x.postings[t] = ids
+		}
+		ids[id] = struct{}{}
+	}
+}
+
+// Remove removes the document with the given ID from the index.
+func (x *Index) Remove(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(id)
+}
+
+func (x *Index) remove(id string) {
+	text, ok := x.docs[id]
+	if !ok {
+		return
+	}
+	delete(x.docs, id)
+	for _, t := range terms(text) {
+		delete(x.postings[t], id)
+		if len(x.postings[t]) == 0 {
+			delete(x.postings, t)
+		}
+	}
+}
+
+// Len returns the number of documents in the index.
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.docs)
+}
+
+// Search returns every line containing all the words of query, ignoring case,
+// in the documents containing all of them. Results are ordered by document ID
+// and line. An empty query matches nothing.
+func (x *Index) Search(query string) []SearchResult {
+	qt := terms(query)
+	if len(qt) == 0 {
+		return nil
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var ids []string
+	for id := range x.postings[qt[0]] {
+		ids = append(ids, id)
+	}
+	for _, t := range qt[1:] {
+		var kept []string
+		for _, id := range ids {
+			if _, ok := x.postings[t][id]; ok {
+				kept = append(kept, id)
+			}
+		}
+		ids = kept
+	}
+	sort.Strings(ids)
+
+	var r []SearchResult
+	for _, id := range ids {
+		for i, line := range strings.Split(x.docs[id], "\n") {
+			if containsTerms(line, qt) {
+				r = append(r, SearchResult{ID: id, Line: i + 1, Text: line})
+			}
+		}
+	}
+	return r
+}
+
+// containsTerms reports whether line contains all of want as words.
+func containsTerms(line string, want []string) bool {
+	have := make(map[string]bool)
+	for _, t := range terms(line) {
+		have[t] = true
+	}
+	for _, t := range want {
+		if !have[t] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIndexSearch(t *testing.T) {
+	x := NewIndex()
+	x.Add("b.pdf", "Quarterly Report\nrevenue grew, costs fell\nrevenue")
+	x.Add("a.doc", "Revenue and costs\nnothing else")
+	x.Add("c.txt", "unrelated")
+
+	tests := []struct {
+		query string
+		want  []SearchResult
+	}{
+		{query: ""},
+		{query: "missing"},
+		{
+			query: "revenue",
+			want: []SearchResult{
+				{ID: "a.doc", Line: 1, Text: "Revenue and costs"},
+				{ID: "b.pdf", Line: 2, Text: "revenue grew, costs fell"},
+				{ID: "b.pdf", Line: 3, Text: "revenue"},
+			},
+		},
+		{
+			query: "COSTS revenue",
+			want: []SearchResult{
+				{ID: "a.doc", Line: 1, Text: "Revenue and costs"},
+				{ID: "b.pdf", Line: 2, Text: "revenue grew, costs fell"},
+			},
+		},
+		{
+			// Both words are in a.doc, but never on the same line.
+			query: "nothing costs",
+		},
+	}
+	for _, test := range tests {
+		if got := x.Search(test.query); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Search(%q) = %+v, want %+v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestIndexReplaceAndRemove(t *testing.T) {
+	x := NewIndex()
+	x.Add("a", "old text")
+	x.Add("a", "new text")
+	if got := x.Search("old"); got != nil {
+		t.Errorf("Search(old) after replacing = %+v, want no results", got)
+	}
+	if got := x.Len(); got != 1 {
+		t.Errorf("Len() = %d, want 1", got)
+	}
+	x.Remove("a")
+	x.Remove("unknown")
+	if got := x.Search("text"); got != nil {
+		t.Errorf("Search(text) after removing = %+v, want no results", got)
+	}
+	if got := x.Len(); got != 0 {
+		t.Errorf("Len() = %d, want 0", got)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WithJARChecksum returns an Option to verify that the JAR has the given
+// checksum when the Server is created and before every Start, so a JAR
+// modified on a long-lived host is never run. The checksum is the MD5,
+// SHA-256 or SHA-512 of the JAR, in hexadecimal, or the content of the .sha512
+// file published with it by Apache. NewServer and Start fail with
+// ErrChecksumMismatch if the checksum differs.
+//
+// Start runs a private copy of the JAR, made as it is verified, so the JAR
+// cannot be modified between the check and the start of the JVM.
+func WithJARChecksum(sum string) Option {
+	return func(s *Server) {
+		s.jarSum = parseChecksum(sum)
+	}
+}
+
+// parseChecksum returns the hexadecimal checksum of sum, which may be the
+// output of sha512sum, as in "<checksum>  tika-server.jar", or of
+// gpg --print-md, as in "tika-server.jar: <checksum in groups>".
+func parseChecksum(sum string) string {
+	fields := strings.Fields(sum)
+	if len(fields) == 0 {
+		return ""
+	}
+	if strings.HasSuffix(fields[0], ":") {
+		return strings.ToLower(strings.Join(fields[1:], ""))
+	}
+	return strings.ToLower(fields[0])
+}
+
+// WithJARVersion returns an Option to verify the JAR like WithJARChecksum,
+// with the MD5 checksum of the given version, as downloaded by
+// DownloadServer. NewServer fails if the version is not supported.
+func WithJARVersion(version Version) Option {
+	return func(s *Server) {
+		s.jarVersion = version
+	}
+}
+
+// checkJARConfig resolves the expected checksum of the JAR of s.
+func (s *Server) checkJARConfig() error {
+	if s.jarSum != "" {
+		_, _, err := checksumHash(s.jarSum)
+		return err
+	}
+	if s.jarVersion == "" {
+		return nil
+	}
+	md5 := md5s[s.jarVersion]
+	if md5 == "" {
+		return fmt.Errorf("unsupported Tika version: %s", s.jarVersion)
+	}
+	s.jarSum = md5
+	return nil
+}
+
+// checksumHash returns the hash computing checksums like sum, by its length,
+// and the name of its algorithm.
+func checksumHash(sum string) (hash.Hash, string, error) {
+	if _, err := hex.DecodeString(sum); err == nil {
+		switch len(sum) {
+		case 2 * md5.Size:
+			return md5.New(), "MD5", nil
+		case 2 * sha256.Size:
+			return sha256.New(), "SHA-256", nil
+		case 2 * sha512.Size:
+			return sha512.New(), "SHA-512", nil
+		}
+	}
+	return nil, "", fmt.Errorf("invalid JAR checksum %q: want the hexadecimal MD5, SHA-256 or SHA-512", sum)
+}
+
+// verifyJAR checks the JAR of s against its expected checksum, if any, and
+// copies it to w as it is read, if w is not nil.
+func (s *Server) verifyJAR(w io.Writer) error {
+	if s.jarSum == "" {
+		return nil
+	}
+	h, alg, err := checksumHash(s.jarSum)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(s.jar)
+	if err != nil {
+		return fmt.Errorf("%w: cannot read %s", ErrChecksumMismatch, s.jar)
+	}
+	defer f.Close()
+	if w != nil {
+		h = &teeHash{Hash: h, w: w}
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("error verifying %s: %w", s.jar, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != s.jarSum {
+		return fmt.Errorf("%w: %s has %s checksum %s, want %s", ErrChecksumMismatch, s.jar, alg, sum, s.jarSum)
+	}
+	return nil
+}
+
+// teeHash is a hash.Hash also writing to w.
+type teeHash struct {
+	hash.Hash
+	w io.Writer
+}
+
+func (h *teeHash) Write(p []byte) (int, error) {
+	if n, err := h.w.Write(p); err != nil {
+		return n, err
+	}
+	return h.Hash.Write(p)
+}
+
+// verifiedJAR returns the path of the JAR to run: the JAR of s if it has no
+// expected checksum, or else a copy verified against it in a new directory,
+// which remove removes.
+func (s *Server) verifiedJAR() (jar string, remove func(), err error) {
+	if s.jarSum == "" {
+		return s.jar, func() {}, nil
+	}
+	dir, err := ioutil.TempDir("", "tika-server-jar")
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+	// Let the server read the copy when it runs as another user. Only the
+	// owner can change it.
+	if err := os.Chmod(dir, 0755); err != nil {
+		return "", nil, err
+	}
+	jar = filepath.Join(dir, filepath.Base(s.jar))
+	f, err := os.OpenFile(jar, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		return "", nil, err
+	}
+	err = s.verifyJAR(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return jar, func() { os.RemoveAll(dir) }, nil
+}
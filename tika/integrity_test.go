@@ -0,0 +1,137 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJARIntegrity(t *testing.T) {
+	jar := filepath.Join(tempDir(t), "tika-server.jar")
+	if err := ioutil.WriteFile(jar, testJAR(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := fmt.Sprintf("%x", md5.Sum(testJAR()))
+	md5s[testVersion] = sum
+	defer delete(md5s, testVersion)
+	sha256Sum := fmt.Sprintf("%X", sha256.Sum256(testJAR()))
+	sha512Sum := fmt.Sprintf("%x", sha512.Sum512(testJAR()))
+	var gpg []string
+	for i := 0; i < len(sha512Sum); i += 8 {
+		gpg = append(gpg, strings.ToUpper(sha512Sum[i:i+8]))
+	}
+
+	tests := []struct {
+		name    string
+		options []Option
+		wantErr error
+	}{
+		{name: "no checksum"},
+		{name: "checksum", options: []Option{WithJARChecksum(sum)}},
+		{name: "SHA-256", options: []Option{WithJARChecksum(sha256Sum)}},
+		{name: "SHA-512", options: []Option{WithJARChecksum(sha512Sum)}},
+		{name: "sha512sum file", options: []Option{WithJARChecksum(sha512Sum + "  tika-server.jar\n")}},
+		{name: "gpg file", options: []Option{WithJARChecksum("tika-server.jar: " + strings.Join(gpg[:8], " ") + "\n                 " + strings.Join(gpg[8:], " ") + "\n")}},
+		{name: "version", options: []Option{WithJARVersion(testVersion)}},
+		{name: "wrong checksum", options: []Option{WithJARChecksum("0123456789abcdef0123456789abcdef")}, wantErr: ErrChecksumMismatch},
+		{name: "wrong version", options: []Option{WithJARVersion(Version114)}, wantErr: ErrChecksumMismatch},
+	}
+	for _, test := range tests {
+		_, err := NewServer(jar, test.options...)
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("NewServer(%s) got error %v, want %v", test.name, err, test.wantErr)
+		}
+	}
+	if _, err := NewServer(jar, WithJARVersion("0.1")); err == nil {
+		t.Errorf("NewServer with an unsupported version got no error")
+	}
+	if _, err := NewServer(jar, WithJARChecksum("0123")); err == nil || errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("NewServer with an invalid checksum got error %v", err)
+	}
+
+	s, err := NewServer(jar, WithJARChecksum(sum))
+	if err != nil {
+		t.Fatalf("NewServer got error: %v", err)
+	}
+	if err := ioutil.WriteFile(jar, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Start(context.Background()); !errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), "MD5 checksum") {
+		t.Errorf("Start with a modified JAR got error %v, want ErrChecksumMismatch of the MD5", err)
+	}
+}
+
+func TestStartVerifiedCopy(t *testing.T) {
+	jar := filepath.Join(tempDir(t), "tika-server.jar")
+	if err := ioutil.WriteFile(jar, testJAR(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "1.14")
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	s, err := NewServer(jar, WithJARChecksum(fmt.Sprintf("%x", sha512.Sum512(testJAR()))), WithHostname(u.Hostname()), WithPort(u.Port()))
+	if err != nil {
+		t.Fatalf("NewServer got error: %v", err)
+	}
+
+	old := cmder
+	defer func() { cmder = old }()
+	var run string
+	var content []byte
+	cmder = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		for i, a := range args {
+			if a == "-jar" {
+				run = args[i+1]
+				content, _ = ioutil.ReadFile(run)
+			}
+		}
+		return old(ctx, name, args...)
+	}
+	cancel, err := s.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start got error: %v", err)
+	}
+	if run == jar || !bytes.Equal(content, testJAR()) {
+		t.Errorf("Start ran %s with %d bytes, want a copy of %s", run, len(content), jar)
+	}
+	// Modifying the JAR once verified does not change the JAR run.
+	ioutil.WriteFile(jar, []byte("tampered"), 0644)
+	if got, _ := ioutil.ReadFile(run); !bytes.Equal(got, testJAR()) {
+		t.Errorf("the JAR run changed with the JAR of the Server")
+	}
+	cancel()
+	if _, err := os.Stat(run); !os.IsNotExist(err) {
+		t.Errorf("Stat of the copy of the JAR after cancel got error %v, want it removed", err)
+	}
+}
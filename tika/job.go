@@ -0,0 +1,616 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobState is the state of a Job.
+type JobState string
+
+// JobStates.
+const (
+	JobIdle      JobState = "idle"      // Not run yet.
+	JobListing   JobState = "listing"   // Listing the inputs of the Source.
+	JobRunning   JobState = "running"   // Extracting the inputs.
+	JobSucceeded JobState = "succeeded" // Finished, possibly with failed documents.
+	JobFailed    JobState = "failed"    // Stopped by an error.
+)
+
+// JobMode is what a Job extracts from its inputs.
+type JobMode int
+
+// JobModes.
+const (
+	// ExtractAll extracts the content and metadata of the inputs.
+	ExtractAll JobMode = iota
+	// MetaOnlyAll only extracts the metadata of the inputs, such as their
+	// type, dates and authors, which is much cheaper, for fast triage passes
+	// over a corpus. The Content of the Documents is empty.
+	MetaOnlyAll
+)
+
+// metaOnlyWorkersPerCPU is the default number of workers per CPU of a
+// MetaOnlyAll Job, which mostly waits for the server.
+const metaOnlyWorkersPerCPU = 4
+
+// maxRecentFailures is the number of failures kept in JobStatus.
+const maxRecentFailures = 20
+
+// A DocumentFailure describes an input a Job failed to extract.
+type DocumentFailure struct {
+	ID  string `json:"id"`
+	Err string `json:"error"`
+	// Canceled is why the input was given up, if it was canceled rather
+	// than failed.
+	Canceled CancelReason `json:"canceled,omitempty"`
+	Time     time.Time    `json:"time"`
+}
+
+// JobEventType is the type of a JobEvent.
+type JobEventType string
+
+// JobEventTypes.
+const (
+	EventJobStarted  JobEventType = "job.started"
+	EventJobProgress JobEventType = "job.progress"
+	// EventJobFailureThreshold is sent once per run, when the number of
+	// failed documents reaches Job.FailureThreshold.
+	EventJobFailureThreshold JobEventType = "job.failure_threshold"
+	EventJobFinished         JobEventType = "job.finished"
+)
+
+// A JobEvent is a step in the lifecycle of a Job.
+type JobEvent struct {
+	Type JobEventType `json:"type"`
+	Time time.Time    `json:"time"`
+	// Percent is the percentage of inputs processed, for EventJobProgress.
+	Percent int       `json:"percent,omitempty"`
+	Status  JobStatus `json:"job"`
+}
+
+// JobStatus is a snapshot of the progress of a Job.
+type JobStatus struct {
+	Name  string   `json:"name"`
+	State JobState `json:"state"`
+	// Total is the number of inputs listed.
+	Total int `json:"total"`
+	// Pending is the number of inputs listed and not started yet.
+	Pending int `json:"pending"`
+	// InFlight is the number of inputs being extracted.
+	InFlight  int `json:"inFlight"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	// Canceled is the number of Failed inputs which were canceled, rather
+	// than failed by the Source or the server.
+	Canceled int `json:"canceled"`
+	// Skipped is the number of inputs unchanged since their Checkpoint,
+	// already emitted according to the Idempotency store, or skipped as
+	// noise.
+	Skipped int `json:"skipped"`
+	// Drifted is the number of Documents whose metadata drifted from their
+	// expected schema, see Job.Drift. Strict drift also counts as Failed.
+	Drifted int `json:"drifted"`
+	// Paused is why the ResourceGuard of the Job paused its intake, if it is
+	// paused.
+	Paused string `json:"paused,omitempty"`
+	// Throughput is the number of inputs processed per second since the Job
+	// started.
+	Throughput float64   `json:"throughput"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	// Err is the error which stopped the Job, if any.
+	Err string `json:"error,omitempty"`
+	// RecentFailures are the latest documents which failed, newest last.
+	RecentFailures []DocumentFailure `json:"recentFailures,omitempty"`
+}
+
+// Done returns the number of inputs processed, successfully or not, or
+// skipped.
+func (s JobStatus) Done() int {
+	return s.Succeeded + s.Failed + s.Skipped
+}
+
+// fail records the failure of the input with the ID.
+func (s *JobStatus) fail(id string, err error) {
+	s.Failed++
+	f := DocumentFailure{ID: id, Err: err.Error(), Time: time.Now()}
+	if reason, ok := CancelReasonOf(err); ok {
+		s.Canceled++
+		f.Canceled = reason
+	}
+	s.RecentFailures = append(s.RecentFailures, f)
+	if len(s.RecentFailures) > maxRecentFailures {
+		s.RecentFailures = s.RecentFailures[1:]
+	}
+}
+
+// A Job extracts the inputs of a Source with a Client, and emits the extracted
+// Documents. The inputs are listed first, so the progress of the Job is known
+// while it runs.
+//
+// A Job can be run again once finished. Its Status can be read concurrently
+// with Run.
+type Job struct {
+	// Name identifies the Job in its JobStatus.
+	Name   string
+	Source Source
+	Client *Client
+	// Mode is what is extracted from the inputs (default ExtractAll).
+	Mode JobMode
+	// Workers is the number of inputs extracted concurrently. Zero means 1,
+	// or 4 per CPU with MetaOnlyAll.
+	Workers int
+	// Emit is called with each extracted Document, concurrently from the
+	// workers. An error returned by Emit stops the Job.
+	Emit func(context.Context, Document) error
+
+	// OnEvent, if not nil, is called with the events of the Job, in order,
+	// from a goroutine of Run, so it does not slow down the workers. Run
+	// returns once all the events are handled.
+	OnEvent func(JobEvent)
+	// ProgressStep is the percentage of inputs processed between
+	// EventJobProgress events. Zero means 10.
+	ProgressStep int
+	// FailureThreshold is the number of failed documents sending an
+	// EventJobFailureThreshold event. Zero means no event.
+	FailureThreshold int
+	// Checkpoints, if not nil, makes runs differential: inputs are skipped if
+	// their size and modification time, or else the hash of their content,
+	// did not change since their Checkpoint. Checkpoints are recorded for the
+	// inputs extracted successfully, removed for the inputs no longer listed,
+	// and saved at the end of each run. Inputs marked with
+	// CheckpointStore.MarkForReprocessing are extracted again.
+	Checkpoints *CheckpointStore
+	// Idempotency, if not nil, records the idempotency key of every emitted
+	// Document as soon as Emit returns, and inputs whose key is recorded are
+	// skipped, so a Job retried after a crash does not emit a Document
+	// twice. The key of a Document is set in its IdempotencyKey. Inputs
+	// marked for reprocessing in the Checkpoints are emitted again.
+	Idempotency *IdempotencyStore
+	// IdempotencyKey returns the idempotency key of an input. If nil, the key
+	// is derived from the ID, size and modification time of the input, so a
+	// changed input is emitted again.
+	IdempotencyKey func(Input) string
+	// Guard, if not nil, pauses the intake of inputs while resources are
+	// short. Inputs in flight are not interrupted.
+	Guard *ResourceGuard
+	// TypeLimits limits the number of inputs extracted concurrently per MIME
+	// type, guessed from their name, since some types, such as images to OCR,
+	// cost much more to extract than others. Keys are MIME types or patterns,
+	// as defined by path.Match, such as "image/*". An input is limited by the
+	// matching key with the fewest wildcards. A worker waiting for a limited
+	// type does not take other inputs.
+	TypeLimits map[string]int
+	// Drift, if not nil, checks the metadata of every Document against the
+	// expected schema of its MIME type. Drifting Documents fail if the
+	// DriftChecker is Strict.
+	Drift *DriftChecker
+	// Links, if set, extracts the hyperlinks of the inputs into the Links of
+	// their Documents. The inputs are then extracted as XHTML, and their
+	// Content is rendered from it, with the block elements on their own
+	// lines, so it may differ slightly from the plain text of Tika.
+	Links bool
+	// Noise is how noise inputs, such as empty files, office lock files and
+	// .DS_Store files, are handled, by NoiseKind (see NoiseOf). Kinds not in
+	// Noise are extracted. For example, to skip all noise:
+	//
+	//	Noise: map[tika.NoiseKind]tika.NoisePolicy{
+	//		tika.NoiseEmpty:      tika.NoiseSkip,
+	//		tika.NoiseLockFile:   tika.NoiseSkip,
+	//		tika.NoiseSystemFile: tika.NoiseSkip,
+	//	}
+	Noise map[NoiseKind]NoisePolicy
+	// Trace, if set, records a runtime/trace task per input, with a region
+	// per stage, for the execution tracer. The goroutines of the Job are
+	// always labelled for pprof with the name of the Job, the MIME type of
+	// the input and the stage.
+	Trace bool
+
+	// emit is Emit, or the emitter of Stream, for the current run.
+	emit func(context.Context, Document) error
+
+	mu     sync.Mutex
+	status JobStatus
+	events chan JobEvent
+	// progress is the percentage of the last EventJobProgress.
+	progress int
+}
+
+// Status returns the current status of the Job.
+func (j *Job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.statusLocked()
+}
+
+func (j *Job) statusLocked() JobStatus {
+	s := j.status
+	s.Name = j.Name
+	if s.State == "" {
+		s.State = JobIdle
+	}
+	s.RecentFailures = append([]DocumentFailure(nil), s.RecentFailures...)
+	end := s.Finished
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if d := end.Sub(s.Started).Seconds(); !s.Started.IsZero() && d > 0 {
+		s.Throughput = float64(s.Done()) / d
+	}
+	return s
+}
+
+// update calls fn with the status of j locked.
+func (j *Job) update(fn func(s *JobStatus)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.status)
+}
+
+// Run lists the inputs of the Source and extracts them. Inputs which fail to
+// extract are counted in the JobStatus and do not stop the Job. Run returns
+// an error if listing the inputs or emitting a Document failed, or if ctx is
+// done.
+func (j *Job) Run(ctx context.Context) error {
+	return j.runEmitting(ctx, j.Emit)
+}
+
+// runEmitting runs j, emitting the Documents with emit instead of Emit.
+func (j *Job) runEmitting(ctx context.Context, emit func(context.Context, Document) error) error {
+	j.emit = emit
+	var dispatched chan struct{}
+	if j.OnEvent != nil {
+		events := make(chan JobEvent, 64)
+		dispatched = make(chan struct{})
+		go func() {
+			defer close(dispatched)
+			for ev := range events {
+				j.OnEvent(ev)
+			}
+		}()
+		j.mu.Lock()
+		j.events = events
+		j.mu.Unlock()
+	}
+
+	var ev *JobEvent
+	j.update(func(s *JobStatus) {
+		*s = JobStatus{State: JobListing, Started: time.Now()}
+		j.progress = 0
+		ev = j.event(EventJobStarted)
+	})
+	j.send(ev)
+	err := j.run(ctx)
+	j.update(func(s *JobStatus) {
+		s.Finished = time.Now()
+		s.State = JobSucceeded
+		if err != nil {
+			s.State, s.Err = JobFailed, err.Error()
+		}
+		ev = j.event(EventJobFinished)
+	})
+	j.send(ev)
+
+	if dispatched != nil {
+		j.mu.Lock()
+		close(j.events)
+		j.events = nil
+		j.mu.Unlock()
+		<-dispatched
+	}
+	return err
+}
+
+// event returns an event of the given type, or nil if there is no OnEvent.
+// j.mu must be held.
+func (j *Job) event(typ JobEventType) *JobEvent {
+	if j.events == nil {
+		return nil
+	}
+	return &JobEvent{Type: typ, Time: time.Now(), Status: j.statusLocked()}
+}
+
+// send queues ev, which may be nil, for OnEvent.
+func (j *Job) send(ev *JobEvent) {
+	if ev == nil {
+		return
+	}
+	j.mu.Lock()
+	events := j.events
+	j.mu.Unlock()
+	events <- *ev
+}
+
+func (j *Job) run(ctx context.Context) (err error) {
+	var inputs []Input
+	ids := map[string]bool{}
+	j.stage(ctx, "", stageList, func(ctx context.Context) {
+		err = j.Source.Walk(ctx, func(in Input) error {
+			if !in.Deleted {
+				inputs = append(inputs, in)
+				ids[in.ID] = true
+				j.update(func(s *JobStatus) { s.Total, s.Pending = s.Total+1, s.Pending+1 })
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("error listing inputs: %w", err)
+	}
+	if j.Checkpoints != nil {
+		j.Checkpoints.Retain(ids)
+		defer func() {
+			// Save the progress even if ctx is done.
+			if serr := j.Checkpoints.Save(context.Background()); err == nil {
+				err = serr
+			}
+		}()
+	}
+	j.update(func(s *JobStatus) { s.State = JobRunning })
+
+	ctx, cancel := WithCancelReason(ctx)
+	defer cancel(CancelShutdown)
+	queue := make(chan Input)
+	var once sync.Once
+	var emitErr error
+	var wg sync.WaitGroup
+	limiter := newTypeLimiter(j.TypeLimits)
+	workers := j.Workers
+	if workers < 1 {
+		workers = 1
+		if j.Mode == MetaOnlyAll {
+			workers = metaOnlyWorkersPerCPU * runtime.NumCPU()
+		}
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for in := range queue {
+				typ := inputType(in)
+				var release func()
+				var err error
+				j.stage(ctx, typ, stageWait, func(ctx context.Context) {
+					release, err = limiter.acquire(ctx, typ)
+				})
+				if err != nil {
+					// Given up before processing, so it is no longer pending.
+					err = canceled(ctx, err)
+					j.update(func(s *JobStatus) {
+						s.Pending--
+						s.fail(in.ID, err)
+					})
+					continue
+				}
+				err = j.process(ctx, in, typ)
+				release()
+				if err != nil {
+					once.Do(func() { emitErr = err })
+					cancel(CancelAborted)
+				}
+			}
+		}()
+	}
+feed:
+	for _, in := range inputs {
+		err := j.Guard.Wait(ctx, func(reason string) {
+			j.update(func(s *JobStatus) { s.Paused = reason })
+		})
+		j.update(func(s *JobStatus) { s.Paused = "" })
+		if err != nil {
+			break feed
+		}
+		select {
+		case queue <- in:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	if emitErr != nil {
+		return emitErr
+	}
+	return canceled(ctx, ctx.Err())
+}
+
+// process extracts in and emits its Document. It only returns the errors of
+// Emit; extraction errors are recorded in the status.
+func (j *Job) process(ctx context.Context, in Input, typ string) error {
+	ctx, end := j.task(ctx, in)
+	defer end()
+	j.update(func(s *JobStatus) { s.Pending, s.InFlight = s.Pending-1, s.InFlight+1 })
+	policy, kind := noisePolicy(j.Noise, in)
+	if policy == NoiseSkip {
+		j.update(func(s *JobStatus) { s.InFlight, s.Skipped = s.InFlight-1, s.Skipped+1 })
+		return nil
+	}
+	var changed bool
+	var err error
+	if policy == NoiseFail {
+		err = fmt.Errorf("%s: %w", kind, ErrNoise)
+	} else {
+		j.stage(ctx, typ, stageCheckpoint, func(ctx context.Context) {
+			changed, err = j.changed(ctx, in)
+		})
+	}
+	var key string
+	if j.Idempotency != nil || j.IdempotencyKey != nil {
+		key = j.idempotencyKey(in)
+	}
+	if err == nil && (!changed || j.Idempotency != nil && j.Idempotency.Seen(key) && !j.reprocessing(in.ID)) {
+		j.update(func(s *JobStatus) { s.InFlight, s.Skipped = s.InFlight-1, s.Skipped+1 })
+		return nil
+	}
+	var doc Document
+	var hash string
+	if err == nil && policy == NoiseRecord {
+		doc = Document{ID: in.ID, Size: in.Size, Metadata: map[string][]string{NoiseKey: {string(kind)}}}
+	} else if err == nil {
+		j.stage(ctx, typ, stageExtract, func(ctx context.Context) {
+			doc, hash, err = j.extract(ctx, in)
+		})
+		if err == nil {
+			err = j.checkDrift(doc)
+		}
+	}
+	doc.IdempotencyKey = key
+	if err == nil && j.emit != nil {
+		var emitErr error
+		j.stage(ctx, typ, stageEmit, func(ctx context.Context) {
+			emitErr = j.emit(ctx, doc)
+			if emitErr == nil && j.Idempotency != nil {
+				emitErr = j.Idempotency.Record(key)
+			}
+		})
+		if emitErr != nil {
+			j.update(func(s *JobStatus) { s.InFlight-- })
+			return fmt.Errorf("error emitting %s: %w", in.ID, emitErr)
+		}
+	}
+	if err == nil && j.Checkpoints != nil {
+		j.Checkpoints.Put(in.ID, Checkpoint{
+			Size:        in.Size,
+			ModTime:     in.ModTime,
+			Hash:        hash,
+			ContentType: doc.ContentType,
+			Extracted:   time.Now().UTC(),
+			ErrorClass:  errorClass(doc.Metadata),
+		})
+	}
+	err = canceled(ctx, err)
+	var events []*JobEvent
+	j.update(func(s *JobStatus) {
+		s.InFlight--
+		if err == nil {
+			s.Succeeded++
+		} else {
+			s.fail(in.ID, err)
+			if s.Failed == j.FailureThreshold {
+				events = append(events, j.event(EventJobFailureThreshold))
+			}
+		}
+		step := j.ProgressStep
+		if step <= 0 {
+			step = 10
+		}
+		if p := s.Done() * 100 / s.Total; p >= j.progress+step {
+			j.progress = p - p%step
+			if ev := j.event(EventJobProgress); ev != nil {
+				ev.Percent = j.progress
+				events = append(events, ev)
+			}
+		}
+	})
+	for _, ev := range events {
+		j.send(ev)
+	}
+	return nil
+}
+
+// reprocessing reports whether the input with the ID is marked for
+// reprocessing in the Checkpoints.
+func (j *Job) reprocessing(id string) bool {
+	if j.Checkpoints == nil {
+		return false
+	}
+	c, ok := j.Checkpoints.Get(id)
+	return ok && c.Reprocess
+}
+
+// changed reports whether in changed since its Checkpoint. An input whose
+// size or modification time changed is hashed, and its Checkpoint updated if
+// its content did not change.
+func (j *Job) changed(ctx context.Context, in Input) (bool, error) {
+	if j.Checkpoints == nil {
+		return true, nil
+	}
+	c, ok := j.Checkpoints.Get(in.ID)
+	if !ok || c.Reprocess {
+		return true, nil
+	}
+	if c.Size == in.Size && c.ModTime.Equal(in.ModTime) {
+		return false, nil
+	}
+	r, err := j.Source.Open(ctx, in.ID)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return false, err
+	}
+	if hash := hex.EncodeToString(h.Sum(nil)); hash != c.Hash {
+		return true, nil
+	}
+	c.Size, c.ModTime = in.Size, in.ModTime
+	j.Checkpoints.Put(in.ID, c)
+	return false, nil
+}
+
+// extract returns the Document of in, and the hex encoded SHA-256 of its
+// content.
+func (j *Job) extract(ctx context.Context, in Input) (Document, string, error) {
+	r, err := j.Source.Open(ctx, in.ID)
+	if err != nil {
+		return Document{}, "", err
+	}
+	defer r.Close()
+	opts := []RequestOption{WithResourceName(in.Name)}
+	if !in.ModTime.IsZero() {
+		opts = append(opts, WithLastModified(in.ModTime))
+	}
+	links := j.Links && j.Mode != MetaOnlyAll
+	if links {
+		opts = append(opts, WithRecursiveHandler(HandlerXML))
+	}
+	h := sha256.New()
+	var m map[string][]string
+	if j.Mode == MetaOnlyAll {
+		m, err = j.Client.metaMap(ctx, io.TeeReader(r, h), opts)
+	} else {
+		m, err = j.Client.containerMeta(ctx, io.TeeReader(r, h), opts)
+	}
+	if err != nil {
+		return Document{}, "", err
+	}
+	doc := Document{
+		ID:          in.ID,
+		ContentType: firstValue(m, "Content-Type"),
+		Size:        in.Size,
+		Content:     firstValue(m, XTIKAContent),
+		Metadata:    m,
+	}
+	delete(m, XTIKAContent)
+	if links && doc.Content != "" {
+		if doc.Links, doc.Content, err = parseLinks(strings.NewReader(doc.Content)); err != nil {
+			return Document{}, "", fmt.Errorf("error extracting links: %w", err)
+		}
+	}
+	return doc, hex.EncodeToString(h.Sum(nil)), nil
+}
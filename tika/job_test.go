@@ -0,0 +1,300 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// rmetaServer is a Tika server whose /rmeta/text endpoint returns the body of
+// the request as content, and fails for bodies starting with "fail".
+func rmetaServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.HasPrefix(string(body), "fail") {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		fmt.Fprintf(w, `[{"Content-Type": "text/plain", "resourceName": %q, "X-TIKA:content": %q}]`,
+			r.Header.Get("Content-Disposition"), body)
+	}))
+}
+
+// testJob returns a Job of the files, collecting the emitted Documents.
+func testJob(ts *httptest.Server, files map[string]string) (*Job, func() []Document) {
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	var mu sync.Mutex
+	var docs []Document
+	j := &Job{
+		Name:    "test",
+		Source:  NewFSSource(fsys),
+		Client:  NewClient(nil, ts.URL),
+		Workers: 2,
+		Emit: func(_ context.Context, d Document) error {
+			mu.Lock()
+			defer mu.Unlock()
+			docs = append(docs, d)
+			return nil
+		},
+	}
+	return j, func() []Document {
+		sort.Slice(docs, func(i, k int) bool { return docs[i].ID < docs[k].ID })
+		return docs
+	}
+}
+
+func TestJob(t *testing.T) {
+	ts := rmetaServer()
+	defer ts.Close()
+	j, docs := testJob(ts, map[string]string{"a.txt": "hello", "b/c.txt": "world", "d.txt": "fail"})
+	if s := j.Status(); s.State != JobIdle || s.Name != "test" {
+		t.Errorf("Status before Run = %+v, want an idle job named test", s)
+	}
+	if err := j.Run(context.Background()); err != nil {
+		t.Fatalf("Run got error: %v", err)
+	}
+	got := docs()
+	if len(got) != 2 || got[0].ID != "a.txt" || got[0].Content != "hello" || got[1].ID != "b/c.txt" {
+		t.Fatalf("Run emitted %+v, want a.txt and b/c.txt", got)
+	}
+	if got[0].ContentType != "text/plain" || got[0].Size != 5 || got[0].Metadata[XTIKAContent] != nil {
+		t.Errorf("Run emitted %+v, want its content type and size, and the content removed from the metadata", got[0])
+	}
+	if rn := firstValue(got[1].Metadata, "resourceName"); !strings.Contains(rn, "c.txt") {
+		t.Errorf("Run sent resource name %q, want c.txt", rn)
+	}
+
+	s := j.Status()
+	if s.State != JobSucceeded || s.Total != 3 || s.Pending != 0 || s.InFlight != 0 || s.Succeeded != 2 || s.Failed != 1 {
+		t.Errorf("Status after Run = %+v", s)
+	}
+	if len(s.RecentFailures) != 1 || s.RecentFailures[0].ID != "d.txt" {
+		t.Errorf("Status after Run has failures %+v, want d.txt", s.RecentFailures)
+	}
+	if s.Done() != 3 || s.Throughput <= 0 || s.Finished.IsZero() {
+		t.Errorf("Status after Run = %+v, want 3 done with a throughput", s)
+	}
+}
+
+func TestJobEmitError(t *testing.T) {
+	ts := rmetaServer()
+	defer ts.Close()
+	j, _ := testJob(ts, map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"})
+	j.Workers = 1
+	j.Emit = func(context.Context, Document) error { return errors.New("sink down") }
+	if err := j.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "sink down") {
+		t.Errorf("Run got error %v, want the Emit error", err)
+	}
+	if s := j.Status(); s.State != JobFailed || s.Err == "" || s.Succeeded != 0 {
+		t.Errorf("Status after a failed Run = %+v", s)
+	}
+}
+
+func TestJobDifferential(t *testing.T) {
+	var mu sync.Mutex
+	parsed := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		parsed[string(body)]++
+		mu.Unlock()
+		fmt.Fprintf(w, `[{"X-TIKA:content": %q}]`, body)
+	}))
+	defer ts.Close()
+
+	old := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"same.txt":    {Data: []byte("same"), ModTime: old},
+		"touched.txt": {Data: []byte("touched"), ModTime: old},
+		"edited.txt":  {Data: []byte("edited"), ModTime: old},
+		"deleted.txt": {Data: []byte("deleted"), ModTime: old},
+	}
+	ctx := context.Background()
+	store, err := OpenCheckpointStore(ctx, filepath.Join(tempDir(t), "checkpoints.json"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := &Job{Source: NewFSSource(fsys), Client: NewClient(nil, ts.URL), Checkpoints: store}
+	if err := j.Run(ctx); err != nil {
+		t.Fatalf("first Run got error: %v", err)
+	}
+	if s := j.Status(); s.Succeeded != 4 || s.Skipped != 0 {
+		t.Errorf("first Run status = %+v, want 4 extracted", s)
+	}
+
+	fsys["touched.txt"].ModTime = old.Add(time.Hour)
+	fsys["edited.txt"].Data = []byte("edited!")
+	delete(fsys, "deleted.txt")
+	fsys["new.txt"] = &fstest.MapFile{Data: []byte("new"), ModTime: old}
+	if err := j.Run(ctx); err != nil {
+		t.Fatalf("second Run got error: %v", err)
+	}
+	if s := j.Status(); s.Succeeded != 2 || s.Skipped != 2 || s.Done() != 4 {
+		t.Errorf("second Run status = %+v, want 2 extracted and 2 skipped", s)
+	}
+	want := map[string]int{"same": 1, "touched": 1, "edited": 1, "deleted": 1, "edited!": 1, "new": 1}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("parsed %v, want %v", parsed, want)
+	}
+	if c, ok := store.Get("touched.txt"); !ok || !c.ModTime.Equal(old.Add(time.Hour)) {
+		t.Errorf("checkpoint of touched.txt = %+v, want its new modification time", c)
+	}
+	if _, ok := store.Get("deleted.txt"); ok || store.Len() != 4 {
+		t.Errorf("store has %d checkpoints, want the one of deleted.txt removed", store.Len())
+	}
+
+	reopened, err := OpenCheckpointStore(ctx, store.path, nil)
+	if err != nil || reopened.Len() != 4 {
+		t.Errorf("reopened store has %d checkpoints, %v, want the saved 4", reopened.Len(), err)
+	}
+}
+
+func TestJobMetaOnly(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/meta" || r.Header.Get("Accept") != "application/json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"Content-Type": "text/plain", "Author": ["ann", "bob"], "size": "%d"}`, len(body))
+	}))
+	defer ts.Close()
+	j, docs := testJob(ts, map[string]string{"a.txt": "abc"})
+	j.Mode = MetaOnlyAll
+	if err := j.Run(context.Background()); err != nil {
+		t.Fatalf("Run got error: %v", err)
+	}
+	want := []Document{{
+		ID:          "a.txt",
+		ContentType: "text/plain",
+		Size:        3,
+		Metadata:    map[string][]string{"Content-Type": {"text/plain"}, "Author": {"ann", "bob"}, "size": {"3"}},
+	}}
+	if got := docs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Run emitted %+v, want %+v", got, want)
+	}
+}
+
+func TestJobDrift(t *testing.T) {
+	ts := rmetaServer()
+	defer ts.Close()
+	schemas := map[string]ExpectedSchema{"text/plain": {Required: []string{"Content-Type", "dc:title"}, Optional: []string{"resourceName"}}}
+	for _, strict := range []bool{false, true} {
+		var mu sync.Mutex
+		var drifted []string
+		j, docs := testJob(ts, map[string]string{"a.txt": "a", "b.txt": "b"})
+		j.Drift = &DriftChecker{Schemas: schemas, Strict: strict, OnDrift: func(id string, d *MetadataDrift) {
+			mu.Lock()
+			defer mu.Unlock()
+			drifted = append(drifted, id)
+		}}
+		if err := j.Run(context.Background()); err != nil {
+			t.Fatalf("Run(strict %v) got error: %v", strict, err)
+		}
+		s := j.Status()
+		if s.Drifted != 2 {
+			t.Errorf("Run(strict %v) status = %+v, want 2 drifted", strict, s)
+		}
+		if strict {
+			if len(docs()) != 0 || s.Failed != 2 || len(drifted) != 0 {
+				t.Errorf("strict Run emitted %d, failed %d and reported %q, want all failed", len(docs()), s.Failed, drifted)
+			}
+			continue
+		}
+		sort.Strings(drifted)
+		if len(docs()) != 2 || s.Failed != 0 || !reflect.DeepEqual(drifted, []string{"a.txt", "b.txt"}) {
+			t.Errorf("Run emitted %d, failed %d and reported %q, want all emitted and reported", len(docs()), s.Failed, drifted)
+		}
+	}
+}
+
+func TestJobReprocess(t *testing.T) {
+	var mu sync.Mutex
+	parsed := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		parsed[string(body)]++
+		mu.Unlock()
+		switch string(body) {
+		case "image":
+			fmt.Fprint(w, `[{"Content-Type": "image/png"}]`)
+		case "broken":
+			fmt.Fprint(w, `[{"Content-Type": "application/pdf", "X-TIKA:EXCEPTION:warn": "truncated"}]`)
+		default:
+			fmt.Fprint(w, `[{"Content-Type": "text/plain"}]`)
+		}
+	}))
+	defer ts.Close()
+	fsys := fstest.MapFS{
+		"a.png": {Data: []byte("image")},
+		"b.pdf": {Data: []byte("broken")},
+		"c.txt": {Data: []byte("text")},
+	}
+	ctx := context.Background()
+	store, err := OpenCheckpointStore(ctx, filepath.Join(tempDir(t), "checkpoints.json"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := OpenIdempotencyStore(filepath.Join(tempDir(t), "keys"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keys.Close()
+	j := &Job{Source: NewFSSource(fsys), Client: NewClient(nil, ts.URL), Checkpoints: store, Idempotency: keys, Emit: func(context.Context, Document) error { return nil }}
+	if err := j.Run(ctx); err != nil {
+		t.Fatalf("first Run got error: %v", err)
+	}
+	if c, _ := store.Get("b.pdf"); c.ErrorClass != "warn" || c.ContentType != "application/pdf" || c.Extracted.IsZero() {
+		t.Errorf("checkpoint of b.pdf = %+v, want its type, error class and extraction time", c)
+	}
+
+	if n := store.MarkForReprocessing(ReprocessFilter{ContentTypes: []string{"image/*"}}); n != 1 {
+		t.Errorf("MarkForReprocessing(image/*) = %d, want 1", n)
+	}
+	if n := store.MarkForReprocessing(ReprocessFilter{ErrorClasses: []string{"*"}}); n != 1 {
+		t.Errorf("MarkForReprocessing(*) = %d, want 1", n)
+	}
+	if err := j.Run(ctx); err != nil {
+		t.Fatalf("second Run got error: %v", err)
+	}
+	if s := j.Status(); s.Succeeded != 2 || s.Skipped != 1 {
+		t.Errorf("second Run status = %+v, want 2 extracted and 1 skipped", s)
+	}
+	if want := map[string]int{"image": 2, "broken": 2, "text": 1}; !reflect.DeepEqual(parsed, want) {
+		t.Errorf("parsed %v, want %v", parsed, want)
+	}
+	if c, _ := store.Get("a.png"); c.Reprocess {
+		t.Errorf("checkpoint of a.png is still marked after reprocessing")
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+)
+
+// inputType returns the MIME type of in, guessed from its name. Unknown types
+// are application/octet-stream.
+func inputType(in Input) string {
+	if t := typeByExtension(path.Ext(in.Name)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// typeLimiter limits the number of inputs processed concurrently per MIME
+// type.
+type typeLimiter struct {
+	patterns []string
+	sems     map[string]chan struct{}
+}
+
+func newTypeLimiter(limits map[string]int) *typeLimiter {
+	l := &typeLimiter{sems: map[string]chan struct{}{}}
+	for p, n := range limits {
+		if n > 0 {
+			l.patterns = append(l.patterns, p)
+			l.sems[p] = make(chan struct{}, n)
+		}
+	}
+	sortBySpecificity(l.patterns)
+	return l
+}
+
+// sortBySpecificity sorts MIME type patterns from the most to the least
+// specific, so the first pattern matching a type is the most specific.
+func sortBySpecificity(patterns []string) {
+	sort.Slice(patterns, func(i, k int) bool {
+		pi, pk := patterns[i], patterns[k]
+		if ni, nk := strings.Count(pi, "*"), strings.Count(pk, "*"); ni != nk {
+			return ni < nk
+		}
+		return pi < pk
+	})
+}
+
+// sem returns the semaphore limiting the MIME type typ, or nil.
+func (l *typeLimiter) sem(typ string) chan struct{} {
+	if sem, ok := l.sems[typ]; ok {
+		return sem
+	}
+	for _, p := range l.patterns {
+		if ok, _ := path.Match(p, typ); ok {
+			return l.sems[p]
+		}
+	}
+	return nil
+}
+
+// acquire waits for a slot for the MIME type typ, and returns the function
+// releasing it.
+func (l *typeLimiter) acquire(ctx context.Context, typ string) (func(), error) {
+	sem := l.sem(typ)
+	if sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInputType(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a.png", "image/png"},
+		{"b.TXT", "text/plain"},
+		{"c.html", "text/html"},
+		{"f.docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{"g.heic", "image/heic"},
+		{"d", "application/octet-stream"},
+		{"e.unknown-ext", "application/octet-stream"},
+	}
+	for _, test := range tests {
+		if got := inputType(Input{Name: test.name}); got != test.want {
+			t.Errorf("inputType(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestTypeLimiter(t *testing.T) {
+	l := newTypeLimiter(map[string]int{"image/*": 2, "image/png": 1, "*/*": 8, "text/plain": 0})
+	tests := []struct {
+		typ  string
+		want int
+	}{
+		{"image/png", 1},
+		{"image/jpeg", 2},
+		{"application/pdf", 8},
+		{"text/plain", 8},
+	}
+	for _, test := range tests {
+		if got := cap(l.sem(test.typ)); got != test.want {
+			t.Errorf("limit of %q = %d, want %d", test.typ, got, test.want)
+		}
+	}
+	if sem := newTypeLimiter(nil).sem("image/png"); sem != nil {
+		t.Errorf("limit without limits = %d, want none", cap(sem))
+	}
+}
+
+func TestJobTypeLimits(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := map[bool]int{}, map[bool]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		image := strings.Contains(r.Header.Get("Content-Disposition"), ".png")
+		mu.Lock()
+		inFlight[image]++
+		if inFlight[image] > maxInFlight[image] {
+			maxInFlight[image] = inFlight[image]
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight[image]--
+		mu.Unlock()
+		w.Write([]byte(`[{"Content-Type": "text/plain"}]`))
+	}))
+	defer ts.Close()
+
+	files := map[string]string{}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		files[name+".png"] = name
+		files[name+".txt"] = name
+	}
+	j, docs := testJob(ts, files)
+	j.Workers = 6
+	j.TypeLimits = map[string]int{"image/*": 1}
+	if err := j.Run(context.Background()); err != nil {
+		t.Fatalf("Run got error: %v", err)
+	}
+	if got := len(docs()); got != len(files) {
+		t.Errorf("Run emitted %d documents, want %d", got, len(files))
+	}
+	if maxInFlight[true] != 1 {
+		t.Errorf("Run extracted up to %d images concurrently, want 1", maxInFlight[true])
+	}
+	if maxInFlight[false] < 2 {
+		t.Errorf("Run extracted up to %d text files concurrently, want more than 1", maxInFlight[false])
+	}
+}
+
+func TestJobTypeLimitsCanceled(t *testing.T) {
+	started := make(chan struct{}, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	j, _ := testJob(ts, map[string]string{"a.png": "a", "b.png": "b"})
+	j.Workers = 2
+	j.TypeLimits = map[string]int{"image/*": 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		// Let the second input wait for the slot of the first.
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if err := j.Run(ctx); err == nil {
+		t.Fatalf("Run with a canceled Context got no error")
+	}
+	s := j.Status()
+	if s.Pending != 0 || s.InFlight != 0 || s.Failed != 2 || s.Canceled != 2 {
+		t.Errorf("Run left status %+v, want 2 canceled inputs and none pending", s)
+	}
+}
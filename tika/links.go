@@ -0,0 +1,91 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// A Link is a hyperlink of a document, such as a link of a web page, a Word
+// document or a PDF annotation.
+type Link struct {
+	// Href is the target of the Link, as written in the document.
+	Href string `json:"href"`
+	// Text is the anchor text of the Link, with its whitespace collapsed.
+	Text string `json:"text,omitempty"`
+	// Page is the page of the Link, starting at 1, or 0 if the document has
+	// no pages.
+	Page int `json:"page,omitempty"`
+	// Offset is the byte offset of the Link in the text of the document
+	// rendered from its XHTML, as Outline.Text.
+	Offset int `json:"offset"`
+}
+
+// ParseLinks returns the Links of the XHTML of a document, as returned by
+// Client.ParseHTML, in order.
+func ParseLinks(xhtml io.Reader) ([]Link, error) {
+	links, _, err := parseLinks(xhtml)
+	return links, err
+}
+
+// parseLinks returns the Links of the XHTML of a document, and its text
+// rendered by xhtmlWalker, which their offsets refer to.
+func parseLinks(xhtml io.Reader) ([]Link, string, error) {
+	var w xhtmlWalker
+	var links []Link
+	var anchor *strings.Builder
+	w.start = func(name string, t xml.StartElement) {
+		if name != "a" || anchor != nil {
+			return
+		}
+		for _, a := range t.Attr {
+			if a.Name.Local == "href" && a.Value != "" {
+				links = append(links, Link{Href: a.Value, Page: w.page, Offset: w.text.Len()})
+				anchor = &strings.Builder{}
+				return
+			}
+		}
+	}
+	w.end = func(name string) {
+		if name == "a" && anchor != nil {
+			links[len(links)-1].Text = strings.Join(strings.Fields(anchor.String()), " ")
+			anchor = nil
+		}
+	}
+	w.chars = func(data []byte) {
+		if anchor != nil {
+			anchor.Write(data)
+		}
+	}
+	if err := w.walk(xhtml); err != nil {
+		return nil, "", err
+	}
+	return links, w.text.String(), nil
+}
+
+// Links parses the given input and returns its Links, from its XHTML.
+func (c *Client) Links(ctx context.Context, input io.Reader, opts ...RequestOption) ([]Link, error) {
+	body, err := c.ParseReader(ctx, input, append(opts[:len(opts):len(opts)], WithFormat(FormatXML))...)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ParseLinks(body)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// linksXHTML is the XHTML of a PDF document with links, as returned by Tika.
+const linksXHTML = `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>Links</title>
+<link rel="stylesheet" href="style.css"/></head><body>
+<div class="page"><p>See <a href="https://example.com/spec">the
+  specification</a> and <a name="anchor">no link</a>.</p></div>
+<div class="page"><p>Mail <a href="mailto:a@example.com"><b>us</b></a>, or read <a href="#part2"></a>.</p></div>
+</body></html>`
+
+func TestParseLinks(t *testing.T) {
+	links, text, err := parseLinks(strings.NewReader(linksXHTML))
+	if err != nil {
+		t.Fatalf("parseLinks got error: %v", err)
+	}
+	want := []Link{
+		{Href: "https://example.com/spec", Text: "the specification", Page: 1},
+		{Href: "mailto:a@example.com", Text: "us", Page: 2},
+		{Href: "#part2", Page: 2},
+	}
+	for i := range links {
+		if i < len(want) {
+			want[i].Offset = links[i].Offset
+		}
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Fatalf("parseLinks =\n%+v\nwant\n%+v", links, want)
+	}
+	for _, l := range links[:2] {
+		if !strings.HasPrefix(text[l.Offset:], strings.Fields(l.Text)[0]) {
+			t.Errorf("link %q at offset %d is at %q in the text", l.Text, l.Offset, text[l.Offset:])
+		}
+	}
+	if o, _ := ParseOutline(strings.NewReader(linksXHTML)); o.Text != text {
+		t.Errorf("parseLinks text %q differs from the Outline text %q", text, o.Text)
+	}
+	if _, err := ParseLinks(strings.NewReader("<html><body><a href='x'>")); err == nil {
+		t.Errorf("ParseLinks of truncated XHTML got no error")
+	}
+}
+
+func TestClientLinks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, linksXHTML)
+	}))
+	defer ts.Close()
+	links, err := NewClient(nil, ts.URL).Links(context.Background(), strings.NewReader("doc"))
+	if err != nil || len(links) != 3 || links[0].Href != "https://example.com/spec" {
+		t.Errorf("Links = %+v, %v, want the 3 links", links, err)
+	}
+}
+
+func TestJobLinks(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		content := "plain text"
+		if r.URL.Path == "/rmeta/xml" {
+			content = linksXHTML
+		}
+		json.NewEncoder(w).Encode([]map[string]string{{"Content-Type": "application/pdf", XTIKAContent: content}})
+	}))
+	defer ts.Close()
+	j, docs := testJob(ts, map[string]string{"a.pdf": "pdf"})
+	j.Workers = 1
+	j.Links = true
+	if err := j.Run(context.Background()); err != nil {
+		t.Fatalf("Run got error: %v", err)
+	}
+	got := docs()
+	if len(got) != 1 || len(got[0].Links) != 3 || !reflect.DeepEqual(paths, []string{"/rmeta/xml"}) {
+		t.Fatalf("Run with Links called %q and emitted %+v", paths, got)
+	}
+	doc := got[0]
+	if strings.Contains(doc.Content, "<") || !strings.HasPrefix(doc.Content[doc.Links[0].Offset:], "the") {
+		t.Errorf("Run with Links emitted content %q, want text indexed by the links", doc.Content)
+	}
+
+	paths = nil
+	j.Links = false
+	if err := j.Run(context.Background()); err != nil {
+		t.Fatalf("Run got error: %v", err)
+	}
+	if got := docs(); len(got) != 2 || got[1].Links != nil || got[1].Content != "plain text" || paths[0] != "/rmeta/text" {
+		t.Errorf("Run without Links called %q and emitted %+v", paths, got)
+	}
+}
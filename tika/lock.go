@@ -0,0 +1,144 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLocked is returned by Locker.TryLock when another holder has the lock.
+var ErrLocked = errors.New("lock is held by another holder")
+
+// ErrLockLost is returned when a Lock expired or was taken over before it was
+// refreshed.
+var ErrLockLost = errors.New("lock was lost")
+
+// A Locker acquires distributed locks, so that only one instance of a fleet
+// runs a job. See LockedRunner, and the locker package for Lockers backed by
+// Redis, Consul and etcd.
+type Locker interface {
+	// TryLock acquires the lock with the given name for ttl, or returns
+	// ErrLocked without waiting if another holder has it.
+	TryLock(ctx context.Context, name string, ttl time.Duration) (Lock, error)
+}
+
+// A Lock is a lock acquired from a Locker. It expires unless refreshed, so a
+// crashed holder does not keep it forever.
+type Lock interface {
+	// Refresh extends the lock by the ttl it was acquired with, or returns
+	// ErrLockLost if it expired.
+	Refresh(ctx context.Context) error
+	// Unlock releases the lock.
+	Unlock(ctx context.Context) error
+}
+
+// DefaultLockTTL is the ttl of the lock of a LockedRunner with no TTL.
+const DefaultLockTTL = 30 * time.Second
+
+// A LockedRunner is a Runner which only runs Runner while it holds the lock
+// Name of Locker, so that when every instance of a fleet schedules the same
+// job, for example with a Cron, one instance runs it while the others stand
+// by. The lock is refreshed every third of TTL while Runner runs, and the run
+// is canceled if the lock is lost, or could not be refreshed for TTL, for
+// example because the instance was cut off from the lock service.
+type LockedRunner struct {
+	Runner Runner
+	Locker Locker
+	Name   string
+	// TTL is how long the lock outlives a crashed instance, at least 1ms.
+	// DefaultLockTTL is used if it is 0.
+	TTL time.Duration
+	// OnStandby, if not nil, is called when the run is skipped because
+	// another instance holds the lock.
+	OnStandby func(name string)
+}
+
+// Run implements Runner. It returns nil without running Runner if another
+// instance holds the lock, and ErrLockLost if the lock was lost during the
+// run.
+func (r *LockedRunner) Run(ctx context.Context) error {
+	ttl := r.TTL
+	if ttl == 0 {
+		ttl = DefaultLockTTL
+	}
+	if ttl < time.Millisecond {
+		return fmt.Errorf("lock %q: ttl %v is under 1ms", r.Name, ttl)
+	}
+	lock, err := r.Locker.TryLock(ctx, r.Name, ttl)
+	if errors.Is(err, ErrLocked) {
+		if r.OnStandby != nil {
+			r.OnStandby(r.Name)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error locking %q: %w", r.Name, err)
+	}
+	defer func() {
+		// Release the lock even if ctx is done.
+		ctx, cancel := context.WithTimeout(context.Background(), ttl)
+		defer cancel()
+		lock.Unlock(ctx)
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	lost := make(chan error, 1)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(ttl / 3)
+		defer t.Stop()
+		refreshed := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			// Bound the refresh by the expiry of the lock, so a lock
+			// service cut off cannot block it past the TTL.
+			rctx, rcancel := context.WithTimeout(ctx, ttl-time.Since(refreshed))
+			err := lock.Refresh(rctx)
+			rcancel()
+			if err == nil {
+				refreshed = time.Now()
+				continue
+			}
+			// Retry other errors, such as network errors, until the
+			// lock expires.
+			if ctx.Err() == nil && (errors.Is(err, ErrLockLost) || time.Since(refreshed) >= ttl) {
+				lost <- err
+				cancel()
+				return
+			}
+		}
+	}()
+	err = r.Runner.Run(ctx)
+	// Stop refreshing.
+	cancel()
+	<-stopped
+	select {
+	case lerr := <-lost:
+		return fmt.Errorf("error refreshing lock %q: %w", r.Name, lerr)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memLocker is an in-memory Locker.
+type memLocker struct {
+	mu         sync.Mutex
+	held       map[string]bool
+	refreshErr error
+	refreshes  int
+	// hang makes Refresh block until its Context is done.
+	hang bool
+}
+
+func (l *memLocker) TryLock(ctx context.Context, name string, ttl time.Duration) (Lock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[name] {
+		return nil, ErrLocked
+	}
+	if l.held == nil {
+		l.held = make(map[string]bool)
+	}
+	l.held[name] = true
+	return &memLock{l: l, name: name}, nil
+}
+
+type memLock struct {
+	l    *memLocker
+	name string
+}
+
+func (lock *memLock) Refresh(ctx context.Context) error {
+	lock.l.mu.Lock()
+	lock.l.refreshes++
+	hang, err := lock.l.hang, lock.l.refreshErr
+	lock.l.mu.Unlock()
+	if hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return err
+}
+
+func (lock *memLock) Unlock(context.Context) error {
+	lock.l.mu.Lock()
+	defer lock.l.mu.Unlock()
+	delete(lock.l.held, lock.name)
+	return nil
+}
+
+type runnerFunc func(context.Context) error
+
+func (f runnerFunc) Run(ctx context.Context) error { return f(ctx) }
+
+func TestLockedRunner(t *testing.T) {
+	l := &memLocker{}
+	ran := 0
+	r := &LockedRunner{
+		Locker: l,
+		Name:   "crawl",
+		TTL:    30 * time.Millisecond,
+		Runner: runnerFunc(func(ctx context.Context) error {
+			ran++
+			time.Sleep(50 * time.Millisecond)
+			return ctx.Err()
+		}),
+	}
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("Run got error: %v", err)
+	}
+	if ran != 1 || l.refreshes == 0 || l.held["crawl"] {
+		t.Errorf("Run ran %d times, refreshed %d times, left the lock held: %v; want 1, > 0, false", ran, l.refreshes, l.held["crawl"])
+	}
+
+	if err := (&LockedRunner{Locker: l, Name: "crawl", TTL: time.Microsecond, Runner: r.Runner}).Run(context.Background()); err == nil {
+		t.Errorf("Run with a ttl under 1ms got no error")
+	}
+
+	// Another instance holds the lock.
+	l.held = map[string]bool{"crawl": true}
+	var standby string
+	r.OnStandby = func(name string) { standby = name }
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("Run on standby got error: %v", err)
+	}
+	if ran != 1 || standby != "crawl" {
+		t.Errorf("Run on standby ran %d times and called OnStandby with %q, want 1 and crawl", ran, standby)
+	}
+
+	// The lock is lost during the run.
+	l.held = nil
+	l.refreshErr = ErrLockLost
+	if err := r.Run(context.Background()); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Run losing the lock got error %v, want %v", err, ErrLockLost)
+	}
+
+	// Refreshes failing for another reason are retried for the TTL.
+	l.refreshErr = errors.New("unreachable")
+	r.TTL = time.Second
+	if err := r.Run(context.Background()); err != nil {
+		t.Errorf("Run with a failed refresh got error: %v", err)
+	}
+	r.TTL = 15 * time.Millisecond
+	if err := r.Run(context.Background()); err == nil || err.Error() != `error refreshing lock "crawl": unreachable` {
+		t.Errorf("Run failing to refresh for the TTL got error %v", err)
+	}
+
+	// A refresh blocked by a lock service cut off is given up at the TTL.
+	l.refreshErr = nil
+	l.hang = true
+	r.TTL = 30 * time.Millisecond
+	r.Runner = runnerFunc(func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	})
+	start := time.Now()
+	if err := r.Run(context.Background()); !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Errorf("Run with a hanging refresh got error %v after %v, want a deadline at the TTL", err, time.Since(start))
+	}
+}
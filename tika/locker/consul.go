@@ -0,0 +1,112 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package locker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-tika/tika"
+)
+
+// Consul is a tika.Locker storing locks in the key/value store of Consul,
+// acquired with sessions which are deleted, with their locks, when they are
+// not renewed within their ttl. Consul does not accept ttls shorter than 10
+// seconds: shorter ones are rounded up.
+type Consul struct {
+	// Address is the URL of the Consul agent, such as
+	// http://127.0.0.1:8500.
+	Address string
+	// Token, if not empty, is the ACL token of the requests.
+	Token string
+	// Prefix is prepended to the names of the locks to make their keys.
+	// "tika/lock/" is used if it is empty.
+	Prefix string
+	// HTTPClient is the client of the requests. If nil, the
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+const minConsulTTL = 10 * time.Second
+
+// TryLock implements tika.Locker.
+func (l *Consul) TryLock(ctx context.Context, name string, ttl time.Duration) (tika.Lock, error) {
+	if ttl < minConsulTTL {
+		ttl = minConsulTTL
+	}
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	var session struct{ ID string }
+	if _, err := l.call(ctx, "/v1/session/create", map[string]string{
+		"Name":      "tika-lock-" + name,
+		"TTL":       fmt.Sprintf("%ds", int64((ttl+time.Second-1)/time.Second)),
+		"Behavior":  "delete",
+		"LockDelay": "0s",
+	}, &session); err != nil {
+		return nil, err
+	}
+	prefix := l.Prefix
+	if prefix == "" {
+		prefix = "tika/lock/"
+	}
+	lock := &consulLock{l: l, key: prefix + url.PathEscape(name), session: session.ID}
+	var acquired bool
+	_, err = l.call(ctx, "/v1/kv/"+lock.key+"?acquire="+url.QueryEscape(session.ID), token, &acquired)
+	if err == nil && !acquired {
+		err = tika.ErrLocked
+	}
+	if err != nil {
+		l.call(ctx, "/v1/session/destroy/"+url.PathEscape(session.ID), nil, nil)
+		return nil, err
+	}
+	return lock, nil
+}
+
+// call sends a PUT request to path of the Consul agent.
+func (l *Consul) call(ctx context.Context, path string, in, out interface{}) (int, error) {
+	header := make(http.Header)
+	if l.Token != "" {
+		header.Set("X-Consul-Token", l.Token)
+	}
+	return callJSON(ctx, l.HTTPClient, "PUT", strings.TrimSuffix(l.Address, "/")+path, header, in, out)
+}
+
+type consulLock struct {
+	l            *Consul
+	key, session string
+}
+
+// Refresh implements tika.Lock.
+func (lock *consulLock) Refresh(ctx context.Context) error {
+	code, err := lock.l.call(ctx, "/v1/session/renew/"+url.PathEscape(lock.session), nil, nil)
+	if code == http.StatusNotFound {
+		return tika.ErrLockLost
+	}
+	return err
+}
+
+// Unlock implements tika.Lock. Destroying the session deletes the lock.
+func (lock *consulLock) Unlock(ctx context.Context) error {
+	_, err := lock.l.call(ctx, "/v1/session/destroy/"+url.PathEscape(lock.session), nil, nil)
+	return err
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package locker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// consulServer is a fake Consul agent implementing the endpoints of
+// Consul.
+type consulServer struct {
+	mu       sync.Mutex
+	sessions map[string]string // sessions maps session IDs to their TTL.
+	kv       map[string]string // kv maps keys to the session holding them.
+	next     int
+}
+
+func (s *consulServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method != "PUT" || r.Header.Get("X-Consul-Token") != "token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch p := r.URL.Path; {
+	case p == "/v1/session/create":
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["Behavior"] != "delete" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.next++
+		id := fmt.Sprintf("session-%d", s.next)
+		s.sessions[id] = body["TTL"]
+		fmt.Fprintf(w, `{"ID": %q}`, id)
+	case strings.HasPrefix(p, "/v1/session/renew/"):
+		if _, ok := s.sessions[strings.TrimPrefix(p, "/v1/session/renew/")]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "session not found")
+			return
+		}
+		fmt.Fprint(w, `[{}]`)
+	case strings.HasPrefix(p, "/v1/session/destroy/"):
+		s.destroy(strings.TrimPrefix(p, "/v1/session/destroy/"))
+		fmt.Fprint(w, "true")
+	case strings.HasPrefix(p, "/v1/kv/"):
+		key, session := strings.TrimPrefix(p, "/v1/kv/"), r.URL.Query().Get("acquire")
+		value, _ := ioutil.ReadAll(r.Body)
+		if _, ok := s.sessions[session]; !ok || len(value) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if _, held := s.kv[key]; held {
+			fmt.Fprint(w, "false")
+			return
+		}
+		s.kv[key] = session
+		fmt.Fprint(w, "true")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// destroy destroys a session, deleting its keys. s.mu must be held.
+func (s *consulServer) destroy(id string) {
+	delete(s.sessions, id)
+	for k, v := range s.kv {
+		if v == id {
+			delete(s.kv, k)
+		}
+	}
+}
+
+func (s *consulServer) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.sessions {
+		s.destroy(id)
+	}
+}
+
+func TestConsul(t *testing.T) {
+	s := &consulServer{sessions: map[string]string{}, kv: map[string]string{}}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	testLocker(t, &Consul{Address: ts.URL + "/", Token: "token"}, s.expire)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.kv["tika/lock/crawl"]; !ok {
+		t.Errorf("Consul has keys %v, want tika/lock/crawl", s.kv)
+	}
+	for id, ttl := range s.sessions {
+		if ttl != "60s" {
+			t.Errorf("session %s has TTL %s, want 60s", id, ttl)
+		}
+	}
+	// Sessions of failed attempts are destroyed.
+	if len(s.sessions) != 1 {
+		t.Errorf("Consul has %d sessions, want 1", len(s.sessions))
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package locker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-tika/tika"
+)
+
+// Etcd is a tika.Locker storing locks in etcd, as keys attached to leases
+// which expire, deleting their keys, when they are not kept alive within their
+// ttl. It uses the JSON gateway of the etcd v3 API.
+type Etcd struct {
+	// Endpoint is the URL of an etcd server, such as
+	// http://127.0.0.1:2379.
+	Endpoint string
+	// Token, if not empty, is the authentication token of the requests, as
+	// returned by /v3/auth/authenticate.
+	Token string
+	// Prefix is prepended to the names of the locks to make their keys.
+	// "tika/lock/" is used if it is empty.
+	Prefix string
+	// HTTPClient is the client of the requests. If nil, the
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// TryLock implements tika.Locker.
+func (l *Etcd) TryLock(ctx context.Context, name string, ttl time.Duration) (tika.Lock, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	secs := int64((ttl + time.Second - 1) / time.Second)
+	var lease struct{ ID json.Number }
+	if _, err := l.call(ctx, "/v3/lease/grant", map[string]interface{}{"TTL": secs}, &lease); err != nil {
+		return nil, err
+	}
+	lock := &etcdLock{l: l, lease: lease.ID}
+	prefix := l.Prefix
+	if prefix == "" {
+		prefix = "tika/lock/"
+	}
+	key := base64.StdEncoding.EncodeToString([]byte(prefix + name))
+	// Put the key only if it does not exist.
+	var txn struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	_, err = l.call(ctx, "/v3/kv/txn", map[string]interface{}{
+		"compare": []map[string]interface{}{{
+			"key":             key,
+			"result":          "EQUAL",
+			"target":          "CREATE",
+			"create_revision": "0",
+		}},
+		"success": []map[string]interface{}{{
+			"request_put": map[string]interface{}{
+				"key":   key,
+				"value": base64.StdEncoding.EncodeToString([]byte(token)),
+				"lease": lease.ID,
+			},
+		}},
+	}, &txn)
+	if err == nil && !txn.Succeeded {
+		err = tika.ErrLocked
+	}
+	if err != nil {
+		lock.Unlock(ctx)
+		return nil, err
+	}
+	return lock, nil
+}
+
+// call sends a POST request to path of the etcd server.
+func (l *Etcd) call(ctx context.Context, path string, in, out interface{}) (int, error) {
+	header := make(http.Header)
+	if l.Token != "" {
+		header.Set("Authorization", l.Token)
+	}
+	return callJSON(ctx, l.HTTPClient, "POST", strings.TrimSuffix(l.Endpoint, "/")+path, header, in, out)
+}
+
+type etcdLock struct {
+	l     *Etcd
+	lease json.Number
+}
+
+// Refresh implements tika.Lock.
+func (lock *etcdLock) Refresh(ctx context.Context) error {
+	var resp struct {
+		Result struct {
+			TTL json.Number
+		} `json:"result"`
+	}
+	if _, err := lock.l.call(ctx, "/v3/lease/keepalive", map[string]interface{}{"ID": lock.lease}, &resp); err != nil {
+		return err
+	}
+	// An expired lease is kept alive with a TTL of 0, or none.
+	if ttl, err := resp.Result.TTL.Int64(); err != nil || ttl <= 0 {
+		return tika.ErrLockLost
+	}
+	return nil
+}
+
+// Unlock implements tika.Lock. Revoking the lease deletes the lock.
+func (lock *etcdLock) Unlock(ctx context.Context) error {
+	_, err := lock.l.call(ctx, "/v3/lease/revoke", map[string]interface{}{"ID": lock.lease}, nil)
+	return err
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package locker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// etcdServer is a fake etcd JSON gateway implementing the endpoints of
+// Etcd. Like the gateway, it encodes int64 values as strings.
+type etcdServer struct {
+	mu     sync.Mutex
+	leases map[string]int64  // leases maps lease IDs to their TTL.
+	kv     map[string]string // kv maps keys to the lease they are attached to.
+	next   int
+}
+
+func (s *etcdServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method != "POST" || r.Header.Get("Authorization") != "token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var body struct {
+		TTL     int64
+		ID      json.Number
+		Compare []struct {
+			Key            string `json:"key"`
+			Target         string `json:"target"`
+			CreateRevision string `json:"create_revision"`
+		} `json:"compare"`
+		Success []struct {
+			RequestPut struct {
+				Key   string      `json:"key"`
+				Value string      `json:"value"`
+				Lease json.Number `json:"lease"`
+			} `json:"request_put"`
+		} `json:"success"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		s.next++
+		id := fmt.Sprint(7000 + s.next)
+		s.leases[id] = body.TTL
+		fmt.Fprintf(w, `{"ID": %q, "TTL": "%d"}`, id, body.TTL)
+	case "/v3/lease/keepalive":
+		ttl, ok := s.leases[body.ID.String()]
+		if !ok {
+			// The gateway responds to expired leases without a TTL.
+			fmt.Fprintf(w, `{"result": {"ID": %q}}`, body.ID)
+			return
+		}
+		fmt.Fprintf(w, `{"result": {"ID": %q, "TTL": "%d"}}`, body.ID, ttl)
+	case "/v3/lease/revoke":
+		s.revoke(body.ID.String())
+		fmt.Fprint(w, `{}`)
+	case "/v3/kv/txn":
+		if len(body.Compare) != 1 || body.Compare[0].Target != "CREATE" || body.Compare[0].CreateRevision != "0" || len(body.Success) != 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		key, _ := base64.StdEncoding.DecodeString(body.Compare[0].Key)
+		put := body.Success[0].RequestPut
+		if _, ok := s.leases[put.Lease.String()]; !ok || put.Key != body.Compare[0].Key || put.Value == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if _, exists := s.kv[string(key)]; exists {
+			fmt.Fprint(w, `{"header": {}}`)
+			return
+		}
+		s.kv[string(key)] = put.Lease.String()
+		fmt.Fprint(w, `{"header": {}, "succeeded": true}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// revoke revokes a lease, deleting its keys. s.mu must be held.
+func (s *etcdServer) revoke(id string) {
+	delete(s.leases, id)
+	for k, v := range s.kv {
+		if v == id {
+			delete(s.kv, k)
+		}
+	}
+}
+
+func (s *etcdServer) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.leases {
+		s.revoke(id)
+	}
+}
+
+func TestEtcd(t *testing.T) {
+	s := &etcdServer{leases: map[string]int64{}, kv: map[string]string{}}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	testLocker(t, &Etcd{Endpoint: ts.URL, Token: "token"}, s.expire)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.kv["tika/lock/crawl"]; !ok {
+		t.Errorf("etcd has keys %v, want tika/lock/crawl", s.kv)
+	}
+	// Leases of failed attempts are revoked.
+	if len(s.leases) != 1 {
+		t.Errorf("etcd has %d leases, want 1", len(s.leases))
+	}
+	for id, ttl := range s.leases {
+		if ttl != 60 {
+			t.Errorf("lease %s has TTL %d, want 60", id, ttl)
+		}
+	}
+}
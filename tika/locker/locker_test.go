@@ -0,0 +1,64 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package locker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-tika/tika"
+)
+
+// testLocker checks that l acquires, refreshes and releases locks. expire
+// makes every lock of the server of l expire.
+func testLocker(t *testing.T, l tika.Locker, expire func()) {
+	t.Helper()
+	ctx := context.Background()
+	lock, err := l.TryLock(ctx, "crawl", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock got error: %v", err)
+	}
+	if _, err := l.TryLock(ctx, "crawl", time.Minute); !errors.Is(err, tika.ErrLocked) {
+		t.Errorf("TryLock of a held lock got error %v, want %v", err, tika.ErrLocked)
+	}
+	other, err := l.TryLock(ctx, "other", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock of another lock got error: %v", err)
+	}
+	if err := lock.Refresh(ctx); err != nil {
+		t.Errorf("Refresh got error: %v", err)
+	}
+	if err := lock.Unlock(ctx); err != nil {
+		t.Errorf("Unlock got error: %v", err)
+	}
+	lock, err = l.TryLock(ctx, "crawl", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock of an unlocked lock got error: %v", err)
+	}
+	expire()
+	if err := lock.Refresh(ctx); !errors.Is(err, tika.ErrLockLost) {
+		t.Errorf("Refresh of an expired lock got error %v, want %v", err, tika.ErrLockLost)
+	}
+	if err := other.Refresh(ctx); !errors.Is(err, tika.ErrLockLost) {
+		t.Errorf("Refresh of an expired lock got error %v, want %v", err, tika.ErrLockLost)
+	}
+	if _, err := l.TryLock(ctx, "crawl", time.Minute); err != nil {
+		t.Errorf("TryLock of an expired lock got error: %v", err)
+	}
+}
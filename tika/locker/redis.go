@@ -0,0 +1,172 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package locker
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/google/go-tika/tika"
+)
+
+// Redis is a tika.Locker storing locks in Redis, as keys set with a random
+// token which expire after their ttl. It speaks the Redis protocol itself,
+// with a connection per operation.
+type Redis struct {
+	// Addr is the host:port of the Redis server.
+	Addr string
+	// Password, if not empty, authenticates the connections.
+	Password string
+	// Prefix is prepended to the names of the locks to make their keys.
+	// "tika:lock:" is used if it is empty.
+	Prefix string
+	// Dial, if not nil, dials the Redis server, for example with TLS.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// The scripts refreshing and deleting a lock only if it still has the token
+// of its holder.
+const (
+	redisRefreshScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+	redisUnlockScript  = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+)
+
+// TryLock implements tika.Locker. Redis expires keys with a precision of a
+// millisecond, so ttl must be at least 1ms.
+func (l *Redis) TryLock(ctx context.Context, name string, ttl time.Duration) (tika.Lock, error) {
+	if ttl < time.Millisecond {
+		return nil, fmt.Errorf("lock ttl %v is under 1ms", ttl)
+	}
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	prefix := l.Prefix
+	if prefix == "" {
+		prefix = "tika:lock:"
+	}
+	lock := &redisLock{l: l, key: prefix + name, token: token, ttl: ms(ttl)}
+	reply, err := l.do(ctx, "SET", lock.key, token, "NX", "PX", lock.ttl)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, tika.ErrLocked
+	}
+	return lock, nil
+}
+
+// ms returns d in milliseconds, rounded up, as a string.
+func ms(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Millisecond-1)/time.Millisecond), 10)
+}
+
+type redisLock struct {
+	l          *Redis
+	key, token string
+	ttl        string
+}
+
+// Refresh implements tika.Lock.
+func (lock *redisLock) Refresh(ctx context.Context) error {
+	reply, err := lock.l.do(ctx, "EVAL", redisRefreshScript, "1", lock.key, lock.token, lock.ttl)
+	if err != nil {
+		return err
+	}
+	if reply != int64(1) {
+		return tika.ErrLockLost
+	}
+	return nil
+}
+
+// Unlock implements tika.Lock.
+func (lock *redisLock) Unlock(ctx context.Context) error {
+	_, err := lock.l.do(ctx, "EVAL", redisUnlockScript, "1", lock.key, lock.token)
+	return err
+}
+
+// do sends a command to Redis and returns its reply: a string, an int64, or
+// nil.
+func (l *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	dial := l.Dial
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	conn, err := dial(ctx, "tcp", l.Addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	r := bufio.NewReader(conn)
+	if l.Password != "" {
+		if _, err := redisCommand(conn, r, "AUTH", l.Password); err != nil {
+			return nil, err
+		}
+	}
+	return redisCommand(conn, r, args...)
+}
+
+// redisCommand writes a command to w and reads its reply from r.
+func redisCommand(w io.Writer, r *bufio.Reader, args ...string) (interface{}, error) {
+	cmd := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		cmd = append(cmd, "$"+strconv.Itoa(len(a))+"\r\n"+a+"\r\n"...)
+	}
+	if _, err := w.Write(cmd); err != nil {
+		return nil, err
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("invalid Redis reply %q", line)
+	}
+	v := line[1 : len(line)-2]
+	switch line[0] {
+	case '+':
+		return v, nil
+	case '-':
+		return nil, errors.New("redis: " + v)
+	case ':':
+		return strconv.ParseInt(v, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Redis reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return string(b[:n]), nil
+	}
+	return nil, fmt.Errorf("unsupported Redis reply %q", line)
+}
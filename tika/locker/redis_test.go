@@ -0,0 +1,183 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package locker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// redisServer is a fake Redis server implementing the commands of
+// Redis.
+type redisServer struct {
+	ln       net.Listener
+	password string
+	mu       sync.Mutex
+	keys     map[string]string
+}
+
+func newRedisServer(t *testing.T, password string) *redisServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &redisServer{ln: ln, password: password, keys: map[string]string{}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		var l int
+		if _, err := fmt.Fscanf(r, "$%d\r\n", &l); err != nil {
+			return nil, err
+		}
+		b := make([]byte, l+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:l])
+	}
+	return args, nil
+}
+
+func (s *redisServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := s.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		var reply string
+		switch {
+		case args[0] == "AUTH":
+			authed = args[1] == s.password
+			reply = "+OK\r\n"
+			if !authed {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case args[0] == "SET" && len(args) == 6 && args[3] == "NX" && args[4] == "PX":
+			reply = "$-1\r\n"
+			if _, ok := s.keys[args[1]]; !ok {
+				s.keys[args[1]] = args[2]
+				reply = "+OK\r\n"
+			}
+		case args[0] == "EVAL" && (args[1] == redisRefreshScript || args[1] == redisUnlockScript):
+			reply = ":0\r\n"
+			if s.keys[args[3]] == args[4] {
+				if args[1] == redisUnlockScript {
+					delete(s.keys, args[3])
+				}
+				reply = ":1\r\n"
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		s.mu.Unlock()
+		io.WriteString(conn, reply)
+	}
+}
+
+func (s *redisServer) expire() {
+	s.mu.Lock()
+	s.keys = map[string]string{}
+	s.mu.Unlock()
+}
+
+func TestRedis(t *testing.T) {
+	s := newRedisServer(t, "secret")
+	l := &Redis{Addr: s.ln.Addr().String(), Password: "secret"}
+	testLocker(t, l, s.expire)
+	s.mu.Lock()
+	_, ok := s.keys["tika:lock:crawl"]
+	s.mu.Unlock()
+	if !ok {
+		t.Errorf("Redis has keys %v, want tika:lock:crawl", s.keys)
+	}
+
+	bad := &Redis{Addr: s.ln.Addr().String(), Password: "wrong"}
+	if _, err := bad.TryLock(context.Background(), "crawl", time.Minute); err == nil || err.Error() != "redis: WRONGPASS invalid password" {
+		t.Errorf("TryLock with a wrong password got error %v", err)
+	}
+	for _, ttl := range []time.Duration{0, time.Microsecond} {
+		if _, err := l.TryLock(context.Background(), "short", ttl); err == nil {
+			t.Errorf("TryLock with ttl %v got no error", ttl)
+		}
+	}
+	if got := ms(1500 * time.Microsecond); got != "2" {
+		t.Errorf("ms(1.5ms) = %s, want 2", got)
+	}
+}
+
+func TestRedisCommand(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  interface{}
+	}{
+		{"+OK\r\n", "OK"},
+		{":42\r\n", int64(42)},
+		{"$5\r\nhello\r\n", "hello"},
+		{"$-1\r\n", nil},
+	}
+	for _, test := range tests {
+		var w strings.Builder
+		got, err := redisCommand(&w, bufio.NewReader(strings.NewReader(test.reply)), "GET", "k")
+		if err != nil {
+			t.Errorf("redisCommand(%q) got error: %v", test.reply, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("redisCommand(%q) = %#v, want %#v", test.reply, got, test.want)
+		}
+		if want := "*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"; w.String() != want {
+			t.Errorf("redisCommand sent %q, want %q", w.String(), want)
+		}
+	}
+	for _, reply := range []string{"", "*1\r\n", "$10\r\nshort\r\n", "OK\n"} {
+		if _, err := redisCommand(ioutil.Discard, bufio.NewReader(strings.NewReader(reply)), "GET", "k"); err == nil {
+			t.Errorf("redisCommand(%q) got no error", reply)
+		}
+	}
+}
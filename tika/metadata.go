@@ -0,0 +1,117 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"mime"
+	"strings"
+	"time"
+)
+
+// Metadata is the metadata of a document, from metadata key to values, with
+// accessors for the common properties. The accessors read the keys of the
+// current Tika version first, then the keys of older versions, such as
+// meta:author and Creation-Date, which Tika 2 replaced by their Dublin Core
+// equivalents. Convert the metadata returned by the Client, such as the maps of
+// MetaRecursive or Document.Metadata, with Metadata(m).
+type Metadata map[string][]string
+
+// Metadata keys read by the accessors of Metadata, in order of precedence.
+var (
+	titleKeys    = []string{"dc:title", "title", "meta:title"}
+	authorKeys   = []string{"dc:creator", "meta:author", "Author", "creator"}
+	createdKeys  = []string{"dcterms:created", "meta:creation-date", "Creation-Date", "created"}
+	languageKeys = []string{"dc:language", "Content-Language", "language"}
+)
+
+// createdLayouts are the layouts of the creation dates set by Tika, in order.
+// Dates without a time zone are in UTC.
+var createdLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// Get returns the first value of key, or "" if there is none.
+func (m Metadata) Get(key string) string {
+	return firstValue(m, key)
+}
+
+// first returns the first non-empty value of the first of keys m has.
+func (m Metadata) first(keys []string) string {
+	for _, k := range keys {
+		for _, v := range m[k] {
+			if v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// Title returns the title of the document, or "" if it has none.
+func (m Metadata) Title() string {
+	return m.first(titleKeys)
+}
+
+// Author returns the first author of the document, or "" if it has none. See
+// Authors for all of them.
+func (m Metadata) Author() string {
+	return m.first(authorKeys)
+}
+
+// Authors returns the authors of the document.
+func (m Metadata) Authors() []string {
+	for _, k := range authorKeys {
+		if len(m[k]) > 0 {
+			return m[k]
+		}
+	}
+	return nil
+}
+
+// Created returns the creation time of the document, or the zero time if it
+// has none which can be parsed.
+func (m Metadata) Created() time.Time {
+	for _, k := range createdKeys {
+		for _, v := range m[k] {
+			for _, layout := range createdLayouts {
+				if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+					return t
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// ContentType returns the MIME type of the document, without parameters such
+// as charset, or "" if it has none.
+func (m Metadata) ContentType() string {
+	v := m.Get("Content-Type")
+	if t, _, err := mime.ParseMediaType(v); err == nil {
+		return t
+	}
+	return v
+}
+
+// Language returns the language of the document, such as "en" or "fr-CA", as
+// declared by the document or detected by Tika, or "" if it has none.
+func (m Metadata) Language() string {
+	return m.first(languageKeys)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	tests := []struct {
+		name                         string
+		m                            Metadata
+		title, author, typ, language string
+		authors                      []string
+		created                      time.Time
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "Tika 2",
+			m: Metadata{
+				"dc:title":        {"Report"},
+				"dc:creator":      {"Ann", "Bob"},
+				"dcterms:created": {"2021-03-04T05:06:07Z"},
+				"Content-Type":    {"application/pdf; version=1.7"},
+				"dc:language":     {"fr-CA"},
+			},
+			title:    "Report",
+			author:   "Ann",
+			authors:  []string{"Ann", "Bob"},
+			created:  time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC),
+			typ:      "application/pdf",
+			language: "fr-CA",
+		},
+		{
+			name: "Tika 1",
+			m: Metadata{
+				"title":         {"Notes"},
+				"meta:author":   {"Ann"},
+				"Author":        {"Ann"},
+				"Creation-Date": {"2017-01-02T03:04:05"},
+				"Content-Type":  {"text/plain; charset=UTF-8"},
+				"language":      {"en"},
+			},
+			title:    "Notes",
+			author:   "Ann",
+			authors:  []string{"Ann"},
+			created:  time.Date(2017, time.January, 2, 3, 4, 5, 0, time.UTC),
+			typ:      "text/plain",
+			language: "en",
+		},
+		{
+			name: "precedence",
+			m: Metadata{
+				"dc:title":        {""},
+				"title":           {"Fallback"},
+				"created":         {"2019-05-06"},
+				"dcterms:created": {"not a date"},
+				"Content-Type":    {"invalid;;"},
+			},
+			title:   "Fallback",
+			created: time.Date(2019, time.May, 6, 0, 0, 0, 0, time.UTC),
+			typ:     "invalid;;",
+		},
+		{
+			name: "offset",
+			m: Metadata{
+				"meta:creation-date": {" 2020-02-03T04:05:06.789+01:00 "},
+			},
+			created: time.Date(2020, time.February, 3, 3, 5, 6, 789e6, time.UTC),
+		},
+	}
+	for _, test := range tests {
+		m := test.m
+		if got := m.Title(); got != test.title {
+			t.Errorf("Title(%s) = %q, want %q", test.name, got, test.title)
+		}
+		if got := m.Author(); got != test.author {
+			t.Errorf("Author(%s) = %q, want %q", test.name, got, test.author)
+		}
+		if got := m.Authors(); !reflect.DeepEqual(got, test.authors) {
+			t.Errorf("Authors(%s) = %q, want %q", test.name, got, test.authors)
+		}
+		if got := m.Created(); !got.Equal(test.created) {
+			t.Errorf("Created(%s) = %v, want %v", test.name, got, test.created)
+		}
+		if got := m.ContentType(); got != test.typ {
+			t.Errorf("ContentType(%s) = %q, want %q", test.name, got, test.typ)
+		}
+		if got := m.Language(); got != test.language {
+			t.Errorf("Language(%s) = %q, want %q", test.name, got, test.language)
+		}
+	}
+	doc := Document{Metadata: map[string][]string{"dc:title": {"Converted"}}}
+	if got := Metadata(doc.Metadata).Title(); got != "Converted" {
+		t.Errorf("Title of converted Document metadata = %q, want Converted", got)
+	}
+}
@@ -0,0 +1,361 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A Middleware wraps the http.RoundTripper making the requests of a Client
+// with cross-cutting behavior, such as authentication, retries or logging. It
+// returns an http.RoundTripper calling next to make the request. The
+// RoundTripper wrappers of the package, such as FaultInjector and
+// ResponseRecorder, are Middlewares once their Transport is set to next.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an http.RoundTripper calling itself, to write
+// Middlewares as functions.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// WithMiddleware returns a ClientOption to add mw to the Client, as by Use.
+func WithMiddleware(mw ...Middleware) ClientOption {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, mw...)
+	}
+}
+
+// Use adds mw to the middleware chain of c. Middlewares see requests in the
+// order they were added, and responses in the reverse order: the first one
+// added is the outermost. Retries of a call, for example, are made by the
+// middlewares added after the retry middleware only. Every middleware sees
+// the requests of c only, and not those of other users of its http.Client.
+//
+// Before the chain, the calls of c are counted in Stats and bounded by their
+// timeout; see WithDefaultTimeout. A call is one request to the first
+// middleware added, which retries within the timeout of the call, and the
+// redirects followed by the http.Client are part of the call.
+//
+// Use must be called before c is used.
+func (c *Client) Use(mw ...Middleware) {
+	c.middlewares = append(c.middlewares, mw...)
+	c.chain()
+}
+
+// chain builds the Transport of c with the middlewares added with Use, and
+// the RoundTripper sending the calls of c to its http.Client with its own
+// middlewares. The last middleware calls the Transport of the http.Client of
+// c at the time of the request, so changes to the http.Client apply.
+func (c *Client) chain() {
+	var t http.RoundTripper = RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		next := c.base().Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		return next.RoundTrip(req)
+	})
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		t = c.middlewares[i](t)
+	}
+	c.chained = t
+	c.calls = c.statsMiddleware(c.timeoutMiddleware(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return c.client().Do(req)
+	})))
+}
+
+// base returns the http.Client of c.
+func (c *Client) base() *http.Client {
+	if c.httpClient == nil {
+		return http.DefaultClient
+	}
+	return c.httpClient
+}
+
+// client returns the http.Client making the requests of c: a copy of its
+// http.Client as it is now, making the requests through the middlewares added
+// with Use.
+func (c *Client) client() *http.Client {
+	hc := *c.base()
+	hc.Transport = c.chained
+	return &hc
+}
+
+// callKey is the Context key of the call of a request made by a Client.
+type callKey struct{}
+
+// A call is a call made by a Client, passed to its middlewares by the
+// Context of the request.
+type call struct {
+	path  string
+	cfg   *callConfig
+	stats *clientStats
+}
+
+// statsMiddleware counts the calls of c in its Stats. A call ends once its
+// response body is closed, and fails if the response is not 200 OK or 204 No
+// Content, or reading its body fails.
+func (c *Client) statsMiddleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		c.stats.start()
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			c.stats.finish(err)
+			return nil, err
+		}
+		b := &statsBody{ReadCloser: resp.Body, stats: &c.stats}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			b.err = errors.New(resp.Status)
+		}
+		resp.Body = b
+		return resp, nil
+	})
+}
+
+// statsBody is the body of a response counted by statsMiddleware.
+type statsBody struct {
+	io.ReadCloser
+	stats *clientStats
+	err   error // err is the first error of the call.
+	once  sync.Once
+}
+
+func (b *statsBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *statsBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.stats.finish(b.err) })
+	return err
+}
+
+// timeoutMiddleware bounds the calls of c by their timeout, until their
+// response body is closed. Errors of canceled calls are CanceledErrors.
+func (c *Client) timeoutMiddleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		cl, ok := ctx.Value(callKey{}).(*call)
+		if !ok {
+			return next.RoundTrip(req)
+		}
+		cancel := func() {}
+		if d := c.callTimeout(ctx, cl.path, cl.cfg); d > 0 {
+			ctx, cancel = context.WithTimeout(ctx, d)
+			req = req.WithContext(ctx)
+		}
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			cancel()
+			// Report the cancellation rather than how the transport
+			// noticed it, as ctxhttp does.
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return nil, canceled(ctx, err)
+		}
+		resp.Body = &callBody{ReadCloser: resp.Body, ctx: ctx, cancel: cancel}
+		return resp, nil
+	})
+}
+
+// callBody is the body of a response of a call bounded by timeoutMiddleware.
+type callBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (b *callBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = canceled(b.ctx, err)
+	}
+	return n, err
+}
+
+func (b *callBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// HeaderMiddleware returns a Middleware setting the header key of requests to
+// value, such as an Authorization header, unless the request already has it.
+func HeaderMiddleware(key, value string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(key) != "" {
+				return next.RoundTrip(req)
+			}
+			// A RoundTripper must not modify the request.
+			req = req.Clone(req.Context())
+			if req.Header == nil {
+				req.Header = make(http.Header)
+			}
+			req.Header.Set(key, value)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// BasicAuthMiddleware returns a Middleware authenticating requests with HTTP
+// basic authentication, for a Tika Server behind an authenticating proxy.
+func BasicAuthMiddleware(username, password string) Middleware {
+	r := &http.Request{Header: make(http.Header)}
+	r.SetBasicAuth(username, password)
+	return HeaderMiddleware("Authorization", r.Header.Get("Authorization"))
+}
+
+// BearerTokenMiddleware returns a Middleware authenticating requests with the
+// bearer token.
+func BearerTokenMiddleware(token string) Middleware {
+	return HeaderMiddleware("Authorization", "Bearer "+token)
+}
+
+// RetryMiddleware returns a Middleware making up to attempts attempts of the
+// requests failing with a network error or a 429, 502, 503 or 504 response.
+// The delay between attempts starts at backoff and doubles after every
+// attempt, unless the response requests a longer one with Retry-After. It is
+// RetryPolicy{Attempts: attempts, Backoff: backoff}.Middleware(); see
+// RetryPolicy for which requests are retried.
+func RetryMiddleware(attempts int, backoff time.Duration) Middleware {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return RetryPolicy{Attempts: attempts, Backoff: backoff}.Middleware()
+}
+
+// RateLimitMiddleware returns a Middleware sending at most n requests per
+// interval on average, in bursts of up to n requests. Requests wait for their
+// turn, or until their Context is done. It is the Middleware of
+// WithRateLimit with a rate of n per interval and a burst of n.
+func RateLimitMiddleware(n int, interval time.Duration) Middleware {
+	if n < 1 {
+		n = 1
+	}
+	return newTokenBucket(float64(n)/interval.Seconds(), n).middleware
+}
+
+// WithRateLimit returns a ClientOption to send at most rps requests per second
+// on average, in bursts of up to burst requests, at least 1, such as the
+// requests of the workers of a Job starting together. Requests wait for a
+// token, or until their Context is done. A rps of 0 or less is unlimited.
+// The tokens unused while the Client is idle are saved, up to burst.
+func WithRateLimit(rps float64, burst int) ClientOption {
+	if rps <= 0 {
+		return func(*Client) {}
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return WithMiddleware(newTokenBucket(rps, burst).middleware)
+}
+
+// A tokenBucket holds the tokens of WithRateLimit and RateLimitMiddleware.
+type tokenBucket struct {
+	rate  float64 // rate is the number of tokens added per second.
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64 // tokens is negative when requests wait for tokens.
+	last   time.Time
+}
+
+// newTokenBucket returns a full tokenBucket adding rate tokens per second.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// middleware is a Middleware making requests wait for a token. The body of
+// the requests whose Context is done first is closed, as a RoundTripper must.
+func (b *tokenBucket) middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if wait := b.take(time.Now()); wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-req.Context().Done():
+				t.Stop()
+				b.giveBack()
+				if req.Body != nil {
+					req.Body.Close()
+				}
+				return nil, req.Context().Err()
+			case <-t.C:
+			}
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+// take takes a token at now, returning how long to wait for it.
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// giveBack returns the token of a request which gave up waiting for it.
+func (b *tokenBucket) giveBack() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens++; b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// LoggingMiddleware returns a Middleware logging every request with logf,
+// such as log.Printf, with its status or error and duration, as in
+// "PUT /tika: 200 OK in 12ms". The bodies are not logged.
+func LoggingMiddleware(logf func(format string, args ...interface{})) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			d := time.Since(start).Round(time.Millisecond)
+			if err != nil {
+				logf("%s %s: %v in %v", req.Method, req.URL.Path, err, d)
+			} else {
+				logf("%s %s: %s in %v", req.Method, req.URL.Path, resp.Status, d)
+			}
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,328 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name+" request")
+				resp, err := next.RoundTrip(req)
+				order = append(order, name+" response")
+				return resp, err
+			})
+		}
+	}
+	c := NewClient(nil, ts.URL, WithMiddleware(trace("a"), BasicAuthMiddleware("user", "pass")))
+	c.Use(trace("b"), BearerTokenMiddleware("ignored"))
+	got, err := c.Parse(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Parse got error: %v", err)
+	}
+	if got != "Basic dXNlcjpwYXNz" {
+		t.Errorf("Parse sent Authorization %q, want the basic authentication of the first middleware", got)
+	}
+	want := []string{"a request", "b request", "b response", "a response"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("middlewares called in order %q, want %q", order, want)
+	}
+	if got, err := c.Parse(context.Background(), nil, WithHeader("Authorization", "Bearer mine")); err != nil || got != "Bearer mine" {
+		t.Errorf("Parse with an Authorization header sent %q, %v, want it unchanged", got, err)
+	}
+	if http.DefaultClient.Transport != nil {
+		t.Errorf("Use modified http.DefaultClient")
+	}
+}
+
+func TestUseLiveClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("X-Transport"))
+	}))
+	defer ts.Close()
+	hc := &http.Client{}
+	c := NewClient(hc, ts.URL)
+	c.Use(HeaderMiddleware("X-Middleware", "1"))
+
+	// The http.Client is changed after the Client was made.
+	hc.Transport = RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("X-Middleware") != "1" {
+			return nil, errors.New("request did not go through the middleware")
+		}
+		req = req.Clone(req.Context())
+		req.Header.Set("X-Transport", "new")
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	hc.Timeout = time.Nanosecond
+	if _, err := c.Parse(context.Background(), nil); err == nil {
+		t.Errorf("Parse after setting the Timeout of the http.Client got no error")
+	}
+	hc.Timeout = 0
+	if got, err := c.Parse(context.Background(), nil); err != nil || got != "new" {
+		t.Errorf("Parse after setting the Transport of the http.Client = %q, %v, want it used", got, err)
+	}
+}
+
+func TestClientMiddlewares(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	var deadline bool
+	var active int64
+	c := NewClient(nil, ts.URL, WithEndpointTimeout(EndpointDetect, time.Minute))
+	c.Use(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			_, deadline = req.Context().Deadline()
+			active = c.Stats().Active
+			return next.RoundTrip(req)
+		})
+	})
+	tests := []struct {
+		name     string
+		call     func() error
+		deadline bool
+	}{
+		{"detect", func() error { _, err := c.Detect(context.Background(), nil); return err }, true},
+		{"parse", func() error { _, err := c.Parse(context.Background(), nil); return err }, false},
+	}
+	for _, test := range tests {
+		if err := test.call(); err != nil {
+			t.Fatalf("%s got error: %v", test.name, err)
+		}
+		if deadline != test.deadline || active != 1 {
+			t.Errorf("%s: middleware saw a deadline: %v and %d active calls, want %v and 1", test.name, deadline, active, test.deadline)
+		}
+	}
+	if s := c.Stats(); s.Active != 0 || s.Requests != 2 {
+		t.Errorf("Stats = %+v, want 2 requests and none active", s)
+	}
+}
+
+func TestClientRedirects(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		if r.URL.Path == "/tika" {
+			http.Redirect(w, r, "/moved", http.StatusTemporaryRedirect)
+			return
+		}
+		fmt.Fprint(w, "moved")
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	if got, err := c.Parse(context.Background(), nil); err != nil || got != "moved" {
+		t.Fatalf("Parse = %q, %v, want the redirected response", got, err)
+	}
+	if s := c.Stats(); s.Requests != 1 || s.Failures != 0 || s.Active != 0 {
+		t.Errorf("Stats = %+v, want the redirect counted as 1 request", s)
+	}
+
+	// Every hop fits in the timeout, but not both.
+	c = NewClient(nil, ts.URL, WithDefaultTimeout(45*time.Millisecond))
+	if _, err := c.Parse(context.Background(), nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Parse redirected past its timeout got error %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		n := len(bodies)
+		mu.Unlock()
+		switch {
+		case string(body) == "bad":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case n < 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, "ok")
+		}
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	c.Use(RetryMiddleware(3, time.Millisecond))
+	tests := []struct {
+		name       string
+		input      io.Reader
+		want       string
+		wantBodies []string
+	}{
+		{
+			name:       "rewindable",
+			input:      strings.NewReader("doc"),
+			want:       "ok",
+			wantBodies: []string{"doc", "doc", "doc"},
+		},
+		{
+			name:       "no input",
+			want:       "ok",
+			wantBodies: []string{"", "", ""},
+		},
+		{
+			name:       "streamed",
+			input:      io.MultiReader(bytes.NewReader([]byte("doc"))),
+			wantBodies: []string{"doc"},
+		},
+		{
+			name:       "not retryable",
+			input:      strings.NewReader("bad"),
+			wantBodies: []string{"bad"},
+		},
+	}
+	for _, test := range tests {
+		bodies = nil
+		got, err := c.Parse(context.Background(), test.input)
+		if (err == nil) != (test.want != "") || got != test.want {
+			t.Errorf("Parse(%s) = %q, %v, want %q", test.name, got, err, test.want)
+		}
+		if !reflect.DeepEqual(bodies, test.wantBodies) {
+			t.Errorf("Parse(%s) sent %q, want %q", test.name, bodies, test.wantBodies)
+		}
+	}
+
+	bodies = nil
+	c = NewClient(nil, ts.URL, WithMiddleware(RetryMiddleware(3, time.Hour)))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Parse(ctx, nil); err == nil || len(bodies) != 1 {
+		t.Errorf("Parse with a canceled retry got error %v after %d attempts, want an error after 1", err, len(bodies))
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL, WithMiddleware(RateLimitMiddleware(2, 100*time.Millisecond)))
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := c.Version(context.Background()); err != nil {
+			t.Fatalf("Version got error: %v", err)
+		}
+	}
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("3 calls at 2 per 100ms in bursts of 2 took %v, want at least 50ms", d)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c = NewClient(nil, ts.URL, WithMiddleware(RateLimitMiddleware(1, time.Hour)))
+	c.Version(context.Background())
+	if _, err := c.Version(ctx); err == nil {
+		t.Errorf("Version with a canceled Context waiting for its turn got no error")
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL, WithRateLimit(20, 3))
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := c.Version(context.Background()); err != nil {
+			t.Fatalf("Version got error: %v", err)
+		}
+	}
+	if d := time.Since(start); d > 40*time.Millisecond {
+		t.Errorf("a burst of 3 calls took %v, want no wait", d)
+	}
+	if _, err := c.Version(context.Background()); err != nil {
+		t.Fatalf("Version got error: %v", err)
+	}
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("4 calls at 20 per second in bursts of 3 took %v, want at least 50ms", d)
+	}
+
+	c = NewClient(nil, ts.URL, WithRateLimit(1, 1))
+	c.Version(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := c.Version(ctx); !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("Version with a Context done while waiting for a token got error %v after %v", err, time.Since(start))
+	}
+
+	b := &tokenBucket{rate: 10, burst: 2, tokens: 2, last: start}
+	for i, want := range []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond} {
+		if got := b.take(start); got != want {
+			t.Errorf("take %d = %v, want %v", i, got, want)
+		}
+	}
+	b.giveBack()
+	if got := b.take(start.Add(time.Second)); got != 0 {
+		t.Errorf("take after the bucket refilled = %v, want 0", got)
+	}
+	if got := b.tokens; got != 1 {
+		t.Errorf("bucket has %v tokens after refilling, want the burst of 2 less 1", got)
+	}
+	NewClient(nil, ts.URL, WithRateLimit(0, 0)).Version(context.Background())
+
+	b = newTokenBucket(1, 1)
+	b.take(time.Now())
+	body := &closeRecorder{Reader: strings.NewReader("body")}
+	req, _ := http.NewRequest("PUT", ts.URL, body)
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.middleware(http.DefaultTransport).RoundTrip(req.WithContext(done)); err == nil || !body.closed {
+		t.Errorf("RoundTrip with a Context done while waiting for a token got error %v, closed the body: %t", err, body.closed)
+	}
+}
+
+// closeRecorder is a request body recording whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer ts.Close()
+	var logs []string
+	logf := func(format string, args ...interface{}) { logs = append(logs, fmt.Sprintf(format, args...)) }
+	c := NewClient(nil, ts.URL, WithMiddleware(LoggingMiddleware(logf)))
+	c.Version(context.Background())
+	c = NewClient(nil, "http://127.0.0.1:0", WithMiddleware(LoggingMiddleware(logf)))
+	c.Version(context.Background())
+	if len(logs) != 2 || !strings.HasPrefix(logs[0], "GET /version: 200 OK in ") || !strings.HasPrefix(logs[1], "GET /version: ") || strings.Contains(logs[1], "200") {
+		t.Errorf("LoggingMiddleware logged %q, want a success and a failure", logs)
+	}
+}
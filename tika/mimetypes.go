@@ -0,0 +1,95 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import "strings"
+
+// extensionTypes are the MIME types of common file extensions, as named by
+// Tika. They are built in rather than read with mime.TypeByExtension, which
+// depends on the mime.types files of the host and knows few types in minimal
+// containers.
+var extensionTypes = map[string]string{
+	".7z":      "application/x-7z-compressed",
+	".avi":     "video/x-msvideo",
+	".bmp":     "image/bmp",
+	".bz2":     "application/x-bzip2",
+	".csv":     "text/csv",
+	".doc":     "application/msword",
+	".docm":    "application/vnd.ms-word.document.macroenabled.12",
+	".docx":    "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".dwg":     "image/vnd.dwg",
+	".eml":     "message/rfc822",
+	".epub":    "application/epub+zip",
+	".flac":    "audio/x-flac",
+	".gif":     "image/gif",
+	".gz":      "application/gzip",
+	".heic":    "image/heic",
+	".htm":     "text/html",
+	".html":    "text/html",
+	".ics":     "text/calendar",
+	".jar":     "application/java-archive",
+	".jpeg":    "image/jpeg",
+	".jpg":     "image/jpeg",
+	".js":      "text/javascript",
+	".json":    "application/json",
+	".key":     "application/vnd.apple.keynote",
+	".m4a":     "audio/mp4",
+	".md":      "text/x-web-markdown",
+	".mov":     "video/quicktime",
+	".mp3":     "audio/mpeg",
+	".mp4":     "video/mp4",
+	".msg":     "application/vnd.ms-outlook",
+	".numbers": "application/vnd.apple.numbers",
+	".odp":     "application/vnd.oasis.opendocument.presentation",
+	".ods":     "application/vnd.oasis.opendocument.spreadsheet",
+	".odt":     "application/vnd.oasis.opendocument.text",
+	".ogg":     "audio/ogg",
+	".pages":   "application/vnd.apple.pages",
+	".pdf":     "application/pdf",
+	".png":     "image/png",
+	".ppt":     "application/vnd.ms-powerpoint",
+	".pptx":    "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".ps":      "application/postscript",
+	".psd":     "image/vnd.adobe.photoshop",
+	".pst":     "application/vnd.ms-outlook-pst",
+	".rar":     "application/x-rar-compressed",
+	".rtf":     "application/rtf",
+	".svg":     "image/svg+xml",
+	".tar":     "application/x-tar",
+	".tgz":     "application/gzip",
+	".tif":     "image/tiff",
+	".tiff":    "image/tiff",
+	".tsv":     "text/tab-separated-values",
+	".txt":     "text/plain",
+	".vsdx":    "application/vnd.ms-visio.drawing",
+	".wav":     "audio/vnd.wave",
+	".webm":    "video/webm",
+	".webp":    "image/webp",
+	".xhtml":   "application/xhtml+xml",
+	".xls":     "application/vnd.ms-excel",
+	".xlsm":    "application/vnd.ms-excel.sheet.macroenabled.12",
+	".xlsx":    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".xml":     "application/xml",
+	".xz":      "application/x-xz",
+	".zip":     "application/zip",
+}
+
+// typeByExtension returns the MIME type of the file extension ext, such as
+// ".pdf", in any case, or "" if it is unknown.
+func typeByExtension(ext string) string {
+	return extensionTypes[strings.ToLower(ext)]
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// A MojibakeRepair is a part of a text fixed by RepairMojibake.
+type MojibakeRepair struct {
+	// Offset is the byte offset of Original in the text.
+	Offset int
+	// Original is the garbled text, such as "dÃ©jÃ ".
+	Original string
+	// Repaired is the text it was replaced with, such as "déjà".
+	Repaired string
+	// Charset is the charset the UTF-8 text was wrongly decoded with, either
+	// "windows-1252" or "iso-8859-1".
+	Charset string
+}
+
+// maxMojibakeDepth is the number of times text is repaired when it was
+// wrongly decoded several times, such as UTF-8 encoded twice.
+const maxMojibakeDepth = 3
+
+// RepairMojibake fixes the UTF-8 text of text which was decoded as
+// windows-1252 or ISO-8859-1, typically by a legacy application, such as
+// "Ã©" for "é" or "â€™" for "’", and returns the fixed text and the repairs
+// applied, in order. Text encoded several times, such as UTF-8 encoded
+// twice, is fixed as well. Only sequences decoding to valid UTF-8 are
+// repaired, which are very unlikely in genuine text.
+func RepairMojibake(text string) (string, []MojibakeRepair) {
+	var b strings.Builder
+	var repairs []MojibakeRepair
+	copied := 0
+	for i := 0; i < len(text); {
+		if text[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		end, fixed, latin1 := misreadRun(text, i)
+		if end == i {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+		for depth := 1; depth < maxMojibakeDepth; depth++ {
+			again, more := RepairMojibake(fixed)
+			if len(more) == 0 {
+				break
+			}
+			fixed = again
+		}
+		if b.Len() == 0 {
+			b.Grow(len(text))
+		}
+		b.WriteString(text[copied:i])
+		b.WriteString(fixed)
+		r := MojibakeRepair{Offset: i, Original: text[i:end], Repaired: fixed, Charset: "windows-1252"}
+		if latin1 {
+			r.Charset = "iso-8859-1"
+		}
+		repairs = append(repairs, r)
+		i, copied = end, end
+	}
+	if len(repairs) == 0 {
+		return text, nil
+	}
+	b.WriteString(text[copied:])
+	return b.String(), repairs
+}
+
+// misreadRun returns the end of the consecutive UTF-8 sequences decoded as
+// windows-1252 or ISO-8859-1 at the start of text[i:], and their text. end is
+// i if there are none. latin1 reports whether a sequence was decoded as
+// ISO-8859-1, with C1 control characters where windows-1252 has punctuation.
+func misreadRun(text string, i int) (end int, fixed string, latin1 bool) {
+	var buf []byte
+	end = i
+	for end < len(text) {
+		n, seqEnd, c1 := misreadSequence(text, end, &buf)
+		if n == 0 {
+			break
+		}
+		latin1 = latin1 || c1
+		end = seqEnd
+	}
+	return end, string(buf), latin1
+}
+
+// misreadSequence appends to buf the UTF-8 sequence decoded as windows-1252
+// or ISO-8859-1 at text[i:], and returns its length in bytes once encoded
+// correctly, or 0 if there is none, and its end in text.
+func misreadSequence(text string, i int, buf *[]byte) (n, end int, c1 bool) {
+	var seq [utf8.UTFMax]byte
+	r, size := utf8.DecodeRuneInString(text[i:])
+	lead, ok := misreadByte(r)
+	if !ok {
+		return 0, i, false
+	}
+	switch {
+	case lead >= 0xC2 && lead <= 0xDF:
+		n = 2
+	case lead >= 0xE0 && lead <= 0xEF:
+		n = 3
+	case lead >= 0xF0 && lead <= 0xF4:
+		n = 4
+	default:
+		return 0, i, false
+	}
+	seq[0] = lead
+	end = i + size
+	for k := 1; k < n; k++ {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		b, ok := misreadByte(r)
+		if !ok || b < 0x80 || b > 0xBF {
+			return 0, i, false
+		}
+		c1 = c1 || r >= 0x80 && r < 0xA0
+		seq[k] = b
+		end += size
+	}
+	if !utf8.Valid(seq[:n]) {
+		return 0, i, false
+	}
+	*buf = append(*buf, seq[:n]...)
+	return n, end, c1
+}
+
+// misreadByte returns the byte decoded as r by windows-1252 or ISO-8859-1,
+// for r of the bytes 0x80 to 0xFF.
+func misreadByte(r rune) (byte, bool) {
+	if r >= 0x80 && r <= 0xFF {
+		return byte(r), true
+	}
+	for i, w := range windows1252 {
+		if w == r {
+			return byte(0x80 + i), true
+		}
+	}
+	return 0, false
+}
+
+// RepairingTextDecoder returns a TextDecoder decoding with d, or DecodeText if
+// d is nil, then fixing the text with RepairMojibake, to improve the text
+// extracted from legacy documents. report, if not nil, is called with the
+// repairs of every response with some.
+//
+//	c := tika.NewClient(nil, url, tika.WithTextDecoder(tika.RepairingTextDecoder(nil, nil)))
+func RepairingTextDecoder(d TextDecoder, report func([]MojibakeRepair)) TextDecoder {
+	if d == nil {
+		d = DecodeText
+	}
+	return func(charset string, body []byte) (string, error) {
+		s, err := d(charset, body)
+		if err != nil {
+			return "", err
+		}
+		s, repairs := RepairMojibake(s)
+		if len(repairs) > 0 && report != nil {
+			report(repairs)
+		}
+		return s, nil
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// asCP1252 and asLatin1 return the UTF-8 encoding of s decoded as
+// windows-1252 and ISO-8859-1.
+func asCP1252(s string) string { return decodeLatin1([]byte(s), &windows1252) }
+func asLatin1(s string) string { return decodeLatin1([]byte(s), nil) }
+
+func TestRepairMojibake(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"plain ASCII text", "plain ASCII text"},
+		{"already correct: déjà vu, naïve, 日本語", "already correct: déjà vu, naïve, 日本語"},
+		{asCP1252("déjà vu"), "déjà vu"},
+		{asCP1252("It’s “quoted” — €5"), "It’s “quoted” — €5"},
+		{asLatin1("It’s “quoted”"), "It’s “quoted”"},
+		{asCP1252("emoji 😀 and 日本語"), "emoji 😀 and 日本語"},
+		{asCP1252(asCP1252("double café")), "double café"},
+		{asCP1252(asCP1252(asCP1252("triple café"))), "triple café"},
+		{"mixed: café and " + asCP1252("café"), "mixed: café and café"},
+		// Not UTF-8 sequences: a lead byte without continuation.
+		{"Ã alone, Â¿", "Ã alone, ¿"},
+		{"price: 5Â", "price: 5Â"},
+		{"Ã©" + "Ã", "é" + "Ã"},
+	}
+	for _, test := range tests {
+		got, _ := RepairMojibake(test.text)
+		if got != test.want {
+			t.Errorf("RepairMojibake(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestRepairMojibakeReport(t *testing.T) {
+	text := "A " + asCP1252("café") + " and " + asLatin1("“") + " and " + asCP1252(asCP1252("é")) + "."
+	got, repairs := RepairMojibake(text)
+	if want := "A café and “ and é."; got != want {
+		t.Errorf("RepairMojibake(%q) = %q, want %q", text, got, want)
+	}
+	want := []MojibakeRepair{
+		{Offset: 5, Original: "Ã©", Repaired: "é", Charset: "windows-1252"},
+		{Offset: 14, Original: "â\u0080\u009c", Repaired: "“", Charset: "iso-8859-1"},
+		{Offset: 25, Original: asCP1252(asCP1252("é")), Repaired: "é", Charset: "windows-1252"},
+	}
+	if !reflect.DeepEqual(repairs, want) {
+		t.Errorf("RepairMojibake(%q) repairs:\n got %+v\nwant %+v", text, repairs, want)
+	}
+	for _, r := range repairs {
+		if text[r.Offset:r.Offset+len(r.Original)] != r.Original {
+			t.Errorf("repair %+v is not at its offset", r)
+		}
+	}
+	if _, repairs := RepairMojibake("no repair"); repairs != nil {
+		t.Errorf("RepairMojibake of clean text reported %v", repairs)
+	}
+}
+
+func TestRepairingTextDecoder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		fmt.Fprint(w, asCP1252("Résumé"))
+	}))
+	defer ts.Close()
+	var reported []MojibakeRepair
+	c := NewClient(nil, ts.URL, WithTextDecoder(RepairingTextDecoder(nil, func(r []MojibakeRepair) {
+		reported = append(reported, r...)
+	})))
+	got, err := c.Parse(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Parse got error: %v", err)
+	}
+	if got != "Résumé" {
+		t.Errorf("Parse got %q, want %q", got, "Résumé")
+	}
+	if len(reported) != 2 {
+		t.Errorf("RepairingTextDecoder reported %+v, want 2 repairs", reported)
+	}
+
+	errDecode := errors.New("decode failed")
+	d := RepairingTextDecoder(func(string, []byte) (string, error) { return "", errDecode }, nil)
+	if _, err := d("", nil); err != errDecode {
+		t.Errorf("RepairingTextDecoder got error %v, want %v", err, errDecode)
+	}
+}
+
+func BenchmarkRepairMojibake(b *testing.B) {
+	body, err := ioutil.ReadFile("mojibake.go")
+	if err != nil {
+		b.Fatal(err)
+	}
+	text := string(body)
+	b.SetBytes(int64(len(text)))
+	for i := 0; i < b.N; i++ {
+		RepairMojibake(text)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// NoiseKind is a kind of noise input: a file of every real corpus which is
+// not a document, and whose extraction pollutes the results and wastes calls
+// to the server.
+type NoiseKind string
+
+// NoiseKinds.
+const (
+	// NoiseEmpty is a file of zero bytes.
+	NoiseEmpty NoiseKind = "empty"
+	// NoiseLockFile is a lock file of an office suite, such as ~$report.docx
+	// of Microsoft Office or .~lock.report.odt# of LibreOffice.
+	NoiseLockFile NoiseKind = "lock file"
+	// NoiseSystemFile is a file written by an operating system, such as
+	// .DS_Store, Thumbs.db, desktop.ini or an AppleDouble ._ file.
+	NoiseSystemFile NoiseKind = "system file"
+)
+
+// systemFiles are the lower case names of the NoiseSystemFiles.
+var systemFiles = map[string]bool{
+	".ds_store":   true,
+	"thumbs.db":   true,
+	"ehthumbs.db": true,
+	"desktop.ini": true,
+	"icon\r":      true,
+	".localized":  true,
+}
+
+// NoiseOf returns the kind of noise of in, judged from its name and size, and
+// whether it is noise. A lock file is noise even if it is empty. Only Inputs
+// whose size is known to be 0 are NoiseEmpty.
+func NoiseOf(in Input) (NoiseKind, bool) {
+	name := in.Name
+	if name == "" {
+		name = path.Base(in.ID)
+	}
+	switch {
+	case strings.HasPrefix(name, "~$"),
+		strings.HasPrefix(name, ".~lock.") && strings.HasSuffix(name, "#"):
+		return NoiseLockFile, true
+	case systemFiles[strings.ToLower(name)], strings.HasPrefix(name, "._"):
+		return NoiseSystemFile, true
+	case in.Size == 0:
+		return NoiseEmpty, true
+	}
+	return "", false
+}
+
+// NoisePolicy is how a Job handles a NoiseKind.
+type NoisePolicy int
+
+// NoisePolicies.
+const (
+	// NoiseExtract extracts noise inputs as any other input.
+	NoiseExtract NoisePolicy = iota
+	// NoiseSkip skips noise inputs, which are counted as Skipped.
+	NoiseSkip
+	// NoiseRecord emits a Document for noise inputs without extracting
+	// them, with their kind in the NoiseKey metadata, so they are accounted
+	// for downstream.
+	NoiseRecord
+	// NoiseFail fails noise inputs with ErrNoise without extracting them.
+	NoiseFail
+)
+
+// NoiseKey is the metadata key of the NoiseKind of the Documents emitted with
+// NoiseRecord.
+const NoiseKey = "X-Tika-Noise"
+
+// ErrNoise is the error of the inputs failed with NoiseFail.
+var ErrNoise = errors.New("noise input")
+
+// noisePolicy returns the policy of the noise kind of in and the kind, or
+// NoiseExtract if in is not noise.
+func noisePolicy(policies map[NoiseKind]NoisePolicy, in Input) (NoisePolicy, NoiseKind) {
+	if len(policies) == 0 {
+		return NoiseExtract, ""
+	}
+	kind, ok := NoiseOf(in)
+	if !ok {
+		return NoiseExtract, ""
+	}
+	return policies[kind], kind
+}
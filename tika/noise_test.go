@@ -0,0 +1,160 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNoiseOf(t *testing.T) {
+	tests := []struct {
+		in   Input
+		want NoiseKind
+	}{
+		{Input{ID: "a/report.docx", Name: "report.docx", Size: 10}, ""},
+		{Input{ID: "a/empty.txt", Name: "empty.txt"}, NoiseEmpty},
+		{Input{ID: "a/~$report.docx", Name: "~$report.docx", Size: 162}, NoiseLockFile},
+		{Input{ID: "a/~$report.docx", Size: 0}, NoiseLockFile},
+		{Input{ID: "a/.~lock.report.odt#", Size: 80}, NoiseLockFile},
+		{Input{ID: "a/.~lock.report.odt", Size: 80}, ""},
+		{Input{ID: "a/.DS_Store", Size: 6148}, NoiseSystemFile},
+		{Input{ID: "a/THUMBS.DB", Size: 10}, NoiseSystemFile},
+		{Input{ID: "desktop.ini", Size: 10}, NoiseSystemFile},
+		{Input{ID: "a/._report.docx", Size: 4096}, NoiseSystemFile},
+		{Input{ID: "a/Icon\r", Size: 0}, NoiseSystemFile},
+		{Input{ID: "a/notes~$.txt", Size: 5}, ""},
+		{Input{ID: "a/Notes.docx", Size: UnknownSize}, ""},
+	}
+	for _, test := range tests {
+		got, ok := NoiseOf(test.in)
+		if got != test.want || ok != (test.want != "") {
+			t.Errorf("NoiseOf(%+v) = %q, %v, want %q", test.in, got, ok, test.want)
+		}
+	}
+}
+
+// noiseFiles are a document and one input of each NoiseKind.
+var noiseFiles = map[string]string{
+	"report.txt":    "hello",
+	"empty.txt":     "",
+	"~$report.docx": "lock",
+	".DS_Store":     "store",
+}
+
+func TestJobNoise(t *testing.T) {
+	var mu sync.Mutex
+	var parsed []string
+	rmeta := rmetaServer()
+	defer rmeta.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		parsed = append(parsed, r.Header.Get("Content-Disposition"))
+		mu.Unlock()
+		rmeta.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name      string
+		policies  map[NoiseKind]NoisePolicy
+		parsed    int
+		emitted   []string
+		status    JobStatus
+		noiseMeta map[string]string
+	}{
+		{
+			name:    "default",
+			parsed:  4,
+			emitted: []string{".DS_Store", "empty.txt", "report.txt", "~$report.docx"},
+			status:  JobStatus{Succeeded: 4},
+		},
+		{
+			name:     "skip",
+			policies: map[NoiseKind]NoisePolicy{NoiseEmpty: NoiseSkip, NoiseLockFile: NoiseSkip, NoiseSystemFile: NoiseSkip},
+			parsed:   1,
+			emitted:  []string{"report.txt"},
+			status:   JobStatus{Succeeded: 1, Skipped: 3},
+		},
+		{
+			name:      "record",
+			policies:  map[NoiseKind]NoisePolicy{NoiseLockFile: NoiseRecord, NoiseSystemFile: NoiseRecord},
+			parsed:    2,
+			emitted:   []string{".DS_Store", "empty.txt", "report.txt", "~$report.docx"},
+			status:    JobStatus{Succeeded: 4},
+			noiseMeta: map[string]string{".DS_Store": "system file", "~$report.docx": "lock file"},
+		},
+		{
+			name:     "fail",
+			policies: map[NoiseKind]NoisePolicy{NoiseEmpty: NoiseFail, NoiseSystemFile: NoiseSkip},
+			parsed:   2,
+			emitted:  []string{"report.txt", "~$report.docx"},
+			status:   JobStatus{Succeeded: 2, Failed: 1, Skipped: 1},
+		},
+	}
+	for _, test := range tests {
+		parsed = nil
+		j, docs := testJob(ts, noiseFiles)
+		j.Noise = test.policies
+		if err := j.Run(context.Background()); err != nil {
+			t.Fatalf("%s: Run got error: %v", test.name, err)
+		}
+		if len(parsed) != test.parsed {
+			t.Errorf("%s: Run parsed %q, want %d inputs", test.name, parsed, test.parsed)
+		}
+		var emitted []string
+		noiseMeta := map[string]string{}
+		for _, d := range docs() {
+			emitted = append(emitted, d.ID)
+			if kind := firstValue(d.Metadata, NoiseKey); kind != "" {
+				noiseMeta[d.ID] = kind
+			}
+		}
+		if !reflect.DeepEqual(emitted, test.emitted) {
+			t.Errorf("%s: Run emitted %q, want %q", test.name, emitted, test.emitted)
+		}
+		if test.noiseMeta == nil {
+			test.noiseMeta = map[string]string{}
+		}
+		if !reflect.DeepEqual(noiseMeta, test.noiseMeta) {
+			t.Errorf("%s: Run emitted noise kinds %v, want %v", test.name, noiseMeta, test.noiseMeta)
+		}
+		s := j.Status()
+		if s.Succeeded != test.status.Succeeded || s.Failed != test.status.Failed || s.Skipped != test.status.Skipped || s.Total != len(noiseFiles) {
+			t.Errorf("%s: Status after Run = %+v, want %+v", test.name, s, test.status)
+		}
+		if test.status.Failed > 0 && (len(s.RecentFailures) != 1 || s.RecentFailures[0].ID != "empty.txt" || !strings.Contains(s.RecentFailures[0].Err, ErrNoise.Error())) {
+			t.Errorf("%s: Status after Run has failures %+v, want empty.txt as noise", test.name, s.RecentFailures)
+		}
+	}
+}
+
+func TestNoisePolicy(t *testing.T) {
+	in := Input{ID: "empty.txt"}
+	if p, _ := noisePolicy(nil, in); p != NoiseExtract {
+		t.Errorf("noisePolicy with no policies = %v, want NoiseExtract", p)
+	}
+	p, kind := noisePolicy(map[NoiseKind]NoisePolicy{NoiseEmpty: NoiseFail}, in)
+	if p != NoiseFail || kind != NoiseEmpty {
+		t.Errorf("noisePolicy = %v, %q, want NoiseFail, %q", p, kind, NoiseEmpty)
+	}
+}
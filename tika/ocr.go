@@ -0,0 +1,77 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"strconv"
+	"time"
+)
+
+// ocrHeaderPrefix is the prefix of the headers setting the properties of the
+// TesseractOCRConfig of a request. Tika derives the name of the setter from
+// the rest of the header name, so it is case sensitive.
+const ocrHeaderPrefix = "X-Tika-OCR"
+
+// OCROptions configure the Tesseract OCR of images, and of PDF pages Tika
+// renders to run OCR on, for a single request. The zero OCROptions keeps the
+// configuration of the server.
+type OCROptions struct {
+	// Language is the language of the text, as Tesseract language codes
+	// joined with "+", such as "eng+fra". Its traineddata must be installed
+	// on the server.
+	Language string
+	// PageSegMode is the Tesseract page segmentation mode, from 0 to 13,
+	// such as 6 for a single uniform block of text. Zero keeps the default
+	// of the server; mode 0 (orientation and script detection only) cannot be
+	// set.
+	PageSegMode int
+	// Timeout is how long Tesseract may run per image, rounded up to the
+	// second. Zero keeps the default of the server.
+	Timeout time.Duration
+	// Skip disables OCR. Tika 1.x servers do not support it.
+	Skip bool
+}
+
+// headers returns the headers setting o.
+func (o OCROptions) headers() map[string]string {
+	h := make(map[string]string)
+	if o.Language != "" {
+		h[ocrHeaderPrefix+"Language"] = o.Language
+	}
+	if o.PageSegMode > 0 {
+		h[ocrHeaderPrefix+"pageSegMode"] = strconv.Itoa(o.PageSegMode)
+	}
+	if o.Timeout > 0 {
+		h[ocrHeaderPrefix+"Timeout"] = strconv.FormatInt(int64((o.Timeout+time.Second-1)/time.Second), 10)
+	}
+	if o.Skip {
+		h[ocrHeaderPrefix+"skipOcr"] = "true"
+	}
+	return h
+}
+
+// WithOCR returns a RequestOption to configure the OCR of the request with o,
+// for example:
+//
+//	text, err := client.Parse(ctx, scan, tika.WithOCR(tika.OCROptions{Language: "deu", Timeout: time.Minute}))
+func WithOCR(o OCROptions) RequestOption {
+	return func(cfg *callConfig) {
+		for k, v := range o.headers() {
+			cfg.setRawHeader(k, v)
+		}
+	}
+}
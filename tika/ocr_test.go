@@ -0,0 +1,79 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithOCR(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer ts.Close()
+	var got http.Header
+	capture := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// The header names are checked before they go on the wire,
+			// where the server canonicalizes them.
+			got = req.Header
+			return next.RoundTrip(req)
+		})
+	}
+	c := NewClient(nil, ts.URL, WithMiddleware(capture))
+	tests := []struct {
+		name string
+		o    OCROptions
+		want http.Header
+	}{
+		{
+			name: "zero",
+			want: http.Header{},
+		},
+		{
+			name: "all",
+			o:    OCROptions{Language: "eng+fra", PageSegMode: 6, Timeout: 1500 * time.Millisecond, Skip: true},
+			want: http.Header{
+				"X-Tika-OCRLanguage":    {"eng+fra"},
+				"X-Tika-OCRpageSegMode": {"6"},
+				"X-Tika-OCRTimeout":     {"2"},
+				"X-Tika-OCRskipOcr":     {"true"},
+			},
+		},
+	}
+	for _, test := range tests {
+		got = nil
+		if _, err := c.Parse(context.Background(), nil, WithOCR(test.o)); err != nil {
+			t.Fatalf("Parse(%s) got error: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Parse(%s) sent header %v, want %v", test.name, got, test.want)
+		}
+		got = nil
+		if _, err := c.MetaRecursive(context.Background(), nil, WithOCR(test.o), WithResourceName("a.png")); err != nil {
+			t.Fatalf("MetaRecursive(%s) got error: %v", test.name, err)
+		}
+		if len(got) != len(test.want)+2 || got.Get("Accept") != "application/json" {
+			t.Errorf("MetaRecursive(%s) sent header %v, want the OCR headers, Content-Disposition and Accept", test.name, got)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A Section is an entry of the Outline of a document: a heading or a
+// bookmark, and the entries nested under it.
+type Section struct {
+	Title string
+	// Level is the depth of the Section, starting at 1, such as 2 for <h2>.
+	Level int
+	// Start and End are the byte offsets of the Section in the Text of the
+	// Outline, from its heading to the next Section of the same or a higher
+	// level. They are -1 for bookmarks not found in the text.
+	Start, End int
+	Children   []Section
+}
+
+// An Outline is the structure of a document, for navigation.
+type Outline struct {
+	// Text is the text of the document, rendered from its XHTML, which the
+	// offsets of the Sections refer to.
+	Text string
+	// Headings are the headings of the document, such as the heading styles
+	// of a Word document, nested by level.
+	Headings []Section
+	// Bookmarks are the bookmarks of the document, such as the outline of a
+	// PDF document.
+	Bookmarks []Section
+}
+
+// blockElements are the XHTML elements written on their own lines in the
+// Text of an Outline.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "table": true,
+	"ul": true, "ol": true, "pre": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// headingLevel returns the level of the heading element name, or 0.
+func headingLevel(name string) int {
+	if len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6' {
+		return int(name[1] - '0')
+	}
+	return 0
+}
+
+// outlineEntry is a heading or bookmark, before it is nested.
+type outlineEntry struct {
+	title string
+	level int
+	start int
+}
+
+// xhtmlWalker renders the XHTML of a document to text, with block elements
+// on their own lines and without the head of the document or its PDF
+// bookmarks, and calls its hooks with the elements and text, once its state
+// reflects them.
+type xhtmlWalker struct {
+	text strings.Builder
+	// stack are the names of the open elements.
+	stack []string
+	// page is the number of pages started, for documents with pages.
+	page           int
+	inHead, inPage bool
+	// listDepth is the nesting of the bookmark lists, 0 outside of them.
+	listDepth int
+
+	start func(name string, t xml.StartElement)
+	end   func(name string)
+	chars func(data []byte)
+}
+
+// newline ends the current line of the text, if any.
+func (w *xhtmlWalker) newline() {
+	if s := w.text.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		w.text.WriteByte('\n')
+	}
+}
+
+// walk walks the XHTML read from r.
+func (w *xhtmlWalker) walk(r io.Reader) error {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error parsing XHTML: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			w.stack = append(w.stack, name)
+			switch {
+			case name == "head":
+				w.inHead = true
+			case name == "div" && hasClass(t, "page"):
+				w.inPage = true
+				w.page++
+			case name == "ul" && (w.listDepth > 0 || w.page > 0 && !w.inPage && len(w.stack) >= 2 && w.stack[len(w.stack)-2] == "body"):
+				w.listDepth++
+			}
+			if blockElements[name] && w.listDepth == 0 {
+				w.newline()
+			}
+			if w.start != nil {
+				w.start(name, t)
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			for i := len(w.stack) - 1; i >= 0; i-- {
+				if w.stack[i] == name {
+					w.stack = w.stack[:i]
+					break
+				}
+			}
+			if w.end != nil {
+				w.end(name)
+			}
+			switch {
+			case name == "head":
+				w.inHead = false
+			case name == "div" && w.inPage && !stackHasPage(w.stack):
+				w.inPage = false
+			case name == "ul" && w.listDepth > 0:
+				w.listDepth--
+			}
+			if blockElements[name] && w.listDepth == 0 {
+				w.newline()
+			}
+		case xml.CharData:
+			if w.inHead {
+				continue
+			}
+			if w.chars != nil {
+				w.chars(t)
+			}
+			// Skip the whitespace between block elements.
+			if w.listDepth == 0 && !(len(strings.TrimSpace(string(t))) == 0 && (w.text.Len() == 0 || strings.HasSuffix(w.text.String(), "\n"))) {
+				w.text.Write(t)
+			}
+		}
+	}
+}
+
+// ParseOutline returns the Outline of the XHTML of a document, as returned by
+// Client.ParseHTML. PDF bookmarks are the nested lists Tika writes after the
+// pages of the document; they are not part of the Text.
+func ParseOutline(xhtml io.Reader) (*Outline, error) {
+	var w xhtmlWalker
+	var headings, bookmarks []outlineEntry
+	// title is the text of the heading or bookmark being read, the last of
+	// entries.
+	var title *strings.Builder
+	var entries *[]outlineEntry
+	endTitle := func() {
+		if title != nil {
+			(*entries)[len(*entries)-1].title = strings.Join(strings.Fields(title.String()), " ")
+			title = nil
+		}
+	}
+	w.start = func(name string, _ xml.StartElement) {
+		switch {
+		case name == "ul" && w.listDepth > 0:
+			// The title of a bookmark ends at its children.
+			endTitle()
+		case name == "li" && w.listDepth > 0:
+			bookmarks = append(bookmarks, outlineEntry{level: w.listDepth, start: -1})
+			title, entries = &strings.Builder{}, &bookmarks
+		case headingLevel(name) > 0 && w.listDepth == 0:
+			headings = append(headings, outlineEntry{level: headingLevel(name), start: w.text.Len()})
+			title, entries = &strings.Builder{}, &headings
+		}
+	}
+	w.end = func(name string) {
+		if name == "li" && w.listDepth > 0 || headingLevel(name) > 0 {
+			endTitle()
+		}
+	}
+	w.chars = func(data []byte) {
+		if title != nil {
+			title.Write(data)
+		}
+	}
+	if err := w.walk(xhtml); err != nil {
+		return nil, err
+	}
+	o := &Outline{Text: w.text.String()}
+	locateBookmarks(o.Text, headings, bookmarks)
+	o.Headings = nestSections(headings, len(o.Text))
+	o.Bookmarks = nestSections(bookmarks, len(o.Text))
+	return o, nil
+}
+
+func hasClass(t xml.StartElement, class string) bool {
+	for _, a := range t.Attr {
+		if a.Name.Local == "class" {
+			for _, c := range strings.Fields(a.Value) {
+				if c == class {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// stackHasPage reports whether a div of a page is still open in stack. Page
+// divs do not nest, and their inner divs are closed first, so a div left in
+// stack is the page.
+func stackHasPage(stack []string) bool {
+	for _, name := range stack {
+		if name == "div" {
+			return true
+		}
+	}
+	return false
+}
+
+// locateBookmarks sets the start of the bookmarks to the heading with the
+// same title, or else the first occurrence of their title in text after the
+// previous bookmark found, since bookmarks are in the order of the document.
+func locateBookmarks(text string, headings, bookmarks []outlineEntry) {
+	used := map[int]bool{}
+	from := 0
+	for i := range bookmarks {
+		b := &bookmarks[i]
+		if b.title == "" {
+			continue
+		}
+		for k, h := range headings {
+			if !used[k] && h.start >= from && strings.EqualFold(h.title, b.title) {
+				b.start, used[k] = h.start, true
+				break
+			}
+		}
+		if b.start < 0 {
+			if j := strings.Index(text[from:], b.title); j >= 0 {
+				b.start = from + j
+			}
+		}
+		if b.start >= 0 {
+			from = b.start
+		}
+	}
+}
+
+// nestSections returns the tree of entries, in order, whose Sections end at
+// the next entry of the same or a higher level with a start, or else at end.
+func nestSections(entries []outlineEntry, end int) []Section {
+	var build func(i, level int) ([]Section, int)
+	build = func(i, level int) ([]Section, int) {
+		var secs []Section
+		for i < len(entries) && entries[i].level >= level {
+			e := entries[i]
+			s := Section{Title: e.title, Level: e.level, Start: e.start, End: -1}
+			i++
+			s.Children, i = build(i, e.level+1)
+			if s.Start >= 0 {
+				s.End = end
+				for _, next := range entries[i:] {
+					if next.level <= e.level && next.start >= 0 {
+						s.End = next.start
+						break
+					}
+				}
+			}
+			secs = append(secs, s)
+		}
+		return secs, i
+	}
+	secs, _ := build(0, 1)
+	return secs
+}
+
+// Outline parses the given input and returns its Outline, from its XHTML.
+func (c *Client) Outline(ctx context.Context, input io.Reader, opts ...RequestOption) (*Outline, error) {
+	body, err := c.ParseReader(ctx, input, append(opts[:len(opts):len(opts)], WithFormat(FormatXML))...)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ParseOutline(body)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// wordXHTML is the XHTML of a Word document with headings, as returned by
+// Tika.
+const wordXHTML = `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>Report</title><meta name="dc:title" content="Report"/></head>
+<body><h1>Introduction</h1><p>Some &amp; text.</p>
+<h2>Scope</h2><p>In scope.</p>
+<h3>Details</h3><p>More.</p>
+<h2>Goals</h2><p>Goals.</p>
+<h1>Conclusion</h1><p>Done.</p>
+</body></html>`
+
+// pdfXHTML is the XHTML of a PDF document with bookmarks, as returned by
+// Tika.
+const pdfXHTML = `<html xmlns="http://www.w3.org/1999/xhtml"><head><title></title></head><body>
+<div class="page"><p>Chapter 1
+Getting started</p><ul><li>a list in the page</li></ul></div>
+<div class="page"><p>Installation steps</p><div class="annotation"><p>note</p></div></div>
+<div class="page"><p>Chapter 2
+Usage</p></div>
+<ul><li>Chapter 1<ul><li>Installation</li><li>Missing bookmark</li></ul></li><li>Chapter 2</li></ul>
+</body></html>`
+
+func TestParseOutlineHeadings(t *testing.T) {
+	o, err := ParseOutline(strings.NewReader(wordXHTML))
+	if err != nil {
+		t.Fatalf("ParseOutline got error: %v", err)
+	}
+	wantText := "Introduction\nSome & text.\nScope\nIn scope.\nDetails\nMore.\nGoals\nGoals.\nConclusion\nDone.\n"
+	if strings.TrimSpace(o.Text) != strings.TrimSpace(wantText) {
+		t.Errorf("Text = %q, want %q", o.Text, wantText)
+	}
+	var titles []string
+	var walk func(secs []Section, indent string)
+	walk = func(secs []Section, indent string) {
+		for _, s := range secs {
+			if !strings.HasPrefix(o.Text[s.Start:], s.Title) || s.End < s.Start {
+				t.Errorf("Section %q at [%d:%d] does not start at its heading", s.Title, s.Start, s.End)
+			}
+			titles = append(titles, fmt.Sprintf("%s%s %d", indent, s.Title, s.Level))
+			walk(s.Children, indent+"  ")
+		}
+	}
+	walk(o.Headings, "")
+	want := []string{"Introduction 1", "  Scope 2", "    Details 3", "  Goals 2", "Conclusion 1"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("Headings = %q, want %q", titles, want)
+	}
+	intro, conclusion := o.Headings[0], o.Headings[1]
+	if intro.End != conclusion.Start || conclusion.End != len(o.Text) {
+		t.Errorf("Introduction ends at %d and Conclusion spans [%d:%d], want them adjacent and to the end %d", intro.End, conclusion.Start, conclusion.End, len(o.Text))
+	}
+	if scope := intro.Children[0]; o.Text[scope.Start:scope.End] != "Scope\nIn scope.\nDetails\nMore.\n" {
+		t.Errorf("Scope section is %q", o.Text[scope.Start:scope.End])
+	}
+	if len(o.Bookmarks) != 0 {
+		t.Errorf("Bookmarks = %+v, want none", o.Bookmarks)
+	}
+}
+
+func TestParseOutlineBookmarks(t *testing.T) {
+	o, err := ParseOutline(strings.NewReader(pdfXHTML))
+	if err != nil {
+		t.Fatalf("ParseOutline got error: %v", err)
+	}
+	if strings.Contains(o.Text, "Missing bookmark") || !strings.Contains(o.Text, "a list in the page") || !strings.Contains(o.Text, "note") {
+		t.Errorf("Text = %q, want the pages without the bookmarks", o.Text)
+	}
+	ch1 := strings.Index(o.Text, "Chapter 1")
+	inst := strings.Index(o.Text, "Installation")
+	ch2 := strings.Index(o.Text, "Chapter 2")
+	want := []Section{
+		{Title: "Chapter 1", Level: 1, Start: ch1, End: ch2, Children: []Section{
+			{Title: "Installation", Level: 2, Start: inst, End: ch2},
+			{Title: "Missing bookmark", Level: 2, Start: -1, End: -1},
+		}},
+		{Title: "Chapter 2", Level: 1, Start: ch2, End: len(o.Text)},
+	}
+	if !reflect.DeepEqual(o.Bookmarks, want) {
+		t.Errorf("Bookmarks =\n%+v\nwant\n%+v", o.Bookmarks, want)
+	}
+	if len(o.Headings) != 0 {
+		t.Errorf("Headings = %+v, want none", o.Headings)
+	}
+}
+
+func TestParseOutlineError(t *testing.T) {
+	if _, err := ParseOutline(strings.NewReader("<html><body><h1>unterminated")); err == nil {
+		t.Errorf("ParseOutline of truncated XHTML got no error")
+	}
+}
+
+func TestNestSections(t *testing.T) {
+	entries := []outlineEntry{{"a", 2, 0}, {"b", 3, 5}, {"c", 1, 10}, {"d", 1, -1}}
+	got := nestSections(entries, 20)
+	want := []Section{
+		{Title: "a", Level: 2, Start: 0, End: 10, Children: []Section{{Title: "b", Level: 3, Start: 5, End: 10}}},
+		{Title: "c", Level: 1, Start: 10, End: 20},
+		{Title: "d", Level: 1, Start: -1, End: -1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("nestSections =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestClientOutline(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tika" || r.Header.Get("Accept") != string(FormatXML) {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, wordXHTML)
+	}))
+	defer ts.Close()
+	o, err := NewClient(nil, ts.URL).Outline(context.Background(), strings.NewReader("doc"))
+	if err != nil {
+		t.Fatalf("Outline got error: %v", err)
+	}
+	if len(o.Headings) != 2 || o.Headings[0].Title != "Introduction" {
+		t.Errorf("Outline got headings %+v", o.Headings)
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"strings"
+)
+
+// WithServerURL returns a RequestOption to send a single call to the Tika
+// Server at url, such as a specific backend of a pool or a sidecar, instead of
+// the URL of the Client. It overrides ContextWithServerURL. Everything else is
+// configured by the Client, including its middlewares.
+func WithServerURL(url string) RequestOption {
+	return func(cfg *callConfig) {
+		cfg.url = strings.TrimSuffix(url, "/")
+	}
+}
+
+type serverURLKey struct{}
+
+// ContextWithServerURL returns a copy of ctx sending the calls made with it to
+// the Tika Server at url instead of the URL of their Client, for example to
+// debug a single backend through code which makes several calls.
+func ContextWithServerURL(ctx context.Context, url string) context.Context {
+	return context.WithValue(ctx, serverURLKey{}, strings.TrimSuffix(url, "/"))
+}
+
+// serverURL returns the URL of the Tika Server a call is sent to.
+func (c *Client) serverURL(ctx context.Context, cfg *callConfig) string {
+	if cfg.url != "" {
+		return cfg.url
+	}
+	if u, ok := ctx.Value(serverURLKey{}).(string); ok && u != "" {
+		return u
+	}
+	return c.url
+}
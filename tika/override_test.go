@@ -0,0 +1,69 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerURL(t *testing.T) {
+	backend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}))
+	}
+	def, a, b := backend("default"), backend("a"), backend("b")
+	defer def.Close()
+	defer a.Close()
+	defer b.Close()
+
+	c := NewClient(nil, def.URL)
+	bg := context.Background()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		opts []RequestOption
+		want string
+	}{
+		{"default", bg, nil, "default"},
+		{"context", ContextWithServerURL(bg, a.URL), nil, "a"},
+		{"request", bg, []RequestOption{WithServerURL(b.URL + "/")}, "b"},
+		{"request over context", ContextWithServerURL(bg, a.URL), []RequestOption{WithServerURL(b.URL)}, "b"},
+	}
+	for _, test := range tests {
+		got, err := c.Parse(test.ctx, strings.NewReader("input"), test.opts...)
+		if err != nil {
+			t.Errorf("Parse(%s) got error: %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Parse(%s) got %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	d := NewClient(nil, def.URL, WithRequestDefaults(WithServerURL(a.URL)))
+	if got, err := d.Parse(bg, strings.NewReader("input")); err != nil || got != "a" {
+		t.Errorf("Parse with default server URL got %q, %v, want %q", got, err, "a")
+	}
+	if got, err := d.Parse(bg, strings.NewReader("input"), WithServerURL(b.URL)); err != nil || got != "b" {
+		t.Errorf("Parse overriding default server URL got %q, %v, want %q", got, err, "b")
+	}
+}
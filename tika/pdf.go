@@ -0,0 +1,94 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import "strconv"
+
+// pdfHeaderPrefix is the prefix of the headers setting the properties of the
+// PDFParserConfig of a request. Like ocrHeaderPrefix, it is case sensitive.
+const pdfHeaderPrefix = "X-Tika-PDF"
+
+// A PDFOCRStrategy is when the PDF parser runs OCR on the pages of a PDF.
+type PDFOCRStrategy string
+
+// PDFOCRStrategy values.
+const (
+	// PDFNoOCR extracts the text of the PDF only, the fastest.
+	PDFNoOCR PDFOCRStrategy = "no_ocr"
+	// PDFOCROnly runs OCR on every page, ignoring its text.
+	PDFOCROnly PDFOCRStrategy = "ocr_only"
+	// PDFOCRAndText extracts the text and runs OCR on every page.
+	PDFOCRAndText PDFOCRStrategy = "ocr_and_text"
+	// PDFOCRAuto runs OCR on the pages with little or no text, such as
+	// scanned pages. It needs Tika 1.21 or later.
+	PDFOCRAuto PDFOCRStrategy = "auto"
+)
+
+// PDFOptions configure the PDF parser for a single request, for example to
+// choose between fast text extraction and OCR of scanned PDFs. The zero
+// PDFOptions keeps the configuration of the server. The OCR itself is
+// configured by OCROptions.
+type PDFOptions struct {
+	// OCRStrategy is when pages are OCRed.
+	OCRStrategy PDFOCRStrategy
+	// OCRDPI is the resolution pages are rendered at for OCR, such as 300.
+	// Higher is more accurate and slower.
+	OCRDPI int
+	// ExtractInlineImages extracts the images of the PDF as embedded
+	// documents, so they are OCRed and returned by MetaRecursive.
+	ExtractInlineImages bool
+	// SortByPosition sorts the text by its position on the page rather than
+	// the order of the PDF, which fixes the text of some multi-column
+	// documents.
+	SortByPosition bool
+	// ExtractFontNames adds the names of the fonts used by the PDF to its
+	// metadata, as font:name; see Client.Resources.
+	ExtractFontNames bool
+}
+
+// headers returns the headers setting o.
+func (o PDFOptions) headers() map[string]string {
+	h := make(map[string]string)
+	if o.OCRStrategy != "" {
+		h[pdfHeaderPrefix+"OcrStrategy"] = string(o.OCRStrategy)
+	}
+	if o.OCRDPI > 0 {
+		h[pdfHeaderPrefix+"ocrDPI"] = strconv.Itoa(o.OCRDPI)
+	}
+	if o.ExtractInlineImages {
+		h[pdfHeaderPrefix+"extractInlineImages"] = "true"
+	}
+	if o.SortByPosition {
+		h[pdfHeaderPrefix+"sortByPosition"] = "true"
+	}
+	if o.ExtractFontNames {
+		h[pdfHeaderPrefix+"extractFontNames"] = "true"
+	}
+	return h
+}
+
+// WithPDF returns a RequestOption to configure the PDF parser for the request
+// with o, for example to OCR the scanned pages of PDFs only:
+//
+//	text, err := client.Parse(ctx, pdf, tika.WithPDF(tika.PDFOptions{OCRStrategy: tika.PDFOCRAuto}))
+func WithPDF(o PDFOptions) RequestOption {
+	return func(cfg *callConfig) {
+		for k, v := range o.headers() {
+			cfg.setRawHeader(k, v)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithPDF(t *testing.T) {
+	tests := []struct {
+		name string
+		o    PDFOptions
+		want map[string][]string
+	}{
+		{
+			name: "zero",
+		},
+		{
+			name: "all",
+			o:    PDFOptions{OCRStrategy: PDFOCRAuto, OCRDPI: 300, ExtractInlineImages: true, SortByPosition: true, ExtractFontNames: true},
+			want: map[string][]string{
+				"X-Tika-PDFOcrStrategy":         {"auto"},
+				"X-Tika-PDFocrDPI":              {"300"},
+				"X-Tika-PDFextractInlineImages": {"true"},
+				"X-Tika-PDFsortByPosition":      {"true"},
+				"X-Tika-PDFextractFontNames":    {"true"},
+			},
+		},
+	}
+	for _, test := range tests {
+		cfg := newCallConfig([]RequestOption{WithPDF(test.o)})
+		got := map[string][]string(cfg.header)
+		if len(got) == 0 && len(test.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("WithPDF(%s) set header %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBuffer is the capacity past which buffers are not returned to the
+// pool, so a rare huge response does not stay in memory.
+const maxPooledBuffer = 16 << 20
+
+// copyBufferSize is the size of the buffers copying streamed responses.
+const copyBufferSize = 32 << 10
+
+// bufferPool holds the *bytes.Buffers reading responses, reused across calls
+// to spare the garbage collector at high throughput.
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// copyBufferPool holds the *[]byte buffers copying streamed responses.
+var copyBufferPool = sync.Pool{New: func() interface{} {
+	b := make([]byte, copyBufferSize)
+	return &b
+}}
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// putBuffer returns b to the pool, unless it is nil or too large.
+func putBuffer(b *bytes.Buffer) {
+	if b == nil || b.Cap() > maxPooledBuffer {
+		return
+	}
+	b.Reset()
+	bufferPool.Put(b)
+}
+
+func getCopyBuffer() *[]byte {
+	return copyBufferPool.Get().(*[]byte)
+}
+
+func putCopyBuffer(b *[]byte) {
+	copyBufferPool.Put(b)
+}
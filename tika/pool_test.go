@@ -0,0 +1,137 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPutBuffer(t *testing.T) {
+	b := getBuffer()
+	b.WriteString("abc")
+	putBuffer(b)
+	putBuffer(nil)
+	large := bytes.NewBuffer(make([]byte, 0, maxPooledBuffer+1))
+	putBuffer(large)
+	for i := 0; i < 10; i++ {
+		if b := getBuffer(); b.Len() != 0 || b == large {
+			t.Fatalf("getBuffer returned a buffer with %d bytes, or a buffer too large to pool", b.Len())
+		}
+	}
+}
+
+func TestPooledResponses(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/rmeta/text" {
+			fmt.Fprintf(w, `[{"X-TIKA:content": %q}]`, body)
+			return
+		}
+		w.Write(bytes.Repeat(body, 1000))
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			in := fmt.Sprintf("<%d>", i)
+			for k := 0; k < 10; k++ {
+				got, err := c.Parse(context.Background(), strings.NewReader(in))
+				if err != nil || got != strings.Repeat(in, 1000) {
+					t.Errorf("Parse(%s) = %.20q..., %v", in, got, err)
+					return
+				}
+				docs, err := c.MetaRecursive(context.Background(), strings.NewReader(in))
+				if err != nil || len(docs) != 1 || docs[0][XTIKAContent][0] != in {
+					t.Errorf("MetaRecursive(%s) = %v, %v", in, docs, err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// largeServer returns a server responding with size bytes of text to /tika,
+// and a document with as much content to /rmeta/text.
+func largeServer(size int) *httptest.Server {
+	text := strings.Repeat("lorem ipsum ", size/12)
+	rmeta := fmt.Sprintf(`[{"Content-Type": "text/plain", "X-TIKA:content": %q}]`, text)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/rmeta/text" {
+			fmt.Fprint(w, rmeta)
+			return
+		}
+		fmt.Fprint(w, text)
+	}))
+}
+
+func BenchmarkParse(b *testing.B) {
+	ts := largeServer(1 << 20)
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	b.SetBytes(1 << 20)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := c.Parse(context.Background(), nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkParseTo(b *testing.B) {
+	ts := largeServer(1 << 20)
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	b.SetBytes(1 << 20)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := c.ParseTo(context.Background(), nil, ioutil.Discard); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMetaRecursive(b *testing.B) {
+	ts := largeServer(1 << 20)
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	b.SetBytes(1 << 20)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := c.MetaRecursive(context.Background(), nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
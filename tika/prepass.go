@@ -0,0 +1,280 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+)
+
+// A ManifestEntry is an Input with the hash and MIME type of its content, as
+// listed by a Prepass.
+type ManifestEntry struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	// SHA256 is the hex encoded SHA-256 of the content.
+	SHA256 string `json:"sha256,omitempty"`
+	// ContentType is the detected MIME type.
+	ContentType string `json:"contentType,omitempty"`
+	// Err is the error reading or detecting the Input, if any.
+	Err string `json:"error,omitempty"`
+}
+
+// Input returns the Input of e.
+func (e ManifestEntry) Input() Input {
+	return Input{ID: e.ID, Name: e.Name, Size: e.Size, ModTime: e.ModTime}
+}
+
+// DefaultDetectBytes is the number of bytes of each Input detected by a
+// Prepass with no DetectBytes.
+const DefaultDetectBytes = 1 << 20
+
+// A Prepass hashes and detects the type of every Input of a Source
+// concurrently, much faster than extracting them, to produce a Manifest used
+// to plan the extraction: to estimate its cost, skip duplicates, or split it
+// into balanced shards run by several workers or machines.
+type Prepass struct {
+	// Source provides the Inputs.
+	Source Source
+	// Client detects the types on the Tika Server, from the first bytes of
+	// each Input and its name. If Client is nil, the types are detected
+	// locally from the extension of the name, or else the first bytes, which
+	// is faster but only knows common types.
+	Client *Client
+	// DetectBytes is the number of bytes of each Input used for detection.
+	// DefaultDetectBytes is used if it is 0.
+	DetectBytes int
+	// Workers is the number of Inputs processed concurrently. 1 is used if
+	// it is less than 1.
+	Workers int
+}
+
+// Run processes every Input of p.Source and calls fn with their
+// ManifestEntry, one call at a time, in no particular order. Errors reading or
+// detecting an Input are recorded in its entry. Run stops at the first error
+// listing the Inputs or returned by fn.
+func (p *Prepass) Run(ctx context.Context, fn func(ManifestEntry) error) error {
+	accept := func(in Input) bool { return !in.Deleted }
+	listErr, err := fanOut(ctx, p.Source, p.Workers, accept, func(ctx context.Context, in Input) func() error {
+		e := p.process(ctx, in)
+		return func() error { return fn(e) }
+	})
+	if listErr != nil {
+		return fmt.Errorf("error listing inputs: %w", listErr)
+	}
+	return err
+}
+
+// Manifest runs p and returns the Manifest of the Inputs, sorted by ID.
+func (p *Prepass) Manifest(ctx context.Context) (Manifest, error) {
+	var m Manifest
+	err := p.Run(ctx, func(e ManifestEntry) error {
+		m = append(m, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(m, func(i, j int) bool { return m[i].ID < m[j].ID })
+	return m, nil
+}
+
+// process hashes and detects in.
+func (p *Prepass) process(ctx context.Context, in Input) ManifestEntry {
+	e := ManifestEntry{ID: in.ID, Name: in.Name, Size: in.Size, ModTime: in.ModTime}
+	rc, err := p.Source.Open(ctx, in.ID)
+	if err != nil {
+		e.Err = err.Error()
+		return e
+	}
+	defer rc.Close()
+	n := p.DetectBytes
+	if n <= 0 {
+		n = DefaultDetectBytes
+	}
+	h := sha256.New()
+	head := make([]byte, n)
+	m, err := io.ReadFull(io.TeeReader(rc, h), head)
+	head = head[:m]
+	if err == nil {
+		_, err = io.Copy(h, rc)
+	} else if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = nil
+	}
+	if err != nil {
+		e.Err = err.Error()
+		return e
+	}
+	e.SHA256 = hex.EncodeToString(h.Sum(nil))
+	if p.Client == nil {
+		e.ContentType = detectLocal(in.Name, head)
+		return e
+	}
+	var opts []RequestOption
+	if in.Name != "" {
+		opts = append(opts, WithResourceName(in.Name))
+	}
+	if e.ContentType, err = p.Client.Detect(ctx, bytes.NewReader(head), opts...); err != nil {
+		e.Err = err.Error()
+	}
+	return e
+}
+
+// detectLocal detects the type of the file named name starting with head.
+func detectLocal(name string, head []byte) string {
+	if t := typeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+	return http.DetectContentType(head)
+}
+
+// A Manifest lists the Inputs of a Source, as produced by a Prepass.
+type Manifest []ManifestEntry
+
+// WriteJSON writes m to w as JSON lines, one entry per line.
+func (m Manifest) WriteJSON(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, e := range m {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadManifest reads a Manifest written by Manifest.WriteJSON.
+func ReadManifest(r io.Reader) (Manifest, error) {
+	var m Manifest
+	dec := json.NewDecoder(r)
+	for {
+		var e ManifestEntry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return m, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading manifest: %w", err)
+		}
+		m = append(m, e)
+	}
+}
+
+// Unique returns the entries of m without those with the same content as a
+// previous entry, so duplicate files are only extracted once. Entries which
+// could not be read are kept.
+func (m Manifest) Unique() Manifest {
+	seen := make(map[string]bool)
+	var r Manifest
+	for _, e := range m {
+		if e.SHA256 != "" {
+			if seen[e.SHA256] {
+				continue
+			}
+			seen[e.SHA256] = true
+		}
+		r = append(r, e)
+	}
+	return r
+}
+
+// Shards splits m into n shards of about the same total size, for example to
+// extract each shard on its own machine. Entries with the same content are in
+// the same shard. The entries of each shard keep their order in m.
+func (m Manifest) Shards(n int) []Manifest {
+	if n < 1 {
+		n = 1
+	}
+	// Group the entries by content, and assign the largest groups first to
+	// the smallest shard.
+	type group struct {
+		entries []int
+		size    int64
+	}
+	var groups []*group
+	byHash := make(map[string]*group)
+	for i, e := range m {
+		g := byHash[e.SHA256]
+		if g == nil || e.SHA256 == "" {
+			g = &group{}
+			groups = append(groups, g)
+			if e.SHA256 != "" {
+				byHash[e.SHA256] = g
+			}
+		}
+		g.entries = append(g.entries, i)
+		if e.Size > 0 {
+			g.size += e.Size
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].size > groups[j].size })
+	sizes := make([]int64, n)
+	shardOf := make([]int, len(m))
+	for _, g := range groups {
+		s := 0
+		for i := range sizes {
+			if sizes[i] < sizes[s] {
+				s = i
+			}
+		}
+		sizes[s] += g.size
+		for _, i := range g.entries {
+			shardOf[i] = s
+		}
+	}
+	shards := make([]Manifest, n)
+	for i, e := range m {
+		shards[shardOf[i]] = append(shards[shardOf[i]], e)
+	}
+	return shards
+}
+
+// Source returns a Source of the Inputs of m, opened from src, for example to
+// run a Job on a shard of a Manifest.
+func (m Manifest) Source(src Source) Source {
+	return &manifestSource{Source: src, m: m}
+}
+
+type manifestSource struct {
+	Source
+	m Manifest
+}
+
+// Walk implements Source.
+func (s *manifestSource) Walk(ctx context.Context, fn func(Input) error) error {
+	for _, e := range s.m {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(e.Input()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func prepassFS() fstest.MapFS {
+	return fstest.MapFS{
+		"a.txt":     {Data: []byte("hello")},
+		"dir/b.txt": {Data: []byte("hello")},
+		"c.csv":     {Data: []byte("a,b\n1,2\n")},
+		"image":     {Data: []byte("\x89PNG\r\n\x1a\n" + strings.Repeat("x", 100))},
+		"large":     {Data: []byte(strings.Repeat("large ", 1000))},
+	}
+}
+
+func TestPrepassLocal(t *testing.T) {
+	fsys := prepassFS()
+	p := &Prepass{Source: NewFSSource(fsys), Workers: 3, DetectBytes: 16}
+	m, err := p.Manifest(context.Background())
+	if err != nil {
+		t.Fatalf("Manifest got error: %v", err)
+	}
+	wantTypes := map[string]string{
+		"a.txt":     "text/plain",
+		"dir/b.txt": "text/plain",
+		"c.csv":     "text/csv",
+		"image":     "image/png",
+		"large":     "text/plain; charset=utf-8",
+	}
+	var ids []string
+	for _, e := range m {
+		ids = append(ids, e.ID)
+		if e.Err != "" {
+			t.Errorf("%s: got error %s", e.ID, e.Err)
+		}
+		if want := fmt.Sprintf("%x", sha256.Sum256(fsys[e.ID].Data)); e.SHA256 != want {
+			t.Errorf("%s: SHA256 got %s, want %s", e.ID, e.SHA256, want)
+		}
+		if e.ContentType != wantTypes[e.ID] {
+			t.Errorf("%s: ContentType got %q, want %q", e.ID, e.ContentType, wantTypes[e.ID])
+		}
+		if e.Size != int64(len(fsys[e.ID].Data)) {
+			t.Errorf("%s: Size got %d, want %d", e.ID, e.Size, len(fsys[e.ID].Data))
+		}
+	}
+	if want := []string{"a.txt", "c.csv", "dir/b.txt", "image", "large"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Manifest got %v, want %v", ids, want)
+	}
+}
+
+func TestPrepassServer(t *testing.T) {
+	var maxBody int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if len(body) > maxBody {
+			maxBody = len(body)
+		}
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Disposition"))
+		if params["filename"] == "image" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, "type/%s", params["filename"])
+	}))
+	defer ts.Close()
+	p := &Prepass{Source: NewFSSource(prepassFS()), Client: NewClient(nil, ts.URL), DetectBytes: 100}
+	m, err := p.Manifest(context.Background())
+	if err != nil {
+		t.Fatalf("Manifest got error: %v", err)
+	}
+	for _, e := range m {
+		switch {
+		case e.ID == "image":
+			if e.Err == "" || e.SHA256 == "" {
+				t.Errorf("%s: got %+v, want an error and a hash", e.ID, e)
+			}
+		case e.ContentType != "type/"+e.Input().Name:
+			t.Errorf("%s: ContentType got %q, want %q", e.ID, e.ContentType, "type/"+e.Input().Name)
+		}
+	}
+	if maxBody > 100 {
+		t.Errorf("server got %d bytes, want at most DetectBytes", maxBody)
+	}
+}
+
+func TestPrepassStop(t *testing.T) {
+	stop := errors.New("stop")
+	p := &Prepass{Source: NewFSSource(prepassFS()), Workers: 2}
+	n := 0
+	err := p.Run(context.Background(), func(ManifestEntry) error {
+		n++
+		return stop
+	})
+	if err != stop {
+		t.Errorf("Run got error %v, want %v", err, stop)
+	}
+	if n != 1 {
+		t.Errorf("Run called fn %d times after an error, want 1", n)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	m := Manifest{
+		{ID: "a", Size: 10, SHA256: "1"},
+		{ID: "b", Size: 10, SHA256: "1"},
+		{ID: "c", Size: 15, SHA256: "2"},
+		{ID: "d", Size: 5, SHA256: "3"},
+		{ID: "e", Size: 4, Err: "unreadable"},
+		{ID: "f", Size: 1, Err: "unreadable"},
+	}
+
+	ids := func(m Manifest) []string {
+		var r []string
+		for _, e := range m {
+			r = append(r, e.ID)
+		}
+		return r
+	}
+	if got, want := ids(m.Unique()), []string{"a", "c", "d", "e", "f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unique got %v, want %v", got, want)
+	}
+
+	shards := m.Shards(2)
+	var got [][]string
+	for _, s := range shards {
+		got = append(got, ids(s))
+	}
+	// The duplicates a and b (20 bytes) go together, then c (15), d (5),
+	// e (4) and f (1) each go to the smallest shard, or the first one.
+	if want := [][]string{{"a", "b", "e"}, {"c", "d", "f"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Shards(2) got %v, want %v", got, want)
+	}
+	if got := m.Shards(0); len(got) != 1 || len(got[0]) != len(m) {
+		t.Errorf("Shards(0) got %v, want one shard", got)
+	}
+
+	var buf bytes.Buffer
+	if err := m.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON got error: %v", err)
+	}
+	read, err := ReadManifest(&buf)
+	if err != nil {
+		t.Fatalf("ReadManifest got error: %v", err)
+	}
+	if !reflect.DeepEqual(read, m) {
+		t.Errorf("ReadManifest got %v, want %v", read, m)
+	}
+	if _, err := ReadManifest(strings.NewReader("{")); err == nil {
+		t.Error("ReadManifest of a truncated manifest got no error")
+	}
+
+	fsys := prepassFS()
+	src := Manifest{{ID: "c.csv"}, {ID: "large"}}.Source(NewFSSource(fsys))
+	var walked []string
+	if err := src.Walk(context.Background(), func(in Input) error {
+		walked = append(walked, in.ID)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk got error: %v", err)
+	}
+	if want := []string{"c.csv", "large"}; !reflect.DeepEqual(walked, want) {
+		t.Errorf("Walk got %v, want %v", walked, want)
+	}
+	rc, err := src.Open(context.Background(), "c.csv")
+	if err != nil {
+		t.Fatalf("Open got error: %v", err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != string(fsys["c.csv"].Data) {
+		t.Errorf("Open got %q, want %q", b, fsys["c.csv"].Data)
+	}
+}
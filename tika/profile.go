@@ -0,0 +1,61 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// The stages of a Job, used as the "stage" pprof label of its goroutines and
+// as the names of its trace regions.
+const (
+	stageList       = "list"
+	stageWait       = "wait" // Waiting for a TypeLimits slot.
+	stageCheckpoint = "checkpoint"
+	stageExtract    = "extract"
+	stageEmit       = "emit"
+)
+
+// stage calls fn with the goroutine labelled with the name of j, the MIME
+// type mimeType, if not empty, and the stage name. With j.Trace, fn runs in a
+// trace region named after the stage.
+func (j *Job) stage(ctx context.Context, mimeType, name string, fn func(context.Context)) {
+	labels := pprof.Labels("job", j.Name, "stage", name)
+	if mimeType != "" {
+		labels = pprof.Labels("job", j.Name, "mime", mimeType, "stage", name)
+	}
+	pprof.Do(ctx, labels, func(ctx context.Context) {
+		if j.Trace {
+			trace.WithRegion(ctx, name, func() { fn(ctx) })
+			return
+		}
+		fn(ctx)
+	})
+}
+
+// task returns ctx within a trace task for the input in, if j.Trace, and the
+// function ending the task.
+func (j *Job) task(ctx context.Context, in Input) (context.Context, func()) {
+	if !j.Trace {
+		return ctx, func() {}
+	}
+	ctx, task := trace.NewTask(ctx, "tika.Document")
+	trace.Log(ctx, "id", in.ID)
+	return ctx, task.End
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"runtime/pprof"
+	"runtime/trace"
+	"testing"
+)
+
+func TestJobLabels(t *testing.T) {
+	ts := rmetaServer()
+	defer ts.Close()
+	j, _ := testJob(ts, map[string]string{"a.txt": "a"})
+	var got map[string]string
+	j.Emit = func(ctx context.Context, _ Document) error {
+		got = map[string]string{}
+		pprof.ForLabels(ctx, func(k, v string) bool {
+			got[k] = v
+			return true
+		})
+		return nil
+	}
+	if err := j.Run(context.Background()); err != nil {
+		t.Fatalf("Run got error: %v", err)
+	}
+	want := map[string]string{"job": "test", "mime": "text/plain", "stage": stageEmit}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Emit labels = %v, want %v", got, want)
+	}
+}
+
+func TestJobTrace(t *testing.T) {
+	ts := rmetaServer()
+	defer ts.Close()
+	j, _ := testJob(ts, map[string]string{"a.txt": "a"})
+	j.Trace = true
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Skipf("cannot start tracing: %v", err)
+	}
+	err := j.Run(context.Background())
+	trace.Stop()
+	if err != nil {
+		t.Fatalf("Run got error: %v", err)
+	}
+	for _, s := range []string{"tika.Document", "a.txt", stageExtract, stageEmit} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("trace does not contain %q", s)
+		}
+	}
+}
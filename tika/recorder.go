@@ -0,0 +1,198 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Headers added to the responses recorded by a ResponseRecorder.
+const (
+	// RecordedRequestHeader is the method and path of the request.
+	RecordedRequestHeader = "X-Tika-Recorded-Request"
+	// RecordedResourceHeader is the file name of the document, as passed to
+	// WithResourceName.
+	RecordedResourceHeader = "X-Tika-Recorded-Resource"
+	// RecordedTruncatedHeader is "true" if the body was cut at MaxBody.
+	RecordedTruncatedHeader = "X-Tika-Recorded-Truncated"
+)
+
+// ResponseRecorder is an http.RoundTripper that saves the raw responses of a
+// sampled fraction of requests, headers and body, so extraction anomalies can
+// be investigated after the fact without parsing the documents again. Use it
+// as the Transport of the http.Client passed to NewClient:
+//
+//	rec := &tika.ResponseRecorder{Dir: "/var/tmp/tika-responses", Rate: 0.01}
+//	client := tika.NewClient(&http.Client{Transport: rec}, url)
+//
+// Each response is saved to its own file in Dir, once its body is closed, and
+// can be read back with ReadRecordedResponse.
+//
+// A ResponseRecorder is safe for concurrent use. Do not change its fields once
+// it is in use.
+type ResponseRecorder struct {
+	// Transport makes the requests. If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+	// Dir is the directory the responses are saved to. It must exist.
+	Dir string
+	// Rate is the probability, between 0 and 1, of recording a response.
+	Rate float64
+	// MaxBody is the number of bytes of the body saved. Zero means 1 MiB.
+	MaxBody int64
+	// Keys, if not nil, encrypts the saved responses, as by
+	// WriteFileEncrypted, since they hold the content of the documents.
+	Keys KeyProvider
+	// Rand is the source of randomness. If nil, a source seeded with the
+	// current time is used.
+	Rand *rand.Rand
+	// OnError, if not nil, is called with the errors saving responses, which
+	// do not fail the requests.
+	OnError func(error)
+
+	once     sync.Once
+	mu       sync.Mutex // mu guards Rand.
+	seq      int64
+	recorded int64
+}
+
+// Recorded returns the number of responses saved so far.
+func (rr *ResponseRecorder) Recorded() int64 {
+	return atomic.LoadInt64(&rr.recorded)
+}
+
+func (rr *ResponseRecorder) sample() bool {
+	rr.once.Do(func() {
+		if rr.Rand == nil {
+			rr.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+		}
+	})
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	return rr.Rand.Float64() < rr.Rate
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rr *ResponseRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	t := rr.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	resp, err := t.RoundTrip(req)
+	if err != nil || !rr.sample() {
+		return resp, err
+	}
+	max := rr.MaxBody
+	if max <= 0 {
+		max = 1 << 20
+	}
+	resp.Body = &recordingBody{rc: resp.Body, rr: rr, resp: resp, max: max}
+	return resp, nil
+}
+
+// recordingBody is the body of a recorded response, which keeps the first max
+// bytes read and saves the response when closed.
+type recordingBody struct {
+	rc        io.ReadCloser
+	rr        *ResponseRecorder
+	resp      *http.Response
+	max       int64
+	buf       bytes.Buffer
+	truncated bool
+	once      sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	if keep := b.max - int64(b.buf.Len()); keep < int64(n) {
+		b.buf.Write(p[:keep])
+		b.truncated = true
+	} else {
+		b.buf.Write(p[:n])
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.rc.Close()
+	b.once.Do(func() {
+		if serr := b.save(); serr != nil && b.rr.OnError != nil {
+			b.rr.OnError(serr)
+		}
+	})
+	return err
+}
+
+// save writes the response, with the part of the body read, to a new file.
+func (b *recordingBody) save() error {
+	h := b.resp.Header.Clone()
+	if h == nil {
+		h = make(http.Header)
+	}
+	req := b.resp.Request
+	if req != nil {
+		h.Set(RecordedRequestHeader, req.Method+" "+req.URL.Path)
+		if _, params, err := mime.ParseMediaType(req.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+			h.Set(RecordedResourceHeader, params["filename"])
+		}
+	}
+	if b.truncated {
+		h.Set(RecordedTruncatedHeader, "true")
+	}
+	resp := &http.Response{
+		Status:        b.resp.Status,
+		StatusCode:    b.resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          ioutil.NopCloser(bytes.NewReader(b.buf.Bytes())),
+		ContentLength: int64(b.buf.Len()),
+	}
+	var raw bytes.Buffer
+	if err := resp.Write(&raw); err != nil {
+		return fmt.Errorf("error recording response: %w", err)
+	}
+	seq := atomic.AddInt64(&b.rr.seq, 1)
+	name := fmt.Sprintf("%s-%06d.http", time.Now().UTC().Format("20060102T150405.000000000"), seq)
+	if err := WriteFileEncrypted(context.Background(), b.rr.Keys, filepath.Join(b.rr.Dir, name), raw.Bytes(), 0600); err != nil {
+		return fmt.Errorf("error recording response: %w", err)
+	}
+	atomic.AddInt64(&b.rr.recorded, 1)
+	return nil
+}
+
+// ReadRecordedResponse reads a response saved by a ResponseRecorder with the
+// given keys. Its body is fully read, so it need not be closed.
+func ReadRecordedResponse(ctx context.Context, keys KeyProvider, path string) (*http.Response, error) {
+	data, err := ReadFileEncrypted(ctx, keys, path)
+	if err != nil {
+		return nil, err
+	}
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+}
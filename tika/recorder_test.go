@@ -0,0 +1,92 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Parsed-By", "test")
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) == "fail" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		fmt.Fprint(w, strings.Repeat(string(body), 10))
+	}))
+	defer ts.Close()
+
+	for _, keys := range []KeyProvider{nil, testKey} {
+		dir := tempDir(t)
+		rr := &ResponseRecorder{Dir: dir, Rate: 1, MaxBody: 20, Keys: keys, Rand: rand.New(rand.NewSource(1))}
+		rr.OnError = func(err error) { t.Errorf("ResponseRecorder got error: %v", err) }
+		c := NewClient(&http.Client{Transport: rr}, ts.URL)
+		if _, err := c.Parse(context.Background(), strings.NewReader("abc"), WithResourceName("日本.txt")); err != nil {
+			t.Fatalf("Parse got error: %v", err)
+		}
+		if _, err := c.Parse(context.Background(), strings.NewReader("fail")); err == nil {
+			t.Fatalf("Parse got no error, want an error")
+		}
+		files, err := filepath.Glob(filepath.Join(dir, "*.http"))
+		if err != nil || len(files) != 2 || rr.Recorded() != 2 {
+			t.Fatalf("ResponseRecorder saved %v (%d), want 2 files", files, rr.Recorded())
+		}
+
+		resp, err := ReadRecordedResponse(context.Background(), keys, files[0])
+		if err != nil {
+			t.Fatalf("ReadRecordedResponse got error: %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		if string(body) != strings.Repeat("abc", 10)[:20] || resp.Header.Get(RecordedTruncatedHeader) != "true" {
+			t.Errorf("recorded body %q, truncated %q, want the first 20 bytes", body, resp.Header.Get(RecordedTruncatedHeader))
+		}
+		for k, want := range map[string]string{
+			"X-Parsed-By":          "test",
+			RecordedRequestHeader:  "PUT /tika",
+			RecordedResourceHeader: "日本.txt",
+		} {
+			if got := resp.Header.Get(k); got != want {
+				t.Errorf("recorded header %s = %q, want %q", k, got, want)
+			}
+		}
+		if resp, err := ReadRecordedResponse(context.Background(), keys, files[1]); err != nil || resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("ReadRecordedResponse of the failure = %v, %v, want status 422", resp, err)
+		}
+		if raw, _ := ioutil.ReadFile(files[0]); keys != nil && bytes.Contains(raw, []byte("abc")) {
+			t.Errorf("ResponseRecorder with keys wrote plaintext %q", raw)
+		}
+	}
+
+	rr := &ResponseRecorder{Dir: tempDir(t), Rate: 0}
+	c := NewClient(&http.Client{Transport: rr}, ts.URL)
+	if _, err := c.Parse(context.Background(), strings.NewReader("abc")); err != nil {
+		t.Fatalf("Parse got error: %v", err)
+	}
+	if rr.Recorded() != 0 {
+		t.Errorf("ResponseRecorder with rate 0 recorded %d responses", rr.Recorded())
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Credentials authenticate a connection to a remote Source.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// A CredentialsProvider provides Credentials when a remote Source connects,
+// so they can be rotated or fetched from a secret store.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// StaticCredentials is a CredentialsProvider always providing the same
+// Credentials.
+type StaticCredentials Credentials
+
+// Credentials implements CredentialsProvider.
+func (c StaticCredentials) Credentials(context.Context) (Credentials, error) {
+	return Credentials(c), nil
+}
+
+// EnvCredentials is a CredentialsProvider reading Credentials from the
+// environment variables named after it: for example, with EnvCredentials("IMAP"),
+// IMAP_USERNAME and IMAP_PASSWORD.
+type EnvCredentials string
+
+// Credentials implements CredentialsProvider. It returns an error if the
+// username is not set.
+func (e EnvCredentials) Credentials(context.Context) (Credentials, error) {
+	p := string(e) + "_"
+	c := Credentials{
+		Username: os.Getenv(p + "USERNAME"),
+		Password: os.Getenv(p + "PASSWORD"),
+	}
+	if c.Username == "" {
+		return c, fmt.Errorf("%sUSERNAME is not set", p)
+	}
+	return c, nil
+}
+
+// remoteConn is the connection of a remote Source, such as a Mailbox. It
+// connects when first used, and is dropped by reset after a failed operation
+// so the next one reconnects with fresh Credentials.
+type remoteConn struct {
+	creds CredentialsProvider
+	dial  func(context.Context, Credentials) (io.Closer, error)
+
+	mu   sync.Mutex
+	conn io.Closer
+}
+
+// connect returns the current connection, connecting if needed.
+func (c *remoteConn) connect(ctx context.Context) (io.Closer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		return c.conn, nil
+	}
+	creds, err := c.creds.Credentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting credentials: %w", err)
+	}
+	conn, err := c.dial(ctx, creds)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting: %w", err)
+	}
+	c.conn = conn
+	return conn, nil
+}
+
+// reset drops conn, if it is still the current connection.
+func (c *remoteConn) reset(conn io.Closer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == conn {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// Close closes the connection, if any.
+func (c *remoteConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
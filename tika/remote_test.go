@@ -0,0 +1,78 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+)
+
+// closer counts its Close calls.
+type closer struct{ closed int }
+
+func (c *closer) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestRemoteConn(t *testing.T) {
+	var dials []Credentials
+	var conns []*closer
+	rc := &remoteConn{creds: StaticCredentials{Username: "u", Password: "p"}, dial: func(_ context.Context, c Credentials) (io.Closer, error) {
+		dials = append(dials, c)
+		conns = append(conns, &closer{})
+		return conns[len(conns)-1], nil
+	}}
+	ctx := context.Background()
+	first, err := rc.connect(ctx)
+	if err != nil {
+		t.Fatalf("connect got error: %v", err)
+	}
+	if again, _ := rc.connect(ctx); again != first || len(dials) != 1 || dials[0].Username != "u" {
+		t.Errorf("dialed with %+v, want one connection as u", dials)
+	}
+	rc.reset(first)
+	rc.reset(first)
+	if conns[0].closed != 1 {
+		t.Errorf("connection closed %d times after a failure, want once", conns[0].closed)
+	}
+	if again, _ := rc.connect(ctx); again == first || len(dials) != 2 {
+		t.Errorf("dialed %d times, want a reconnection after a failure", len(dials))
+	}
+	if err := rc.Close(); err != nil || conns[1].closed != 1 {
+		t.Errorf("Close got error %v and closed the connection %d times", err, conns[1].closed)
+	}
+}
+
+func TestEnvCredentials(t *testing.T) {
+	os.Unsetenv("TIKA_TEST_USERNAME")
+	rc := &remoteConn{creds: EnvCredentials("TIKA_TEST"), dial: func(context.Context, Credentials) (io.Closer, error) {
+		t.Fatal("dialed without credentials")
+		return nil, nil
+	}}
+	if _, err := rc.connect(context.Background()); err == nil {
+		t.Errorf("connect without credentials got no error")
+	}
+
+	os.Setenv("TIKA_TEST_USERNAME", "u")
+	defer os.Unsetenv("TIKA_TEST_USERNAME")
+	if c, err := EnvCredentials("TIKA_TEST").Credentials(context.Background()); err != nil || c.Username != "u" {
+		t.Errorf("EnvCredentials got %+v, %v, want username u", c, err)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// WithPortReservation returns an Option to make Start reserve the port of the
+// Server before starting the Java process: Start fails with ErrPortInUse if
+// the port is taken, and otherwise holds it until just before the process
+// starts. With WithPort("0"), which implies WithPortReservation, Start picks a
+// free port, and URL returns it once Start is called.
+//
+// The Tika Server cannot inherit a listening socket, so another process may
+// still take the port while the JVM starts. Within a process, the ports of
+// the running Servers are never picked again.
+func WithPortReservation() Option {
+	return func(s *Server) {
+		s.reserve = true
+	}
+}
+
+// reservedPorts are the ports of the Servers of this process, which must not
+// be picked for other Servers. The kernel is free to pick them again once
+// their listener is closed, before the Tika Server listens on them.
+var (
+	reservedMu    sync.Mutex
+	reservedPorts = map[int]bool{}
+)
+
+// maxPortAttempts is the number of free ports Start tries before giving up on
+// finding one not reserved by another Server.
+const maxPortAttempts = 10
+
+// A portReservation holds the port of a Server until its handOff, and keeps
+// it reserved in the process until its release. The methods of a nil
+// portReservation do nothing.
+type portReservation struct {
+	l    net.Listener
+	port int
+	once sync.Once
+}
+
+// reservePort reserves the port of s, picking a free one if it is "0", and
+// updates the URL of s. The port of s is kept, so a Server with port "0"
+// picks a new one every Start.
+func (s *Server) reservePort() (*portReservation, error) {
+	reservedMu.Lock()
+	defer reservedMu.Unlock()
+	for i := 0; i < maxPortAttempts; i++ {
+		l, err := net.Listen("tcp", net.JoinHostPort(s.bind, s.port))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrPortInUse, s.port, err)
+		}
+		port := l.Addr().(*net.TCPAddr).Port
+		if reservedPorts[port] {
+			if s.port != "0" {
+				l.Close()
+				return nil, fmt.Errorf("%w: %s is reserved by another Server", ErrPortInUse, s.port)
+			}
+			// Another Server is starting on it. Keep l open so the next
+			// attempt gets another port.
+			defer l.Close()
+			continue
+		}
+		reservedPorts[port] = true
+		s.mu.Lock()
+		s.picked = strconv.Itoa(port)
+		s.mu.Unlock()
+		if err := s.setURL(); err != nil {
+			l.Close()
+			delete(reservedPorts, port)
+			return nil, err
+		}
+		return &portReservation{l: l, port: port}, nil
+	}
+	return nil, fmt.Errorf("%w: no free port after %d attempts", ErrPortInUse, maxPortAttempts)
+}
+
+// handOff closes the listener holding the port, for the server to listen on
+// it.
+func (r *portReservation) handOff() {
+	if r != nil {
+		r.l.Close()
+	}
+}
+
+// release makes the port available to other Servers.
+func (r *portReservation) release() {
+	if r == nil {
+		return
+	}
+	r.once.Do(func() {
+		r.l.Close()
+		reservedMu.Lock()
+		delete(reservedPorts, r.port)
+		reservedMu.Unlock()
+	})
+}
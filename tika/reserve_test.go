@@ -0,0 +1,108 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func reservingServer(t *testing.T, port string) *Server {
+	s, err := NewServer("server_test.go", WithHostname("127.0.0.1"), WithPort(port), WithPortReservation())
+	if err != nil {
+		t.Fatalf("NewServer got error: %v", err)
+	}
+	return s
+}
+
+func TestReservePort(t *testing.T) {
+	s := reservingServer(t, "0")
+	r, err := s.reservePort()
+	if err != nil {
+		t.Fatalf("reservePort got error: %v", err)
+	}
+	defer r.release()
+	picked := s.listenPort()
+	if picked == "0" || s.port != "0" || s.URL() != "http://127.0.0.1:"+picked {
+		t.Errorf("reservePort picked port %s and URL %s, want a free port", picked, s.URL())
+	}
+	r.handOff()
+
+	// The port is free, but reserved for s.
+	other := reservingServer(t, picked)
+	if _, err := other.reservePort(); !errors.Is(err, ErrPortInUse) {
+		t.Errorf("reservePort of a reserved port got error %v, want ErrPortInUse", err)
+	}
+	r.release()
+	r.release()
+	ro, err := other.reservePort()
+	if err != nil {
+		t.Fatalf("reservePort of a released port got error: %v", err)
+	}
+	defer ro.release()
+
+	// Another start of s picks a free port again, rather than the one of the
+	// previous start, now taken by other.
+	r, err = s.reservePort()
+	if err != nil {
+		t.Fatalf("second reservePort got error: %v", err)
+	}
+	defer r.release()
+	if p := s.listenPort(); p == "0" || p == picked {
+		t.Errorf("second reservePort picked port %s, want a free port other than %s", p, picked)
+	}
+}
+
+func TestReservePortConcurrent(t *testing.T) {
+	// The URL of a Server with port "0" can be read while it starts.
+	s := reservingServer(t, "0")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := s.reservePort()
+			if err != nil {
+				t.Errorf("reservePort got error: %v", err)
+				return
+			}
+			r.release()
+			if s.URL() == "" || s.listenPort() == "0" {
+				t.Errorf("reservePort left URL %q and port %s", s.URL(), s.listenPort())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestReservePortInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	s := reservingServer(t, strconv.Itoa(l.Addr().(*net.TCPAddr).Port))
+	if _, err := s.reservePort(); !errors.Is(err, ErrPortInUse) {
+		t.Errorf("reservePort of a port in use got error %v, want ErrPortInUse", err)
+	}
+	var r *portReservation
+	r.handOff()
+	r.release()
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metadata keys of the fonts and images of a document.
+const (
+	// fontNameKey holds the names of the fonts of a PDF, with
+	// PDFOptions.ExtractFontNames.
+	fontNameKey = "font:name"
+	// nonEmbeddedFontKey is "true" when a PDF uses a font it does not embed.
+	nonEmbeddedFontKey = "pdf:containsNonEmbeddedFont"
+	// embeddedPathKey is the path of an embedded document in its container,
+	// such as /image0.jpg.
+	embeddedPathKey = "X-TIKA:embedded_resource_path"
+)
+
+// An Image is an image embedded in a document, as listed by a
+// ResourceInventory. Its fields are zero when Tika does not report them.
+type Image struct {
+	// Path is the path of the image in the document, such as /image0.jpg.
+	Path string `json:"path,omitempty"`
+	// ContentType is the MIME type of the image, such as image/jpeg.
+	ContentType string `json:"content_type"`
+	// Width and Height are the size of the image in pixels.
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// XDPI and YDPI are the resolution of the image in dots per inch, from
+	// its own metadata.
+	XDPI float64 `json:"x_dpi,omitempty"`
+	YDPI float64 `json:"y_dpi,omitempty"`
+}
+
+// A ResourceInventory lists the fonts and images of a document, for example
+// to flag PDFs which do not embed their fonts or scans of low resolution
+// before archiving or printing them.
+type ResourceInventory struct {
+	// Fonts are the names of the fonts of the document, sorted.
+	Fonts []string `json:"fonts,omitempty"`
+	// NonEmbeddedFonts is whether the document uses fonts it does not embed,
+	// which are replaced when the document is rendered elsewhere.
+	NonEmbeddedFonts bool `json:"non_embedded_fonts,omitempty"`
+	// Images are the images embedded in the document, in order.
+	Images []Image `json:"images,omitempty"`
+}
+
+// ImageTypes returns the number of images of each MIME type.
+func (inv *ResourceInventory) ImageTypes() map[string]int {
+	types := make(map[string]int)
+	for _, img := range inv.Images {
+		types[img.ContentType]++
+	}
+	return types
+}
+
+// LowResolution returns the images whose resolution is known and below dpi in
+// either direction.
+func (inv *ResourceInventory) LowResolution(dpi float64) []Image {
+	var low []Image
+	for _, img := range inv.Images {
+		if (img.XDPI > 0 && img.XDPI < dpi) || (img.YDPI > 0 && img.YDPI < dpi) {
+			low = append(low, img)
+		}
+	}
+	return low
+}
+
+// NewResourceInventory returns the inventory of a document from its metadata
+// and the metadata of its embedded documents, as returned by MetaRecursive.
+func NewResourceInventory(docs []map[string][]string) *ResourceInventory {
+	inv := &ResourceInventory{}
+	fonts := make(map[string]bool)
+	for i, d := range docs {
+		for _, f := range d[fontNameKey] {
+			if f = strings.TrimSpace(f); f != "" && !fonts[f] {
+				fonts[f] = true
+				inv.Fonts = append(inv.Fonts, f)
+			}
+		}
+		if metaBool(d, nonEmbeddedFontKey) {
+			inv.NonEmbeddedFonts = true
+		}
+		m := Metadata(d)
+		if i == 0 || !strings.HasPrefix(m.ContentType(), "image/") {
+			continue
+		}
+		img := Image{Path: m.Get(embeddedPathKey), ContentType: m.ContentType()}
+		img.Width, _ = strconv.Atoi(pixels(m.Get("tiff:ImageWidth")))
+		img.Height, _ = strconv.Atoi(pixels(m.Get("tiff:ImageLength")))
+		unit := m.Get("tiff:ResolutionUnit")
+		img.XDPI = dpi(m.Get("tiff:XResolution"), unit)
+		img.YDPI = dpi(m.Get("tiff:YResolution"), unit)
+		inv.Images = append(inv.Images, img)
+	}
+	sort.Strings(inv.Fonts)
+	return inv
+}
+
+// pixels returns the number of a size, such as "1200 pixels".
+func pixels(v string) string {
+	if f := strings.Fields(v); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
+
+// dpi returns the resolution in dots per inch of a resolution v, such as
+// "300.0", in unit, such as Inch or cm, or 0 if it is invalid.
+func dpi(v, unit string) float64 {
+	r, err := strconv.ParseFloat(pixels(v), 64)
+	if err != nil || r <= 0 {
+		return 0
+	}
+	if u := strings.ToLower(unit); u == "cm" || strings.HasPrefix(u, "centimet") {
+		r *= 2.54
+	}
+	return r
+}
+
+// Resources returns the inventory of the fonts and images of the given input,
+// such as a PDF. It extracts the font names and the inline images of PDFs, and
+// skips the content of the documents.
+func (c *Client) Resources(ctx context.Context, input io.Reader, opts ...RequestOption) (*ResourceInventory, error) {
+	opts = append([]RequestOption{
+		WithRecursiveHandler(HandlerIgnore),
+		WithPDF(PDFOptions{ExtractInlineImages: true, ExtractFontNames: true}),
+	}, opts...)
+	docs, err := c.MetaRecursive(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewResourceInventory(docs), nil
+}
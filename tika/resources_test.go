@@ -0,0 +1,107 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewResourceInventory(t *testing.T) {
+	docs := []map[string][]string{
+		{
+			"Content-Type":     {"application/pdf"},
+			fontNameKey:        {"Helvetica", "Arial-BoldMT", "Helvetica"},
+			nonEmbeddedFontKey: {"true"},
+			"tiff:ImageWidth":  {"595"},
+		},
+		{
+			"Content-Type":        {"image/jpeg"},
+			embeddedPathKey:       {"/image0.jpg"},
+			"tiff:ImageWidth":     {"2480 pixels"},
+			"tiff:ImageLength":    {"3508 pixels"},
+			"tiff:XResolution":    {"300.0"},
+			"tiff:YResolution":    {"300.0"},
+			"tiff:ResolutionUnit": {"Inch"},
+		},
+		{
+			"Content-Type":        {"image/png"},
+			embeddedPathKey:       {"/image1.png"},
+			"tiff:XResolution":    {"40"},
+			"tiff:YResolution":    {"40"},
+			"tiff:ResolutionUnit": {"cm"},
+		},
+		{
+			"Content-Type":     {"image/png"},
+			embeddedPathKey:    {"/image2.png"},
+			"tiff:XResolution": {"72"},
+		},
+		{
+			"Content-Type": {"text/plain; charset=UTF-8"},
+			fontNameKey:    {"Courier"},
+		},
+	}
+	inv := NewResourceInventory(docs)
+	want := &ResourceInventory{
+		Fonts:            []string{"Arial-BoldMT", "Courier", "Helvetica"},
+		NonEmbeddedFonts: true,
+		Images: []Image{
+			{Path: "/image0.jpg", ContentType: "image/jpeg", Width: 2480, Height: 3508, XDPI: 300, YDPI: 300},
+			{Path: "/image1.png", ContentType: "image/png", XDPI: 101.6, YDPI: 101.6},
+			{Path: "/image2.png", ContentType: "image/png", XDPI: 72},
+		},
+	}
+	if !reflect.DeepEqual(inv, want) {
+		t.Errorf("NewResourceInventory =\n%+v\nwant\n%+v", inv, want)
+	}
+	if got := inv.ImageTypes(); !reflect.DeepEqual(got, map[string]int{"image/jpeg": 1, "image/png": 2}) {
+		t.Errorf("ImageTypes = %v, want 1 JPEG and 2 PNGs", got)
+	}
+	low := inv.LowResolution(150)
+	if len(low) != 2 || low[0].Path != "/image1.png" || low[1].Path != "/image2.png" {
+		t.Errorf("LowResolution(150) = %+v, want the 2 PNGs", low)
+	}
+	if inv := NewResourceInventory(docs[:1]); inv.Images != nil || len(inv.Fonts) != 2 {
+		t.Errorf("NewResourceInventory of a document without images = %+v", inv)
+	}
+}
+
+func TestClientResources(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rmeta/ignore" || r.Header.Get("X-Tika-PDFextractFontNames") != "true" || r.Header.Get("X-Tika-PDFextractInlineImages") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]string{
+			{"Content-Type": "application/pdf", fontNameKey: "Times-Roman", nonEmbeddedFontKey: "false"},
+			{"Content-Type": "image/jpeg", embeddedPathKey: "/image0.jpg"},
+		})
+	}))
+	defer ts.Close()
+	inv, err := NewClient(nil, ts.URL).Resources(context.Background(), strings.NewReader("pdf"))
+	if err != nil {
+		t.Fatalf("Resources got error: %v", err)
+	}
+	if inv.NonEmbeddedFonts || !reflect.DeepEqual(inv.Fonts, []string{"Times-Roman"}) || len(inv.Images) != 1 {
+		t.Errorf("Resources = %+v, want 1 embedded font and 1 image", inv)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"sync"
+)
+
+// A ResponseCache stores the responses saved by a CacheMiddleware, by key. It
+// must be safe for concurrent use.
+type ResponseCache interface {
+	// Get returns the response saved with the key, and whether there is one.
+	Get(key string) ([]byte, bool)
+	// Put saves the response with the key.
+	Put(key string, resp []byte)
+}
+
+// cacheIgnoredHeaders are the request headers which do not change the
+// response, such as tracing headers set per call, and so are not part of the
+// keys of a CacheMiddleware.
+var cacheIgnoredHeaders = map[string]bool{
+	"Baggage":      true,
+	"Traceparent":  true,
+	"Tracestate":   true,
+	"User-Agent":   true,
+	"X-Request-Id": true,
+}
+
+// CacheMiddleware returns a Middleware saving the 200 OK responses in cache,
+// and answering the same requests, with the same method, URL, headers and
+// body, from it without calling the server, so documents are not parsed
+// again, for example when a Job is rerun. Requests and responses with a body
+// larger than maxBody bytes are neither answered from nor saved in the cache.
+// A maxBody of 0 or less means 1 MiB. Both bodies are held in memory.
+//
+// Add it before RetryMiddleware, so that answers from the cache are not
+// delayed by a retry.
+func CacheMiddleware(cache ResponseCache, maxBody int64) Middleware {
+	if maxBody <= 0 {
+		maxBody = 1 << 20
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// A RoundTripper must not modify the request.
+			req = req.Clone(req.Context())
+			var body []byte
+			if req.Body != nil && req.Body != http.NoBody {
+				var ok bool
+				var err error
+				body, req.Body, ok, err = readUpTo(req.Body, maxBody)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					return next.RoundTrip(req)
+				}
+				req.GetBody = func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader(body)), nil
+				}
+			}
+			key := cacheKey(req, body)
+			if data, ok := cache.Get(key); ok {
+				if resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req); err == nil {
+					return resp, nil
+				}
+			}
+			resp, err := next.RoundTrip(req)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				return resp, err
+			}
+			_, rc, ok, err := readUpTo(resp.Body, maxBody)
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = rc
+			if !ok {
+				return resp, nil
+			}
+			if dump, err := httputil.DumpResponse(resp, true); err == nil {
+				cache.Put(key, dump)
+			}
+			return resp, nil
+		})
+	}
+}
+
+// readUpTo reads rc if it is at most max bytes, closes it, and returns its
+// content, a reader of it, and true. If rc is larger, readUpTo returns false
+// and a reader of all of rc instead, which closes rc.
+func readUpTo(rc io.ReadCloser, max int64) ([]byte, io.ReadCloser, bool, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(rc, max+1))
+	if err != nil {
+		rc.Close()
+		return nil, nil, false, err
+	}
+	if int64(len(b)) > max {
+		return nil, readCloser{io.MultiReader(bytes.NewReader(b), rc), rc}, false, nil
+	}
+	rc.Close()
+	return b, ioutil.NopCloser(bytes.NewReader(b)), true, nil
+}
+
+// cacheKey returns the key of the response of req, with the given body, in a
+// ResponseCache.
+func cacheKey(req *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.String()+"\n")
+	keys := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		if !cacheIgnoredHeaders[http.CanonicalHeaderKey(k)] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range req.Header[k] {
+			io.WriteString(h, http.CanonicalHeaderKey(k)+": "+v+"\n")
+		}
+	}
+	io.WriteString(h, "\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MemoryCache is a ResponseCache in memory, which evicts the least recently
+// used responses past its size.
+type MemoryCache struct {
+	max int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List // lru lists the entries, most recently used first.
+	entries map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key  string
+	resp []byte
+}
+
+// NewMemoryCache returns a MemoryCache holding up to maxBytes of responses.
+func NewMemoryCache(maxBytes int64) *MemoryCache {
+	return &MemoryCache{max: maxBytes, lru: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get implements ResponseCache.
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*memoryCacheEntry).resp, true
+}
+
+// Put implements ResponseCache. Responses larger than the cache are not
+// saved.
+func (c *MemoryCache) Put(key string, resp []byte) {
+	if int64(len(resp)) > c.max {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.size -= int64(len(e.Value.(*memoryCacheEntry).resp))
+		c.lru.Remove(e)
+	}
+	c.entries[key] = c.lru.PushFront(&memoryCacheEntry{key: key, resp: resp})
+	c.size += int64(len(resp))
+	for c.size > c.max {
+		e := c.lru.Back()
+		old := e.Value.(*memoryCacheEntry)
+		c.lru.Remove(e)
+		delete(c.entries, old.key)
+		c.size -= int64(len(old.resp))
+	}
+}
+
+// Len returns the number of responses in c.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCacheMiddleware(t *testing.T) {
+	var calls int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		b, _ := ioutil.ReadAll(r.Body)
+		if string(b) == "fail" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		w.Header().Set("X-Seen", r.Header.Get("X-Tika-OCRLanguage"))
+		w.Write([]byte(strings.ToUpper(string(b)) + r.Header.Get("X-Tika-OCRLanguage")))
+	}))
+	defer ts.Close()
+	cache := NewMemoryCache(1 << 20)
+	c := NewClient(nil, ts.URL, WithMiddleware(CacheMiddleware(cache, 16)))
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		input string
+		opts  []RequestOption
+		want  string
+		calls int64
+	}{
+		{name: "first", input: "hello", want: "HELLO", calls: 1},
+		{name: "cached", input: "hello", want: "HELLO", calls: 1},
+		{name: "traced", input: "hello", opts: []RequestOption{WithHeader("Traceparent", "00-1-2-01")}, want: "HELLO", calls: 1},
+		{name: "other body", input: "world", want: "WORLD", calls: 2},
+		{name: "other header", input: "hello", opts: []RequestOption{WithHeader("X-Tika-OCRLanguage", "fra")}, want: "HELLOfra", calls: 3},
+		{name: "large body", input: strings.Repeat("a", 17), want: strings.Repeat("A", 17), calls: 4},
+		{name: "large body again", input: strings.Repeat("a", 17), want: strings.Repeat("A", 17), calls: 5},
+		{name: "failure", input: "fail", calls: 6},
+		{name: "failure again", input: "fail", calls: 7},
+	}
+	for _, test := range tests {
+		got, err := c.Parse(ctx, strings.NewReader(test.input), test.opts...)
+		if (err != nil) != (test.want == "") || got != test.want || atomic.LoadInt64(&calls) != test.calls {
+			t.Errorf("Parse(%s) = %q, %v after %d calls, want %q after %d calls", test.name, got, err, calls, test.want, test.calls)
+		}
+	}
+	if cache.Len() != 3 {
+		t.Errorf("cache has %d responses, want 3", cache.Len())
+	}
+	if s := c.Stats(); s.Requests != int64(len(tests)) || s.Failures != 2 || s.Active != 0 {
+		t.Errorf("Stats = %+v, want %d requests and 2 failures", s, len(tests))
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	c := NewMemoryCache(10)
+	c.Put("a", []byte("aaaa"))
+	c.Put("b", []byte("bbbb"))
+	c.Get("a")
+	c.Put("c", []byte("cccc"))
+	c.Put("huge", []byte("more than ten bytes"))
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "huge": false} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("Get(%s) found a response: %v, want %v", key, ok, want)
+		}
+	}
+	c.Put("a", []byte("a"))
+	if got, _ := c.Get("a"); string(got) != "a" || c.Len() != 2 {
+		t.Errorf("Get(a) after replacing it = %q with %d responses, want a with 2", got, c.Len())
+	}
+}
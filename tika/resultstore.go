@@ -0,0 +1,473 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A StoredDocument is a Document saved in a ResultStore.
+type StoredDocument struct {
+	Document
+	// Hash is the hash of the content of the Document: its ContentRef if it
+	// has one, or else the BlobRef its Content would have in a BlobStore.
+	Hash BlobRef `json:"hash"`
+	// Stored is the time the Document was saved.
+	Stored time.Time `json:"stored"`
+}
+
+// storeRecord is a line of the file of a ResultStore.
+type storeRecord struct {
+	*StoredDocument
+	// Deleted is the ID of a deleted Document.
+	Deleted string `json:"deleted,omitempty"`
+}
+
+// A ResultQuery selects the Documents of a ResultStore. A Document must match
+// every field which is set; the zero ResultQuery matches all of them.
+type ResultQuery struct {
+	// ContentTypes are MIME types or patterns, as defined by path.Match, such
+	// as "image/*". They match the MIME type without parameters.
+	ContentTypes []string
+	// Languages are languages, such as "en", matching the language of the
+	// metadata, as returned by Metadata.Language, and its regional variants,
+	// such as "en-US".
+	Languages []string
+	// Hash is the hash of the content, as in StoredDocument.Hash.
+	Hash BlobRef
+	// CreatedAfter and CreatedBefore bound the creation time of the
+	// documents, as returned by Metadata.Created. Documents without a
+	// creation time never match a time range.
+	CreatedAfter, CreatedBefore time.Time
+	// StoredAfter and StoredBefore bound the time the Documents were saved.
+	StoredAfter, StoredBefore time.Time
+	// Limit is the maximum number of Documents returned, if positive.
+	Limit int
+}
+
+func (q ResultQuery) match(d *StoredDocument) bool {
+	m := Metadata(d.Metadata)
+	if len(q.ContentTypes) > 0 {
+		ct := (Metadata{"Content-Type": {d.ContentType}}).ContentType()
+		if ct == "" {
+			ct = m.ContentType()
+		}
+		if !matchAny(q.ContentTypes, ct) {
+			return false
+		}
+	}
+	if len(q.Languages) > 0 && !matchLanguage(q.Languages, m.Language()) {
+		return false
+	}
+	if q.Hash != "" && q.Hash != d.Hash {
+		return false
+	}
+	if !q.CreatedAfter.IsZero() || !q.CreatedBefore.IsZero() {
+		created := m.Created()
+		if created.IsZero() ||
+			!q.CreatedAfter.IsZero() && !created.After(q.CreatedAfter) ||
+			!q.CreatedBefore.IsZero() && !created.Before(q.CreatedBefore) {
+			return false
+		}
+	}
+	if !q.StoredAfter.IsZero() && !d.Stored.After(q.StoredAfter) {
+		return false
+	}
+	if !q.StoredBefore.IsZero() && !d.Stored.Before(q.StoredBefore) {
+		return false
+	}
+	return true
+}
+
+// matchLanguage reports whether lang is one of langs or a variant of one.
+func matchLanguage(langs []string, lang string) bool {
+	lang = strings.ToLower(lang)
+	for _, l := range langs {
+		l = strings.ToLower(l)
+		if lang == l || strings.HasPrefix(lang, l+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// A ResultStore saves extracted Documents in a local file and queries them,
+// for tools processing modest corpora on a single machine without an external
+// database. Documents are kept in memory and appended to the file as JSON
+// lines when saved, so the file grows with every update until Compact. It is
+// not backed by an embedded database such as BadgerDB or SQLite, so the
+// Documents of a ResultStore must fit in memory.
+//
+// Opening a ResultStore fails if a line of its file cannot be read, except
+// the last line if it was cut short by a crash, which is dropped. A
+// ResultStore opened with OpenEncryptedResultStore seals every line with
+// AES-GCM, so opening it with another key, or without one, fails rather than
+// returning an empty store. A ResultStore opened WithResultCompressor
+// compresses every line, before it is sealed.
+//
+// A ResultStore is safe for concurrent use.
+type ResultStore struct {
+	path string
+	gcm  cipher.AEAD // gcm seals the lines, if not nil.
+	// compressor compresses the lines, if not nil.
+	compressor Compressor
+	mu         sync.RWMutex
+	f          *os.File
+	docs       map[string]*StoredDocument
+	// stale is the number of lines of the file replaced by later lines.
+	stale int
+}
+
+// resultStoreNow is the time Documents are saved at, replaced by tests.
+var resultStoreNow = time.Now
+
+// A ResultStoreOption configures a ResultStore.
+type ResultStoreOption func(*ResultStore)
+
+// WithResultCompressor compresses the saved Documents with c, such as a
+// DictCompressor trained on the corpus. Documents saved without compression
+// are still read, and compressed by Compact. Documents compressed with a
+// DictCompressor can only be read with the same Compressor; gzip content is
+// read even without one.
+func WithResultCompressor(c Compressor) ResultStoreOption {
+	return func(s *ResultStore) {
+		s.compressor = c
+	}
+}
+
+// OpenResultStore opens the ResultStore saved at path, or creates an empty
+// one if there is no file at path. The store must be closed with Close.
+func OpenResultStore(path string, opts ...ResultStoreOption) (*ResultStore, error) {
+	return OpenEncryptedResultStore(context.Background(), path, nil, opts...)
+}
+
+// OpenEncryptedResultStore is like OpenResultStore, but encrypts the
+// Documents with the key of keys, if not nil. A store written without
+// encryption is an error wrapping ErrNotSealed, unless keys is
+// AllowPlaintext; it is then encrypted by Compact.
+func OpenEncryptedResultStore(ctx context.Context, path string, keys KeyProvider, opts ...ResultStoreOption) (*ResultStore, error) {
+	s := &ResultStore{path: path, docs: make(map[string]*StoredDocument)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if keys != nil {
+		gcm, err := newGCM(ctx, keys)
+		if err != nil {
+			return nil, fmt.Errorf("error opening result store: %w", err)
+		}
+		s.gcm = gcm
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening result store: %w", err)
+	}
+	s.f = f
+	if err := s.load(keys); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error reading result store: %w", err)
+	}
+	return s, nil
+}
+
+// load applies the lines of the file of s. The last line, if it has no
+// newline and cannot be decoded, was cut short by a crash: it is truncated,
+// so that the next write does not bury it. It must fail authentication only
+// if earlier lines were authenticated with the same key, since it is
+// otherwise more likely sealed with another key than cut short.
+func (s *ResultStore) load(keys KeyProvider) error {
+	r := bufio.NewReader(s.f)
+	var off int64 // off is the end of the last complete line.
+	authenticated := false
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		cut := err == io.EOF
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			rec, sealed, compressed, err := s.decode(trimmed)
+			switch {
+			case err != nil && cut && !errors.Is(err, errOtherCompressor) && !errors.Is(err, errSealedLine) && (authenticated || !errors.Is(err, errAuthentication)):
+				return s.f.Truncate(off)
+			case err != nil:
+				return fmt.Errorf("line %d: %w", n, err)
+			case s.gcm != nil && !sealed && !allowsPlaintext(keys):
+				return fmt.Errorf("line %d: %w", n, ErrNotSealed)
+			}
+			authenticated = authenticated || sealed
+			if sealed != (s.gcm != nil) || compressed != (s.compressor != nil) {
+				s.stale++ // Compact rewrites it.
+			}
+			s.apply(rec)
+		}
+		if cut {
+			return nil
+		}
+		off += int64(len(line))
+	}
+}
+
+// encode returns the line of rec, without its newline: its JSON, or else the
+// JSON compressed, sealed, or both, in base64.
+func (s *ResultStore) encode(rec storeRecord) ([]byte, error) {
+	data, err := json.Marshal(rec)
+	if err != nil || s.gcm == nil && s.compressor == nil {
+		return data, err
+	}
+	if s.compressor != nil {
+		var buf bytes.Buffer
+		if err := compress(s.compressor, &buf, bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+	}
+	if s.gcm != nil {
+		if data, err = seal(s.gcm, data); err != nil {
+			return nil, err
+		}
+	}
+	line := make([]byte, base64.StdEncoding.EncodedLen(len(data)))
+	base64.StdEncoding.Encode(line, data)
+	return line, nil
+}
+
+// errSealedLine is the error of a sealed line of a ResultStore opened without
+// a key.
+var errSealedLine = errors.New("line is encrypted, and the store has no key")
+
+// decode returns the record of a line written by encode, with or without
+// sealing and compression, and whether the line was sealed and compressed.
+func (s *ResultStore) decode(line []byte) (rec storeRecord, sealed, compressed bool, err error) {
+	data := line
+	if line[0] != '{' {
+		if data, err = base64.StdEncoding.DecodeString(string(line)); err != nil {
+			return rec, false, false, err
+		}
+		if bytes.HasPrefix(data, sealedMagic) {
+			if s.gcm == nil {
+				return rec, false, false, errSealedLine
+			}
+			if data, err = unseal(s.gcm, data); err != nil {
+				return rec, false, false, err
+			}
+			sealed = true
+		}
+		if bytes.HasPrefix(data, compressedMagic) {
+			rc, err := decompress(s.compressor, ioutil.NopCloser(bytes.NewReader(data)))
+			if err != nil {
+				return rec, sealed, false, err
+			}
+			data, err = ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return rec, sealed, false, err
+			}
+			compressed = true
+		}
+	}
+	err = json.Unmarshal(data, &rec)
+	return rec, sealed, compressed, err
+}
+
+// apply applies rec to the Documents of s.
+func (s *ResultStore) apply(rec storeRecord) {
+	id := rec.Deleted
+	if rec.StoredDocument != nil {
+		id = rec.ID
+	}
+	if _, ok := s.docs[id]; ok {
+		s.stale++
+	}
+	if rec.StoredDocument == nil {
+		delete(s.docs, id)
+		s.stale++
+		return
+	}
+	s.docs[id] = rec.StoredDocument
+}
+
+// write appends rec to the file of s and applies it. s.mu must be held.
+func (s *ResultStore) write(rec storeRecord) error {
+	data, err := s.encode(rec)
+	if err != nil {
+		return err
+	}
+	// Start a new line, in case the last write was cut short.
+	data = append([]byte("\n"), data...)
+	if _, err := s.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	s.apply(rec)
+	return nil
+}
+
+// contentHash returns the StoredDocument.Hash of doc.
+func contentHash(doc Document) BlobRef {
+	if doc.ContentRef != "" {
+		return doc.ContentRef
+	}
+	h := sha256.Sum256([]byte(doc.Content))
+	return BlobRef(blobRefPrefix + hex.EncodeToString(h[:]))
+}
+
+// Put saves doc, replacing any Document saved with the same ID.
+func (s *ResultStore) Put(doc Document) error {
+	rec := storeRecord{StoredDocument: &StoredDocument{
+		Document: doc,
+		Hash:     contentHash(doc),
+		Stored:   resultStoreNow().UTC(),
+	}}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.write(rec); err != nil {
+		return fmt.Errorf("error saving %q: %w", doc.ID, err)
+	}
+	return nil
+}
+
+// Emit saves doc like Put. It can be used as Job.Emit.
+func (s *ResultStore) Emit(ctx context.Context, doc Document) error {
+	return s.Put(doc)
+}
+
+// Get returns the Document saved with the given ID, and whether there is one.
+func (s *ResultStore) Get(id string) (StoredDocument, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, ok := s.docs[id]
+	if !ok {
+		return StoredDocument{}, false
+	}
+	return *d, true
+}
+
+// Delete deletes the Document saved with the given ID, if any.
+func (s *ResultStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.docs[id]; !ok {
+		return nil
+	}
+	if err := s.write(storeRecord{Deleted: id}); err != nil {
+		return fmt.Errorf("error deleting %q: %w", id, err)
+	}
+	return nil
+}
+
+// Query returns the Documents matching q, by ID.
+func (s *ResultStore) Query(q ResultQuery) []StoredDocument {
+	s.mu.RLock()
+	ids := make([]string, 0, len(s.docs))
+	for id, d := range s.docs {
+		if q.match(d) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if q.Limit > 0 && len(ids) > q.Limit {
+		ids = ids[:q.Limit]
+	}
+	r := make([]StoredDocument, len(ids))
+	for i, id := range ids {
+		r[i] = *s.docs[id]
+	}
+	s.mu.RUnlock()
+	return r
+}
+
+// Len returns the number of saved Documents.
+func (s *ResultStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.docs)
+}
+
+// Compact rewrites the file of s with the current Documents only, dropping
+// the lines of replaced and deleted Documents.
+func (s *ResultStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stale == 0 {
+		return nil
+	}
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("error compacting result store: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, d := range s.docs {
+		var line []byte
+		if line, err = s.encode(storeRecord{StoredDocument: d}); err != nil {
+			break
+		}
+		if _, err = w.Write(append(line, '\n')); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error compacting result store: %w", err)
+	}
+	nf, err := os.OpenFile(s.path, os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("error reopening result store: %w", err)
+	}
+	s.f.Close()
+	s.f = nf
+	s.stale = 0
+	return nil
+}
+
+// Close syncs and closes the file of the store.
+func (s *ResultStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.f.Sync(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResultStore(t *testing.T) {
+	defer func(now func() time.Time) { resultStoreNow = now }(resultStoreNow)
+	stored := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	resultStoreNow = func() time.Time { return stored }
+
+	path := filepath.Join(tempDir(t), "results.jsonl")
+	s, err := OpenResultStore(path)
+	if err != nil {
+		t.Fatalf("OpenResultStore got error: %v", err)
+	}
+	docs := []Document{
+		{ID: "a.pdf", ContentType: "application/pdf", Content: "a", Metadata: map[string][]string{"dc:language": {"en-US"}, "dcterms:created": {"2019-05-01T00:00:00Z"}}},
+		{ID: "b.txt", ContentType: "text/plain; charset=UTF-8", Content: "b", Metadata: map[string][]string{"dc:language": {"fr"}}},
+		{ID: "c.png", ContentType: "image/png", Content: "a", Metadata: map[string][]string{"dcterms:created": {"2018-01-01"}}},
+	}
+	for _, d := range docs {
+		if err := s.Put(d); err != nil {
+			t.Fatalf("Put(%s) got error: %v", d.ID, err)
+		}
+		resultStoreNow = func() time.Time { return stored.Add(time.Hour) }
+	}
+
+	ids := func(r []StoredDocument) []string {
+		var got []string
+		for _, d := range r {
+			got = append(got, d.ID)
+		}
+		return got
+	}
+	tests := []struct {
+		name string
+		q    ResultQuery
+		want []string
+	}{
+		{"all", ResultQuery{}, []string{"a.pdf", "b.txt", "c.png"}},
+		{"limit", ResultQuery{Limit: 2}, []string{"a.pdf", "b.txt"}},
+		{"content type", ResultQuery{ContentTypes: []string{"text/plain", "image/*"}}, []string{"b.txt", "c.png"}},
+		{"language", ResultQuery{Languages: []string{"en"}}, []string{"a.pdf"}},
+		{"hash", ResultQuery{Hash: contentHash(Document{Content: "a"})}, []string{"a.pdf", "c.png"}},
+		{"created", ResultQuery{CreatedAfter: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)}, []string{"a.pdf"}},
+		{"stored", ResultQuery{StoredBefore: stored.Add(time.Minute)}, []string{"a.pdf"}},
+	}
+	for _, test := range tests {
+		if got := ids(s.Query(test.q)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Query(%s) got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	if err := s.Put(Document{ID: "a.pdf", ContentType: "application/pdf", Content: "new"}); err != nil {
+		t.Fatalf("Put got error: %v", err)
+	}
+	if err := s.Delete("b.txt"); err != nil {
+		t.Fatalf("Delete got error: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close got error: %v", err)
+	}
+
+	// A write cut short by a crash is ignored, but not a bad line followed by
+	// others.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":"d.pdf","con`)
+	f.Close()
+	raw, _ := ioutil.ReadFile(path)
+	ioutil.WriteFile(path+".bad", append(raw, "\n{}\n"...), 0600)
+	if _, err := OpenResultStore(path + ".bad"); err == nil {
+		t.Errorf("OpenResultStore with a bad line before the last got no error")
+	}
+
+	s, err = OpenResultStore(path)
+	if err != nil {
+		t.Fatalf("OpenResultStore got error: %v", err)
+	}
+	defer s.Close()
+	if got := ids(s.Query(ResultQuery{})); !reflect.DeepEqual(got, []string{"a.pdf", "c.png"}) {
+		t.Errorf("reopened store has %v, want [a.pdf c.png]", got)
+	}
+	if d, ok := s.Get("a.pdf"); !ok || d.Content != "new" || d.Hash != contentHash(Document{Content: "new"}) {
+		t.Errorf("Get(a.pdf) got %+v, %v, want the new content", d, ok)
+	}
+
+	before, _ := os.Stat(path)
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact got error: %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("Compact got size %d, want less than %d", after.Size(), before.Size())
+	}
+	if err := s.Put(Document{ID: "e.txt"}); err != nil {
+		t.Fatalf("Put after Compact got error: %v", err)
+	}
+	s.Close()
+	s, err = OpenResultStore(path)
+	if err != nil {
+		t.Fatalf("OpenResultStore got error: %v", err)
+	}
+	defer s.Close()
+	if got := ids(s.Query(ResultQuery{})); !reflect.DeepEqual(got, []string{"a.pdf", "c.png", "e.txt"}) {
+		t.Errorf("compacted store has %v, want [a.pdf c.png e.txt]", got)
+	}
+}
+
+func TestEncryptedResultStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(tempDir(t), "results.jsonl")
+	s, err := OpenResultStore(path)
+	if err != nil {
+		t.Fatalf("OpenResultStore got error: %v", err)
+	}
+	s.Put(Document{ID: "old.txt", Content: "legacy secret"})
+	s.Close()
+
+	if _, err := OpenEncryptedResultStore(ctx, path, testKey); !errors.Is(err, ErrNotSealed) {
+		t.Fatalf("OpenEncryptedResultStore of a plaintext store got error %v, want ErrNotSealed", err)
+	}
+	s, err = OpenEncryptedResultStore(ctx, path, AllowPlaintext{testKey})
+	if err != nil {
+		t.Fatalf("OpenEncryptedResultStore allowing plaintext got error: %v", err)
+	}
+	s.Put(Document{ID: "new.txt", Content: "new secret"})
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact got error: %v", err)
+	}
+	s.Put(Document{ID: "newer.txt", Content: "newer secret"})
+	s.Close()
+	if raw, _ := ioutil.ReadFile(path); bytes.Contains(raw, []byte("secret")) {
+		t.Errorf("encrypted store wrote plaintext %q", raw)
+	}
+
+	// The store cannot be opened with another key, or without one, rather
+	// than being opened empty and losing its Documents on the next Compact.
+	if _, err := OpenEncryptedResultStore(ctx, path, StaticKey(bytes.Repeat([]byte{9}, 32))); !errors.Is(err, errAuthentication) {
+		t.Errorf("OpenEncryptedResultStore with the wrong key got error %v, want an authentication error", err)
+	}
+	if _, err := OpenResultStore(path); err == nil {
+		t.Errorf("OpenResultStore of an encrypted store got no error")
+	}
+
+	// A tampered line is refused, a sealed line cut short by a crash dropped.
+	valid, _ := ioutil.ReadFile(path)
+	tampered := base64.StdEncoding.EncodeToString(append(append([]byte{}, sealedMagic...), make([]byte, 40)...))
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	f.WriteString("\n" + tampered + "\n")
+	f.Close()
+	if _, err := OpenEncryptedResultStore(ctx, path, testKey); !errors.Is(err, errAuthentication) {
+		t.Errorf("OpenEncryptedResultStore with a tampered line got error %v, want an authentication error", err)
+	}
+	ioutil.WriteFile(path, append(valid, "\n"+tampered[:len(tampered)-8]...), 0600)
+	s, err = OpenEncryptedResultStore(ctx, path, testKey)
+	if err != nil {
+		t.Fatalf("OpenEncryptedResultStore with a cut line got error: %v", err)
+	}
+	if info, _ := os.Stat(path); info.Size() != int64(len(valid))+1 {
+		t.Errorf("OpenEncryptedResultStore left a file of %d bytes, want the cut line truncated", info.Size())
+	}
+	if s.Len() != 3 {
+		t.Errorf("encrypted store has %d documents, want 3", s.Len())
+	}
+	if d, ok := s.Get("old.txt"); !ok || d.Content != "legacy secret" {
+		t.Errorf("Get of a migrated document = %+v, %v", d, ok)
+	}
+	s.Close()
+	f, _ = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	f.WriteString(`{"id":"planted.txt","content":"x"}` + "\n")
+	f.Close()
+	if _, err := OpenEncryptedResultStore(ctx, path, testKey); !errors.Is(err, ErrNotSealed) {
+		t.Errorf("OpenEncryptedResultStore with a planted plaintext line got error %v, want ErrNotSealed", err)
+	}
+	if _, err := OpenEncryptedResultStore(ctx, path, StaticKey("short")); err == nil {
+		t.Errorf("OpenEncryptedResultStore with an invalid key got no error")
+	}
+}
+
+func TestCompressedResultStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(tempDir(t), "results.jsonl")
+	s, err := OpenResultStore(path)
+	if err != nil {
+		t.Fatalf("OpenResultStore got error: %v", err)
+	}
+	s.Put(Document{ID: "old.txt", Content: invoice(1)})
+	s.Close()
+	plain, _ := os.Stat(path)
+
+	dc, _ := NewDictCompressor(TrainDictionary([]string{invoice(2), invoice(3)}, 4096), 0)
+	for _, keys := range []KeyProvider{nil, AllowPlaintext{testKey}} {
+		s, err = OpenEncryptedResultStore(ctx, path, keys, WithResultCompressor(dc))
+		if err != nil {
+			t.Fatalf("OpenEncryptedResultStore got error: %v", err)
+		}
+		s.Put(Document{ID: "new.txt", Content: invoice(4)})
+		if err := s.Compact(); err != nil {
+			t.Fatalf("Compact got error: %v", err)
+		}
+		s.Close()
+		if raw, _ := ioutil.ReadFile(path); bytes.Contains(raw, []byte("INVOICE")) || int64(len(raw)) >= 2*plain.Size() {
+			t.Errorf("compressed store wrote %d bytes: %q", len(raw), raw)
+		}
+		s, err = OpenEncryptedResultStore(ctx, path, keys, WithResultCompressor(dc))
+		if err != nil {
+			t.Fatalf("OpenEncryptedResultStore got error: %v", err)
+		}
+		for id, want := range map[string]string{"old.txt": invoice(1), "new.txt": invoice(4)} {
+			if d, ok := s.Get(id); !ok || d.Content != want {
+				t.Errorf("Get(%s) got %+v, %v", id, d, ok)
+			}
+		}
+		s.Close()
+	}
+
+	// The dictionary is needed to read the store.
+	if _, err := OpenEncryptedResultStore(ctx, path, testKey); !errors.Is(err, errOtherCompressor) {
+		t.Errorf("OpenEncryptedResultStore without the Compressor got error %v, want %v", err, errOtherCompressor)
+	}
+}
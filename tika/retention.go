@@ -0,0 +1,135 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// A RetentionPolicy bounds the files kept in a directory, such as a cache,
+// checkpoints, dead letters or captured logs, so long-running processes do not
+// accumulate local state forever.
+type RetentionPolicy struct {
+	// Dir is the directory the policy applies to, including subdirectories.
+	Dir string
+	// Pattern restricts the policy to files whose name matches it, as defined
+	// by filepath.Match. If empty, the policy applies to all files.
+	Pattern string
+	// MaxAge is the age, by modification time, past which files are removed.
+	// Zero means no limit.
+	MaxAge time.Duration
+	// MaxSize is the total size in bytes the files are kept under, by removing
+	// the oldest files first. Zero means no limit.
+	MaxSize int64
+}
+
+// A CleanupReport describes the files removed by a RetentionPolicy.
+type CleanupReport struct {
+	Removed []string // Removed are the paths of the removed files.
+	Freed   int64    // Freed is the total size of the removed files.
+}
+
+type retainedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Apply removes the files of p.Dir exceeding the limits of p, and the
+// directories left empty. A missing Dir is not an error.
+func (p RetentionPolicy) Apply() (CleanupReport, error) {
+	var r CleanupReport
+	var files []retainedFile
+	var dirs []string
+	err := filepath.Walk(p.Dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.IsDir() {
+			if path != p.Dir {
+				dirs = append(dirs, path)
+			}
+			return nil
+		}
+		if p.Pattern != "" {
+			if ok, err := filepath.Match(p.Pattern, fi.Name()); err != nil || !ok {
+				return err
+			}
+		}
+		files = append(files, retainedFile{path: path, size: fi.Size(), modTime: fi.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return r, fmt.Errorf("error listing %s: %w", p.Dir, err)
+	}
+
+	// Oldest first, so the size limit removes them first.
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	now := time.Now()
+	for _, f := range files {
+		expired := p.MaxAge > 0 && now.Sub(f.modTime) > p.MaxAge
+		tooBig := p.MaxSize > 0 && total > p.MaxSize
+		if !expired && !tooBig {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return r, fmt.Errorf("error removing %s: %w", f.path, err)
+		}
+		total -= f.size
+		r.Removed = append(r.Removed, f.path)
+		r.Freed += f.size
+	}
+
+	// Deepest first, so parents are empty by the time they are checked.
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, d := range dirs {
+		os.Remove(d) // Fails, as intended, if d is not empty.
+	}
+	return r, nil
+}
+
+// RunRetention applies the policies every interval until ctx is done, and
+// returns ctx.Err(). Errors applying a policy are passed to onError, which may
+// be nil, and do not stop RunRetention.
+func RunRetention(ctx context.Context, interval time.Duration, onError func(error), policies ...RetentionPolicy) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		for _, p := range policies {
+			if _, err := p.Apply(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
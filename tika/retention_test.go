@@ -0,0 +1,126 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// writeAged writes a file of size bytes at dir/name, modified age ago.
+func writeAged(t *testing.T, dir, name string, size int, age time.Duration) {
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mt := time.Now().Add(-age)
+	if err := os.Chtimes(path, mt, mt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func remaining(t *testing.T, dir string) []string {
+	var r []string
+	filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err == nil && path != dir {
+			rel, _ := filepath.Rel(dir, path)
+			r = append(r, rel)
+		}
+		return nil
+	})
+	sort.Strings(r)
+	return r
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     RetentionPolicy
+		wantFreed  int64
+		wantRemain []string
+	}{
+		{
+			name:       "max age",
+			policy:     RetentionPolicy{MaxAge: 2 * time.Hour},
+			wantFreed:  10 + 30,
+			wantRemain: []string{"new.json"},
+		},
+		{
+			name:       "max size",
+			policy:     RetentionPolicy{MaxSize: 25},
+			wantFreed:  10 + 30,
+			wantRemain: []string{"new.json"},
+		},
+		{
+			name:       "pattern",
+			policy:     RetentionPolicy{Pattern: "*.log", MaxAge: time.Hour},
+			wantFreed:  10,
+			wantRemain: []string{"new.json", "sub", "sub/mid.state"},
+		},
+		{
+			name:       "no limits",
+			wantRemain: []string{"new.json", "old.log", "sub", "sub/mid.state"},
+		},
+	}
+	for _, test := range tests {
+		dir := tempDir(t)
+		writeAged(t, dir, "old.log", 10, 4*time.Hour)
+		writeAged(t, dir, "sub/mid.state", 30, 3*time.Hour)
+		writeAged(t, dir, "new.json", 20, 0)
+		test.policy.Dir = dir
+		r, err := test.policy.Apply()
+		if err != nil {
+			t.Errorf("Apply(%s) got error: %v", test.name, err)
+			continue
+		}
+		if r.Freed != test.wantFreed {
+			t.Errorf("Apply(%s) freed %d bytes, want %d", test.name, r.Freed, test.wantFreed)
+		}
+		if got := remaining(t, dir); !reflect.DeepEqual(got, test.wantRemain) {
+			t.Errorf("Apply(%s) left %v, want %v", test.name, got, test.wantRemain)
+		}
+	}
+}
+
+func TestRetentionMissingDir(t *testing.T) {
+	p := RetentionPolicy{Dir: filepath.Join(tempDir(t), "missing"), MaxAge: time.Second}
+	if _, err := p.Apply(); err != nil {
+		t.Errorf("Apply on a missing directory got error: %v", err)
+	}
+}
+
+func TestRunRetention(t *testing.T) {
+	dir := tempDir(t)
+	writeAged(t, dir, "old.log", 10, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := RunRetention(ctx, time.Hour, nil, RetentionPolicy{Dir: dir, MaxAge: time.Minute}); err != context.Canceled {
+		t.Errorf("RunRetention got error %v, want context.Canceled", err)
+	}
+	if got := remaining(t, dir); len(got) != 0 {
+		t.Errorf("RunRetention left %v, want the policy applied once", got)
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults of a RetryPolicy.
+const (
+	DefaultRetryAttempts = 3
+	DefaultRetryBackoff  = 500 * time.Millisecond
+)
+
+// A RetryPolicy retries the requests of a Client failing with a network error
+// or a 429, 502, 503 or 504 response, such as the 503 responses of a Tika
+// Server while its child process restarts. The delay between attempts starts
+// at Backoff and doubles after every attempt, unless the response requests a
+// longer one with Retry-After.
+//
+// Only idempotent requests whose body can be read again are retried:
+// requests with the GET, HEAD, OPTIONS, TRACE, PUT or DELETE method, or an
+// Idempotency-Key header, and without input or whose input is a
+// *bytes.Buffer, *bytes.Reader or *strings.Reader. Other inputs are streamed
+// once.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts of a request, including the
+	// first one. DefaultRetryAttempts is used if it is 0.
+	Attempts int
+	// Backoff is the delay before the first retry. DefaultRetryBackoff is
+	// used if it is 0.
+	Backoff time.Duration
+	// MaxBackoff, if not 0, caps the delay between attempts, except those
+	// requested with Retry-After.
+	MaxBackoff time.Duration
+	// Jitter is the fraction, between 0 and 1, of every delay which is
+	// random, so that clients failing together do not retry together. A
+	// Jitter of 0.2 waits between 80% and 100% of the delay.
+	Jitter float64
+	// MaxRetryAfter, if not 0, is the longest delay requested with
+	// Retry-After which is waited for. Responses requesting a longer one are
+	// returned without retrying.
+	MaxRetryAfter time.Duration
+}
+
+// WithRetry returns a ClientOption to retry the requests of the Client
+// according to p, as the Middleware of p.
+func WithRetry(p RetryPolicy) ClientOption {
+	return WithMiddleware(p.Middleware())
+}
+
+// retryRand returns a random number in [0, 1) for the jitter of retries.
+var retryRand = func() func() float64 {
+	var mu sync.Mutex
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return r.Float64()
+	}
+}()
+
+// delay returns the delay before the retry following attempt, given the
+// delay requested by the response with Retry-After, and whether to retry.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) (time.Duration, bool) {
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	wait := backoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || wait < p.MaxBackoff); i++ {
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		j := p.Jitter
+		if j > 1 {
+			j = 1
+		}
+		wait -= time.Duration(float64(wait) * j * retryRand())
+	}
+	if retryAfter > wait {
+		if p.MaxRetryAfter > 0 && retryAfter > p.MaxRetryAfter {
+			return 0, false
+		}
+		wait = retryAfter
+	}
+	return wait, true
+}
+
+// idempotent reports whether req can be sent several times with the same
+// effect as once.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// retryable returns whether a request which got resp and err may succeed if
+// retried.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Middleware returns a Middleware retrying requests according to p.
+func (p RetryPolicy) Middleware() Middleware {
+	attempts := p.Attempts
+	if attempts <= 0 {
+		attempts = DefaultRetryAttempts
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			replayable := idempotent(req) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+			for i := 1; ; i++ {
+				resp, err := next.RoundTrip(req)
+				if i >= attempts || !replayable || !retryable(resp, err) || ctx.Err() != nil {
+					return resp, err
+				}
+				var ra time.Duration
+				if resp != nil {
+					ra = retryAfter(resp, time.Now())
+				}
+				wait, ok := p.delay(i, ra)
+				if !ok {
+					return resp, err
+				}
+				if resp != nil {
+					io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+					resp.Body.Close()
+				}
+				t := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return nil, ctx.Err()
+				case <-t.C:
+				}
+				statsOf(req).retry()
+				if req.GetBody != nil {
+					body, err := req.GetBody()
+					if err != nil {
+						return nil, err
+					}
+					req = req.Clone(ctx)
+					req.Body = body
+				}
+			}
+		})
+	}
+}
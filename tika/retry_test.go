@@ -0,0 +1,188 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-tika/tika/tikatest"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	defer func(r func() float64) { retryRand = r }(retryRand)
+	retryRand = func() float64 { return 0.5 }
+	tests := []struct {
+		name       string
+		p          RetryPolicy
+		attempt    int
+		retryAfter time.Duration
+		want       time.Duration
+		wantRetry  bool
+	}{
+		{"default", RetryPolicy{}, 1, 0, DefaultRetryBackoff, true},
+		{"doubles", RetryPolicy{Backoff: time.Second}, 3, 0, 4 * time.Second, true},
+		{"capped", RetryPolicy{Backoff: time.Second, MaxBackoff: 3 * time.Second}, 5, 0, 3 * time.Second, true},
+		{"capped many", RetryPolicy{Backoff: time.Second, MaxBackoff: time.Minute}, 100, 0, time.Minute, true},
+		{"jitter", RetryPolicy{Backoff: time.Second, Jitter: 0.2}, 1, 0, 900 * time.Millisecond, true},
+		{"full jitter", RetryPolicy{Backoff: time.Second, Jitter: 5}, 1, 0, 500 * time.Millisecond, true},
+		{"retry after", RetryPolicy{Backoff: time.Second}, 1, 10 * time.Second, 10 * time.Second, true},
+		{"shorter retry after", RetryPolicy{Backoff: time.Second}, 1, time.Millisecond, time.Second, true},
+		{"retry after allowed", RetryPolicy{Backoff: time.Second, MaxRetryAfter: time.Minute}, 1, time.Minute, time.Minute, true},
+		{"retry after too long", RetryPolicy{Backoff: time.Second, MaxRetryAfter: time.Minute}, 1, time.Hour, 0, false},
+	}
+	for _, test := range tests {
+		got, ok := test.p.delay(test.attempt, test.retryAfter)
+		if got != test.want || ok != test.wantRetry {
+			t.Errorf("%s: delay = %v, %v, want %v, %v", test.name, got, ok, test.want, test.wantRetry)
+		}
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	tests := []struct {
+		method string
+		header string
+		want   bool
+	}{
+		{"", "", true},
+		{http.MethodGet, "", true},
+		{http.MethodPut, "", true},
+		{http.MethodPost, "", false},
+		{http.MethodPost, "Idempotency-Key", true},
+		{http.MethodPost, "X-Idempotency-Key", true},
+		{http.MethodPatch, "", false},
+	}
+	for _, test := range tests {
+		req := &http.Request{Method: test.method, Header: http.Header{}}
+		if test.header != "" {
+			req.Header.Set(test.header, "key")
+		}
+		if got := idempotent(req); got != test.want {
+			t.Errorf("idempotent(%s with %q) = %v, want %v", test.method, test.header, got, test.want)
+		}
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	s := tikatest.NewServer()
+	defer s.Close()
+	s.Inject("/tika", tikatest.Fault{Status: http.StatusServiceUnavailable, Times: 2})
+	c := NewClient(nil, s.URL, WithRetry(RetryPolicy{Backoff: time.Millisecond, Jitter: 0.5}))
+	if got, err := c.Parse(context.Background(), strings.NewReader("doc")); err != nil || got != "doc" {
+		t.Errorf("Parse = %q, %v, want it to succeed after the restarts", got, err)
+	}
+	if n := len(s.Requests()); n != 3 {
+		t.Errorf("Parse made %d attempts, want 3", n)
+	}
+	if st := c.Stats(); st.Requests != 1 || st.Retries != 2 {
+		t.Errorf("Stats() = %+v, want 1 request and 2 retries", st)
+	}
+}
+
+func TestRetryPolicyMiddleware(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	var retryAfter string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		n := attempts
+		mu.Unlock()
+		if n == 1 {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+	tests := []struct {
+		name       string
+		p          RetryPolicy
+		retryAfter string
+		call       func(c *Client) (string, error)
+		want       int
+		wantErr    bool
+	}{
+		{
+			name: "put",
+			p:    RetryPolicy{Backoff: time.Millisecond},
+			call: func(c *Client) (string, error) { return c.Parse(context.Background(), strings.NewReader("doc")) },
+			want: 2,
+		},
+		{
+			name: "post",
+			p:    RetryPolicy{Backoff: time.Millisecond},
+			call: func(c *Client) (string, error) {
+				return c.Translate(context.Background(), strings.NewReader("doc"), GoogleTranslator, "fr", "en")
+			},
+			want:    1,
+			wantErr: true,
+		},
+		{
+			name: "post with idempotency key",
+			p:    RetryPolicy{Backoff: time.Millisecond},
+			call: func(c *Client) (string, error) {
+				return c.Translate(context.Background(), strings.NewReader("doc"), GoogleTranslator, "fr", "en", WithHeader("Idempotency-Key", "k"))
+			},
+			want: 2,
+		},
+		{
+			name:       "retry after",
+			p:          RetryPolicy{Backoff: time.Millisecond, MaxRetryAfter: 2 * time.Second},
+			retryAfter: "1",
+			call:       func(c *Client) (string, error) { return c.Parse(context.Background(), strings.NewReader("doc")) },
+			want:       2,
+		},
+		{
+			name:       "retry after too long",
+			p:          RetryPolicy{Backoff: time.Millisecond, MaxRetryAfter: time.Second},
+			retryAfter: "3600",
+			call:       func(c *Client) (string, error) { return c.Parse(context.Background(), strings.NewReader("doc")) },
+			want:       1,
+			wantErr:    true,
+		},
+		{
+			name: "single attempt",
+			p:    RetryPolicy{Attempts: 1},
+			call: func(c *Client) (string, error) { return c.Parse(context.Background(), nil) },
+			want: 1, wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		attempts, retryAfter = 0, test.retryAfter
+		start := time.Now()
+		_, err := test.call(NewClient(nil, ts.URL, WithRetry(test.p)))
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error: %v", test.name, err, test.wantErr)
+		}
+		if attempts != test.want {
+			t.Errorf("%s: made %d attempts, want %d", test.name, attempts, test.want)
+		}
+		if test.retryAfter == "1" && time.Since(start) < time.Second {
+			t.Errorf("%s: retried after %v, want the requested 1s", test.name, time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2017, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		code  int
+		value string
+		want  time.Duration
+	}{
+		{code: 503, value: "", want: 0},
+		{code: 503, value: "120", want: 2 * time.Minute},
+		{code: 429, value: " 3 ", want: 3 * time.Second},
+		{code: 503, value: "-1", want: 0},
+		{code: 503, value: "99999999999999999", want: maxRetryAfterHeader},
+		{code: 503, value: "Mon, 01 May 2017 12:00:30 GMT", want: 30 * time.Second},
+		{code: 503, value: "Mon, 01 May 2017 11:00:00 GMT", want: 0},
+		{code: 503, value: "soon", want: 0},
+		{code: 500, value: "10", want: 0},
+	}
+	for _, test := range tests {
+		resp := &http.Response{StatusCode: test.code, Header: http.Header{}}
+		if test.value != "" {
+			resp.Header.Set("Retry-After", test.value)
+		}
+		if got := retryAfter(resp, now); got != test.want {
+			t.Errorf("retryAfter(%d, %q) = %v, want %v", test.code, test.value, got, test.want)
+		}
+	}
+}
+
+func TestTikaErrorRetryAfter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	_, err := NewClient(nil, ts.URL).Parse(context.Background(), strings.NewReader("input"))
+	var te *TikaError
+	if !errors.As(err, &te) || te.StatusCode != http.StatusServiceUnavailable || te.RetryAfter != 7*time.Second {
+		t.Errorf("Parse got error %#v, want a 503 TikaError with RetryAfter 7s", err)
+	}
+}
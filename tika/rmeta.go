@@ -0,0 +1,129 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// A MetaRecursiveIterator iterates over the metadata of the documents returned
+// by MetaRecursive, decoding them one at a time as they are streamed by the
+// server, so inputs with many embedded documents, such as large archives, are
+// not held in memory:
+//
+//	it, err := client.MetaRecursiveStream(ctx, input)
+//	if err != nil {
+//		return err
+//	}
+//	defer it.Close()
+//	for it.Next() {
+//		m := it.Metadata()
+//		// ...
+//	}
+//	return it.Err()
+type MetaRecursiveIterator struct {
+	body io.ReadCloser
+	dec  *json.Decoder
+	// started is whether the opening bracket of the array was read.
+	started bool
+	m       map[string][]string
+	err     error
+}
+
+// Next advances to the metadata of the next document, which is then available
+// through Metadata. It returns false when there are no more documents, or an
+// error occurred; see Err.
+func (it *MetaRecursiveIterator) Next() bool {
+	it.m = nil
+	if it.err != nil {
+		return false
+	}
+	if !it.started {
+		t, err := it.dec.Token()
+		if err == io.EOF {
+			// No content.
+			it.err = io.EOF
+			return false
+		}
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if d, ok := t.(json.Delim); !ok || d != '[' {
+			it.err = fmt.Errorf("expected an array of metadata, got %v", t)
+			return false
+		}
+		it.started = true
+	}
+	if !it.dec.More() {
+		if _, err := it.dec.Token(); err != nil {
+			it.err = err
+			return false
+		}
+		it.err = io.EOF
+		return false
+	}
+	var d map[string]interface{}
+	if err := it.dec.Decode(&d); err != nil {
+		it.err = err
+		return false
+	}
+	m, err := decodeMetadata(d)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.m = m
+	return true
+}
+
+// Metadata returns the metadata of the current document, from metadata key to
+// values, as in the result of MetaRecursive.
+func (it *MetaRecursiveIterator) Metadata() map[string][]string {
+	return it.m
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *MetaRecursiveIterator) Err() error {
+	if it.err == io.EOF {
+		return nil
+	}
+	return it.err
+}
+
+// Close ends the call. It must be called once done with the iterator.
+func (it *MetaRecursiveIterator) Close() error {
+	it.m = nil
+	return it.body.Close()
+}
+
+// MetaRecursiveStream is like MetaRecursive, but returns an iterator decoding
+// the metadata of the documents as they are streamed by the server. The first
+// document is the input, followed by its embedded documents. The caller must
+// close the iterator.
+func (c *Client) MetaRecursiveStream(ctx context.Context, input io.Reader, opts ...RequestOption) (*MetaRecursiveIterator, error) {
+	cfg := newCallConfig(opts)
+	cfg.setHeader("Accept", "application/json")
+	body, err := c.callStream(ctx, input, "PUT", c.recursivePath(cfg), cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &MetaRecursiveIterator{body: body, dec: json.NewDecoder(body)}, nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMetaRecursiveStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+
+	tests := []struct {
+		name    string
+		body    string
+		want    []map[string][]string
+		wantErr bool
+	}{
+		{
+			name: "documents",
+			body: `[{"X-TIKA:content":"a","resourceName":"a.zip"},{"X-TIKA:content":"b","k":["1","2"]}, {}]`,
+			want: []map[string][]string{
+				{"X-TIKA:content": {"a"}, "resourceName": {"a.zip"}},
+				{"X-TIKA:content": {"b"}, "k": {"1", "2"}},
+				{},
+			},
+		},
+		{name: "empty array", body: `[]`},
+		{name: "no content", body: ``},
+		{name: "not an array", body: `{"k":"v"}`, wantErr: true},
+		{name: "bad field type", body: `[{"k":"v"},{"k":1}]`, want: []map[string][]string{{"k": {"v"}}}, wantErr: true},
+		{name: "truncated", body: `[{"k":"v"},{"k":`, want: []map[string][]string{{"k": {"v"}}}, wantErr: true},
+	}
+	for _, test := range tests {
+		it, err := c.MetaRecursiveStream(context.Background(), strings.NewReader(test.body))
+		if err != nil {
+			t.Errorf("MetaRecursiveStream(%s) got error: %v", test.name, err)
+			continue
+		}
+		var got []map[string][]string
+		for it.Next() {
+			got = append(got, it.Metadata())
+		}
+		if err := it.Err(); (err != nil) != test.wantErr {
+			t.Errorf("MetaRecursiveStream(%s) got error %v, want error: %v", test.name, err, test.wantErr)
+		}
+		if err := it.Close(); err != nil {
+			t.Errorf("MetaRecursiveStream(%s) Close got error: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("MetaRecursiveStream(%s) got %v, want %v", test.name, got, test.want)
+		}
+		if it.Next() {
+			t.Errorf("MetaRecursiveStream(%s) Next after the end got true", test.name)
+		}
+	}
+}
+
+func TestMetaRecursiveStreamError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}))
+	defer ts.Close()
+	if _, err := NewClient(nil, ts.URL).MetaRecursiveStream(context.Background(), strings.NewReader("input")); err == nil {
+		t.Error("MetaRecursiveStream got no error")
+	}
+}
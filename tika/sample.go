@@ -0,0 +1,218 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// sizeClasses are the upper bounds of the size strata of a Sampler.
+var sizeClasses = []int64{10 << 10, 100 << 10, 1 << 20, 10 << 20}
+
+// sizeClass returns the index of the size stratum of size.
+func sizeClass(size int64) int {
+	for i, max := range sizeClasses {
+		if size < max {
+			return i
+		}
+	}
+	return len(sizeClasses)
+}
+
+type stratumKey struct {
+	contentType string
+	sizeClass   int
+}
+
+// stratum is a uniform random sample of the Documents of a stratum, kept
+// with reservoir sampling.
+type stratum struct {
+	key       stratumKey
+	seen      int
+	reservoir []Document
+}
+
+// Sampler selects a representative sample of Documents for quality review.
+// Documents are grouped by content type and order of magnitude of size, and
+// each group is represented in proportion to its number of Documents, with at
+// least one Document per group when the sample is large enough. Sampler only
+// keeps the Documents it may select, so it can be used on runs of any size. A
+// Sampler is safe for concurrent use.
+type Sampler struct {
+	n    int
+	seed int64
+	mu   sync.Mutex
+	rnd  *rand.Rand
+
+	total  int
+	strata map[stratumKey]*stratum
+}
+
+// NewSampler creates a Sampler selecting up to n Documents, using the given
+// seed for randomness.
+func NewSampler(n int, seed int64) *Sampler {
+	return &Sampler{
+		n:      n,
+		seed:   seed,
+		rnd:    rand.New(rand.NewSource(seed)),
+		strata: make(map[stratumKey]*stratum),
+	}
+}
+
+// Add offers d to the sample.
+func (s *Sampler) Add(d Document) {
+	ct := d.ContentType
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = strings.TrimSpace(ct[:i])
+	}
+	k := stratumKey{contentType: ct, sizeClass: sizeClass(d.Size)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.strata[k]
+	if st == nil {
+		st = &stratum{key: k}
+		s.strata[k] = st
+	}
+	s.total++
+	st.seen++
+	if len(st.reservoir) < s.n {
+		st.reservoir = append(st.reservoir, d)
+		return
+	}
+	if j := s.rnd.Intn(st.seen); j < s.n {
+		st.reservoir[j] = d
+	}
+}
+
+// Sample returns the selected Documents, grouped by stratum. It does not change
+// the Sampler, so Sample may be called again after more Documents were added.
+func (s *Sampler) Sample() []Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var strata []*stratum
+	for _, st := range s.strata {
+		strata = append(strata, st)
+	}
+	sort.Slice(strata, func(i, j int) bool {
+		if strata[i].seen != strata[j].seen {
+			return strata[i].seen > strata[j].seen
+		}
+		if strata[i].key.contentType != strata[j].key.contentType {
+			return strata[i].key.contentType < strata[j].key.contentType
+		}
+		return strata[i].key.sizeClass < strata[j].key.sizeClass
+	})
+
+	// Give one Document to each stratum, largest first, then hand out the rest
+	// to the strata furthest below their proportional share.
+	alloc := make([]int, len(strata))
+	remaining := s.n
+	for i := range strata {
+		if remaining == 0 {
+			break
+		}
+		alloc[i] = 1
+		remaining--
+	}
+	for ; remaining > 0; remaining-- {
+		best, bestDeficit := -1, 0.0
+		for i, st := range strata {
+			if alloc[i] >= len(st.reservoir) {
+				continue
+			}
+			deficit := float64(st.seen)*float64(s.n)/float64(s.total) - float64(alloc[i])
+			if best < 0 || deficit > bestDeficit {
+				best, bestDeficit = i, deficit
+			}
+		}
+		if best < 0 {
+			break // Every Document seen is in the sample.
+		}
+		alloc[best]++
+	}
+
+	// Shuffle copies of the reservoirs, with a source of its own, so the
+	// sampling of the Documents added later is unchanged.
+	rnd := rand.New(rand.NewSource(s.seed + int64(s.total)))
+	var r []Document
+	for i, st := range strata {
+		docs := append([]Document(nil), st.reservoir...)
+		rnd.Shuffle(len(docs), func(a, b int) {
+			docs[a], docs[b] = docs[b], docs[a]
+		})
+		r = append(r, docs[:alloc[i]]...)
+	}
+	return r
+}
+
+// reviewEntry is an entry of the manifest of a review bundle.
+type reviewEntry struct {
+	ID          string `json:"id"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Content     string `json:"content"`
+	Metadata    string `json:"metadata"`
+}
+
+// WriteReviewBundle writes docs to dir for manual review. Each Document is
+// written as a text file with its content and a JSON file with its metadata,
+// and manifest.json lists the Documents and their files.
+func WriteReviewBundle(dir string, docs []Document) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating review bundle: %w", err)
+	}
+	var manifest []reviewEntry
+	for i, d := range docs {
+		e := reviewEntry{
+			ID:          d.ID,
+			ContentType: d.ContentType,
+			Size:        d.Size,
+			Content:     fmt.Sprintf("%04d.txt", i),
+			Metadata:    fmt.Sprintf("%04d.json", i),
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, e.Content), []byte(d.Content), 0644); err != nil {
+			return fmt.Errorf("error writing review bundle: %w", err)
+		}
+		if err := writeJSONFile(filepath.Join(dir, e.Metadata), d.Metadata); err != nil {
+			return fmt.Errorf("error writing review bundle: %w", err)
+		}
+		manifest = append(manifest, e)
+	}
+	if err := writeJSONFile(filepath.Join(dir, "manifest.json"), manifest); err != nil {
+		return fmt.Errorf("error writing review bundle: %w", err)
+	}
+	return nil
+}
+
+// writeJSONFile writes v to path as indented JSON.
+func writeJSONFile(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
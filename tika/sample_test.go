@@ -0,0 +1,119 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSampler(t *testing.T) {
+	s := NewSampler(10, 1)
+	// 90 small PDFs, 9 large PDFs and a single email.
+	for i := 0; i < 90; i++ {
+		s.Add(Document{ID: fmt.Sprintf("small-%d", i), ContentType: "application/pdf", Size: 100})
+	}
+	for i := 0; i < 9; i++ {
+		s.Add(Document{ID: fmt.Sprintf("large-%d", i), ContentType: "application/pdf", Size: 50 << 20})
+	}
+	s.Add(Document{ID: "mail", ContentType: "message/rfc822; charset=UTF-8", Size: 100})
+
+	got := make(map[string]int)
+	seen := make(map[string]bool)
+	for _, d := range s.Sample() {
+		if seen[d.ID] {
+			t.Errorf("Sample() returned %s twice", d.ID)
+		}
+		seen[d.ID] = true
+		got[fmt.Sprintf("%s/%d", d.ContentType, sizeClass(d.Size))]++
+	}
+	want := map[string]int{
+		"application/pdf/0":               8,
+		"application/pdf/4":               1,
+		"message/rfc822; charset=UTF-8/0": 1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Sample() strata = %v, want %v", got, want)
+	}
+
+	// Sample leaves the Sampler as it was.
+	before := make(map[stratumKey][]Document)
+	for k, st := range s.strata {
+		before[k] = append([]Document(nil), st.reservoir...)
+	}
+	if first, second := s.Sample(), s.Sample(); !reflect.DeepEqual(first, second) {
+		t.Errorf("second Sample() = %v, want %v", second, first)
+	}
+	after := make(map[stratumKey][]Document)
+	for k, st := range s.strata {
+		after[k] = st.reservoir
+	}
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("Sample() changed the reservoirs")
+	}
+}
+
+func TestSamplerSmallRun(t *testing.T) {
+	s := NewSampler(10, 1)
+	for i := 0; i < 3; i++ {
+		s.Add(Document{ID: fmt.Sprint(i)})
+	}
+	if got := len(s.Sample()); got != 3 {
+		t.Errorf("Sample() of 3 Documents returned %d, want 3", got)
+	}
+	if got := NewSampler(0, 1).Sample(); len(got) != 0 {
+		t.Errorf("Sample() of size 0 returned %v", got)
+	}
+}
+
+func TestWriteReviewBundle(t *testing.T) {
+	dir := filepath.Join(tempDir(t), "bundle")
+	docs := []Document{
+		{ID: "a.pdf", ContentType: "application/pdf", Size: 3, Content: "text a", Metadata: map[string][]string{"k": {"v"}}},
+		{ID: "b.txt", Content: "text b"},
+	}
+	if err := WriteReviewBundle(dir, docs); err != nil {
+		t.Fatalf("WriteReviewBundle got error: %v", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("error reading manifest: %v", err)
+	}
+	var manifest []reviewEntry
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		t.Fatalf("invalid manifest: %v", err)
+	}
+	if len(manifest) != 2 || manifest[0].ID != "a.pdf" {
+		t.Fatalf("manifest = %+v, want both documents", manifest)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, manifest[1].Content))
+	if err != nil || string(content) != "text b" {
+		t.Errorf("content of b.txt = %q, %v, want %q", content, err, "text b")
+	}
+	meta, err := ioutil.ReadFile(filepath.Join(dir, manifest[0].Metadata))
+	if err != nil {
+		t.Fatalf("error reading metadata: %v", err)
+	}
+	var m map[string][]string
+	if err := json.Unmarshal(meta, &m); err != nil || !reflect.DeepEqual(m, docs[0].Metadata) {
+		t.Errorf("metadata of a.pdf = %s, want %v", meta, docs[0].Metadata)
+	}
+}
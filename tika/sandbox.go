@@ -0,0 +1,89 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+// deadProxyHost and deadProxyPort are the proxy of a server without network:
+// nothing listens on the discard port of loopback, so connections fail at
+// once.
+const deadProxyHost, deadProxyPort = "127.0.0.1", "9"
+
+// noNetworkFlags are the JVM flags of WithNoNetwork.
+var noNetworkFlags = []string{
+	// Refuse to fetch external DTDs, schemas and stylesheets, the usual way
+	// parsers are made to leak data.
+	"-Djavax.xml.accessExternalDTD=",
+	"-Djavax.xml.accessExternalSchema=",
+	"-Djavax.xml.accessExternalStylesheet=",
+	// Send every other outgoing connection of the standard library to a
+	// dead proxy.
+	"-Djava.net.useSystemProxies=false",
+	"-Dhttp.proxyHost=" + deadProxyHost, "-Dhttp.proxyPort=" + deadProxyPort,
+	"-Dhttps.proxyHost=" + deadProxyHost, "-Dhttps.proxyPort=" + deadProxyPort,
+	"-Dftp.proxyHost=" + deadProxyHost, "-Dftp.proxyPort=" + deadProxyPort,
+	"-DsocksProxyHost=" + deadProxyHost, "-DsocksProxyPort=" + deadProxyPort,
+	"-Dhttp.nonProxyHosts=", "-Dftp.nonProxyHosts=",
+}
+
+// WithNoNetwork returns an Option to keep the Java process from reaching the
+// network, since parsers fetching remote DTDs and other resources is a known
+// way to exfiltrate the content of documents. The server still listens on
+// its port.
+//
+// WithNoNetwork is enforced by the JVM: external XML resources are refused
+// and connections go through a dead proxy, which code opening raw sockets
+// bypasses. For the kernel to enforce it, also run the server in a sandbox
+// allowing loopback only, with WithLauncher, for example:
+//
+//	tika.WithLauncher("systemd-run", "--user", "--scope",
+//		"-p", "IPAddressDeny=any", "-p", "IPAddressAllow=localhost")
+//
+// A network namespace cannot be used, since the server would not be reachable
+// from the Client.
+func WithNoNetwork() Option {
+	return func(s *Server) {
+		s.jvmFlags = append(s.jvmFlags, noNetworkFlags...)
+	}
+}
+
+// WithJVMFlags returns an Option to pass flags to the JVM, such as
+// "-Xmx2g" or "-Djava.security.manager".
+func WithJVMFlags(flags ...string) Option {
+	return func(s *Server) {
+		s.jvmFlags = append(s.jvmFlags, flags...)
+	}
+}
+
+// WithLauncher returns an Option to start java through the command argv,
+// followed by the java command line, to run the server in a sandbox. For
+// example, WithAppArmorProfile uses aa-exec; seccomp filters can be applied
+// the same way with a launcher such as bwrap --seccomp or systemd-run -p
+// SystemCallFilter=.
+func WithLauncher(argv ...string) Option {
+	return func(s *Server) {
+		s.launcher = append([]string{}, argv...)
+	}
+}
+
+// WithAppArmorProfile returns an Option to run the server confined by the
+// AppArmor profile named profile, which must be loaded. A profile for Tika
+// needs to allow reading the JAR and the Java runtime, writing to the
+// temporary directory and listening on TCP; it should deny everything else,
+// notably executing programs. WithAppArmorProfile is a WithLauncher of
+// aa-exec.
+func WithAppArmorProfile(profile string) Option {
+	return WithLauncher("aa-exec", "-p", profile, "--")
+}
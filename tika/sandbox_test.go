@@ -0,0 +1,72 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestSandboxCommand(t *testing.T) {
+	old := cmder
+	defer func() { cmder = old }()
+	var got []string
+	cmder = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		got = append([]string{name}, args...)
+		return exec.CommandContext(ctx, name, args...)
+	}
+	tail := []string{"-jar", "server_test.go", "-h", "localhost", "-p", "9998"}
+	tests := []struct {
+		name    string
+		options []Option
+		want    []string
+	}{
+		{
+			name: "defaults",
+			want: append([]string{"java"}, tail...),
+		},
+		{
+			name:    "JVM flags",
+			options: []Option{WithJVMFlags("-Xmx1g"), WithJVMFlags("-Dx=y")},
+			want:    append([]string{"java", "-Xmx1g", "-Dx=y"}, tail...),
+		},
+		{
+			name:    "no network",
+			options: []Option{WithNoNetwork()},
+			want:    append(append([]string{"java"}, noNetworkFlags...), tail...),
+		},
+		{
+			name:    "AppArmor",
+			options: []Option{WithAppArmorProfile("tika"), WithJVMFlags("-Xmx1g")},
+			want:    append([]string{"aa-exec", "-p", "tika", "--", "java", "-Xmx1g"}, tail...),
+		},
+	}
+	for _, test := range tests {
+		s, err := NewServer("server_test.go", test.options...)
+		if err != nil {
+			t.Fatalf("NewServer(%s) got error: %v", test.name, err)
+		}
+		if _, err := s.command(context.Background(), s.jar, ""); err != nil {
+			t.Fatalf("command(%s) got error: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("command(%s) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
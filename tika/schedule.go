@@ -0,0 +1,191 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Scheduler shares a fixed number of concurrent calls to a Tika Server, called
+// slots, between jobs. When several jobs wait for a slot, the next one goes to
+// the job which has used the least slot time relative to its weight, so a
+// large job cannot starve the others, and a job parsing large documents does
+// not get more of the server than one parsing small ones. A Scheduler is safe
+// for concurrent use.
+//
+// Wrap each call with Acquire and the release function it returns:
+//
+//	release, err := sched.Acquire(ctx, "backfill")
+//	if err != nil {
+//		return err
+//	}
+//	body, err := client.Parse(ctx, input)
+//	release()
+type Scheduler struct {
+	mu   sync.Mutex
+	free int
+	jobs map[string]*schedJob
+	now  func() time.Time // now is stubbed out for testing.
+}
+
+// schedJob is the state of a job of a Scheduler.
+type schedJob struct {
+	name   string
+	weight int
+	// vtime is the slot time used by the job, divided by its weight.
+	vtime   time.Duration
+	waiters []*schedWaiter
+	running int
+}
+
+type schedWaiter struct {
+	ready   chan struct{}
+	granted bool
+	start   time.Time
+}
+
+// NewScheduler creates a Scheduler with the given number of slots.
+func NewScheduler(slots int) *Scheduler {
+	return &Scheduler{
+		free: slots,
+		jobs: make(map[string]*schedJob),
+		now:  time.Now,
+	}
+}
+
+// SetWeight sets the weight of the job with the given name (default 1). A job
+// with weight 2 gets twice as much slot time as a job with weight 1 when both
+// are waiting.
+func (s *Scheduler) SetWeight(job string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.job(job).weight = weight
+}
+
+// job returns the job with the given name, creating it if needed.
+func (s *Scheduler) job(name string) *schedJob {
+	j := s.jobs[name]
+	if j == nil {
+		j = &schedJob{name: name, weight: 1}
+		s.jobs[name] = j
+	}
+	return j
+}
+
+// Acquire waits for a slot for the given job and returns a function to
+// release it, which must be called once the call to Tika is done. Acquire
+// returns an error if ctx is done first.
+func (s *Scheduler) Acquire(ctx context.Context, job string) (release func(), err error) {
+	s.mu.Lock()
+	j := s.job(job)
+	if len(j.waiters) == 0 && j.running == 0 {
+		// A job which was idle does not get credit for the time it was idle.
+		if min, ok := s.minActiveVTime(); ok && j.vtime < min {
+			j.vtime = min
+		}
+	}
+	w := &schedWaiter{ready: make(chan struct{})}
+	j.waiters = append(j.waiters, w)
+	s.dispatch()
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return s.releaser(j, w), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if w.granted {
+			s.release(j, w)
+		} else {
+			j.remove(w)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// releaser returns a function releasing the slot of w once.
+func (s *Scheduler) releaser(j *schedJob, w *schedWaiter) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.release(j, w)
+		})
+	}
+}
+
+// release charges j for the slot time of w and hands the slot out again.
+func (s *Scheduler) release(j *schedJob, w *schedWaiter) {
+	j.vtime += s.now().Sub(w.start) / time.Duration(j.weight)
+	j.running--
+	s.free++
+	s.dispatch()
+}
+
+func (j *schedJob) remove(w *schedWaiter) {
+	for i, o := range j.waiters {
+		if o == w {
+			j.waiters = append(j.waiters[:i], j.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// minActiveVTime returns the smallest vtime of the jobs which are running or
+// waiting.
+func (s *Scheduler) minActiveVTime() (time.Duration, bool) {
+	var min time.Duration
+	found := false
+	for _, j := range s.jobs {
+		if (len(j.waiters) > 0 || j.running > 0) && (!found || j.vtime < min) {
+			min, found = j.vtime, true
+		}
+	}
+	return min, found
+}
+
+// dispatch hands out free slots to the waiting jobs with the smallest vtime.
+func (s *Scheduler) dispatch() {
+	for s.free > 0 {
+		var next *schedJob
+		for _, j := range s.jobs {
+			if len(j.waiters) == 0 {
+				continue
+			}
+			if next == nil || j.vtime < next.vtime || (j.vtime == next.vtime && j.name < next.name) {
+				next = j
+			}
+		}
+		if next == nil {
+			return
+		}
+		w := next.waiters[0]
+		next.waiters = next.waiters[1:]
+		next.running++
+		s.free--
+		w.granted = true
+		w.start = s.now()
+		close(w.ready)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock which only moves when told to.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+// waiting returns the number of calls waiting for a slot.
+func (s *Scheduler) waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, j := range s.jobs {
+		n += len(j.waiters)
+	}
+	return n
+}
+
+func TestSchedulerFairness(t *testing.T) {
+	tests := []struct {
+		name    string
+		weightB int
+		want    []string
+	}{
+		{
+			name:    "equal weights",
+			weightB: 1,
+			want:    []string{"A", "B", "A", "B", "A", "A", "A"},
+		},
+		{
+			name:    "double weight",
+			weightB: 2,
+			want:    []string{"A", "B", "B", "A", "A", "A", "A"},
+		},
+	}
+	for _, test := range tests {
+		clock := &fakeClock{t: time.Unix(0, 0)}
+		s := NewScheduler(1)
+		s.now = clock.now
+		s.SetWeight("B", test.weightB)
+
+		// A holds the only slot while A queues 4 more calls and B queues 2.
+		first, err := s.Acquire(context.Background(), "A")
+		if err != nil {
+			t.Fatalf("Acquire(%s) got error: %v", test.name, err)
+		}
+		granted := make(chan string)
+		releases := make(chan func())
+		queue := func(job string) {
+			go func() {
+				release, err := s.Acquire(context.Background(), job)
+				if err != nil {
+					t.Errorf("Acquire(%s) got error: %v", test.name, err)
+					return
+				}
+				granted <- job
+				releases <- release
+			}()
+			for n := s.waiting(); s.waiting() == n; {
+				runtime.Gosched()
+			}
+		}
+		for _, job := range []string{"A", "A", "A", "A", "B", "B"} {
+			queue(job)
+		}
+
+		got := []string{"A"}
+		release := first
+		for len(got) < len(test.want) {
+			// Every call holds the slot for a second.
+			clock.t = clock.t.Add(time.Second)
+			release()
+			got = append(got, <-granted)
+			release = <-releases
+		}
+		release()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Scheduler(%s) ran %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	s := NewScheduler(1)
+	release, err := s.Acquire(context.Background(), "A")
+	if err != nil {
+		t.Fatalf("Acquire got error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx, "B"); err == nil {
+		t.Fatalf("Acquire with no free slot got no error, want a timeout")
+	}
+	if n := s.waiting(); n != 0 {
+		t.Errorf("canceled Acquire left %d waiters", n)
+	}
+	release()
+	release() // Releasing twice is a no-op.
+	r1, err := s.Acquire(context.Background(), "B")
+	if err != nil {
+		t.Fatalf("Acquire after release got error: %v", err)
+	}
+	r1()
+	if s.free != 1 {
+		t.Errorf("Scheduler has %d free slots after all releases, want 1", s.free)
+	}
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// FieldType is the inferred type of a metadata field.
+type FieldType string
+
+// Types of metadata fields, from the most to the least specific.
+const (
+	FieldBoolean FieldType = "boolean"
+	FieldInteger FieldType = "integer"
+	FieldFloat   FieldType = "float"
+	FieldDate    FieldType = "date"
+	FieldString  FieldType = "string"
+)
+
+// maxCardinality is the number of distinct values counted per field.
+const maxCardinality = 1000
+
+// dateLayouts are the date formats used by Tika.
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// valueType returns the most specific FieldType of v.
+func valueType(v string) FieldType {
+	if v == "true" || v == "false" {
+		return FieldBoolean
+	}
+	if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return FieldInteger
+	}
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return FieldFloat
+	}
+	for _, l := range dateLayouts {
+		if _, err := time.Parse(l, v); err == nil {
+			return FieldDate
+		}
+	}
+	return FieldString
+}
+
+// widen returns the most specific FieldType of values of types a and b.
+func widen(a, b FieldType) FieldType {
+	switch {
+	case a == "" || a == b:
+		return b
+	case (a == FieldInteger && b == FieldFloat) || (a == FieldFloat && b == FieldInteger):
+		return FieldFloat
+	}
+	return FieldString
+}
+
+// A FieldSchema describes a metadata field across a corpus.
+type FieldSchema struct {
+	Name string    `json:"name"`
+	Type FieldType `json:"type"`
+	// Count is the number of documents with the field.
+	Count int `json:"count"`
+	// MultiValued is whether a document had more than one value.
+	MultiValued bool `json:"multiValued"`
+	// Cardinality is the number of distinct values, counted up to 1000. Past
+	// that, CardinalityCapped is true.
+	Cardinality       int  `json:"cardinality"`
+	CardinalityCapped bool `json:"cardinalityCapped"`
+	// MaxLength is the length of the longest value.
+	MaxLength int `json:"maxLength"`
+}
+
+// A Schema describes the metadata fields of a corpus.
+type Schema struct {
+	// Documents is the number of documents the Schema was inferred from.
+	Documents int `json:"documents"`
+	// Fields are sorted by Name.
+	Fields []FieldSchema `json:"fields"`
+}
+
+// SchemaBuilder infers a Schema from the metadata of many documents. A
+// SchemaBuilder is safe for concurrent use.
+type SchemaBuilder struct {
+	mu     sync.Mutex
+	docs   int
+	fields map[string]*fieldStats
+}
+
+type fieldStats struct {
+	FieldSchema
+	values map[string]struct{}
+}
+
+// NewSchemaBuilder creates an empty SchemaBuilder.
+func NewSchemaBuilder() *SchemaBuilder {
+	return &SchemaBuilder{fields: make(map[string]*fieldStats)}
+}
+
+// Add adds the metadata of a document, such as an element of the result of
+// MetaRecursive, to the Schema.
+func (b *SchemaBuilder) Add(metadata map[string][]string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.docs++
+	for k, vs := range metadata {
+		f := b.fields[k]
+		if f == nil {
+			f = &fieldStats{FieldSchema: FieldSchema{Name: k}, values: make(map[string]struct{})}
+			b.fields[k] = f
+		}
+		f.Count++
+		if len(vs) > 1 {
+			f.MultiValued = true
+		}
+		for _, v := range vs {
+			f.Type = widen(f.Type, valueType(v))
+			if len(v) > f.MaxLength {
+				f.MaxLength = len(v)
+			}
+			if _, ok := f.values[v]; !ok {
+				if len(f.values) < maxCardinality {
+					f.values[v] = struct{}{}
+				} else {
+					f.CardinalityCapped = true
+				}
+			}
+		}
+	}
+}
+
+// Schema returns the Schema inferred so far.
+func (b *SchemaBuilder) Schema() *Schema {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := &Schema{Documents: b.docs}
+	for _, f := range b.fields {
+		fs := f.FieldSchema
+		fs.Cardinality = len(f.values)
+		if fs.Type == "" {
+			fs.Type = FieldString // The field never had a value.
+		}
+		s.Fields = append(s.Fields, fs)
+	}
+	sort.Slice(s.Fields, func(i, j int) bool { return s.Fields[i].Name < s.Fields[j].Name })
+	return s
+}
+
+// WriteReport writes a human readable table of s to w.
+func (s *Schema) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "FIELD\tTYPE\tCOVERAGE\tMULTI\tDISTINCT\tMAX LEN\n")
+	for _, f := range s.Fields {
+		distinct := strconv.Itoa(f.Cardinality)
+		if f.CardinalityCapped {
+			distinct += "+"
+		}
+		coverage := 0.0
+		if s.Documents > 0 {
+			coverage = 100 * float64(f.Count) / float64(s.Documents)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.1f%%\t%t\t%s\t%d\n", f.Name, f.Type, coverage, f.MultiValued, distinct, f.MaxLength)
+	}
+	return tw.Flush()
+}
+
+// keywordCardinality is the largest number of distinct values of a string
+// field mapped as a keyword rather than text.
+const keywordCardinality = 100
+
+// ElasticsearchMapping returns an Elasticsearch index mapping for the fields
+// of s, as JSON. Strings with few distinct, short values are mapped as
+// keywords, and other strings as text with a keyword subfield.
+func (s *Schema) ElasticsearchMapping() ([]byte, error) {
+	props := make(map[string]interface{})
+	for _, f := range s.Fields {
+		var m map[string]interface{}
+		switch f.Type {
+		case FieldBoolean:
+			m = map[string]interface{}{"type": "boolean"}
+		case FieldInteger:
+			m = map[string]interface{}{"type": "long"}
+		case FieldFloat:
+			m = map[string]interface{}{"type": "double"}
+		case FieldDate:
+			m = map[string]interface{}{"type": "date"}
+		default:
+			if !f.CardinalityCapped && f.Cardinality <= keywordCardinality && f.MaxLength <= 256 {
+				m = map[string]interface{}{"type": "keyword"}
+			} else {
+				m = map[string]interface{}{
+					"type": "text",
+					"fields": map[string]interface{}{
+						"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256},
+					},
+				}
+			}
+		}
+		props[f.Name] = m
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"mappings": map[string]interface{}{"properties": props},
+	}, "", "  ")
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValueType(t *testing.T) {
+	tests := map[string]FieldType{
+		"true":                 FieldBoolean,
+		"42":                   FieldInteger,
+		"-1.5":                 FieldFloat,
+		"2017-01-01T00:00:00Z": FieldDate,
+		"2017-01-01":           FieldDate,
+		"hello":                FieldString,
+	}
+	for v, want := range tests {
+		if got := valueType(v); got != want {
+			t.Errorf("valueType(%q) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestSchemaBuilder(t *testing.T) {
+	b := NewSchemaBuilder()
+	b.Add(map[string][]string{
+		"Content-Type":  {"application/pdf"},
+		"xmpTPg:NPages": {"3"},
+		"size":          {"1"},
+		"dc:creator":    {"ann", "bob"},
+	})
+	b.Add(map[string][]string{
+		"Content-Type":    {"application/pdf"},
+		"size":            {"1.5"},
+		"dcterms:created": {"2017-01-01T00:00:00Z"},
+		"mixed":           {"true"},
+	})
+	b.Add(map[string][]string{
+		"Content-Type": {"text/plain"},
+		"mixed":        {"2"},
+	})
+	got := b.Schema()
+	want := &Schema{
+		Documents: 3,
+		Fields: []FieldSchema{
+			{Name: "Content-Type", Type: FieldString, Count: 3, Cardinality: 2, MaxLength: 15},
+			{Name: "dc:creator", Type: FieldString, Count: 1, MultiValued: true, Cardinality: 2, MaxLength: 3},
+			{Name: "dcterms:created", Type: FieldDate, Count: 1, Cardinality: 1, MaxLength: 20},
+			{Name: "mixed", Type: FieldString, Count: 2, Cardinality: 2, MaxLength: 4},
+			{Name: "size", Type: FieldFloat, Count: 2, Cardinality: 2, MaxLength: 3},
+			{Name: "xmpTPg:NPages", Type: FieldInteger, Count: 1, Cardinality: 1, MaxLength: 1},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Schema() = %+v, want %+v", got, want)
+	}
+
+	var report bytes.Buffer
+	if err := got.WriteReport(&report); err != nil {
+		t.Fatalf("WriteReport got error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(report.String()), "\n"); len(lines) != 7 || !strings.Contains(lines[1], "100.0%") {
+		t.Errorf("WriteReport wrote:\n%s\nwant a header and 6 fields", report.String())
+	}
+}
+
+func TestElasticsearchMapping(t *testing.T) {
+	b := NewSchemaBuilder()
+	for i := 0; i < keywordCardinality+1; i++ {
+		b.Add(map[string][]string{
+			"title":   {fmt.Sprintf("title %d", i)},
+			"type":    {"application/pdf"},
+			"pages":   {"1"},
+			"created": {"2017-01-01"},
+		})
+	}
+	mapping, err := b.Schema().ElasticsearchMapping()
+	if err != nil {
+		t.Fatalf("ElasticsearchMapping got error: %v", err)
+	}
+	var got struct {
+		Mappings struct {
+			Properties map[string]struct {
+				Type string `json:"type"`
+			} `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := json.Unmarshal(mapping, &got); err != nil {
+		t.Fatalf("ElasticsearchMapping returned invalid JSON: %v", err)
+	}
+	want := map[string]string{"title": "text", "type": "keyword", "pages": "long", "created": "date"}
+	for field, typ := range want {
+		if got := got.Mappings.Properties[field].Type; got != typ {
+			t.Errorf("mapping of %s has type %q, want %q", field, got, typ)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// A LanguageSpan is a part of a text in a single language, as returned by
+// LanguageSegmenter.Segment.
+type LanguageSpan struct {
+	// Language is the language code of the span, such as "en".
+	Language string
+	// Start and End are the byte offsets of the span in the text.
+	Start, End int
+	// Text is the text of the span.
+	Text string
+}
+
+// DefaultMinParagraph is the number of characters under which paragraphs are
+// not detected by a LanguageSegmenter with no MinParagraph.
+const DefaultMinParagraph = 40
+
+// A LanguageSegmenter splits mixed-language texts, such as a contract with an
+// appendix in another language, into spans of a single language, by detecting
+// the language of each paragraph.
+type LanguageSegmenter struct {
+	// Client detects the languages with LanguageString, unless Detect is not
+	// nil, and translates the spans in Translate.
+	Client *Client
+	// Detect, if not nil, detects the language of a paragraph instead of the
+	// Tika Server, for example with a local detection library, which is
+	// faster than a call per paragraph.
+	Detect func(ctx context.Context, text string) (string, error)
+	// MinParagraph is the number of characters under which paragraphs, such
+	// as titles or list items, are too short for a reliable detection and are
+	// made part of the previous span, or the next one at the start of the
+	// text. DefaultMinParagraph is used if it is 0.
+	MinParagraph int
+}
+
+func (s *LanguageSegmenter) detect(ctx context.Context, text string) (string, error) {
+	if s.Detect != nil {
+		return s.Detect(ctx, text)
+	}
+	if s.Client == nil {
+		return "", errors.New("no Client or Detect to detect languages")
+	}
+	lang, err := s.Client.LanguageString(ctx, text)
+	return strings.TrimSpace(lang), err
+}
+
+// paragraphs returns the end offsets of the paragraphs of text, which are
+// separated by blank lines. Each paragraph includes the blank lines after it.
+func paragraphs(text string) []int {
+	var ends []int
+	blank, seenText := false, false
+	lineStart := 0
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && text[i] != '\n' {
+			continue
+		}
+		isBlank := strings.TrimSpace(text[lineStart:i]) == ""
+		if !isBlank && blank && seenText {
+			ends = append(ends, lineStart)
+		}
+		if !isBlank {
+			seenText = true
+		}
+		blank = isBlank
+		lineStart = i + 1
+	}
+	if len(text) > 0 {
+		ends = append(ends, len(text))
+	}
+	return ends
+}
+
+// Segment returns the spans of text in each language, in order. The spans
+// cover all of text, and consecutive spans have different languages. If no
+// paragraph is long enough to be detected, text is detected as a whole.
+func (s *LanguageSegmenter) Segment(ctx context.Context, text string) ([]LanguageSpan, error) {
+	min := s.MinParagraph
+	if min == 0 {
+		min = DefaultMinParagraph
+	}
+	var spans []LanguageSpan
+	// pending is the start of the short paragraphs at the start of text,
+	// waiting for the language of the next span.
+	pending := -1
+	start := 0
+	for _, end := range paragraphs(text) {
+		para := text[start:end]
+		if utf8.RuneCountInString(strings.TrimSpace(para)) < min {
+			if len(spans) > 0 {
+				spans[len(spans)-1].End = end
+			} else if pending < 0 {
+				pending = start
+			}
+			start = end
+			continue
+		}
+		lang, err := s.detect(ctx, para)
+		if err != nil {
+			return nil, fmt.Errorf("error detecting the language at offset %d: %w", start, err)
+		}
+		switch {
+		case len(spans) > 0 && spans[len(spans)-1].Language == lang:
+			spans[len(spans)-1].End = end
+		case pending >= 0:
+			spans = append(spans, LanguageSpan{Language: lang, Start: pending, End: end})
+			pending = -1
+		default:
+			spans = append(spans, LanguageSpan{Language: lang, Start: start, End: end})
+		}
+		start = end
+	}
+	if len(spans) == 0 && strings.TrimSpace(text) != "" {
+		lang, err := s.detect(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("error detecting the language: %w", err)
+		}
+		spans = append(spans, LanguageSpan{Language: lang, End: len(text)})
+	}
+	for i := range spans {
+		spans[i].Text = text[spans[i].Start:spans[i].End]
+	}
+	return spans, nil
+}
+
+// Translate translates the spans of text not in the dst language with t, and
+// returns text with the translated spans in place of the original ones. Spans
+// already in dst are not sent to the translator, which saves the cost of
+// translating documents mostly in dst. Spans of an unknown language are
+// translated with TranslateAuto.
+func (s *LanguageSegmenter) Translate(ctx context.Context, text string, t Translator, dst string, opts ...RequestOption) (string, error) {
+	if err := checkLanguage(dst); err != nil {
+		return "", err
+	}
+	if s.Client == nil {
+		return "", errors.New("no Client to translate with")
+	}
+	spans, err := s.Segment(ctx, text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, span := range spans {
+		if sameLanguage(span.Language, dst) {
+			b.WriteString(span.Text)
+			continue
+		}
+		var tr string
+		if checkLanguage(span.Language) == nil {
+			tr, err = s.Client.Translate(ctx, strings.NewReader(span.Text), t, span.Language, dst, opts...)
+		} else {
+			tr, err = s.Client.TranslateAuto(ctx, strings.NewReader(span.Text), t, dst, opts...)
+		}
+		if err != nil {
+			return "", fmt.Errorf("error translating the span at offset %d: %w", span.Start, err)
+		}
+		b.WriteString(tr)
+	}
+	return b.String(), nil
+}
+
+// sameLanguage reports whether the language codes a and b have the same
+// language, ignoring case and regions, so "en-GB" is the same as "en".
+func sameLanguage(a, b string) bool {
+	base := func(s string) string {
+		if i := strings.IndexAny(s, "-_"); i >= 0 {
+			s = s[:i]
+		}
+		return strings.ToLower(s)
+	}
+	return base(a) == base(b)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// toyLanguage detects French texts by their articles, and anything else as
+// English.
+func toyLanguage(_ context.Context, text string) (string, error) {
+	if strings.Contains(text, " le ") || strings.Contains(text, " la ") {
+		return "fr", nil
+	}
+	return "en", nil
+}
+
+const (
+	enPara = "This agreement is made between the parties named below.\n"
+	frPara = "Le contrat est conclu entre les parties et la société.\n"
+)
+
+func TestParagraphs(t *testing.T) {
+	tests := []struct {
+		text string
+		want []int
+	}{
+		{"", nil},
+		{"one", []int{3}},
+		{"one\ntwo\n", []int{8}},
+		{"one\n\ntwo", []int{5, 8}},
+		{"\n\none\n \n\ntwo\n\n", []int{9, 14}},
+	}
+	for _, test := range tests {
+		if got := paragraphs(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("paragraphs(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestSegment(t *testing.T) {
+	tests := []struct {
+		name  string
+		paras []string
+		want  []string // language and text of each span
+	}{
+		{
+			name:  "single language",
+			paras: []string{enPara, enPara},
+			want:  []string{"en", enPara + "\n" + enPara},
+		},
+		{
+			name:  "mixed",
+			paras: []string{enPara, frPara, frPara, enPara},
+			want:  []string{"en", enPara + "\n", "fr", frPara + "\n" + frPara + "\n", "en", enPara},
+		},
+		{
+			name:  "short paragraphs",
+			paras: []string{"Title\n", enPara, "Annexe\n", frPara, "1.\n"},
+			want:  []string{"en", "Title\n\n" + enPara + "\nAnnexe\n\n", "fr", frPara + "\n1.\n"},
+		},
+		{
+			name:  "short text",
+			paras: []string{"Bonjour la France"},
+			want:  []string{"fr", "Bonjour la France"},
+		},
+		{
+			name:  "empty",
+			paras: []string{" \n"},
+		},
+	}
+	s := &LanguageSegmenter{Detect: toyLanguage}
+	for _, test := range tests {
+		text := strings.Join(test.paras, "\n")
+		spans, err := s.Segment(context.Background(), text)
+		if err != nil {
+			t.Fatalf("%s: Segment got error: %v", test.name, err)
+		}
+		var got []string
+		end := 0
+		for _, span := range spans {
+			got = append(got, span.Language, span.Text)
+			if span.Start != end || text[span.Start:span.End] != span.Text {
+				t.Errorf("%s: span %+v does not follow offset %d", test.name, span, end)
+			}
+			end = span.End
+		}
+		if len(spans) > 0 && end != len(text) {
+			t.Errorf("%s: spans end at %d, want %d", test.name, end, len(text))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Segment got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSegmentError(t *testing.T) {
+	errDetect := errors.New("detector failed")
+	s := &LanguageSegmenter{Detect: func(context.Context, string) (string, error) { return "", errDetect }}
+	if _, err := s.Segment(context.Background(), enPara); !errors.Is(err, errDetect) {
+		t.Errorf("Segment got error %v, want %v", err, errDetect)
+	}
+	if _, err := (&LanguageSegmenter{}).Segment(context.Background(), enPara); err == nil {
+		t.Errorf("Segment with no Client or Detect got no error")
+	}
+}
+
+func TestLanguageSegmenterTranslate(t *testing.T) {
+	var mu sync.Mutex
+	var translated []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/language/string":
+			lang, _ := toyLanguage(r.Context(), string(b))
+			fmt.Fprint(w, lang)
+		case strings.HasPrefix(r.URL.Path, "/translate/all/"):
+			mu.Lock()
+			translated = append(translated, r.URL.Path)
+			mu.Unlock()
+			fmt.Fprintf(w, "[%d bytes translated]\n", len(b))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	s := &LanguageSegmenter{Client: NewClient(nil, ts.URL)}
+	text := enPara + "\n" + frPara + "\n" + enPara
+	got, err := s.Translate(context.Background(), text, GoogleTranslator, "en-US")
+	if err != nil {
+		t.Fatalf("Translate got error: %v", err)
+	}
+	want := enPara + "\n" + fmt.Sprintf("[%d bytes translated]\n", len(frPara)+1) + enPara
+	if got != want {
+		t.Errorf("Translate got %q, want %q", got, want)
+	}
+	if want := []string{"/translate/all/" + string(GoogleTranslator) + "/fr/en-US"}; !reflect.DeepEqual(translated, want) {
+		t.Errorf("Translate called %q, want %q", translated, want)
+	}
+	if _, err := s.Translate(context.Background(), text, GoogleTranslator, "../en"); err == nil {
+		t.Errorf("Translate with an invalid language got no error")
+	}
+}
+
+func TestSameLanguage(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"en", "en", true},
+		{"en-GB", "en", true},
+		{"EN", "en_US", true},
+		{"en", "fr", false},
+		{"", "en", false},
+	}
+	for _, test := range tests {
+		if got := sameLanguage(test.a, test.b); got != test.want {
+			t.Errorf("sameLanguage(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -21,13 +21,14 @@ import (
 	"crypto/md5"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"net"
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
-
-	"golang.org/x/net/context/ctxhttp"
 )
 
 // Server represents a Tika server. Create a new Server with NewServer,
@@ -36,30 +37,78 @@ import (
 // There is no need to create a Server for an already running Tika Server
 // since you can pass its URL directly to a Client.
 type Server struct {
-	jar            string
+	jar  string
+	port string
+	// startMu serializes Start, so each Start waits for the server on the
+	// port it picked.
+	startMu sync.Mutex
+	// mu guards url and picked, which Start changes for port "0".
+	mu             sync.Mutex
 	url            string // url is derived from port and hostname.
-	port           string
+	picked         string // picked is the port Start picked for port "0".
 	hostname       string
+	bind           string // bind is the address the server listens on.
+	reserve        bool   // reserve is whether Start reserves the port.
+	env            []string
+	user           *processUser
+	launcher       []string // launcher is the command prefixed to java.
+	jvmFlags       []string
+	serverFlags    []string // serverFlags are passed to the server.
+	spawnChild     bool     // spawnChild is whether serverFlags spawn a child.
+	config         string   // config is the path of tika-config.xml.
+	jarSum         string   // jarSum is the expected checksum of the JAR.
+	jarVersion     Version
+	translators    map[Translator]translatorConfig
 	cancel         func()
 	startupTimeout time.Duration
+	// startupBackoff and maxStartupBackoff are the delays between probes
+	// while the server starts. See WithStartupBackoff.
+	startupBackoff    time.Duration
+	maxStartupBackoff time.Duration
+	warmup            []string // warmup are the MIME types to warm up with.
 }
 
-// URL returns the URL of this Server.
+// URL returns the URL of this Server. For port "0", it is the URL of the
+// port picked by the last Start.
 func (s *Server) URL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.url
 }
 
 // An Option can be passed to NewServer to configure the Server.
 type Option func(*Server)
 
-// WithHostname returns an Option to set the host of the Server (default localhost).
+// WithHostname returns an Option to set the host of the Server (default
+// localhost). IPv6 literals are accepted with or without brackets, such as
+// "::1" or "[::1]".
+//
+// Unless WithBindAddress is used, the server only listens on the address of
+// the host, so the default server is only reachable through loopback.
 func WithHostname(h string) Option {
 	return func(s *Server) {
+		if strings.HasPrefix(h, "[") && strings.HasSuffix(h, "]") {
+			h = h[1 : len(h)-1]
+		}
 		s.hostname = h
 	}
 }
 
+// WithBindAddress returns an Option to set the address the Server listens on,
+// such as "0.0.0.0" or "::" to listen on every interface (default the host
+// set by WithHostname). Listening on other interfaces than loopback exposes
+// the parsers of the server to the network.
+func WithBindAddress(addr string) Option {
+	return func(s *Server) {
+		if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+			addr = addr[1 : len(addr)-1]
+		}
+		s.bind = addr
+	}
+}
+
 // WithPort returns an Option to set the port of the Server (default 9998).
+// Port "0" picks a free port when the Server starts; see WithPortReservation.
 func WithPort(p string) Option {
 	return func(s *Server) {
 		s.port = p
@@ -67,7 +116,7 @@ func WithPort(p string) Option {
 }
 
 // WithStartupTimeout returns an Option to set the timeout for how long to wait
-// for the Server to start (default 10s).
+// for the Server to start (default 10s). See also WithStartupBackoff.
 func WithStartupTimeout(d time.Duration) Option {
 	return func(s *Server) {
 		s.startupTimeout = d
@@ -91,13 +140,60 @@ func NewServer(jar string, options ...Option) (*Server, error) {
 	for _, o := range options {
 		o(s)
 	}
-	urlString := "http://" + s.hostname + ":" + s.port
-	u, err := url.Parse(urlString)
+	if s.bind == "" {
+		s.bind = s.hostname
+	}
+	if err := s.setURL(); err != nil {
+		return nil, err
+	}
+	if err := s.checkWarmup(); err != nil {
+		return nil, err
+	}
+	if err := s.checkConfig(); err != nil {
+		return nil, err
+	}
+	if err := s.checkTranslators(); err != nil {
+		return nil, err
+	}
+	if err := s.checkJARConfig(); err != nil {
+		return nil, err
+	}
+	if err := s.checkSpawnChild(); err != nil {
+		return nil, err
+	}
+	if err := s.verifyJAR(nil); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// listenPort returns the port the server listens on: the one picked by the
+// last Start if the port of s is "0".
+func (s *Server) listenPort() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.port == "0" && s.picked != "" {
+		return s.picked
+	}
+	return s.port
+}
+
+// setURL derives the URL of s from its hostname and port.
+func (s *Server) setURL() error {
+	host := s.hostname
+	if strings.Contains(host, ":") {
+		// Escape the zone of IPv6 literals, as in "fe80::1%25eth0".
+		host = strings.Replace(host, "%", "%25", 1)
+	}
+	port := s.listenPort()
+	u, err := url.Parse("http://" + net.JoinHostPort(host, port))
 	if err != nil {
-		return nil, fmt.Errorf("invalid hostname %q or port %q: %v", s.hostname, s.port, err)
+		return fmt.Errorf("invalid hostname %q or port %q: %w", s.hostname, port, err)
 	}
+	s.mu.Lock()
 	s.url = u.String()
-	return s, nil
+	s.mu.Unlock()
+	return nil
 }
 
 type commander func(context.Context, string, ...string) *exec.Cmd
@@ -108,10 +204,47 @@ var cmder commander = exec.CommandContext
 // Start starts the given server. Start will start a new Java process. The
 // caller must call cancel() to shut down the process when finished with the
 // Server. The given Context is used for the Java process, not for cancellation
-// of startup.
+// of startup. If the Java process runs but the Server does not start, the
+// error is a *StartError with diagnostics. Calls of Start are serialized: a
+// Start waits until the previous one returned.
 func (s *Server) Start(ctx context.Context) (cancel func(), err error) {
-	ctx, cancel = context.WithCancel(ctx)
-	cmd := cmder(ctx, "java", "-jar", s.jar, "-p", s.port)
+	s.startMu.Lock()
+	defer s.startMu.Unlock()
+	jar, removeJAR, err := s.verifiedJAR()
+	if err != nil {
+		return nil, err
+	}
+	var r *portReservation
+	if s.reserve || s.port == "0" {
+		if r, err = s.reservePort(); err != nil {
+			removeJAR()
+			return nil, err
+		}
+	}
+	ctx, stop := context.WithCancel(ctx)
+	cancel = func() {
+		stop()
+		r.release()
+		removeJAR()
+	}
+	dir, err := s.writeTranslators()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if dir != "" {
+		cancel = func() {
+			stop()
+			r.release()
+			removeJAR()
+			os.RemoveAll(dir)
+		}
+	}
+	cmd, err := s.command(ctx, jar, dir)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
@@ -119,38 +252,86 @@ func (s *Server) Start(ctx context.Context) (cancel func(), err error) {
 		return nil, err
 	}
 
+	// Hand the port over to the server as late as possible.
+	r.handOff()
 	if err := cmd.Start(); err != nil {
-		cancel()
-		return nil, err
+		return nil, s.startError(cancel, cmd, nil, err)
 	}
 
 	if err := s.waitForStart(ctx); err != nil {
-		cancel()
-		buf, readErr := ioutil.ReadAll(stderr)
-		if readErr != nil {
-			return nil, fmt.Errorf("error reading stderr: %v", readErr)
-		}
 		// Report stderr since sometimes the server says why it failed to start.
-		return nil, fmt.Errorf("error starting server: %v\nserver stderr:\n\n%v", err, string(buf))
+		return nil, s.startError(cancel, cmd, stderr, err)
+	}
+
+	if err := s.warmUp(ctx); err != nil {
+		return nil, s.startError(cancel, cmd, stderr, fmt.Errorf("error warming up server: %w", err))
 	}
 	return cancel, nil
 }
 
-// waitForServer waits until the given Server is responding to requests.
-// waitForStart returns an error if the server does not respond within the
-// timeout set by WithStartupTimeout or if ctx is Done() first.
-func (s Server) waitForStart(ctx context.Context) error {
-	c := NewClient(nil, s.url)
+// command returns the command running the server from jar.
+func (s *Server) command(ctx context.Context, jar, classpath string) (*exec.Cmd, error) {
+	name, args := "java", s.args(jar, classpath)
+	if len(s.launcher) > 0 {
+		args = append(append(append([]string{}, s.launcher[1:]...), name), args...)
+		name = s.launcher[0]
+	}
+	cmd := cmder(ctx, name, args...)
+	if s.env != nil {
+		cmd.Env = s.env
+	}
+	if s.user != nil {
+		if err := setUser(cmd, s.user); err != nil {
+			return nil, err
+		}
+	}
+	return cmd, nil
+}
+
+// args returns the arguments of java to run the server from jar, with the
+// directory classpath, if any, in front of the JAR on the class path.
+func (s *Server) args(jar, classpath string) []string {
+	args := append([]string{}, s.jvmFlags...)
+	if classpath != "" {
+		args = append(args, "-cp", classpath+string(filepath.ListSeparator)+jar, serverMainClass)
+	} else {
+		args = append(args, "-jar", jar)
+	}
+	args = append(args, "-h", s.bind, "-p", s.listenPort())
+	if s.config != "" {
+		args = append(args, "-c", s.config)
+	}
+	return append(args, s.serverFlags...)
+}
+
+// waitForStart waits until the given Server is responding to requests,
+// probing it with the backoff set by WithStartupBackoff. waitForStart returns
+// a *StartupError if the server does not respond within the timeout set by
+// WithStartupTimeout, wrapping ErrStartupTimeout, or if ctx is Done() first.
+func (s *Server) waitForStart(ctx context.Context) error {
+	c := NewClient(nil, s.URL())
 	ctx, cancel := context.WithTimeout(ctx, s.startupTimeout)
 	defer cancel()
-	for {
+	start := time.Now()
+	var probes []StartupProbe
+	for i := 0; ; i++ {
+		t := time.NewTimer(s.startupDelay(i))
 		select {
-		case <-time.Tick(500 * time.Millisecond):
-			if _, err := c.Version(ctx); err == nil {
+		case <-t.C:
+			_, err := c.Version(ctx)
+			if err == nil {
 				return nil
 			}
+			if ctx.Err() == nil {
+				probes = append(probes, newStartupProbe(time.Since(start), err))
+			}
 		case <-ctx.Done():
-			return ctx.Err()
+			t.Stop()
+			err := ctx.Err()
+			if err == context.DeadlineExceeded {
+				err = ErrStartupTimeout
+			}
+			return &StartupError{Elapsed: time.Since(start), Probes: probes, Err: err}
 		}
 	}
 }
@@ -186,13 +367,22 @@ var md5s = map[Version]string{
 	Version116: "6a549ce6ef6e186e019766059fd82fb2",
 }
 
+// serverJARURL returns the URL to download the given server version from.
+var serverJARURL = func(version Version) string {
+	return fmt.Sprintf("http://search.maven.org/remotecontent?filepath=org/apache/tika/tika-server/%s/tika-server-%s.jar", version, version)
+}
+
 // DownloadServer downloads and validates the given server version,
 // saving it at path. DownloadServer returns an error if it could
 // not be downloaded/validated. Valid values for the version are 1.14.
 // It is the caller's responsibility to remove the file when no longer needed.
 // If the file already exists and has the correct MD5, DownloadServer will
 // do nothing.
-func DownloadServer(ctx context.Context, version Version, path string) error {
+//
+// The JAR is downloaded to path+".part" with parallel range requests. If the
+// download is interrupted, for example because ctx is canceled, calling
+// DownloadServer again resumes it.
+func DownloadServer(ctx context.Context, version Version, path string, options ...DownloadOption) error {
 	wantH := md5s[version]
 	if wantH == "" {
 		return fmt.Errorf("unsupported Tika version: %s", version)
@@ -203,28 +393,24 @@ func DownloadServer(ctx context.Context, version Version, path string) error {
 			return nil
 		}
 	}
-	out, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("error creating file: %v", err)
-	}
-	defer out.Close()
 
-	url := fmt.Sprintf("http://search.maven.org/remotecontent?filepath=org/apache/tika/tika-server/%s/tika-server-%s.jar", version, version)
-	resp, err := ctxhttp.Get(ctx, nil, url)
-	if err != nil {
-		return fmt.Errorf("unable to download %q: %v", url, err)
+	cfg := &downloadConfig{concurrency: 4}
+	for _, o := range options {
+		o(cfg)
 	}
-	defer resp.Body.Close()
-
-	if _, err := io.Copy(out, resp.Body); err != nil {
-		return fmt.Errorf("error saving download: %v", err)
+	part := path + ".part"
+	if err := download(ctx, serverJARURL(version), part, cfg); err != nil {
+		return err
 	}
 
-	if ok, md5 := validateFileMD5(path, wantH); !ok {
-		if err := os.Remove(path); err != nil {
-			return fmt.Errorf("invalid md5: %s: error removing %s: %v", md5, path, err)
+	if ok, md5 := validateFileMD5(part, wantH); !ok {
+		if err := os.Remove(part); err != nil {
+			return fmt.Errorf("%w: MD5 checksum %s, want %s: error removing %s: %w", ErrChecksumMismatch, md5, wantH, part, err)
 		}
-		return fmt.Errorf("invalid md5: %s", md5)
+		return fmt.Errorf("%w: MD5 checksum %s, want %s", ErrChecksumMismatch, md5, wantH)
+	}
+	if err := os.Rename(part, path); err != nil {
+		return fmt.Errorf("error saving download: %w", err)
 	}
 	return nil
 }
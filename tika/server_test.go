@@ -18,12 +18,14 @@ package tika
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"os/exec"
+	"reflect"
 	"strconv"
 	"testing"
 	"time"
@@ -106,6 +108,37 @@ func TestStart(t *testing.T) {
 	}
 }
 
+func TestServerAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []Option
+		wantURL  string
+		wantBind string
+	}{
+		{name: "defaults", wantURL: "http://localhost:9998", wantBind: "localhost"},
+		{name: "IPv4", options: []Option{WithHostname("127.0.0.1")}, wantURL: "http://127.0.0.1:9998", wantBind: "127.0.0.1"},
+		{name: "IPv6", options: []Option{WithHostname("::1")}, wantURL: "http://[::1]:9998", wantBind: "::1"},
+		{name: "bracketed IPv6", options: []Option{WithHostname("[::1]"), WithPort("9000")}, wantURL: "http://[::1]:9000", wantBind: "::1"},
+		{name: "IPv6 zone", options: []Option{WithHostname("fe80::1%eth0")}, wantURL: "http://[fe80::1%25eth0]:9998", wantBind: "fe80::1%eth0"},
+		{name: "all interfaces", options: []Option{WithBindAddress("0.0.0.0")}, wantURL: "http://localhost:9998", wantBind: "0.0.0.0"},
+		{name: "all IPv6 interfaces", options: []Option{WithBindAddress("[::]")}, wantURL: "http://localhost:9998", wantBind: "::"},
+	}
+	for _, test := range tests {
+		s, err := NewServer("server_test.go", test.options...)
+		if err != nil {
+			t.Errorf("NewServer(%s) got error: %v", test.name, err)
+			continue
+		}
+		if got := s.URL(); got != test.wantURL {
+			t.Errorf("NewServer(%s).URL() = %q, want %q", test.name, got, test.wantURL)
+		}
+		want := []string{"-jar", "server_test.go", "-h", test.wantBind, "-p", s.port}
+		if got := s.args(s.jar, ""); !reflect.DeepEqual(got, want) {
+			t.Errorf("NewServer(%s).args() = %q, want %q", test.name, got, want)
+		}
+	}
+}
+
 func bouncyServer(bounce int) *httptest.Server {
 	bounced := 0
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -151,9 +184,14 @@ func TestStartError(t *testing.T) {
 			t.Errorf("NewServer(%s) got error: %v", test.name, err)
 			continue
 		}
-		if cancel, err := s.Start(context.Background()); err == nil {
+		cancel, err := s.Start(context.Background())
+		if err == nil {
 			t.Errorf("s.Start(%s) got no error, want error", test.name)
 			cancel()
+			continue
+		}
+		if !errors.Is(err, ErrServerNotStarted) || !errors.Is(err, ErrStartupTimeout) {
+			t.Errorf("s.Start(%s) got %v, want ErrServerNotStarted and ErrStartupTimeout", test.name, err)
 		}
 	}
 }
@@ -218,12 +256,24 @@ func TestHelperProcess(*testing.T) {
 		}
 		args = args[1:]
 	}
-	if args[0] == "sleep" {
+	switch args[0] {
+	case "sleep":
 		l, err := strconv.Atoi(args[1])
 		if err != nil {
 			os.Exit(1)
 		}
 		time.Sleep(time.Duration(l) * time.Second)
+	case "stderr":
+		// Write the given number of lines to stderr, as a failing server.
+		l, err := strconv.Atoi(args[1])
+		if err != nil {
+			os.Exit(1)
+		}
+		for i := 1; i <= l; i++ {
+			fmt.Fprintf(os.Stderr, "line %d\n", i)
+		}
+	case "java-version":
+		fmt.Fprint(os.Stderr, "openjdk version \"17.0.2\" 2022-01-18\nOpenJDK Runtime Environment\n")
 	}
 }
 
@@ -250,7 +300,7 @@ func TestValidateFileMD5(t *testing.T) {
 		},
 	}
 	for _, test := range tests {
-		if got := validateFileMD5(test.path, test.md5String); got != test.want {
+		if got, _ := validateFileMD5(test.path, test.md5String); got != test.want {
 			t.Errorf("validateFileMD5(%q, %q) = %t, want %t", test.path, test.md5String, got, test.want)
 		}
 	}
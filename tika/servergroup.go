@@ -0,0 +1,154 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// WithLabel returns a ClientOption to name the server of the Client, such as
+// "stable" or "canary", to tell Clients of different servers apart.
+func WithLabel(label string) ClientOption {
+	return func(c *Client) {
+		c.label = label
+	}
+}
+
+// Label returns the label set by WithLabel, if any.
+func (c *Client) Label() string {
+	return c.label
+}
+
+// A ServerGroup runs several labeled Servers in one process, for example two
+// Tika versions side by side to compare a canary against the stable version
+// or to migrate traffic gradually. Create a ServerGroup with NewServerGroup
+// and add Servers to it with Add. A ServerGroup is safe for concurrent use.
+type ServerGroup struct {
+	mu      sync.Mutex
+	labels  []string // labels are in the order the Servers were added.
+	servers map[string]*Server
+}
+
+// NewServerGroup creates an empty ServerGroup.
+func NewServerGroup() *ServerGroup {
+	return &ServerGroup{servers: make(map[string]*Server)}
+}
+
+// Add adds s to the group under label. The Servers of a group must have
+// distinct labels and listen on distinct addresses, since they run
+// simultaneously; a Server picking its port with WithPort("0") never
+// conflicts with another.
+func (g *ServerGroup) Add(label string, s *Server) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.servers[label]; ok {
+		return fmt.Errorf("duplicate server label %q", label)
+	}
+	for _, l := range g.labels {
+		o := g.servers[l]
+		if s.port != "0" && o.port == s.port && (o.bind == s.bind || o.hostname == s.hostname) {
+			return fmt.Errorf("server %q uses the address of server %q: %s", label, l, s.URL())
+		}
+	}
+	g.labels = append(g.labels, label)
+	g.servers[label] = s
+	return nil
+}
+
+// Server returns the Server added under label, or nil.
+func (g *ServerGroup) Server(label string) *Server {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.servers[label]
+}
+
+// Labels returns the labels of the group, in the order they were added.
+func (g *ServerGroup) Labels() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string{}, g.labels...)
+}
+
+// Start starts every Server of the group, in the order they were added. If
+// a Server fails to start, the Servers already started are shut down and the
+// error names the label of the Server. Otherwise, the caller must call
+// cancel() to shut down every Server when finished. As for Server.Start, ctx
+// is used for the Java processes.
+func (g *ServerGroup) Start(ctx context.Context) (cancel func(), err error) {
+	g.mu.Lock()
+	labels := append([]string{}, g.labels...)
+	g.mu.Unlock()
+
+	var cancels []func()
+	cancel = func() {
+		for _, c := range cancels {
+			c()
+		}
+	}
+	for _, l := range labels {
+		c, err := g.Server(l).Start(ctx)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("server %q: %w", l, err)
+		}
+		cancels = append(cancels, c)
+	}
+	return cancel, nil
+}
+
+// Client returns a Client of the Server added under label, labeled with
+// label, or nil if there is no such Server. The httpClient and options are
+// passed to NewClient.
+func (g *ServerGroup) Client(label string, httpClient *http.Client, options ...ClientOption) *Client {
+	s := g.Server(label)
+	if s == nil {
+		return nil
+	}
+	options = append(append([]ClientOption{}, options...), WithLabel(label))
+	return NewClient(httpClient, s.URL(), options...)
+}
+
+// Clients returns a Client of every Server of the group, by label. See
+// Client.
+func (g *ServerGroup) Clients(httpClient *http.Client, options ...ClientOption) map[string]*Client {
+	cs := make(map[string]*Client)
+	for _, l := range g.Labels() {
+		cs[l] = g.Client(l, httpClient, options...)
+	}
+	return cs
+}
+
+// CompareServers parses input with both base and canary, typically Clients
+// of two Servers of a ServerGroup, and returns the difference from the result
+// of base to the result of canary, as CompareDocuments. The given
+// RequestOptions are used for both calls. If the error is not nil, the diff
+// is undefined.
+func CompareServers(ctx context.Context, base, canary *Client, input []byte, opts ...RequestOption) (*DocumentDiff, error) {
+	am, err := base.containerMeta(ctx, bytes.NewReader(input), opts)
+	if err != nil {
+		return nil, fmt.Errorf("server %q: %w", base.label, err)
+	}
+	bm, err := canary.containerMeta(ctx, bytes.NewReader(input), opts)
+	if err != nil {
+		return nil, fmt.Errorf("server %q: %w", canary.label, err)
+	}
+	return diffDocuments(am, bm), nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// versionedServer returns a server answering as Tika version with the given
+// recursive metadata.
+func versionedServer(version, rmeta string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/version" {
+			fmt.Fprint(w, version)
+			return
+		}
+		fmt.Fprint(w, rmeta)
+	}))
+}
+
+// serverFor returns a Server with the address of ts.
+func serverFor(t *testing.T, ts *httptest.Server, options ...Option) *Server {
+	path, err := os.Executable() // Use the test executable path as a dummy jar.
+	if err != nil {
+		t.Skip("cannot find current test executable")
+	}
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("error creating test server: %v", err)
+	}
+	options = append([]Option{WithHostname(u.Hostname()), WithPort(u.Port())}, options...)
+	s, err := NewServer(path, options...)
+	if err != nil {
+		t.Fatalf("NewServer got error: %v", err)
+	}
+	return s
+}
+
+func TestServerGroup(t *testing.T) {
+	stable := versionedServer("Apache Tika 1.14", `[{"X-TIKA:content":"title\nbody\n","author":"ann"}]`)
+	defer stable.Close()
+	canary := versionedServer("Apache Tika 1.21", `[{"X-TIKA:content":"title\nbody\n","author":"ann","pages":"1"}]`)
+	defer canary.Close()
+
+	g := NewServerGroup()
+	if err := g.Add("stable", serverFor(t, stable)); err != nil {
+		t.Fatalf("Add(stable) got error: %v", err)
+	}
+	if err := g.Add("canary", serverFor(t, canary)); err != nil {
+		t.Fatalf("Add(canary) got error: %v", err)
+	}
+	if err := g.Add("stable", serverFor(t, canary)); err == nil {
+		t.Errorf("Add with a duplicate label got no error")
+	}
+	if err := g.Add("other", serverFor(t, canary)); err == nil {
+		t.Errorf("Add with a duplicate address got no error")
+	}
+	if got, want := g.Labels(), []string{"stable", "canary"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Labels() = %v, want %v", got, want)
+	}
+
+	cancel, err := g.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start got error: %v", err)
+	}
+	defer cancel()
+
+	cs := g.Clients(nil)
+	for _, test := range []struct{ label, version string }{
+		{"stable", "Apache Tika 1.14"},
+		{"canary", "Apache Tika 1.21"},
+	} {
+		c := cs[test.label]
+		if c.Label() != test.label {
+			t.Errorf("Clients()[%q].Label() = %q", test.label, c.Label())
+		}
+		got, err := c.Version(context.Background())
+		if err != nil || got != test.version {
+			t.Errorf("Clients()[%q].Version() = %q, %v, want %q", test.label, got, err, test.version)
+		}
+	}
+	if c := g.Client("missing", nil); c != nil {
+		t.Errorf("Client(missing) = %v, want nil", c)
+	}
+
+	d, err := CompareServers(context.Background(), cs["stable"], cs["canary"], []byte("input"))
+	if err != nil {
+		t.Fatalf("CompareServers got error: %v", err)
+	}
+	if want := map[string][]string{"pages": {"1"}}; !reflect.DeepEqual(d.Added, want) || len(d.Changed) != 0 || len(d.Removed) != 0 {
+		t.Errorf("CompareServers got %+v, want only %v added", d, want)
+	}
+}
+
+func TestServerGroupStartError(t *testing.T) {
+	good := versionedServer("Apache Tika 1.14", "[]")
+	defer good.Close()
+	bad := bouncyServer(4)
+	defer bad.Close()
+
+	g := NewServerGroup()
+	if err := g.Add("good", serverFor(t, good)); err != nil {
+		t.Fatalf("Add(good) got error: %v", err)
+	}
+	if err := g.Add("bad", serverFor(t, bad, WithStartupTimeout(time.Second))); err != nil {
+		t.Fatalf("Add(bad) got error: %v", err)
+	}
+	cancel, err := g.Start(context.Background())
+	if err == nil {
+		cancel()
+		t.Fatalf("Start got no error, want an error")
+	}
+	if !errors.Is(err, ErrServerNotStarted) || !strings.Contains(err.Error(), `"bad"`) {
+		t.Errorf("Start got error %v, want ErrServerNotStarted naming the bad server", err)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A ServerProfile is a set of settings for a kind of deployment of a Server,
+// passed to NewServer with WithProfile. Start from one of the predefined
+// profiles and override its fields as needed:
+//
+//	p := tika.BatchProfile()
+//	p.MaxHeap = "8g"
+//	s, err := tika.NewServer(jar, tika.WithProfile(p))
+//
+// Options passed after WithProfile override the profile too.
+type ServerProfile struct {
+	// MaxHeap is the maximum heap of the JVM parsing documents, as passed to
+	// -Xmx, such as "512m" or "4g". If empty, the JVM default is used.
+	MaxHeap string
+	// SpawnChild is whether the server parses documents in a child process,
+	// which is restarted when it runs out of memory, hangs or crashes. It
+	// requires Tika 1.19 or later, newer than the versions DownloadServer
+	// supports: NewServer fails if the JAR is older, as set by
+	// WithJARVersion.
+	SpawnChild bool
+	// TaskTimeout is how long the child process may spend on a document
+	// before it is restarted, with SpawnChild. Zero is the Tika default.
+	TaskTimeout time.Duration
+	// MaxFiles is the number of documents after which the child process is
+	// restarted, with SpawnChild, to reclaim leaked memory. Zero is the Tika
+	// default.
+	MaxFiles int
+	// StartupTimeout overrides the timeout of WithStartupTimeout, if not
+	// zero.
+	StartupTimeout time.Duration
+	// Config is the path of a tika-config.xml file configuring the parsers,
+	// for example the timeouts and languages of Tesseract. NewServer fails if
+	// the file does not exist.
+	Config string
+	// JVMFlags are passed to the JVM parsing documents, such as its garbage
+	// collector: the child process with SpawnChild, and the JVM of the
+	// server otherwise, as with WithJVMFlags.
+	JVMFlags []string
+}
+
+// The predefined ServerProfiles run with every Tika version, in a single JVM.
+// Set SpawnChild, TaskTimeout and MaxFiles on them to isolate the parsing of
+// Tika 1.19 and later.
+
+// LowMemoryProfile returns a ServerProfile suiting small machines and
+// containers: a small heap and a serial collector.
+func LowMemoryProfile() ServerProfile {
+	return ServerProfile{
+		MaxHeap:  "512m",
+		JVMFlags: []string{"-XX:+UseSerialGC", "-XX:TieredStopAtLevel=1"},
+	}
+}
+
+// OCRHeavyProfile returns a ServerProfile suiting scanned documents run
+// through Tesseract: a large heap for page images, and a long startup
+// timeout. Configure Tesseract with Config.
+func OCRHeavyProfile() ServerProfile {
+	return ServerProfile{
+		MaxHeap:        "4g",
+		StartupTimeout: time.Minute,
+	}
+}
+
+// BatchProfile returns a ServerProfile suiting throughput-oriented batch
+// extraction: a medium heap and a parallel collector.
+func BatchProfile() ServerProfile {
+	return ServerProfile{
+		MaxHeap:        "2g",
+		StartupTimeout: 30 * time.Second,
+		JVMFlags:       []string{"-XX:+UseParallelGC"},
+	}
+}
+
+// WithProfile returns an Option to configure the Server with the profile p.
+func WithProfile(p ServerProfile) Option {
+	return func(s *Server) {
+		if p.StartupTimeout > 0 {
+			s.startupTimeout = p.StartupTimeout
+		}
+		if p.SpawnChild {
+			s.spawnChild = true
+			s.serverFlags = append(s.serverFlags, "-spawnChild")
+			// The server passes -J flags to the child JVM, without the J.
+			if p.MaxHeap != "" {
+				s.serverFlags = append(s.serverFlags, "-JXmx"+p.MaxHeap)
+			}
+			for _, f := range p.JVMFlags {
+				s.serverFlags = append(s.serverFlags, "-J"+strings.TrimPrefix(f, "-"))
+			}
+			if p.TaskTimeout > 0 {
+				s.serverFlags = append(s.serverFlags, "-taskTimeoutMillis", strconv.FormatInt(int64(p.TaskTimeout/time.Millisecond), 10))
+			}
+			if p.MaxFiles > 0 {
+				s.serverFlags = append(s.serverFlags, "-maxFiles", strconv.Itoa(p.MaxFiles))
+			}
+		} else {
+			s.jvmFlags = append(s.jvmFlags, p.JVMFlags...)
+			if p.MaxHeap != "" {
+				s.jvmFlags = append(s.jvmFlags, "-Xmx"+p.MaxHeap)
+			}
+		}
+		if p.Config != "" {
+			s.config = p.Config
+		}
+	}
+}
+
+// checkSpawnChild returns an error if s spawns a child process with a JAR
+// known to be too old for it.
+func (s *Server) checkSpawnChild() error {
+	if s.spawnChild && s.jarVersion != "" && versionBefore(s.jarVersion, 1, 19) {
+		return fmt.Errorf("SpawnChild requires Tika 1.19 or later, the JAR is %s", s.jarVersion)
+	}
+	return nil
+}
+
+// versionBefore returns whether v is a version older than major.minor. Invalid
+// versions are not.
+func versionBefore(v Version, major, minor int) bool {
+	parts := strings.SplitN(string(v), ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	ma, err1 := strconv.Atoi(parts[0])
+	mi, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return ma < major || (ma == major && mi < minor)
+}
+
+// checkConfig returns an error if the configuration file of s does not exist.
+func (s *Server) checkConfig() error {
+	if s.config == "" {
+		return nil
+	}
+	if _, err := os.Stat(s.config); err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	return nil
+}
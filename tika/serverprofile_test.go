@@ -0,0 +1,103 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithProfile(t *testing.T) {
+	custom := BatchProfile()
+	custom.MaxHeap = "8g"
+	custom.Config = "server_test.go"
+	child := LowMemoryProfile()
+	child.SpawnChild = true
+	child.TaskTimeout = 2 * time.Minute
+	child.MaxFiles = 100
+	tests := []struct {
+		name        string
+		options     []Option
+		want        []string
+		wantTimeout time.Duration
+	}{
+		{
+			name:        "low memory",
+			options:     []Option{WithProfile(LowMemoryProfile())},
+			want:        []string{"-XX:+UseSerialGC", "-XX:TieredStopAtLevel=1", "-Xmx512m", "-jar", "server_test.go", "-h", "localhost", "-p", "9998"},
+			wantTimeout: 10 * time.Second,
+		},
+		{
+			name:        "OCR heavy",
+			options:     []Option{WithProfile(OCRHeavyProfile())},
+			want:        []string{"-Xmx4g", "-jar", "server_test.go", "-h", "localhost", "-p", "9998"},
+			wantTimeout: time.Minute,
+		},
+		{
+			name:        "overridden batch",
+			options:     []Option{WithProfile(custom), WithStartupTimeout(time.Second)},
+			want:        []string{"-XX:+UseParallelGC", "-Xmx8g", "-jar", "server_test.go", "-h", "localhost", "-p", "9998", "-c", "server_test.go"},
+			wantTimeout: time.Second,
+		},
+		{
+			name:        "child",
+			options:     []Option{WithProfile(child)},
+			want:        []string{"-jar", "server_test.go", "-h", "localhost", "-p", "9998", "-spawnChild", "-JXmx512m", "-JXX:+UseSerialGC", "-JXX:TieredStopAtLevel=1", "-taskTimeoutMillis", "120000", "-maxFiles", "100"},
+			wantTimeout: 10 * time.Second,
+		},
+	}
+	for _, test := range tests {
+		s, err := NewServer("server_test.go", test.options...)
+		if err != nil {
+			t.Fatalf("NewServer(%s) got error: %v", test.name, err)
+		}
+		if got := s.args(s.jar, ""); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("NewServer(%s).args() = %q, want %q", test.name, got, test.want)
+		}
+		if s.startupTimeout != test.wantTimeout {
+			t.Errorf("NewServer(%s) has startup timeout %v, want %v", test.name, s.startupTimeout, test.wantTimeout)
+		}
+	}
+	custom.JVMFlags[0] = "-XX:+UseG1GC"
+	if BatchProfile().MaxHeap != "2g" || BatchProfile().JVMFlags[0] != "-XX:+UseParallelGC" {
+		t.Errorf("overriding a copy of BatchProfile changed it")
+	}
+	if _, err := NewServer("server_test.go", WithProfile(ServerProfile{Config: "missing.xml"})); err == nil {
+		t.Errorf("NewServer with a missing config file got no error")
+	}
+}
+
+func TestSpawnChildVersion(t *testing.T) {
+	child := ServerProfile{SpawnChild: true}
+	if _, err := NewServer("server_test.go", WithProfile(child), WithJARVersion(Version116), WithJARChecksum("0123456789abcdef0123456789abcdef")); err == nil || !strings.Contains(err.Error(), "1.19") {
+		t.Errorf("NewServer spawning a child with Tika %s got error %v, want one requiring 1.19", Version116, err)
+	}
+	if _, err := NewServer("server_test.go", WithJARVersion(Version116), WithProfile(BatchProfile()), WithJARChecksum("0123456789abcdef0123456789abcdef")); err != nil && !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("NewServer with BatchProfile and Tika %s got error %v", Version116, err)
+	}
+	for _, test := range []struct {
+		v    Version
+		want bool
+	}{{"1.16", true}, {"1.19", false}, {"1.2", true}, {"2.0.0", false}, {"0.9", true}, {"latest", false}} {
+		if got := versionBefore(test.v, 1, 19); got != test.want {
+			t.Errorf("versionBefore(%s, 1.19) = %v, want %v", test.v, got, test.want)
+		}
+	}
+}
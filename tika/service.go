@@ -0,0 +1,45 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io"
+)
+
+// Service is the interface of the main calls of a Client, implemented by
+// *Client, so code using them can be unit tested with a fake instead of a
+// Tika Server:
+//
+//	type fakeTika struct{ tika.Service }
+//
+//	func (fakeTika) Parse(context.Context, io.Reader, ...tika.RequestOption) (string, error) {
+//		return "extracted text", nil
+//	}
+//
+// Embedding Service in the fake, as above, only requires implementing the
+// methods used by the code under test.
+type Service interface {
+	Parse(ctx context.Context, input io.Reader, opts ...RequestOption) (string, error)
+	Meta(ctx context.Context, input io.Reader, opts ...RequestOption) (string, error)
+	MetaRecursive(ctx context.Context, input io.Reader, opts ...RequestOption) ([]map[string][]string, error)
+	Detect(ctx context.Context, input io.Reader, opts ...RequestOption) (string, error)
+	Language(ctx context.Context, input io.Reader, opts ...RequestOption) (string, error)
+	Translate(ctx context.Context, input io.Reader, t Translator, src, dst string, opts ...RequestOption) (string, error)
+}
+
+var _ Service = (*Client)(nil)
@@ -0,0 +1,65 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// fakeService is a Service parsing inputs to upper case, without a server.
+type fakeService struct {
+	Service
+}
+
+func (fakeService) Parse(_ context.Context, input io.Reader, _ ...RequestOption) (string, error) {
+	b, err := ioutil.ReadAll(input)
+	if err != nil {
+		return "", err
+	}
+	if len(b) == 0 {
+		return "", errors.New("empty input")
+	}
+	return strings.ToUpper(string(b)), nil
+}
+
+func TestServiceFake(t *testing.T) {
+	var s Service = fakeService{}
+	inputs := []io.Reader{strings.NewReader("one"), strings.NewReader(""), strings.NewReader("two")}
+	got, err := ParseAll(context.Background(), s, inputs, 2)
+	if err != nil {
+		t.Fatalf("ParseAll got error: %v", err)
+	}
+	if got[0].Content != "ONE" || got[1].Err == nil || got[2].Content != "TWO" {
+		t.Errorf("ParseAll with a fake Service got %+v", got)
+	}
+
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("a")}}
+	var files []FileResult
+	err = ParseFS(context.Background(), s, fsys, nil, 1, func(r FileResult) error {
+		files = append(files, r)
+		return nil
+	})
+	if err != nil || len(files) != 1 || files[0].Content != "A" {
+		t.Errorf("ParseFS with a fake Service got %+v, %v", files, err)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// A Shard selects the Inputs processed by one of several workers splitting a
+// corpus, by ID. Shards are deterministic, so workers agree on the split
+// without a coordinator as long as they are configured alike.
+type Shard func(id string) bool
+
+// shardHash hashes s with FNV-1a, which is stable across processes and
+// versions, unlike the hash of maps.
+func shardHash(s ...string) uint64 {
+	h := fnv.New64a()
+	for i, p := range s {
+		if i > 0 {
+			h.Write([]byte{0})
+		}
+		h.Write([]byte(p))
+	}
+	// Finalize the hash so that similar IDs spread over the shards.
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	return x
+}
+
+// ShardIndex returns the index of the shard of the Input with the given ID,
+// among n shards.
+func ShardIndex(id string, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return int(shardHash(id) % uint64(n))
+}
+
+// ModShard returns the Shard index of n, selecting the Inputs whose hashed
+// ID modulo n is index. Every Input moves to another shard when n changes:
+// use RendezvousShard for a set of workers which changes.
+func ModShard(index, n int) Shard {
+	return func(id string) bool {
+		return ShardIndex(id, n) == index
+	}
+}
+
+// RendezvousWorker returns the worker of the Input with the given ID among
+// workers, by rendezvous (highest random weight) hashing: when a worker is
+// added or removed, only the Inputs of that worker move. It returns "" if
+// there are no workers.
+func RendezvousWorker(id string, workers []string) string {
+	var best string
+	var bestWeight uint64
+	for i, w := range workers {
+		if weight := shardHash(w, id); i == 0 || weight > bestWeight || weight == bestWeight && w < best {
+			best, bestWeight = w, weight
+		}
+	}
+	return best
+}
+
+// RendezvousShard returns the Shard of the worker named self among workers,
+// such as host names, selecting the Inputs RendezvousWorker assigns to it.
+func RendezvousShard(self string, workers []string) Shard {
+	workers = append([]string(nil), workers...)
+	return func(id string) bool {
+		return RendezvousWorker(id, workers) == self
+	}
+}
+
+// ShardSource returns a Source of the Inputs of src in shard, so that a Job
+// run by each worker with its own Shard extracts its part of the corpus. If
+// src is a ChangeSource, so is the returned Source, and each worker lists the
+// changes of its shard.
+func ShardSource(src Source, shard Shard) Source {
+	s := &shardSource{Source: src, shard: shard}
+	if cs, ok := src.(ChangeSource); ok {
+		return &shardChangeSource{shardSource: s, changes: cs}
+	}
+	return s
+}
+
+type shardSource struct {
+	Source
+	shard Shard
+}
+
+// filter returns fn called only for the Inputs of the shard.
+func (s *shardSource) filter(fn func(Input) error) func(Input) error {
+	return func(in Input) error {
+		if !s.shard(in.ID) {
+			return nil
+		}
+		return fn(in)
+	}
+}
+
+// Walk implements Source.
+func (s *shardSource) Walk(ctx context.Context, fn func(Input) error) error {
+	return s.Source.Walk(ctx, s.filter(fn))
+}
+
+type shardChangeSource struct {
+	*shardSource
+	changes ChangeSource
+}
+
+// Changes implements ChangeSource.
+func (s *shardChangeSource) Changes(ctx context.Context, token string, fn func(Input) error) (string, error) {
+	return s.changes.Changes(ctx, token, s.filter(fn))
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+)
+
+func TestModShard(t *testing.T) {
+	const n = 4
+	counts := make([]int, n)
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("docs/%04d.pdf", i)
+		in := 0
+		for s := 0; s < n; s++ {
+			if ModShard(s, n)(id) {
+				in++
+				counts[s]++
+			}
+		}
+		if in != 1 {
+			t.Fatalf("%s is in %d shards, want 1", id, in)
+		}
+	}
+	for s, c := range counts {
+		if c < 200 || c > 300 {
+			t.Errorf("shard %d has %d of 1000 inputs, want about 250", s, c)
+		}
+	}
+	// Workers running different versions must agree on the shards.
+	for _, test := range []struct {
+		id   string
+		n    int
+		want int
+	}{
+		{"docs/0001.pdf", 1000, 865},
+		{"a", 1000, 429},
+		{"a", 1, 0},
+	} {
+		if got := ShardIndex(test.id, test.n); got != test.want {
+			t.Errorf("ShardIndex(%q, %d) = %d, want %d", test.id, test.n, got, test.want)
+		}
+	}
+}
+
+func TestRendezvousShard(t *testing.T) {
+	workers := []string{"w1", "w2", "w3", "w4"}
+	before := map[string]string{}
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("%d", i)
+		w := RendezvousWorker(id, workers)
+		before[id] = w
+		counts[w]++
+		if !RendezvousShard(w, workers)(id) {
+			t.Fatalf("RendezvousShard(%s) does not select %s", w, id)
+		}
+	}
+	for _, w := range workers {
+		if counts[w] < 200 || counts[w] > 300 {
+			t.Errorf("worker %s has %d of 1000 inputs, want about 250", w, counts[w])
+		}
+	}
+	// Removing a worker only moves its inputs.
+	for id, w := range before {
+		after := RendezvousWorker(id, []string{"w1", "w2", "w4"})
+		if w != "w3" && after != w {
+			t.Errorf("%s moved from %s to %s", id, w, after)
+		}
+		if after == "w3" {
+			t.Errorf("%s is still on the removed worker", id)
+		}
+	}
+	if got := RendezvousWorker("x", nil); got != "" {
+		t.Errorf("RendezvousWorker with no workers got %q", got)
+	}
+}
+
+type testChangeSource struct {
+	Source
+	changed []Input
+}
+
+func (s testChangeSource) Changes(ctx context.Context, token string, fn func(Input) error) (string, error) {
+	for _, in := range s.changed {
+		if err := fn(in); err != nil {
+			return "", err
+		}
+	}
+	return token + "+", nil
+}
+
+func TestShardSource(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := 0; i < 20; i++ {
+		fsys[fmt.Sprintf("%02d.txt", i)] = &fstest.MapFile{Data: []byte("x")}
+	}
+	var all []string
+	for s := 0; s < 3; s++ {
+		src := ShardSource(NewFSSource(fsys), ModShard(s, 3))
+		if _, ok := src.(ChangeSource); ok {
+			t.Errorf("ShardSource of an FSSource is a ChangeSource")
+		}
+		if err := src.Walk(context.Background(), func(in Input) error {
+			if ShardIndex(in.ID, 3) != s {
+				t.Errorf("shard %d walked %s of shard %d", s, in.ID, ShardIndex(in.ID, 3))
+			}
+			all = append(all, in.ID)
+			return nil
+		}); err != nil {
+			t.Fatalf("Walk got error: %v", err)
+		}
+	}
+	sort.Strings(all)
+	var want []string
+	for id := range fsys {
+		want = append(want, id)
+	}
+	sort.Strings(want)
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("shards walked %v, want %v", all, want)
+	}
+
+	cs := testChangeSource{Source: NewFSSource(fsys), changed: []Input{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d", Deleted: true}}}
+	src, ok := ShardSource(cs, func(id string) bool { return id != "b" }).(ChangeSource)
+	if !ok {
+		t.Fatalf("ShardSource of a ChangeSource is not a ChangeSource")
+	}
+	var changed []string
+	token, err := src.Changes(context.Background(), "t", func(in Input) error {
+		changed = append(changed, in.ID)
+		return nil
+	})
+	if err != nil || token != "t+" {
+		t.Errorf("Changes got %q, %v, want %q", token, err, "t+")
+	}
+	if want := []string{"a", "c", "d"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("Changes got %v, want %v", changed, want)
+	}
+}
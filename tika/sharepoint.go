@@ -0,0 +1,163 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// graphURL is the base URL of the Microsoft Graph API. It is a variable for
+// testing.
+var graphURL = "https://graph.microsoft.com/v1.0"
+
+// GraphDriveSource is a ChangeSource providing the files of a Microsoft Graph
+// drive, such as a SharePoint document library or a OneDrive. The ID of an
+// Input is the ID of the drive item, which does not change when the file is
+// renamed or moved.
+//
+// Changes uses the delta API of the drive, so it reports deleted files, and
+// its tokens are delta links.
+type GraphDriveSource struct {
+	httpClient *http.Client
+	drive      string // drive is the URL of the drive.
+}
+
+// NewSharePointSource creates a Source of the files of a document library of
+// a SharePoint site. If driveID is empty, the default document library of the
+// site is used.
+//
+// httpClient must authenticate requests to Microsoft Graph, for example with a
+// client from golang.org/x/oauth2.
+func NewSharePointSource(httpClient *http.Client, siteID, driveID string) *GraphDriveSource {
+	drive := graphURL + "/sites/" + url.PathEscape(siteID) + "/drive"
+	if driveID != "" {
+		drive += "s/" + url.PathEscape(driveID)
+	}
+	return &GraphDriveSource{httpClient: httpClient, drive: drive}
+}
+
+// NewOneDriveSource creates a Source of the files of the OneDrive of a user.
+// If userID is empty, the OneDrive of the signed-in user is used.
+//
+// httpClient must authenticate requests to Microsoft Graph, as for
+// NewSharePointSource.
+func NewOneDriveSource(httpClient *http.Client, userID string) *GraphDriveSource {
+	drive := graphURL + "/me/drive"
+	if userID != "" {
+		drive = graphURL + "/users/" + url.PathEscape(userID) + "/drive"
+	}
+	return &GraphDriveSource{httpClient: httpClient, drive: drive}
+}
+
+// graphItem is a drive item, as returned by the delta API.
+type graphItem struct {
+	ID                   string    `json:"id"`
+	Name                 string    `json:"name"`
+	Size                 int64     `json:"size"`
+	LastModifiedDateTime time.Time `json:"lastModifiedDateTime"`
+	File                 *struct{} `json:"file"`
+	Deleted              *struct{} `json:"deleted"`
+}
+
+// graphPage is a page of the delta API.
+type graphPage struct {
+	Value     []graphItem `json:"value"`
+	NextLink  string      `json:"@odata.nextLink"`
+	DeltaLink string      `json:"@odata.deltaLink"`
+}
+
+func (s *GraphDriveSource) get(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := ctxhttp.Do(ctx, s.httpClient, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("response code %v", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Walk implements Source.
+func (s *GraphDriveSource) Walk(ctx context.Context, fn func(Input) error) error {
+	_, err := s.Changes(ctx, "", func(in Input) error {
+		if in.Deleted {
+			return nil
+		}
+		return fn(in)
+	})
+	return err
+}
+
+// Changes implements ChangeSource.
+func (s *GraphDriveSource) Changes(ctx context.Context, token string, fn func(Input) error) (string, error) {
+	next := token
+	if next == "" {
+		next = s.drive + "/root/delta"
+	}
+	for {
+		var page graphPage
+		if err := s.get(ctx, next, &page); err != nil {
+			return "", fmt.Errorf("error listing changes: %w", err)
+		}
+		for _, item := range page.Value {
+			if item.File == nil && item.Deleted == nil {
+				continue // A folder.
+			}
+			in := Input{
+				ID:      item.ID,
+				Name:    item.Name,
+				Size:    item.Size,
+				ModTime: item.LastModifiedDateTime,
+				Deleted: item.Deleted != nil,
+			}
+			if err := fn(in); err != nil {
+				return "", err
+			}
+		}
+		if page.NextLink == "" {
+			return page.DeltaLink, nil
+		}
+		next = page.NextLink
+	}
+}
+
+// Open implements Source.
+func (s *GraphDriveSource) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", s.drive+"/items/"+url.PathEscape(id)+"/content", nil)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := openHTTP(ctx, s.httpClient, req)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", id, err)
+	}
+	return rc, nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// graphServer serves the delta API of a drive at path, in two pages.
+func graphServer(t *testing.T, path string) *httptest.Server {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case path + "/root/delta":
+			if r.URL.Query().Get("token") == "latest" {
+				fmt.Fprintf(w, `{"value": [{"id": "2", "name": "b.pdf", "deleted": {}}],
+					"@odata.deltaLink": "%s%s/root/delta?token=later"}`, ts.URL, path)
+				return
+			}
+			if r.URL.Query().Get("page") == "" {
+				fmt.Fprintf(w, `{"value": [{"id": "0", "name": "root", "folder": {}},
+					{"id": "1", "name": "a.docx", "size": 3, "lastModifiedDateTime": "2017-01-02T15:04:05Z", "file": {}}],
+					"@odata.nextLink": "%s%s/root/delta?page=2"}`, ts.URL, path)
+				return
+			}
+			fmt.Fprintf(w, `{"value": [{"id": "2", "name": "b.pdf", "size": 5, "file": {}}],
+				"@odata.deltaLink": "%s%s/root/delta?token=latest"}`, ts.URL, path)
+		case path + "/items/1/content":
+			fmt.Fprint(w, "abc")
+		default:
+			t.Errorf("unexpected request for %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return ts
+}
+
+func TestSharePointSource(t *testing.T) {
+	ts := graphServer(t, "/sites/site/drives/lib")
+	defer ts.Close()
+	defer func(u string) { graphURL = u }(graphURL)
+	graphURL = ts.URL
+	s := NewSharePointSource(ts.Client(), "site", "lib")
+
+	if got, want := walkIDs(t, s), []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk got %v, want %v", got, want)
+	}
+
+	var changes []Input
+	collect := func(in Input) error {
+		changes = append(changes, in)
+		return nil
+	}
+	token, err := s.Changes(context.Background(), "", collect)
+	if err != nil {
+		t.Fatalf("Changes got error: %v", err)
+	}
+	if want := ts.URL + "/sites/site/drives/lib/root/delta?token=latest"; token != want {
+		t.Errorf("Changes returned token %q, want %q", token, want)
+	}
+	changes = nil
+	if _, err := s.Changes(context.Background(), token, collect); err != nil {
+		t.Fatalf("Changes(%q) got error: %v", token, err)
+	}
+	if len(changes) != 1 || changes[0].ID != "2" || !changes[0].Deleted {
+		t.Errorf("Changes(%q) got %+v, want b.pdf deleted", token, changes)
+	}
+
+	rc, err := s.Open(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("Open got error: %v", err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != "abc" {
+		t.Errorf("Open read %q, want %q", b, "abc")
+	}
+}
+
+func TestOneDriveSource(t *testing.T) {
+	ts := graphServer(t, "/users/ann/drive")
+	defer ts.Close()
+	defer func(u string) { graphURL = u }(graphURL)
+	graphURL = ts.URL
+	s := NewOneDriveSource(ts.Client(), "ann")
+	if got, want := walkIDs(t, s), []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk got %v, want %v", got, want)
+	}
+}
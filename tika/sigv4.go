@@ -0,0 +1,245 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSCredentials sign requests to AWS with SigV4Middleware.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the token of temporary credentials, if any.
+	SessionToken string
+}
+
+// An AWSCredentialsProvider provides AWSCredentials for every request, so
+// temporary credentials can be refreshed, for example from the AWS SDK.
+type AWSCredentialsProvider interface {
+	AWSCredentials(ctx context.Context) (AWSCredentials, error)
+}
+
+// StaticAWSCredentials is an AWSCredentialsProvider always providing the same
+// AWSCredentials.
+type StaticAWSCredentials AWSCredentials
+
+// AWSCredentials implements AWSCredentialsProvider.
+func (c StaticAWSCredentials) AWSCredentials(context.Context) (AWSCredentials, error) {
+	return AWSCredentials(c), nil
+}
+
+// EnvAWSCredentials is an AWSCredentialsProvider reading AWSCredentials from
+// the standard environment variables AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+// and AWS_SESSION_TOKEN.
+type EnvAWSCredentials struct{}
+
+// AWSCredentials implements AWSCredentialsProvider. It returns an error if the
+// access key is not set.
+func (EnvAWSCredentials) AWSCredentials(context.Context) (AWSCredentials, error) {
+	c := AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return AWSCredentials{}, errors.New("AWS_ACCESS_KEY_ID or AWS_SECRET_ACCESS_KEY is not set")
+	}
+	return c, nil
+}
+
+// sigV4Now is the time requests are signed at, replaced by tests.
+var sigV4Now = time.Now
+
+// SigV4Middleware returns a Middleware signing requests with AWS Signature
+// Version 4, for a Tika Server behind a gateway authenticating with IAM, such
+// as Amazon API Gateway (service "execute-api") or a Lambda function URL
+// (service "lambda"), in the given region.
+//
+// The signature covers the body, so bodies which cannot be read again are
+// read into memory first: gateways limit the size of bodies anyway.
+func SigV4Middleware(region, service string, creds AWSCredentialsProvider) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			c, err := creds.AWSCredentials(req.Context())
+			if err != nil {
+				if req.Body != nil {
+					req.Body.Close()
+				}
+				return nil, fmt.Errorf("error getting AWS credentials: %w", err)
+			}
+			req, err = signV4(req, c, region, service, sigV4Now().UTC())
+			if err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// signV4 returns a copy of req signed with c at t.
+func signV4(req *http.Request, c AWSCredentials, region, service string, t time.Time) (*http.Request, error) {
+	payload, body, err := hashBody(req)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	if body != nil {
+		req.Body = body
+	}
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	amzDate := t.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for _, k := range []string{"X-Amz-Date", "X-Amz-Security-Token"} {
+		if v := req.Header.Get(k); v != "" {
+			headers[strings.ToLower(k)] = strings.Join(strings.Fields(v), " ")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		// Services other than S3 expect the escaped path to be escaped
+		// again.
+		awsEscape(path, false),
+		canonicalQuery(req),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payload,
+	}, "\n")
+
+	date := amzDate[:8]
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	h := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(h[:])
+	key := []byte("AWS4" + c.SecretAccessKey)
+	for _, s := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", c.AccessKeyID, scope, signedHeaders, signature))
+	return req, nil
+}
+
+// hashBody returns the hex encoded SHA-256 of the body of req and, if the body
+// had to be read, a new body with the same content.
+func hashBody(req *http.Request) (string, *bodyReadCloser, error) {
+	var data []byte
+	var body *bodyReadCloser
+	switch {
+	case req.Body == nil || req.Body == http.NoBody:
+	case req.GetBody != nil:
+		rc, err := req.GetBody()
+		if err != nil {
+			return "", nil, err
+		}
+		data, err = ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return "", nil, err
+		}
+	default:
+		var err error
+		data, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return "", nil, err
+		}
+		body = &bodyReadCloser{bytes.NewReader(data)}
+	}
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:]), body, nil
+}
+
+// bodyReadCloser is a request body read into memory.
+type bodyReadCloser struct {
+	*bytes.Reader
+}
+
+func (bodyReadCloser) Close() error { return nil }
+
+// canonicalQuery returns the query of req, sorted and escaped for SigV4.
+func canonicalQuery(req *http.Request) string {
+	var params []string
+	for k, vs := range req.URL.Query() {
+		for _, v := range vs {
+			params = append(params, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// awsEscape escapes s as specified for SigV4: every byte but the unreserved
+// characters of RFC 3986 is percent-encoded, and so is "/" if escapeSlash.
+func awsEscape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The requests and signatures are from the AWS SigV4 test suite.
+func TestSignV4(t *testing.T) {
+	creds := AWSCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	at := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{
+			name: "get-vanilla",
+			url:  "http://example.amazonaws.com/",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name: "get-vanilla-query-order-key-case",
+			url:  "http://example.amazonaws.com/?Param2=value2&Param1=value1",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signed, err := signV4(req, creds, "us-east-1", "service", at)
+		if err != nil {
+			t.Fatalf("%s: signV4 got error: %v", test.name, err)
+		}
+		if got := signed.Header.Get("Authorization"); got != test.want {
+			t.Errorf("%s: Authorization got\n%s\nwant\n%s", test.name, got, test.want)
+		}
+		if got := signed.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+			t.Errorf("%s: X-Amz-Date got %q, want %q", test.name, got, "20150830T123600Z")
+		}
+		if req.Header.Get("Authorization") != "" {
+			t.Errorf("%s: signV4 modified the original request", test.name)
+		}
+	}
+}
+
+func TestSigV4Middleware(t *testing.T) {
+	defer func(now func() time.Time) { sigV4Now = now }(sigV4Now)
+	sigV4Now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+
+	var gotAuth, gotToken, gotBody string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotToken = r.Header.Get("X-Amz-Security-Token")
+		b, _ := ioutil.ReadAll(r.Body)
+		gotBody = string(b)
+		w.Write([]byte("text"))
+	}))
+	defer ts.Close()
+
+	creds := StaticAWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token"}
+	c := NewClient(nil, ts.URL, WithMiddleware(SigV4Middleware("eu-west-1", "execute-api", creds)))
+	// A body which cannot be read again is read into memory to be signed.
+	got, err := c.Parse(context.Background(), ioutil.NopCloser(strings.NewReader("body")))
+	if err != nil {
+		t.Fatalf("Parse got error: %v", err)
+	}
+	if got != "text" {
+		t.Errorf("Parse got %q, want %q", got, "text")
+	}
+	if gotBody != "body" {
+		t.Errorf("server got body %q, want %q", gotBody, "body")
+	}
+	if gotToken != "token" {
+		t.Errorf("X-Amz-Security-Token got %q, want %q", gotToken, "token")
+	}
+	wantPrefix := "AWS4-HMAC-SHA256 Credential=AKID/20150830/eu-west-1/execute-api/aws4_request, SignedHeaders=host;x-amz-date;x-amz-security-token, Signature="
+	if !strings.HasPrefix(gotAuth, wantPrefix) {
+		t.Errorf("Authorization got %q, want prefix %q", gotAuth, wantPrefix)
+	}
+}
+
+func TestEnvAWSCredentials(t *testing.T) {
+	for _, k := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"} {
+		t.Setenv(k, "")
+	}
+	if _, err := (EnvAWSCredentials{}).AWSCredentials(context.Background()); err == nil {
+		t.Error("AWSCredentials got no error with unset variables")
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "id")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	got, err := (EnvAWSCredentials{}).AWSCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("AWSCredentials got error: %v", err)
+	}
+	if want := (AWSCredentials{AccessKeyID: "id", SecretAccessKey: "secret"}); got != want {
+		t.Errorf("AWSCredentials got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// An Input is a document available from a Source.
+type Input struct {
+	// ID identifies the Input within its Source, for example by its path.
+	ID string
+	// Name is the file name of the Input, which can be passed to
+	// WithResourceName.
+	Name string
+	// Size is the size of the Input in bytes, or UnknownSize if the Source
+	// cannot tell it before reading the content.
+	Size    int64
+	ModTime time.Time
+	// Deleted reports that the Input was removed, when listed by
+	// ChangeSource.Changes.
+	Deleted bool
+}
+
+// UnknownSize is the Input.Size of Inputs whose size is not known, such as
+// Google Docs exported when opened.
+const UnknownSize int64 = -1
+
+// A Source provides documents to extract, such as the files of a directory or
+// of a network share.
+type Source interface {
+	// Walk calls fn for every Input of the Source, and stops at the first
+	// error returned by fn or encountered while listing the Inputs.
+	Walk(ctx context.Context, fn func(Input) error) error
+	// Open opens the Input with the given ID for reading.
+	Open(ctx context.Context, id string) (io.ReadCloser, error)
+}
+
+// A ChangeSource is a Source which can list the Inputs changed since a
+// previous listing, for incremental crawls.
+type ChangeSource interface {
+	Source
+	// Changes calls fn for every Input added, modified or deleted since the
+	// listing which returned token, or for every Input if token is empty, and
+	// returns the token to pass to the next call. Tokens are opaque and can be
+	// saved between runs.
+	Changes(ctx context.Context, token string, fn func(Input) error) (string, error)
+}
+
+// FSSource is a Source providing the regular files of an fs.FS, such as the
+// result of os.DirFS. The ID of an Input is its path in the fs.FS.
+type FSSource struct {
+	fsys fs.FS
+}
+
+// NewFSSource creates a Source of the files of fsys.
+func NewFSSource(fsys fs.FS) *FSSource {
+	return &FSSource{fsys: fsys}
+}
+
+// Walk implements Source. Inputs are walked in lexical order.
+func (s *FSSource) Walk(ctx context.Context, fn func(Input) error) error {
+	return fs.WalkDir(s.fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(Input{ID: path, Name: d.Name(), Size: fi.Size(), ModTime: fi.ModTime()})
+	})
+}
+
+// Open implements Source.
+func (s *FSSource) Open(_ context.Context, id string) (io.ReadCloser, error) {
+	return s.fsys.Open(id)
+}
+
+// openHTTP sends req and returns the body of the response, for the Open method
+// of sources served over HTTP.
+func openHTTP(ctx context.Context, httpClient *http.Client, req *http.Request) (io.ReadCloser, error) {
+	resp, err := ctxhttp.Do(ctx, httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("response code %v", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// walkIDs returns the IDs of the Inputs of s.
+func walkIDs(t *testing.T, s Source) []string {
+	var ids []string
+	err := s.Walk(context.Background(), func(in Input) error {
+		ids = append(ids, in.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk got error: %v", err)
+	}
+	return ids
+}
+
+func TestFSSource(t *testing.T) {
+	s := NewFSSource(fstest.MapFS{
+		"b.txt":     {Data: []byte("b")},
+		"a/c.pdf":   {Data: []byte("c")},
+		"a/d/e.doc": {Data: []byte("e")},
+	})
+	want := []string{"a/c.pdf", "a/d/e.doc", "b.txt"}
+	if got := walkIDs(t, s); !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk got %v, want %v", got, want)
+	}
+	rc, err := s.Open(context.Background(), "a/c.pdf")
+	if err != nil {
+		t.Fatalf("Open got error: %v", err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != "c" {
+		t.Errorf("Open read %q, want %q", b, "c")
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Value implements driver.Valuer, storing m as a JSON object, such as in a
+// Postgres jsonb column. A nil m is stored as NULL.
+func (m Metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return jsonValue(m)
+}
+
+// Scan implements sql.Scanner, reading m from a JSON object stored by Value.
+func (m *Metadata) Scan(src interface{}) error {
+	data, err := jsonColumn(src)
+	if err != nil || data == nil {
+		*m = nil
+		return err
+	}
+	var v Metadata
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("error scanning Metadata: %w", err)
+	}
+	*m = v
+	return nil
+}
+
+// Value implements driver.Valuer, storing d as a JSON object, such as in a
+// Postgres jsonb column.
+func (d Document) Value() (driver.Value, error) {
+	return jsonValue(d)
+}
+
+// Scan implements sql.Scanner, reading d from a JSON object stored by Value.
+// NULL is read as the zero Document.
+func (d *Document) Scan(src interface{}) error {
+	data, err := jsonColumn(src)
+	if err != nil || data == nil {
+		*d = Document{}
+		return err
+	}
+	var v Document
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("error scanning Document: %w", err)
+	}
+	*d = v
+	return nil
+}
+
+// jsonValue returns the JSON of v as a string rather than []byte, which some
+// drivers, such as lib/pq, send as bytea.
+func jsonValue(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// jsonColumn returns the JSON of a column value, or nil for NULL.
+func jsonColumn(src interface{}) ([]byte, error) {
+	switch v := src.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("cannot scan %T as JSON", src)
+	}
+}
+
+// PostgresUpsert is the statement of a Postgres SQLEmitter, inserting or
+// replacing the Documents in the table created by
+//
+//	CREATE TABLE documents (
+//		id text PRIMARY KEY,
+//		content_type text,
+//		size bigint,
+//		content text,
+//		metadata jsonb
+//	)
+//
+// Use it with any Postgres driver, for example:
+//
+//	db, err := sql.Open("pgx", "postgres://localhost/tika")
+//	...
+//	job.Emit = tika.SQLEmitter(db, tika.PostgresUpsert)
+const PostgresUpsert = `INSERT INTO documents (id, content_type, size, content, metadata)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (id) DO UPDATE SET
+	content_type = EXCLUDED.content_type,
+	size = EXCLUDED.size,
+	content = EXCLUDED.content,
+	metadata = EXCLUDED.metadata`
+
+// SQLEmitter returns a function emitting Documents to db, which can be used
+// as Job.Emit. It executes query with the ID, ContentType, Size, Content and
+// Metadata of each Document as arguments, in that order, with the Metadata as
+// JSON. Write query with the placeholders of the driver of db, such as $1 for
+// Postgres or ? for MySQL and SQLite; see PostgresUpsert.
+func SQLEmitter(db *sql.DB, query string) func(context.Context, Document) error {
+	return func(ctx context.Context, doc Document) error {
+		if _, err := db.ExecContext(ctx, query, doc.ID, doc.ContentType, doc.Size, doc.Content, Metadata(doc.Metadata)); err != nil {
+			return fmt.Errorf("error emitting %q: %w", doc.ID, err)
+		}
+		return nil
+	}
+}
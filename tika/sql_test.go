@@ -0,0 +1,142 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestMetadataValueScan(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Metadata
+	}{
+		{"nil", nil},
+		{"empty", Metadata{}},
+		{"values", Metadata{"dc:title": {"title"}, "dc:creator": {"a", "b"}}},
+	}
+	for _, test := range tests {
+		v, err := test.m.Value()
+		if err != nil {
+			t.Errorf("%s: Value got error: %v", test.name, err)
+			continue
+		}
+		var got Metadata
+		if err := got.Scan(v); err != nil {
+			t.Errorf("%s: Scan(%v) got error: %v", test.name, v, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.m) {
+			t.Errorf("%s: Scan(Value()) got %v, want %v", test.name, got, test.m)
+		}
+	}
+
+	var m Metadata
+	if err := m.Scan([]byte(`{"k":["v"]}`)); err != nil || m.Get("k") != "v" {
+		t.Errorf("Scan([]byte) got %v, %v, want k=v", m, err)
+	}
+	for _, src := range []interface{}{42, "not json"} {
+		if err := m.Scan(src); err == nil {
+			t.Errorf("Scan(%v) got no error", src)
+		}
+	}
+}
+
+func TestDocumentValueScan(t *testing.T) {
+	want := Document{ID: "a.pdf", ContentType: "application/pdf", Size: 3, Content: "text", Metadata: map[string][]string{"k": {"v"}}}
+	v, err := want.Value()
+	if err != nil {
+		t.Fatalf("Value got error: %v", err)
+	}
+	var got Document
+	if err := got.Scan(v); err != nil {
+		t.Fatalf("Scan got error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan(Value()) got %+v, want %+v", got, want)
+	}
+	if err := got.Scan(nil); err != nil || !reflect.DeepEqual(got, Document{}) {
+		t.Errorf("Scan(nil) got %+v, %v, want the zero Document", got, err)
+	}
+}
+
+// recordingDriver is a database/sql driver recording the statements executed.
+type recordingDriver struct {
+	mu    sync.Mutex
+	execs [][]driver.Value
+	err   error
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return recordingConn{d}, nil }
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) { return recordingStmt{c.d}, nil }
+func (recordingConn) Close() error                                { return nil }
+func (recordingConn) Begin() (driver.Tx, error)                   { return nil, errors.New("no transactions") }
+
+type recordingStmt struct{ d *recordingDriver }
+
+func (recordingStmt) Close() error  { return nil }
+func (recordingStmt) NumInput() int { return -1 }
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if s.d.err != nil {
+		return nil, s.d.err
+	}
+	s.d.execs = append(s.d.execs, args)
+	return driver.RowsAffected(1), nil
+}
+func (recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("no queries")
+}
+
+var testDriver = &recordingDriver{}
+
+func init() {
+	sql.Register("tika-recording", testDriver)
+}
+
+func TestSQLEmitter(t *testing.T) {
+	db, err := sql.Open("tika-recording", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	emit := SQLEmitter(db, PostgresUpsert)
+	doc := Document{ID: "a.pdf", ContentType: "application/pdf", Size: 3, Content: "text", Metadata: map[string][]string{"k": {"v"}}}
+	if err := emit(context.Background(), doc); err != nil {
+		t.Fatalf("emit got error: %v", err)
+	}
+	want := [][]driver.Value{{"a.pdf", "application/pdf", int64(3), "text", `{"k":["v"]}`}}
+	if !reflect.DeepEqual(testDriver.execs, want) {
+		t.Errorf("emit executed %v, want %v", testDriver.execs, want)
+	}
+
+	testDriver.err = errors.New("down")
+	defer func() { testDriver.err = nil }()
+	if err := emit(context.Background(), doc); !errors.Is(err, testDriver.err) {
+		t.Errorf("emit got error %v, want %v", err, testDriver.err)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Default delays between the probes of a starting Server.
+const (
+	defaultStartupBackoff    = 500 * time.Millisecond
+	defaultMaxStartupBackoff = 5 * time.Second
+)
+
+// WithStartupBackoff returns an Option to set the delay before the first
+// probe of a starting Server (default 500ms), doubled after every failed
+// probe up to max (default 5s). Each delay is picked at random between half
+// and all of its value, so Servers started together do not probe in step.
+// Probing stops after the timeout set by WithStartupTimeout.
+func WithStartupBackoff(initial, max time.Duration) Option {
+	return func(s *Server) {
+		s.startupBackoff = initial
+		s.maxStartupBackoff = max
+	}
+}
+
+// jitter returns a random duration in [0, d). It is a variable for testing.
+var jitter = func(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// startupDelay returns the delay before the probe of the given number,
+// starting from 0.
+func (s *Server) startupDelay(probe int) time.Duration {
+	d, max := s.startupBackoff, s.maxStartupBackoff
+	if d <= 0 {
+		d = defaultStartupBackoff
+	}
+	if max <= 0 {
+		max = defaultMaxStartupBackoff
+	}
+	for i := 0; i < probe && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d/2 + jitter(d-d/2)
+}
+
+// A StartupProbe is a request made to check whether a Server has started.
+type StartupProbe struct {
+	// Elapsed is the time from the start of the wait to the probe.
+	Elapsed time.Duration
+	// StatusCode is the status code of the response, or 0 if there was none.
+	StatusCode int
+	// Err is the error of the probe.
+	Err error
+}
+
+// A StartupError is returned, wrapped with ErrServerNotStarted, by
+// Server.Start when the server did not start responding. Test for it with
+// errors.As.
+type StartupError struct {
+	// Elapsed is how long Start waited for the server.
+	Elapsed time.Duration
+	// Probes are the failed probes, in order.
+	Probes []StartupProbe
+	// Err is ErrStartupTimeout or the error of the Context of Start.
+	Err error
+}
+
+func (e *StartupError) Error() string {
+	msg := fmt.Sprintf("%v after %v and %d probes", e.Err, e.Elapsed.Round(time.Millisecond), len(e.Probes))
+	if n := len(e.Probes); n > 0 {
+		msg += fmt.Sprintf(", last: %v", e.Probes[n-1].Err)
+	}
+	return msg
+}
+
+func (e *StartupError) Unwrap() error {
+	return e.Err
+}
+
+// newStartupProbe returns the probe made after elapsed with the error err.
+func newStartupProbe(elapsed time.Duration, err error) StartupProbe {
+	p := StartupProbe{Elapsed: elapsed, Err: err}
+	var te *TikaError
+	if errors.As(err, &te) {
+		p.StatusCode = te.StatusCode
+	}
+	return p
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStartupDelay(t *testing.T) {
+	old := jitter
+	defer func() { jitter = old }()
+	tests := []struct {
+		name    string
+		options []Option
+		// jitter is the fraction of its maximum the jitter is.
+		jitter float64
+		want   []time.Duration
+	}{
+		{
+			name: "defaults without jitter",
+			want: []time.Duration{250 * time.Millisecond, 500 * time.Millisecond, time.Second, 2 * time.Second, 2500 * time.Millisecond, 2500 * time.Millisecond},
+		},
+		{
+			name:   "defaults with full jitter",
+			jitter: 1,
+			want:   []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name:    "custom",
+			options: []Option{WithStartupBackoff(100*time.Millisecond, 300*time.Millisecond)},
+			jitter:  1,
+			want:    []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond},
+		},
+	}
+	for _, test := range tests {
+		jitter = func(d time.Duration) time.Duration { return time.Duration(float64(d) * test.jitter) }
+		s, err := NewServer("server_test.go", test.options...)
+		if err != nil {
+			t.Fatalf("NewServer(%s) got error: %v", test.name, err)
+		}
+		for i, want := range test.want {
+			if got := s.startupDelay(i); got != want {
+				t.Errorf("startupDelay(%s, %d) = %v, want %v", test.name, i, got, want)
+			}
+		}
+	}
+}
+
+func TestWaitForStartProbes(t *testing.T) {
+	ts := bouncyServer(1000)
+	defer ts.Close()
+	s := &Server{url: ts.URL, startupTimeout: 500 * time.Millisecond, startupBackoff: 10 * time.Millisecond, maxStartupBackoff: 50 * time.Millisecond}
+	err := s.waitForStart(context.Background())
+	if !errors.Is(err, ErrStartupTimeout) {
+		t.Fatalf("waitForStart got error %v, want ErrStartupTimeout", err)
+	}
+	var se *StartupError
+	if !errors.As(err, &se) {
+		t.Fatalf("waitForStart got error %v, want a *StartupError", err)
+	}
+	if len(se.Probes) < 3 || se.Elapsed < s.startupTimeout {
+		t.Fatalf("waitForStart got %d probes in %v, want at least 3 in %v", len(se.Probes), se.Elapsed, s.startupTimeout)
+	}
+	for i, p := range se.Probes {
+		if p.StatusCode != http.StatusInternalServerError || p.Err == nil {
+			t.Errorf("waitForStart got probe %d = %+v, want status %d", i, p, http.StatusInternalServerError)
+		}
+		if i > 0 && p.Elapsed <= se.Probes[i-1].Elapsed {
+			t.Errorf("waitForStart got probe %d at %v, before probe %d at %v", i, p.Elapsed, i-1, se.Probes[i-1].Elapsed)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.waitForStart(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("waitForStart with a canceled Context got error %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import "context"
+
+// Stream runs the Job in a new goroutine and sends the extracted Documents
+// on the returned channel, instead of passing them to Emit, for Jobs feeding
+// a larger streaming pipeline:
+//
+//	docs, errc := job.Stream(ctx, 16)
+//	for doc := range docs {
+//		// ...
+//	}
+//	if err := <-errc; err != nil {
+//		// ...
+//	}
+//
+// The channel holds up to buffer Documents. When it is full, the workers
+// block until the consumer catches up, so a slow consumer slows down the Job
+// rather than piling up Documents in memory. The Documents channel is closed
+// when the Job finishes, and the result of Run is then sent on the error
+// channel. A consumer giving up before the end must cancel ctx, or the Job
+// never finishes.
+func (j *Job) Stream(ctx context.Context, buffer int) (<-chan Document, <-chan error) {
+	docs := make(chan Document, buffer)
+	errc := make(chan error, 1)
+	go func() {
+		err := j.runEmitting(ctx, func(ctx context.Context, doc Document) error {
+			select {
+			case docs <- doc:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(docs)
+		errc <- err
+		close(errc)
+	}()
+	return docs, errc
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestJobStream(t *testing.T) {
+	ts := rmetaServer()
+	defer ts.Close()
+	files := map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c", "d.txt": "d"}
+	j, _ := testJob(ts, files)
+	j.Emit = func(context.Context, Document) error {
+		t.Errorf("Stream called Emit")
+		return nil
+	}
+	docs, errc := j.Stream(context.Background(), 0)
+
+	// Without a consumer, the workers block on their first Document.
+	time.Sleep(100 * time.Millisecond)
+	if s := j.Status(); s.Succeeded != 0 || s.InFlight != j.Workers {
+		t.Errorf("Stream without a consumer has status %+v, want %d in flight", s, j.Workers)
+	}
+	var ids []string
+	for d := range docs {
+		ids = append(ids, d.ID)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Stream got error: %v", err)
+	}
+	sort.Strings(ids)
+	if len(ids) != 4 || ids[0] != "a.txt" || ids[3] != "d.txt" {
+		t.Errorf("Stream sent %v, want the 4 files", ids)
+	}
+}
+
+func TestJobStreamCanceled(t *testing.T) {
+	ts := rmetaServer()
+	defer ts.Close()
+	j, _ := testJob(ts, map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"})
+	ctx, cancel := context.WithCancel(context.Background())
+	docs, errc := j.Stream(ctx, 0)
+	<-docs
+	cancel()
+	for range docs {
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("Stream got error %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,148 @@
+# Decodes an Arrow IPC file, such as docs.arrow, and prints its schema and
+# rows, checking its layout along the way:
+#
+#	python3 arrow_verify.py docs.arrow
+#
+# It is written from the Arrow format specification (File.fbs, Message.fbs and
+# Schema.fbs) and the FlatBuffers binary format, independently of the reader
+# of arrow_test.go, to check the golden file of TestArrowWriterGolden.
+import struct, sys, json
+b = open(sys.argv[1], 'rb').read()
+u8 = lambda p: b[p]
+u16 = lambda p: struct.unpack_from('<H', b, p)[0]
+i16 = lambda p: struct.unpack_from('<h', b, p)[0]
+u32 = lambda p: struct.unpack_from('<I', b, p)[0]
+i32 = lambda p: struct.unpack_from('<i', b, p)[0]
+i64 = lambda p: struct.unpack_from('<q', b, p)[0]
+
+class T:
+    def __init__(s, pos):
+        s.pos = pos
+        s.vt = pos - i32(pos)
+        s.vlen = u16(s.vt)
+        s.tlen = u16(s.vt + 2)
+        assert s.vlen % 2 == 0 and s.vlen >= 4
+    def off(s, i):
+        o = 4 + 2 * i
+        if o >= s.vlen: return 0
+        return u16(s.vt + o)
+    def scalar(s, i, f, d=0):
+        o = s.off(i)
+        return d if o == 0 else f(s.pos + o)
+    def ref(s, i):
+        o = s.off(i)
+        if o == 0: return None
+        p = s.pos + o
+        return p + u32(p)
+    def table(s, i):
+        r = s.ref(i); return None if r is None else T(r)
+    def string(s, i):
+        r = s.ref(i)
+        if r is None: return None
+        n = u32(r); assert b[r + 4 + n] == 0, 'strings are NUL terminated'
+        return b[r + 4:r + 4 + n].decode()
+    def vec(s, i):
+        r = s.ref(i)
+        if r is None: return None
+        return r + 4, u32(r)
+    def tables(s, i):
+        v = s.vec(i)
+        if v is None: return None
+        p, n = v
+        return [T(p + 4 * k + u32(p + 4 * k)) for k in range(n)]
+
+def root(p):
+    return T(p + u32(p))
+
+assert b[:6] == b'ARROW1' and b[6:8] == b'\0\0'
+assert b[-6:] == b'ARROW1'
+flen = i32(len(b) - 10)
+footer = root(len(b) - 10 - flen)
+assert footer.scalar(0, i16) == 4, 'MetadataVersion V5'
+
+def schema_of(t):
+    assert t.scalar(0, i16) == 0, 'little endian'
+    fields = []
+    for f in t.tables(1):
+        name = f.string(0)
+        nullable = bool(f.scalar(1, u8))
+        tt = f.scalar(2, u8)
+        ty = f.table(3)
+        assert ty is not None, 'type is required'
+        assert f.table(4) is None, 'no dictionary'
+        ch = f.vec(5)
+        assert ch is not None and ch[1] == 0, 'children must be an empty vector'
+        if tt == 5:
+            kind = 'utf8'
+        elif tt == 2:
+            kind = 'int%d%s' % (ty.scalar(0, i32), '' if ty.scalar(1, u8) else 'u')
+        else:
+            raise Exception('type %d' % tt)
+        fields.append((name, kind, nullable))
+    return fields
+
+fields = schema_of(footer.table(1))
+print('schema', fields)
+
+def message(off):
+    assert u32(off) == 0xFFFFFFFF, 'continuation marker'
+    mlen = i32(off + 4)
+    assert (off + 8 + mlen) % 8 == 0, 'body is 8-byte aligned'
+    m = root(off + 8)
+    assert m.scalar(0, i16) == 4
+    return m, mlen, off + 8 + mlen
+
+m, mlen, body = message(8)
+assert m.scalar(1, u8) == 1 and m.scalar(3, i64) == 0
+assert schema_of(m.table(2)) == fields
+
+dicts = footer.vec(2)
+assert dicts is None or dicts[1] == 0
+p, n = footer.vec(3)
+rows = []
+end = 0
+for k in range(n):
+    off, metalen, bodylen = i64(p + 24 * k), i32(p + 24 * k + 8), i64(p + 24 * k + 16)
+    assert off % 8 == 0
+    m, mlen, body = message(off)
+    assert 8 + mlen == metalen, 'Block.metaDataLength includes the prefix'
+    assert m.scalar(1, u8) == 3, 'RecordBatch'
+    assert m.scalar(3, i64) == bodylen
+    rb = m.table(2)
+    length = rb.scalar(0, i64)
+    np_, nn = rb.vec(1)
+    bp, nb = rb.vec(2)
+    assert nn == len(fields)
+    nodes = [(i64(np_ + 16 * j), i64(np_ + 16 * j + 8)) for j in range(nn)]
+    bufs = [(i64(bp + 16 * j), i64(bp + 16 * j + 8)) for j in range(nb)]
+    for o, l in bufs:
+        assert o % 8 == 0 and o + l <= bodylen
+    cols = []
+    bi = 0
+    for (name, kind, nullable), (nl, nc) in zip(fields, nodes):
+        assert nl == length
+        vo, vl = bufs[bi]; bi += 1
+        if nc == 0:
+            valid = [True] * nl
+        else:
+            assert nullable and vl >= (nl + 7) // 8
+            valid = [bool(b[body + vo + j // 8] >> (j % 8) & 1) for j in range(nl)]
+            assert valid.count(False) == nc
+        if kind == 'utf8':
+            oo, ol = bufs[bi]; dof, dl = bufs[bi + 1]; bi += 2
+            assert ol >= 4 * (nl + 1)
+            offs = [i32(body + oo + 4 * j) for j in range(nl + 1)]
+            assert offs[0] == 0 and offs == sorted(offs) and offs[-1] <= dl
+            cols.append([b[body + dof + offs[j]:body + dof + offs[j + 1]].decode() if valid[j] else None for j in range(nl)])
+        else:
+            do, dl = bufs[bi]; bi += 1
+            assert kind == 'int64' and dl >= 8 * nl
+            cols.append([i64(body + do + 8 * j) if valid[j] else None for j in range(nl)])
+    assert bi == nb
+    rows += [list(r) for r in zip(*cols)]
+    end = max(end, body + bodylen)
+
+assert u32(end) == 0xFFFFFFFF and u32(end + 4) == 0, 'end-of-stream marker'
+assert end + 8 == len(b) - 10 - flen, 'footer follows the end-of-stream marker'
+for r in rows:
+    print(json.dumps(r))
@@ -17,16 +17,17 @@ limitations under the License.
 package tika
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strings"
-
-	"golang.org/x/net/context/ctxhttp"
+	"time"
 )
 
 // Client represents a connection to a Tika Server.
@@ -38,12 +39,58 @@ type Client struct {
 	// client is specified, a default client will be used. Since http.Clients are
 	// thread safe, the same client will be used for all requests by this Client.
 	httpClient *http.Client
+	// stats counts the calls made by this Client. See Stats.
+	stats clientStats
+	// timeout is the default timeout of calls, and timeouts overrides it per
+	// Endpoint. Zero means no timeout.
+	timeout  time.Duration
+	timeouts map[Endpoint]time.Duration
+	// textDecoder decodes text responses. If nil, DecodeText is used.
+	textDecoder TextDecoder
+	// dnsConfig, if not nil, configures how the host of the Tika Server is
+	// resolved. See WithResolver.
+	dnsConfig *dnsConfig
+	// label names the server of this Client. See WithLabel.
+	label string
+	// contextHeaders are the headers set from the Context of calls. See
+	// WithContextHeader.
+	contextHeaders []contextHeader
+	// middlewares wrap the Transport of httpClient, first outermost, in
+	// chained. See Use.
+	middlewares []Middleware
+	chained     http.RoundTripper
+	// calls sends the requests of calls to the http.Client, counting them in
+	// Stats and bounding them by their timeout.
+	calls http.RoundTripper
+	// errorBodyLimit is the limit of the error bodies read. See
+	// WithErrorBodyLimit.
+	errorBodyLimit int64
+	// requestDefaults are applied to every call before its RequestOptions.
+	// See WithRequestDefaults.
+	requestDefaults []RequestOption
+	// breakers are the CircuitBreakers whose state is reported by Stats. See
+	// WithCircuitBreaker.
+	breakers []*CircuitBreaker
 }
 
+// A ClientOption can be passed to NewClient to configure the Client.
+type ClientOption func(*Client)
+
 // NewClient creates a new Client. If httpClient is nil, the http.DefaultClient will be
-// used.
-func NewClient(httpClient *http.Client, urlString string) *Client {
-	return &Client{httpClient: httpClient, url: urlString}
+// used. Changes made to httpClient later, such as to its Timeout or Transport,
+// apply to the calls made after them, except that the options configuring how
+// the Tika Server is dialed, such as WithResolver, use a clone of its Transport
+// made by NewClient.
+func NewClient(httpClient *http.Client, urlString string, options ...ClientOption) *Client {
+	c := &Client{httpClient: httpClient, url: urlString}
+	for _, o := range options {
+		o(c)
+	}
+	if c.dnsConfig != nil {
+		c.httpClient = c.dnsConfig.wrap(c.httpClient, c.url)
+	}
+	c.chain()
+	return c
 }
 
 // A Parser represents a Tika Parser. To get a list of all Parsers, see Parsers().
@@ -74,7 +121,8 @@ type Detector struct {
 type Translator string
 
 // Translators available by defult in Tika. You must configure all required
-// authentication details in Tika Server (for example, an API key).
+// authentication details in Tika Server (for example, an API key). See
+// WithTranslatorProperties.
 const (
 	Lingo24Translator   Translator = "org.apache.tika.language.translate.Lingo24Translator"
 	GoogleTranslator    Translator = "org.apache.tika.language.translate.GoogleTranslator"
@@ -88,52 +136,321 @@ const (
 // parsing. See ParseRecursive and MetaRecursive.
 const XTIKAContent = "X-TIKA:content"
 
+// callConfig is the configuration of a single call to the Tika Server. It is
+// built from the RequestOptions passed to a Client method.
+type callConfig struct {
+	header  http.Header
+	timeout time.Duration
+	// url overrides the URL of the Client. See WithServerURL.
+	url string
+	// handler is the content handler of MetaRecursive. See
+	// WithRecursiveHandler.
+	handler RecursiveHandler
+}
+
+// A RequestOption configures a single call made by a Client.
+type RequestOption func(*callConfig)
+
+// newCallConfig applies opts to a new callConfig.
+func newCallConfig(opts []RequestOption) *callConfig {
+	cfg := &callConfig{}
+	for _, o := range opts {
+		o(cfg)
+	}
+	return cfg
+}
+
+// setHeader sets the header key to value, creating the header if needed.
+func (cfg *callConfig) setHeader(key, value string) {
+	if cfg.header == nil {
+		cfg.header = make(http.Header)
+	}
+	cfg.header.Set(key, value)
+}
+
+// setRawHeader sets the header key to value without canonicalizing key, for
+// the headers whose name Tika reads as a case sensitive property name.
+func (cfg *callConfig) setRawHeader(key, value string) {
+	if cfg.header == nil {
+		cfg.header = make(http.Header)
+	}
+	cfg.header[key] = []string{value}
+}
+
+// WithResourceName returns a RequestOption to tell Tika the original file name
+// of the input. Tika uses the name (and in particular the extension) as a hint
+// when detecting the type of the input, which is required to tell apart
+// formats sharing a container, such as .key and .numbers files.
+//
+// Names which are not ASCII are sent as an RFC 5987 filename* parameter, in
+// UTF-8, after a filename parameter with the other characters replaced by
+// underscores, for servers which only read the latter.
+func WithResourceName(name string) RequestOption {
+	return func(cfg *callConfig) {
+		cfg.setHeader("Content-Disposition", contentDisposition(name))
+	}
+}
+
+// contentDisposition returns the Content-Disposition header of an attachment
+// named name.
+func contentDisposition(name string) string {
+	return disposition("attachment", name, nil)
+}
+
+// disposition returns a Content-Disposition header of the given type, with the
+// filename name and params. Names which are not printable ASCII are encoded as
+// defined by RFC 5987, with an ASCII fallback for older parsers.
+func disposition(typ, name string, params map[string]string) string {
+	p := map[string]string{"filename": name}
+	for k, v := range params {
+		p[k] = v
+	}
+	v := mime.FormatMediaType(typ, p)
+	i := strings.Index(v, "filename*=")
+	if i < 0 {
+		return v
+	}
+	encoded := v[i:]
+	if j := strings.Index(encoded, ";"); j >= 0 {
+		encoded = encoded[:j]
+	}
+	p["filename"] = strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, name)
+	return mime.FormatMediaType(typ, p) + "; " + encoded
+}
+
+// WithHeader returns a RequestOption to set the header key of the request to
+// value, for server features without a dedicated option. It replaces the
+// values set by previous options, and those set from the Context.
+func WithHeader(key, value string) RequestOption {
+	return func(cfg *callConfig) {
+		cfg.setHeader(key, value)
+	}
+}
+
+// WithAccept returns a RequestOption to request the given media type as a
+// response, such as "text/html" for the XHTML of Parse, or "text/csv" for
+// Meta. Methods decoding JSON responses override it.
+func WithAccept(mediaType string) RequestOption {
+	return WithHeader("Accept", mediaType)
+}
+
+// WithContentTypeHint returns a RequestOption to tell Tika the MIME type of the
+// input, such as "application/pdf", when it is known. Tika uses it as a hint
+// when detecting the type of the input, like WithResourceName.
+func WithContentTypeHint(mediaType string) RequestOption {
+	return WithHeader("Content-Type", mediaType)
+}
+
+// WithLastModified returns a RequestOption to tell Tika the modification time
+// of the input. Tika records it in the metadata of the document, and some
+// parsers use it to pick between versions of a format.
+func WithLastModified(t time.Time) RequestOption {
+	return func(cfg *callConfig) {
+		cfg.setHeader("Last-Modified", t.UTC().Format(http.TimeFormat))
+	}
+}
+
+// response is the body and header of a successful response.
+type response struct {
+	body   []byte
+	header http.Header
+	// buf holds body, and is returned to the pool by release.
+	buf *bytes.Buffer
+}
+
+// release returns the buffer of the body of r to the pool. The body must not
+// be used afterwards.
+func (r *response) release() {
+	putBuffer(r.buf)
+	r.body, r.buf = nil, nil
+}
+
 // call makes the given request to c and returns the result as a []byte and
-// error. call returns an error if the response code is not 200 StatusOK.
-func (c *Client) call(ctx context.Context, input io.Reader, method, path string, header http.Header) ([]byte, error) {
-	if c.httpClient == nil {
-		c.httpClient = http.DefaultClient
+// error. call returns an error if the response code is not 200 StatusOK. cfg
+// may be nil. The result is owned by the caller.
+func (c *Client) call(ctx context.Context, input io.Reader, method, path string, cfg *callConfig) ([]byte, error) {
+	resp, err := c.callResponse(ctx, input, method, path, cfg)
+	if err != nil {
+		return nil, err
 	}
+	return resp.body, nil
+}
 
-	req, err := http.NewRequest(method, c.url+path, input)
+// callJSON is like call, but unmarshals the JSON response into v.
+func (c *Client) callJSON(ctx context.Context, input io.Reader, method, path string, cfg *callConfig, v interface{}) error {
+	resp, err := c.callResponse(ctx, input, method, path, cfg)
+	if err != nil {
+		return err
+	}
+	defer resp.release()
+	return json.Unmarshal(resp.body, v)
+}
+
+// callResponse is like call, but also returns the header of the response. The
+// caller may release the response once it is done with the body.
+func (c *Client) callResponse(ctx context.Context, input io.Reader, method, path string, cfg *callConfig) (*response, error) {
+	req, ctx, err := c.newRequest(ctx, input, method, path, cfg)
 	if err != nil {
 		return nil, err
 	}
-	req.Header = header
+	resp, err := c.do(ctx, req, path)
+	return resp, callError(ctx, err)
+}
+
+// newRequest returns the request of a call to c, and the Context of the call,
+// which carries the call to the middlewares of c. cfg may be nil.
+func (c *Client) newRequest(ctx context.Context, input io.Reader, method, path string, cfg *callConfig) (*http.Request, context.Context, error) {
+	if cfg == nil {
+		cfg = &callConfig{}
+	}
+	if len(c.requestDefaults) > 0 {
+		cfg = c.withDefaults(cfg)
+	}
 
-	resp, err := ctxhttp.Do(ctx, c.httpClient, req)
+	req, err := http.NewRequest(method, c.serverURL(ctx, cfg)+path, input)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header = cfg.header
+	if len(c.contextHeaders) > 0 {
+		// Copy the header, which may be shared with other calls.
+		req.Header = c.setContextHeaders(ctx, cfg.header.Clone())
+	}
+	return req, context.WithValue(ctx, callKey{}, &call{path: path, cfg: cfg, stats: &c.stats}), nil
+}
+
+// callStream makes the given request to c and returns the body of the
+// response, without reading it. callStream returns an error if the response
+// code is not 200 StatusOK or 204 StatusNoContent, whose body is empty. The
+// call lasts until the body is closed.
+func (c *Client) callStream(ctx context.Context, input io.Reader, method, path string, cfg *callConfig) (io.ReadCloser, error) {
+	req, ctx, err := c.newRequest(ctx, input, method, path, cfg)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.calls.RoundTrip(req.WithContext(ctx))
+	if err == nil && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		err = c.tikaError(resp, path)
+		resp.Body.Close()
+	}
+	if err != nil {
+		return nil, callError(ctx, err)
+	}
+	return resp.Body, nil
+}
+
+// callError returns the error of a call made with ctx, as a CanceledError if
+// the call was canceled. The http.Client wraps the CanceledErrors of
+// middlewares, such as that of an open CircuitBreaker, in a url.Error, which
+// is dropped.
+func callError(ctx context.Context, err error) error {
+	if ue, ok := err.(*url.Error); ok {
+		if _, ok := ue.Err.(*CanceledError); ok {
+			return ue.Err
+		}
+	}
+	return canceled(ctx, err)
+}
+
+// do sends req, a call to path, and reads the response.
+func (c *Client) do(ctx context.Context, req *http.Request, path string) (*response, error) {
+	resp, err := c.calls.RoundTrip(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("response code %v", resp.StatusCode)
+		return nil, c.tikaError(resp, path)
 	}
-	return ioutil.ReadAll(resp.Body)
+	buf := getBuffer()
+	if n := resp.ContentLength; n > 0 && n <= maxPooledBuffer {
+		buf.Grow(int(n))
+	}
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	return &response{body: buf.Bytes(), header: resp.Header, buf: buf}, nil
 }
 
 // callString makes the given request to c and returns the result as a string
 // and error. callString returns an error if the response code is not 200 StatusOK.
-func (c *Client) callString(ctx context.Context, input io.Reader, method, path string) (string, error) {
-	body, err := c.call(ctx, input, method, path, nil)
+// The response is decoded according to its charset; see WithTextDecoder.
+func (c *Client) callString(ctx context.Context, input io.Reader, method, path string, opts ...RequestOption) (string, error) {
+	resp, err := c.callResponse(ctx, input, method, path, newCallConfig(opts))
 	if err != nil {
 		return "", err
 	}
-	return string(body), nil
+	defer resp.release()
+	return c.decodeText(resp)
 }
 
 // Parse parses the given input, returning the body of the input and an error.
 // If the error is not nil, the body is undefined.
-func (c *Client) Parse(ctx context.Context, input io.Reader) (string, error) {
-	return c.callString(ctx, input, "PUT", "/tika")
+func (c *Client) Parse(ctx context.Context, input io.Reader, opts ...RequestOption) (string, error) {
+	return c.callString(ctx, input, "PUT", "/tika", opts...)
+}
+
+// ParseReader parses the given input and returns the body of the input as
+// it is streamed by the server, so it can be copied to a file or an indexer
+// without holding it all in memory. The caller must close the body. The body
+// is in the charset of the server, UTF-8 for Tika, and is not decoded by the
+// TextDecoder of c. Timeouts of c cover reading the body.
+func (c *Client) ParseReader(ctx context.Context, input io.Reader, opts ...RequestOption) (io.ReadCloser, error) {
+	return c.callStream(ctx, input, "PUT", "/tika", newCallConfig(opts))
+}
+
+// ParseTo parses the given input and copies the body of the input to w as it
+// is streamed by the server, like ParseReader, and returns the number of bytes
+// written. If the error is not nil, w may hold part of the body.
+func (c *Client) ParseTo(ctx context.Context, input io.Reader, w io.Writer, opts ...RequestOption) (int64, error) {
+	body, err := c.ParseReader(ctx, input, opts...)
+	if err != nil {
+		return 0, err
+	}
+	buf := getCopyBuffer()
+	defer putCopyBuffer(buf)
+	n, err := io.CopyBuffer(w, body, *buf)
+	if cerr := body.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// A ParseResult is the content and metadata of a document, as returned by
+// ParseWithMeta.
+type ParseResult struct {
+	// Content is the extracted text.
+	Content string
+	// Metadata is the metadata of the document, without the content.
+	Metadata map[string][]string
+}
+
+// ParseWithMeta parses the given input and returns its content and metadata
+// with a single call. Only the container document is returned, not its
+// embedded documents; see MetaRecursive. If the error is not nil, the result
+// is undefined.
+func (c *Client) ParseWithMeta(ctx context.Context, input io.Reader, opts ...RequestOption) (*ParseResult, error) {
+	m, err := c.containerMeta(ctx, input, opts)
+	if err != nil {
+		return nil, err
+	}
+	r := &ParseResult{Content: firstValue(m, XTIKAContent), Metadata: m}
+	delete(m, XTIKAContent)
+	return r, nil
 }
 
 // ParseRecursive parses the given input and all embedded documents, returning a
 // list of the contents of the input with one element per document. See
 // MetaRecursive for access to all metadata fields. If the error is not nil, the
 // result is undefined.
-func (c *Client) ParseRecursive(ctx context.Context, input io.Reader) ([]string, error) {
-	m, err := c.MetaRecursive(ctx, input)
+func (c *Client) ParseRecursive(ctx context.Context, input io.Reader, opts ...RequestOption) ([]string, error) {
+	m, err := c.MetaRecursive(ctx, input, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -148,103 +465,171 @@ func (c *Client) ParseRecursive(ctx context.Context, input io.Reader) ([]string,
 
 // Meta parses the metadata from the given input, returning the metadata and an
 // error. If the error is not nil, the metadata is undefined.
-func (c *Client) Meta(ctx context.Context, input io.Reader) (string, error) {
-	return c.callString(ctx, input, "PUT", "/meta")
+func (c *Client) Meta(ctx context.Context, input io.Reader, opts ...RequestOption) (string, error) {
+	return c.callString(ctx, input, "PUT", "/meta", opts...)
 }
 
 // MetaField parses the metadata from the given input and returns the given
-// field. If the error is not nil, the result string is undefined.
-func (c *Client) MetaField(ctx context.Context, input io.Reader, field string) (string, error) {
-	return c.callString(ctx, input, "PUT", fmt.Sprintf("/meta/%v", field))
+// field, such as "Content-Type" or "dc:creator", without the rest of the
+// metadata. The field name is escaped in the path. If the error is not nil,
+// the result string is undefined.
+func (c *Client) MetaField(ctx context.Context, input io.Reader, field string, opts ...RequestOption) (string, error) {
+	return c.callString(ctx, input, "PUT", "/meta/"+url.PathEscape(field), opts...)
 }
 
 // Detect gets the mimetype of the given input, returning the mimetype and an
-// error. If the error is not nil, the mimetype is undefined.
-func (c *Client) Detect(ctx context.Context, input io.Reader) (string, error) {
-	return c.callString(ctx, input, "PUT", "/detect/stream")
+// error. If the error is not nil, the mimetype is undefined. Give the file
+// name of the input with WithResourceName, or use DetectWithName, to detect
+// inputs whose content is ambiguous, such as CSV files.
+func (c *Client) Detect(ctx context.Context, input io.Reader, opts ...RequestOption) (string, error) {
+	return c.callString(ctx, input, "PUT", "/detect/stream", opts...)
+}
+
+// DetectWithName is like Detect, with the file name of the input as a hint, as
+// given by WithResourceName. Tika combines the name with the content, so the
+// name tells apart types the content alone does not, such as CSV and plain
+// text, or a .docx file and other zip files.
+func (c *Client) DetectWithName(ctx context.Context, input io.Reader, name string, opts ...RequestOption) (string, error) {
+	return c.Detect(ctx, input, append(opts[:len(opts):len(opts)], WithResourceName(name))...)
 }
 
 // Language detects the language of the given input, returning the two letter
 // language code and an error. If the error is not nil, the language is
 // undefined.
-func (c *Client) Language(ctx context.Context, input io.Reader) (string, error) {
-	return c.callString(ctx, input, "PUT", "/language/stream")
+func (c *Client) Language(ctx context.Context, input io.Reader, opts ...RequestOption) (string, error) {
+	return c.callString(ctx, input, "PUT", "/language/stream", opts...)
 }
 
 // LanguageString detects the language of the given string, returning the two letter
 // language code and an error. If the error is not nil, the language is
-// undefined.
-func (c *Client) LanguageString(ctx context.Context, input string) (string, error) {
+// undefined. The string is sent as UTF-8 text, so that the server does not
+// decode it with another default charset.
+func (c *Client) LanguageString(ctx context.Context, input string, opts ...RequestOption) (string, error) {
 	r := strings.NewReader(input)
-	return c.callString(ctx, r, "PUT", "/language/string")
+	opts = append([]RequestOption{WithContentTypeHint("text/plain; charset=UTF-8")}, opts...)
+	return c.callString(ctx, r, "PUT", "/language/string", opts...)
 }
 
 // MetaRecursive parses the given input and all embedded documents. The result
 // is a list of maps from metadata key to value for each document. The content
 // of each document is in the XTIKAContent field. See ParseRecursive to just get
-// the content of each document. If the error is not nil, the result list is
-// undefined.
-func (c *Client) MetaRecursive(ctx context.Context, input io.Reader) ([]map[string][]string, error) {
-	body, err := c.call(ctx, input, "PUT", "/rmeta/text", nil)
-	if err != nil {
-		return nil, err
-	}
+// the content of each document, and MetaRecursiveStream for inputs with many
+// embedded documents. If the error is not nil, the result list is undefined.
+func (c *Client) MetaRecursive(ctx context.Context, input io.Reader, opts ...RequestOption) ([]map[string][]string, error) {
+	cfg := newCallConfig(opts)
+	cfg.setHeader("Accept", "application/json")
 	var m []map[string]interface{}
-	if err := json.Unmarshal(body, &m); err != nil {
+	if err := c.callJSON(ctx, input, "PUT", c.recursivePath(cfg), cfg, &m); err != nil {
 		return nil, err
 	}
 	var r []map[string][]string
 	for _, d := range m {
-		doc := make(map[string][]string)
+		doc, err := decodeMetadata(d)
+		if err != nil {
+			return nil, err
+		}
 		r = append(r, doc)
-		for k, v := range d {
-			switch vt := v.(type) {
-			case string:
-				doc[k] = []string{vt}
-			case []interface{}:
-				for _, i := range vt {
-					s, ok := i.(string)
-					if !ok {
-						return nil, fmt.Errorf("field %q has value %v and type %T, expected a string or []string", k, v, vt)
-					}
-					doc[k] = append(doc[k], s)
+	}
+	return r, nil
+}
+
+// metaMap returns the metadata of the given input, without its content, from
+// the /meta endpoint.
+func (c *Client) metaMap(ctx context.Context, input io.Reader, opts []RequestOption) (map[string][]string, error) {
+	cfg := newCallConfig(opts)
+	cfg.setHeader("Accept", "application/json")
+	var m map[string]interface{}
+	if err := c.callJSON(ctx, input, "PUT", "/meta", cfg, &m); err != nil {
+		return nil, err
+	}
+	return decodeMetadata(m)
+}
+
+// decodeMetadata converts the metadata of a document, as decoded from JSON,
+// to a map from metadata key to values.
+func decodeMetadata(d map[string]interface{}) (map[string][]string, error) {
+	doc := make(map[string][]string)
+	for k, v := range d {
+		switch vt := v.(type) {
+		case string:
+			doc[k] = []string{vt}
+		case []interface{}:
+			for _, i := range vt {
+				s, ok := i.(string)
+				if !ok {
+					return nil, fmt.Errorf("field %q has value %v and type %T, expected a string or []string", k, v, vt)
 				}
-			default:
-				return nil, fmt.Errorf("field %q has value %v and type %v, expected a string or []string", k, v, reflect.TypeOf(v))
+				doc[k] = append(doc[k], s)
 			}
+		default:
+			return nil, fmt.Errorf("field %q has value %v and type %v, expected a string or []string", k, v, reflect.TypeOf(v))
 		}
 	}
-	return r, nil
+	return doc, nil
 }
 
 // Translate returns an error and the translated input from src language to
 // dst language using t. If the error is not nil, the translation is undefined.
-func (c *Client) Translate(ctx context.Context, input io.Reader, t Translator, src, dst string) (string, error) {
-	return c.callString(ctx, input, "POST", fmt.Sprintf("/translate/all/%s/%s/%s", t, src, dst))
+// Languages are language codes, such as "en" or "pt-BR", and Translate returns
+// an error without calling the server if they are not.
+func (c *Client) Translate(ctx context.Context, input io.Reader, t Translator, src, dst string, opts ...RequestOption) (string, error) {
+	if err := checkLanguage(src); err != nil {
+		return "", err
+	}
+	if err := checkLanguage(dst); err != nil {
+		return "", err
+	}
+	return c.callString(ctx, input, "POST", fmt.Sprintf("/translate/all/%s/%s/%s", url.PathEscape(string(t)), src, dst), opts...)
 }
 
-// Version returns the default hello message from Tika server.
-func (c *Client) Version(ctx context.Context) (string, error) {
-	return c.callString(ctx, nil, "GET", "/version")
+// TranslateAuto is like Translate, but lets t detect the language of the
+// input.
+func (c *Client) TranslateAuto(ctx context.Context, input io.Reader, t Translator, dst string, opts ...RequestOption) (string, error) {
+	if err := checkLanguage(dst); err != nil {
+		return "", err
+	}
+	return c.callString(ctx, input, "POST", fmt.Sprintf("/translate/all/%s/%s", url.PathEscape(string(t)), dst), opts...)
 }
 
-var jsonHeader = http.Header{"Accept": []string{"application/json"}}
-
-// callUnmarshal is like call, but unmarshals the JSON response into v.
-func (c *Client) callUnmarshal(ctx context.Context, path string, v interface{}) error {
-	body, err := c.call(ctx, nil, "GET", path, jsonHeader)
-	if err != nil {
-		return err
+// checkLanguage returns an error if lang is not a language code: an ISO 639
+// code of 2 or 3 letters, optionally followed by subtags of 1 to 8 letters or
+// digits separated by "-" or "_", such as "pt-BR" or "zh_CN".
+func checkLanguage(lang string) error {
+	tags := strings.FieldsFunc(lang, func(r rune) bool { return r == '-' || r == '_' })
+	if len(tags) == 0 || len(strings.Join(tags, "-")) != len(lang) || len(tags[0]) < 2 || len(tags[0]) > 3 {
+		return fmt.Errorf("invalid language code %q", lang)
 	}
-	return json.Unmarshal(body, v)
+	for i, tag := range tags {
+		if len(tag) > 8 {
+			return fmt.Errorf("invalid language code %q", lang)
+		}
+		for _, r := range tag {
+			if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || i > 0 && '0' <= r && r <= '9') {
+				return fmt.Errorf("invalid language code %q", lang)
+			}
+		}
+	}
+	return nil
+}
+
+// Version returns the default hello message from Tika server.
+func (c *Client) Version(ctx context.Context, opts ...RequestOption) (string, error) {
+	return c.callString(ctx, nil, "GET", "/version", opts...)
+}
+
+// callUnmarshal gets path and unmarshals the JSON response into v.
+func (c *Client) callUnmarshal(ctx context.Context, path string, v interface{}, opts []RequestOption) error {
+	cfg := newCallConfig(opts)
+	cfg.setHeader("Accept", "application/json")
+	return c.callJSON(ctx, nil, "GET", path, cfg, v)
 }
 
 // Parsers returns the list of available parsers and an error. If the error is
 // not nil, the list is undefined. To get all available parsers, iterate through
 // the Children of every Parser.
-func (c *Client) Parsers(ctx context.Context) (*Parser, error) {
+func (c *Client) Parsers(ctx context.Context, opts ...RequestOption) (*Parser, error) {
 	p := new(Parser)
-	if err := c.callUnmarshal(ctx, "/parsers/details", p); err != nil {
+	if err := c.callUnmarshal(ctx, "/parsers/details", p, opts); err != nil {
 		return nil, err
 	}
 	return p, nil
@@ -252,9 +637,9 @@ func (c *Client) Parsers(ctx context.Context) (*Parser, error) {
 
 // MIMETypes returns a map from MIME Type name to MIMEType, or properties about
 // that specific MIMEType.
-func (c *Client) MIMETypes(ctx context.Context) (map[string]MIMEType, error) {
+func (c *Client) MIMETypes(ctx context.Context, opts ...RequestOption) (map[string]MIMEType, error) {
 	mt := make(map[string]MIMEType)
-	if err := c.callUnmarshal(ctx, "/mime-types", &mt); err != nil {
+	if err := c.callUnmarshal(ctx, "/mime-types", &mt, opts); err != nil {
 		return nil, err
 	}
 	return mt, nil
@@ -262,9 +647,9 @@ func (c *Client) MIMETypes(ctx context.Context) (map[string]MIMEType, error) {
 
 // Detectors returns the list of available Detectors for this server. To get all
 // available detectors, iterate through the Children of every Detector.
-func (c *Client) Detectors(ctx context.Context) (*Detector, error) {
+func (c *Client) Detectors(ctx context.Context, opts ...RequestOption) (*Detector, error) {
 	d := new(Detector)
-	if err := c.callUnmarshal(ctx, "/detectors", d); err != nil {
+	if err := c.callUnmarshal(ctx, "/detectors", d, opts); err != nil {
 		return nil, err
 	}
 	return d, nil
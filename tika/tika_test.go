@@ -17,13 +17,19 @@ limitations under the License.
 package tika
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 // errorServer always responds with http.StatusInternalServerError.
@@ -72,6 +78,217 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseReader(t *testing.T) {
+	want := strings.Repeat("streamed line\n", 10000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, want)
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	r, err := c.ParseReader(context.Background(), strings.NewReader("input"))
+	if err != nil {
+		t.Fatalf("ParseReader got error: %v", err)
+	}
+	if s := c.Stats(); s.Active != 1 {
+		t.Errorf("Stats() while reading = %+v, want 1 active call", s)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading the ParseReader body got error: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Close got error: %v", err)
+	}
+	r.Close()
+	if string(got) != want {
+		t.Errorf("ParseReader got %d bytes, want %d", len(got), len(want))
+	}
+	if s := c.Stats(); s.Active != 0 || s.Requests != 1 || s.Failures != 0 {
+		t.Errorf("Stats() after Close = %+v, want 1 request and none active", s)
+	}
+
+	if _, err := errorClient.ParseReader(context.Background(), nil); !errors.As(err, new(*TikaError)) {
+		t.Errorf("ParseReader of an error response got %v, want a TikaError", err)
+	}
+}
+
+func TestParseTo(t *testing.T) {
+	want := strings.Repeat("written line\n", 10000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, want)
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	var b bytes.Buffer
+	n, err := c.ParseTo(context.Background(), strings.NewReader("input"), &b)
+	if err != nil {
+		t.Fatalf("ParseTo got error: %v", err)
+	}
+	if n != int64(len(want)) || b.String() != want {
+		t.Errorf("ParseTo wrote %d bytes (reported %d), want %d", b.Len(), n, len(want))
+	}
+	if s := c.Stats(); s.Active != 0 {
+		t.Errorf("Stats() after ParseTo = %+v, want none active", s)
+	}
+
+	if n, err := errorClient.ParseTo(context.Background(), nil, &b); err == nil || n != 0 {
+		t.Errorf("ParseTo of an error response = %d, %v, want an error", n, err)
+	}
+}
+
+func TestParseWithMeta(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/rmeta/text" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `[{"X-TIKA:content": "body text", "Content-Type": "application/pdf", "Author": ["ann", "bob"]}, {"X-TIKA:content": "embedded"}]`)
+	}))
+	defer ts.Close()
+	got, err := NewClient(nil, ts.URL).ParseWithMeta(context.Background(), strings.NewReader("input"))
+	if err != nil {
+		t.Fatalf("ParseWithMeta got error: %v", err)
+	}
+	want := &ParseResult{
+		Content:  "body text",
+		Metadata: map[string][]string{"Content-Type": {"application/pdf"}, "Author": {"ann", "bob"}},
+	}
+	if !reflect.DeepEqual(got, want) || calls != 1 {
+		t.Errorf("ParseWithMeta = %+v in %d calls, want %+v in 1 call", got, calls, want)
+	}
+	if _, err := errorClient.ParseWithMeta(context.Background(), nil); err == nil {
+		t.Errorf("ParseWithMeta of an error response got no error")
+	}
+}
+
+func TestParseHints(t *testing.T) {
+	modified := time.Date(2017, time.March, 1, 12, 30, 0, 0, time.FixedZone("test", 3600))
+	tests := []struct {
+		name    string
+		options []RequestOption
+		want    http.Header
+	}{
+		{
+			name: "no hints",
+			want: http.Header{},
+		},
+		{
+			name:    "resource name",
+			options: []RequestOption{WithResourceName("slides.key")},
+			want:    http.Header{"Content-Disposition": {"attachment; filename=slides.key"}},
+		},
+		{
+			name:    "quoted resource name",
+			options: []RequestOption{WithResourceName("my slides.key")},
+			want:    http.Header{"Content-Disposition": {`attachment; filename="my slides.key"`}},
+		},
+		{
+			name:    "non-ASCII resource name",
+			options: []RequestOption{WithResourceName("文書 😀.pdf")},
+			want:    http.Header{"Content-Disposition": {`attachment; filename="__ _.pdf"; filename*=utf-8''%E6%96%87%E6%9B%B8%20%F0%9F%98%80.pdf`}},
+		},
+		{
+			name:    "last modified",
+			options: []RequestOption{WithLastModified(modified)},
+			want:    http.Header{"Last-Modified": {"Wed, 01 Mar 2017 11:30:00 GMT"}},
+		},
+		{
+			name:    "both",
+			options: []RequestOption{WithResourceName("a.numbers"), WithLastModified(modified)},
+			want: http.Header{
+				"Content-Disposition": {"attachment; filename=a.numbers"},
+				"Last-Modified":       {"Wed, 01 Mar 2017 11:30:00 GMT"},
+			},
+		},
+	}
+	for _, test := range tests {
+		got := http.Header{}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, k := range []string{"Content-Disposition", "Last-Modified"} {
+				if v, ok := r.Header[k]; ok {
+					got[k] = v
+				}
+			}
+		}))
+		defer ts.Close()
+		c := NewClient(nil, ts.URL)
+		if _, err := c.Parse(context.Background(), nil, test.options...); err != nil {
+			t.Errorf("Parse(%s) returned an error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Parse(%s) sent headers %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRequestOptions(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		switch r.URL.Path {
+		case "/rmeta/text":
+			fmt.Fprint(w, "[]")
+		case "/parsers/details", "/detectors", "/mime-types":
+			fmt.Fprint(w, "{}")
+		}
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	ctx := context.Background()
+	opts := []RequestOption{WithHeader("X-Test", "a"), WithHeader("X-Test", "b"), WithAccept("text/html"), WithContentTypeHint("application/pdf")}
+	calls := map[string]func() error{
+		"Parse":          func() error { _, err := c.Parse(ctx, nil, opts...); return err },
+		"ParseRecursive": func() error { _, err := c.ParseRecursive(ctx, nil, opts...); return err },
+		"MetaRecursive":  func() error { _, err := c.MetaRecursive(ctx, nil, opts...); return err },
+		"MetaRecursiveStream": func() error {
+			it, err := c.MetaRecursiveStream(ctx, nil, opts...)
+			if err == nil {
+				it.Close()
+			}
+			return err
+		},
+		"Meta":           func() error { _, err := c.Meta(ctx, nil, opts...); return err },
+		"MetaField":      func() error { _, err := c.MetaField(ctx, nil, "f", opts...); return err },
+		"Detect":         func() error { _, err := c.Detect(ctx, nil, opts...); return err },
+		"Language":       func() error { _, err := c.Language(ctx, nil, opts...); return err },
+		"LanguageString": func() error { _, err := c.LanguageString(ctx, "", opts...); return err },
+		"Translate":      func() error { _, err := c.Translate(ctx, nil, "t", "src", "dst", opts...); return err },
+		"Version":        func() error { _, err := c.Version(ctx, opts...); return err },
+		"Parsers":        func() error { _, err := c.Parsers(ctx, opts...); return err },
+		"MIMETypes":      func() error { _, err := c.MIMETypes(ctx, opts...); return err },
+		"Detectors":      func() error { _, err := c.Detectors(ctx, opts...); return err },
+	}
+	for name, call := range calls {
+		got = nil
+		if err := call(); err != nil {
+			t.Errorf("%s got error: %v", name, err)
+			continue
+		}
+		wantAccept := "text/html"
+		switch name {
+		case "ParseRecursive", "MetaRecursive", "MetaRecursiveStream", "Parsers", "MIMETypes", "Detectors":
+			wantAccept = "application/json"
+		}
+		if got.Get("X-Test") != "b" || got.Get("Accept") != wantAccept || got.Get("Content-Type") != "application/pdf" {
+			t.Errorf("%s sent header %v, want X-Test b, Accept %s and Content-Type application/pdf", name, got, wantAccept)
+		}
+	}
+}
+
+func TestResourceNameRoundTrip(t *testing.T) {
+	for _, name := range []string{"report.pdf", "文書.pdf", "한국어 🎉.docx", `naïve "quoted" \ name.txt`, "tab\there.txt"} {
+		cfg := &callConfig{}
+		WithResourceName(name)(cfg)
+		_, params, err := mime.ParseMediaType(cfg.header.Get("Content-Disposition"))
+		if err != nil || params["filename"] != name {
+			t.Errorf("WithResourceName(%q) sent %q, parsed as %q (%v)", name, cfg.header.Get("Content-Disposition"), params["filename"], err)
+		}
+	}
+}
+
 func TestParseRecursive(t *testing.T) {
 	tests := []struct {
 		response string
@@ -148,6 +365,24 @@ func TestMetaField(t *testing.T) {
 	}
 }
 
+func TestMetaFieldPath(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.EscapedPath())
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	for field, want := range map[string]string{
+		"Content-Type":  "/meta/Content-Type",
+		"dc:creator":    "/meta/dc:creator",
+		"a/b c?#":       "/meta/a%2Fb%20c%3F%23",
+		"X-TIKA:digest": "/meta/X-TIKA:digest",
+	} {
+		if got, err := c.MetaField(context.Background(), nil, field); err != nil || got != want {
+			t.Errorf("MetaField(%q) requested %q, %v, want %q", field, got, err, want)
+		}
+	}
+}
+
 func TestDetect(t *testing.T) {
 	want := "test value"
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -164,6 +399,37 @@ func TestDetect(t *testing.T) {
 	}
 }
 
+func TestDetectWithName(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Disposition"))
+		if strings.HasSuffix(params["filename"], ".csv") {
+			fmt.Fprint(w, "text/csv")
+			return
+		}
+		fmt.Fprint(w, "text/plain")
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"data.csv", "text/csv"},
+		{"data.txt", "text/plain"},
+		{"données.csv", "text/csv"},
+	}
+	for _, test := range tests {
+		got, err := c.DetectWithName(context.Background(), strings.NewReader("a,b\n1,2\n"), test.name)
+		if err != nil {
+			t.Errorf("DetectWithName(%q) got error: %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("DetectWithName(%q) got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
 func TestLanguage(t *testing.T) {
 	want := "test value"
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -181,13 +447,22 @@ func TestLanguage(t *testing.T) {
 }
 
 func TestLanguageString(t *testing.T) {
-	want := "test value"
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	want := "fr"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != "PUT" || r.URL.Path != "/language/string" || string(body) != "Ça va très bien" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if got := r.Header.Get("Content-Type"); got != "text/plain; charset=UTF-8" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
 		fmt.Fprint(w, want)
 	}))
 	defer ts.Close()
 	c := NewClient(nil, ts.URL)
-	got, err := c.LanguageString(context.Background(), "")
+	got, err := c.LanguageString(context.Background(), "Ça va très bien")
 	if err != nil {
 		t.Errorf("LanguageString returned an error: %v, want %q", err, want)
 	}
@@ -289,6 +564,56 @@ func TestTranslate(t *testing.T) {
 	}
 }
 
+func TestTranslatePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		dst     string
+		auto    bool
+		want    string
+		wantErr bool
+	}{
+		{name: "codes", src: "en", dst: "fr", want: "/translate/all/org.apache.tika.language.translate.Lingo24Translator/en/fr"},
+		{name: "regions", src: "pt-BR", dst: "zh_CN", want: "/translate/all/org.apache.tika.language.translate.Lingo24Translator/pt-BR/zh_CN"},
+		{name: "auto", dst: "de", auto: true, want: "/translate/all/org.apache.tika.language.translate.Lingo24Translator/de"},
+		{name: "empty source", src: "", dst: "fr", wantErr: true},
+		{name: "long source", src: "english", dst: "fr", wantErr: true},
+		{name: "path source", src: "en/../x", dst: "fr", wantErr: true},
+		{name: "empty subtag", src: "en--US", dst: "fr", wantErr: true},
+		{name: "trailing separator", src: "en", dst: "fr-", wantErr: true},
+		{name: "digit language", src: "e1", dst: "fr", wantErr: true},
+		{name: "auto invalid", dst: "fr/en", auto: true, wantErr: true},
+	}
+	for _, test := range tests {
+		var path string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			fmt.Fprint(w, "translated")
+		}))
+		c := NewClient(nil, ts.URL)
+		var err error
+		if test.auto {
+			_, err = c.TranslateAuto(context.Background(), strings.NewReader("text"), Lingo24Translator, test.dst)
+		} else {
+			_, err = c.Translate(context.Background(), strings.NewReader("text"), Lingo24Translator, test.src, test.dst)
+		}
+		ts.Close()
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%s: got error %v, want error: %v", test.name, err, test.wantErr)
+			continue
+		}
+		if test.wantErr {
+			if path != "" {
+				t.Errorf("%s: called the server at %q, want no call", test.name, path)
+			}
+			continue
+		}
+		if path != test.want {
+			t.Errorf("%s: got path %q, want %q", test.name, path, test.want)
+		}
+	}
+}
+
 func TestParsers(t *testing.T) {
 	tests := []struct {
 		response string
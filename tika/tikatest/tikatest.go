@@ -0,0 +1,392 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package tikatest provides a fake Tika Server, to test code built on the tika
+package hermetically, without Java or a Tika Server JAR. The fake serves the
+main endpoints with canned responses per MIME type, and can inject errors and
+latency:
+
+	s := tikatest.NewServer()
+	defer s.Close()
+	s.SetResponse("application/pdf", tikatest.Response{
+		Content:  "Quarterly report",
+		Metadata: map[string][]string{"dc:title": {"Q3"}},
+	})
+	s.SetResponse("application/zip", tikatest.Response{Status: http.StatusUnprocessableEntity})
+	client := tika.NewClient(nil, s.URL)
+
+The fake is not a parser: it only detects types, from the Content-Type of the
+request, the file name in its Content-Disposition, or else the first bytes of
+the body.
+*/
+package tikatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is the version returned by the /version endpoint of a Server.
+const Version = "Apache Tika 2.9.2 (tikatest)"
+
+// A Response is the canned result of the documents of a MIME type.
+type Response struct {
+	// Content is the extracted text.
+	Content string
+	// Metadata is the extracted metadata. Its Content-Type is set to the
+	// detected type if it is not set.
+	Metadata map[string][]string
+	// Language is the language code returned by the /language endpoints,
+	// "en" if it is empty.
+	Language string
+	// Embedded are the embedded documents, returned after the container by
+	// the /rmeta endpoints, with their depth and path.
+	Embedded []Response
+	// Status, if not 0, is the HTTP status returned instead of the result,
+	// such as http.StatusUnprocessableEntity for a corrupt or encrypted
+	// document.
+	Status int
+	// Delay is how long the Server waits before responding, to simulate slow
+	// documents, or until the request is canceled.
+	Delay time.Duration
+}
+
+// A Fault is an error or latency injected into the requests of an endpoint
+// with Server.Inject, whatever their document.
+type Fault struct {
+	// Status, if not 0, is the HTTP status returned instead of the result,
+	// such as http.StatusServiceUnavailable for an overloaded server.
+	Status int
+	// Delay is how long the Server waits before responding, or until the
+	// request is canceled.
+	Delay time.Duration
+	// Times is the number of requests the Fault applies to. 0 means all.
+	Times int
+}
+
+// A Request is a request received by a Server.
+type Request struct {
+	Method string
+	Path   string
+	// Type is the MIME type detected for the body.
+	Type string
+	// Name is the file name of the Content-Disposition, if any.
+	Name string
+	// Size is the size of the body in bytes.
+	Size int
+}
+
+type fault struct {
+	endpoint string
+	Fault
+	applied int
+}
+
+// Server is a fake Tika Server. Its URL is passed to tika.NewClient. It is
+// safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses map[string]Response
+	faults    []*fault
+	requests  []Request
+}
+
+// NewServer starts a Server. The caller must close it. Types without a
+// Response set with SetResponse return the body as content if they are text,
+// and no content otherwise.
+func NewServer() *Server {
+	s := &Server{responses: map[string]Response{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// SetResponse sets the Response to the documents of mimeType. mimeType can be
+// a pattern with a wildcard subtype, such as "image/*", used for the types
+// without a Response of their own.
+func (s *Server) SetResponse(mimeType string, r Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[mimeType] = r
+}
+
+// Inject injects f into the requests whose path starts with endpoint, such as
+// "/rmeta", or into all requests if endpoint is empty. Faults apply in the
+// order they were injected; the first one matching a request applies.
+func (s *Server) Inject(endpoint string, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault{endpoint: endpoint, Fault: f})
+}
+
+// ClearFaults removes the injected Faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the requests received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// detect returns the MIME type of a request with body.
+func detect(r *http.Request, name string, body []byte) string {
+	if t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && t != "application/octet-stream" {
+		return t
+	}
+	if ext := path.Ext(name); ext != "" {
+		if t, _, err := mime.ParseMediaType(mime.TypeByExtension(ext)); err == nil {
+			return t
+		}
+	}
+	t, _, _ := mime.ParseMediaType(http.DetectContentType(body))
+	return t
+}
+
+// response returns the Response of the documents of typ, and the body as
+// content if there is none.
+func (s *Server) response(typ string, body []byte) Response {
+	if r, ok := s.responses[typ]; ok {
+		return r
+	}
+	if i := strings.Index(typ, "/"); i >= 0 {
+		if r, ok := s.responses[typ[:i]+"/*"]; ok {
+			return r
+		}
+	}
+	if strings.HasPrefix(typ, "text/") {
+		return Response{Content: string(body)}
+	}
+	return Response{}
+}
+
+// fault returns the Fault of a request to path, if any.
+func (s *Server) fault(path string) (Fault, bool) {
+	for _, f := range s.faults {
+		if !strings.HasPrefix(path, f.endpoint) || f.Times > 0 && f.applied >= f.Times {
+			continue
+		}
+		f.applied++
+		return f.Fault, true
+	}
+	return Fault{}, false
+}
+
+// wait waits for d, or until r is canceled, and reports whether d elapsed.
+func wait(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var name string
+	if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	typ := detect(r, name, body)
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Type: typ, Name: name, Size: len(body)})
+	f, faulty := s.fault(r.URL.Path)
+	resp := s.response(typ, body)
+	s.mu.Unlock()
+
+	if faulty {
+		if !wait(r, f.Delay) {
+			return
+		}
+		if f.Status != 0 {
+			http.Error(w, http.StatusText(f.Status), f.Status)
+			return
+		}
+	}
+	if r.URL.Path == "/version" {
+		fmt.Fprint(w, Version)
+		return
+	}
+	if !wait(r, resp.Delay) {
+		return
+	}
+	if resp.Status != 0 {
+		http.Error(w, http.StatusText(resp.Status), resp.Status)
+		return
+	}
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	asJSON := strings.Contains(r.Header.Get("Accept"), "application/json")
+	switch p := r.URL.Path; {
+	case p == "/tika":
+		accept := r.Header.Get("Accept")
+		if strings.Contains(accept, "html") || strings.Contains(accept, "xml") {
+			w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+			fmt.Fprint(w, xhtml(resp.Content))
+			return
+		}
+		writeText(w, resp.Content)
+	case p == "/meta":
+		writeMetadata(w, metadata(resp, typ), asJSON)
+	case strings.HasPrefix(p, "/meta/"):
+		field := strings.TrimPrefix(p, "/meta/")
+		v, ok := metadata(resp, typ)[field]
+		if !ok {
+			http.Error(w, "no such field", http.StatusNotFound)
+			return
+		}
+		writeMetadata(w, map[string][]string{field: v}, asJSON)
+	case p == "/rmeta" || strings.HasPrefix(p, "/rmeta/"):
+		handler := strings.TrimPrefix(strings.TrimPrefix(p, "/rmeta"), "/")
+		var docs []map[string][]string
+		flatten(&docs, resp, typ, handler, 0, "")
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, docs)
+	case p == "/detect/stream":
+		writeText(w, typ)
+	case p == "/language/stream" || p == "/language/string":
+		lang := resp.Language
+		if lang == "" {
+			lang = "en"
+		}
+		writeText(w, lang)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeText(w http.ResponseWriter, s string) {
+	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	fmt.Fprint(w, s)
+}
+
+// metadata returns the metadata of resp, with its Content-Type.
+func metadata(resp Response, typ string) map[string][]string {
+	m := map[string][]string{"Content-Type": {typ}}
+	for k, v := range resp.Metadata {
+		m[k] = v
+	}
+	return m
+}
+
+// writeMetadata writes m as a JSON object, or else as CSV like Tika.
+func writeMetadata(w http.ResponseWriter, m map[string][]string, asJSON bool) {
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json")
+		obj := map[string]interface{}{}
+		for k, v := range m {
+			if len(v) == 1 {
+				obj[k] = v[0]
+			} else {
+				obj[k] = v
+			}
+		}
+		writeJSON(w, obj)
+		return
+	}
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+	for _, k := range keys {
+		fields := []string{csvQuote(k)}
+		for _, v := range m[k] {
+			fields = append(fields, csvQuote(v))
+		}
+		fmt.Fprintln(w, strings.Join(fields, ","))
+	}
+}
+
+func csvQuote(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// xhtml returns content as the XHTML document of Tika.
+func xhtml(content string) string {
+	var b strings.Builder
+	b.WriteString(`<html xmlns="http://www.w3.org/1999/xhtml"><head></head><body>`)
+	for _, p := range strings.Split(content, "\n") {
+		if p != "" {
+			b.WriteString("<p>" + html.EscapeString(p) + "</p>")
+		}
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+// flatten appends the metadata of resp and its embedded documents to docs, as
+// returned by /rmeta with handler.
+func flatten(docs *[]map[string][]string, resp Response, typ, handler string, depth int, parent string) {
+	m := metadata(resp, typ)
+	if t := m["Content-Type"]; len(t) > 0 {
+		typ = t[0]
+	}
+	switch handler {
+	case "ignore":
+	case "text":
+		m["X-TIKA:content"] = []string{resp.Content}
+	default:
+		m["X-TIKA:content"] = []string{xhtml(resp.Content)}
+	}
+	if depth > 0 {
+		m["X-TIKA:embedded_depth"] = []string{fmt.Sprint(depth)}
+		m["X-TIKA:embedded_resource_path"] = []string{parent}
+	}
+	*docs = append(*docs, m)
+	for i, e := range resp.Embedded {
+		name := fmt.Sprintf("embedded-%d", i+1)
+		if n := e.Metadata["resourceName"]; len(n) > 0 {
+			name = n[0]
+		}
+		flatten(docs, e, "application/octet-stream", handler, depth+1, parent+"/"+name)
+	}
+}
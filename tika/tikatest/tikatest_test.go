@@ -0,0 +1,167 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tikatest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-tika/tika"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.SetResponse("application/pdf", Response{
+		Content:  "Quarterly report",
+		Metadata: map[string][]string{"dc:title": {"Q3"}},
+		Language: "fr",
+		Embedded: []Response{{Content: "chart", Metadata: map[string][]string{"resourceName": {"chart.png"}, "Content-Type": {"image/png"}}}},
+	})
+	c := tika.NewClient(nil, s.URL)
+	ctx := context.Background()
+	pdf := func() *strings.Reader { return strings.NewReader("%PDF-1.4 fake") }
+
+	if got, err := c.Parse(ctx, pdf()); err != nil || got != "Quarterly report" {
+		t.Errorf("Parse = %q, %v, want the canned content", got, err)
+	}
+	if got, err := c.Parse(ctx, strings.NewReader("plain text")); err != nil || got != "plain text" {
+		t.Errorf("Parse of text = %q, %v, want the text", got, err)
+	}
+	if got, err := c.Detect(ctx, pdf()); err != nil || got != "application/pdf" {
+		t.Errorf("Detect = %q, %v, want application/pdf", got, err)
+	}
+	if got, err := c.DetectWithName(ctx, strings.NewReader("a,b"), "data.csv"); err != nil || got != "text/csv" {
+		t.Errorf("DetectWithName = %q, %v, want text/csv", got, err)
+	}
+	if got, err := c.Language(ctx, pdf()); err != nil || got != "fr" {
+		t.Errorf("Language = %q, %v, want fr", got, err)
+	}
+	if got, err := c.LanguageString(ctx, "hello"); err != nil || got != "en" {
+		t.Errorf("LanguageString = %q, %v, want en", got, err)
+	}
+	if got, err := c.Version(ctx); err != nil || got != Version {
+		t.Errorf("Version = %q, %v, want %q", got, err, Version)
+	}
+	if got, err := c.Meta(ctx, pdf()); err != nil || got != "\"Content-Type\",\"application/pdf\"\n\"dc:title\",\"Q3\"\n" {
+		t.Errorf("Meta = %q, %v, want CSV", got, err)
+	}
+	if got, err := c.MetaField(ctx, pdf(), "dc:title"); err != nil || !strings.Contains(got, "Q3") {
+		t.Errorf("MetaField = %q, %v, want Q3", got, err)
+	}
+
+	docs, err := c.MetaRecursive(ctx, pdf(), tika.WithRecursiveHandler(tika.HandlerText))
+	if err != nil {
+		t.Fatalf("MetaRecursive got error: %v", err)
+	}
+	want := []map[string][]string{
+		{"Content-Type": {"application/pdf"}, "dc:title": {"Q3"}, "X-TIKA:content": {"Quarterly report"}},
+		{"Content-Type": {"image/png"}, "resourceName": {"chart.png"}, "X-TIKA:content": {"chart"},
+			"X-TIKA:embedded_depth": {"1"}, "X-TIKA:embedded_resource_path": {"/chart.png"}},
+	}
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("MetaRecursive got\n%v\nwant\n%v", docs, want)
+	}
+	r, err := c.ParseWithMeta(ctx, pdf())
+	if err != nil || r.Content != "Quarterly report" || r.Metadata["dc:title"][0] != "Q3" {
+		t.Errorf("ParseWithMeta = %+v, %v, want the canned document", r, err)
+	}
+
+	reqs := s.Requests()
+	if len(reqs) == 0 || reqs[0].Path != "/tika" || reqs[0].Type != "application/pdf" || reqs[0].Method != http.MethodPut {
+		t.Errorf("Requests()[0] = %+v, want a PUT of a PDF to /tika", reqs[0])
+	}
+}
+
+func TestServerWildcard(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.SetResponse("image/*", Response{Content: "OCR text"})
+	s.SetResponse("image/gif", Response{Status: http.StatusUnprocessableEntity})
+	c := tika.NewClient(nil, s.URL)
+	ctx := context.Background()
+	if got, err := c.Parse(ctx, strings.NewReader("x"), tika.WithResourceName("scan.png")); err != nil || got != "OCR text" {
+		t.Errorf("Parse of a PNG = %q, %v, want the image/* response", got, err)
+	}
+	_, err := c.Parse(ctx, strings.NewReader("GIF89a"))
+	var terr *tika.TikaError
+	if !errors.As(err, &terr) || terr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Parse of a GIF got error %v, want status 422", err)
+	}
+	if got, err := c.Parse(ctx, strings.NewReader("\x00\x01binary")); err != nil || got != "" {
+		t.Errorf("Parse of unknown binary = %q, %v, want no content", got, err)
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	c := tika.NewClient(nil, s.URL)
+	ctx := context.Background()
+	s.Inject("/tika", Fault{Status: http.StatusServiceUnavailable, Times: 2})
+	for i := 0; i < 2; i++ {
+		if _, err := c.Parse(ctx, strings.NewReader("a")); err == nil {
+			t.Errorf("Parse %d got no error, want the injected fault", i)
+		}
+	}
+	if _, err := c.Detect(ctx, strings.NewReader("a")); err != nil {
+		t.Errorf("Detect got error %v, want no fault on another endpoint", err)
+	}
+	if got, err := c.Parse(ctx, strings.NewReader("a")); err != nil || got != "a" {
+		t.Errorf("Parse after the faults = %q, %v", got, err)
+	}
+
+	s.Inject("", Fault{Delay: time.Minute})
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Parse(tctx, strings.NewReader("a")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Parse with a delay got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	s.ClearFaults()
+
+	s.SetResponse("text/plain", Response{Content: "slow", Delay: 20 * time.Millisecond})
+	start := time.Now()
+	if got, err := c.Parse(ctx, strings.NewReader("a")); err != nil || got != "slow" {
+		t.Errorf("Parse of a slow type = %q, %v", got, err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("Parse of a slow type took %v, want at least 20ms", d)
+	}
+}
+
+func TestServerHandlers(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	c := tika.NewClient(nil, s.URL)
+	ctx := context.Background()
+	docs, err := c.MetaRecursive(ctx, strings.NewReader("a <b>"), tika.WithRecursiveHandler(tika.HandlerXML))
+	if err != nil || len(docs) != 1 || !strings.Contains(docs[0]["X-TIKA:content"][0], "<p>a &lt;b&gt;</p>") {
+		t.Errorf("MetaRecursive = %v, %v, want XHTML content", docs, err)
+	}
+	docs, err = c.MetaRecursive(ctx, strings.NewReader("a"), tika.WithRecursiveHandler(tika.HandlerIgnore))
+	if err != nil || len(docs) != 1 || docs[0]["X-TIKA:content"] != nil {
+		t.Errorf("MetaRecursive ignoring content = %v, %v, want no content", docs, err)
+	}
+	if got, err := c.ParseHTML(ctx, strings.NewReader("a")); err != nil || !strings.Contains(got, "<body><p>a</p></body>") {
+		t.Errorf("ParseHTML = %q, %v, want XHTML", got, err)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// An Endpoint is a resource of Tika Server, named after the first element of
+// its path. For example, Parse calls the "tika" endpoint and Detect calls the
+// "detect" endpoint.
+type Endpoint string
+
+// Endpoints called by Client.
+const (
+	EndpointParse     Endpoint = "tika"
+	EndpointMeta      Endpoint = "meta"
+	EndpointRecursive Endpoint = "rmeta"
+	EndpointDetect    Endpoint = "detect"
+	EndpointLanguage  Endpoint = "language"
+	EndpointTranslate Endpoint = "translate"
+	EndpointVersion   Endpoint = "version"
+	EndpointParsers   Endpoint = "parsers"
+	EndpointMIMETypes Endpoint = "mime-types"
+	EndpointDetectors Endpoint = "detectors"
+)
+
+// endpointOf returns the Endpoint of the given request path.
+func endpointOf(path string) Endpoint {
+	path = strings.TrimPrefix(path, "/")
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		path = path[:i]
+	}
+	return Endpoint(path)
+}
+
+// WithDefaultTimeout returns a ClientOption to set the timeout of every call
+// made by the Client, unless overridden by WithEndpointTimeout,
+// ContextWithTimeout or WithRequestTimeout. These timeouts are independent
+// from the Timeout of the http.Client, which applies to every request.
+func WithDefaultTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithEndpointTimeout returns a ClientOption to set the timeout of calls to the
+// given Endpoint, overriding WithDefaultTimeout. Use it to give quick calls,
+// such as Detect, a shorter timeout than expensive ones, such as Parse.
+func WithEndpointTimeout(e Endpoint, d time.Duration) ClientOption {
+	return func(c *Client) {
+		if c.timeouts == nil {
+			c.timeouts = make(map[Endpoint]time.Duration)
+		}
+		c.timeouts[e] = d
+	}
+}
+
+// WithRequestTimeout returns a RequestOption to set the timeout of a single
+// call, overriding every other timeout setting of the Client.
+func WithRequestTimeout(d time.Duration) RequestOption {
+	return func(cfg *callConfig) {
+		cfg.timeout = d
+	}
+}
+
+type timeoutKey struct{}
+
+// ContextWithTimeout returns a copy of ctx which sets the timeout of the calls
+// made with it, overriding the timeouts set with WithDefaultTimeout and
+// WithEndpointTimeout. Unlike context.WithTimeout, the timeout starts
+// separately for each call.
+func ContextWithTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
+
+// callTimeout returns the timeout of a call to path, or 0 for no timeout.
+func (c *Client) callTimeout(ctx context.Context, path string, cfg *callConfig) time.Duration {
+	if cfg.timeout > 0 {
+		return cfg.timeout
+	}
+	if d, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		return d
+	}
+	if d, ok := c.timeouts[endpointOf(path)]; ok {
+		return d
+	}
+	return c.timeout
+}
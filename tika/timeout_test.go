@@ -0,0 +1,87 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEndpointOf(t *testing.T) {
+	tests := []struct {
+		path string
+		want Endpoint
+	}{
+		{"", ""},
+		{"/tika", EndpointParse},
+		{"/detect/stream", EndpointDetect},
+		{"/meta/Content-Type", EndpointMeta},
+		{"/translate/all/t/src/dst", EndpointTranslate},
+	}
+	for _, test := range tests {
+		if got := endpointOf(test.path); got != test.want {
+			t.Errorf("endpointOf(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	c := NewClient(nil, "", WithDefaultTimeout(time.Minute), WithEndpointTimeout(EndpointDetect, time.Second))
+	bg := context.Background()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		path string
+		opts []RequestOption
+		want time.Duration
+	}{
+		{"default", bg, "/tika", nil, time.Minute},
+		{"endpoint", bg, "/detect/stream", nil, time.Second},
+		{"context", ContextWithTimeout(bg, 2*time.Second), "/detect/stream", nil, 2 * time.Second},
+		{"request", ContextWithTimeout(bg, 2*time.Second), "/tika", []RequestOption{WithRequestTimeout(3 * time.Second)}, 3 * time.Second},
+	}
+	for _, test := range tests {
+		if got := c.callTimeout(test.ctx, test.path, newCallConfig(test.opts)); got != test.want {
+			t.Errorf("callTimeout(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+	if got := NewClient(nil, "").callTimeout(bg, "/tika", &callConfig{}); got != 0 {
+		t.Errorf("callTimeout with no timeouts = %v, want 0", got)
+	}
+}
+
+func TestEndpointTimeoutExpires(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/detect/stream" {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		}
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL, WithEndpointTimeout(EndpointDetect, 10*time.Millisecond))
+	if _, err := c.Detect(context.Background(), nil); err == nil {
+		t.Errorf("Detect got no error, want a timeout")
+	}
+	if _, err := c.Parse(context.Background(), nil); err != nil {
+		t.Errorf("Parse got error %v, want no error", err)
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// serverMainClass is the main class of the server JAR, run explicitly when
+// the class path is extended.
+const serverMainClass = "org.apache.tika.server.TikaServerCli"
+
+// translatorFiles are the resources Translators read their configuration
+// from, next to their class.
+var translatorFiles = map[Translator]string{
+	Lingo24Translator:   "translator.lingo24.properties",
+	GoogleTranslator:    "translator.google.properties",
+	MosesTranslator:     "translator.moses.properties",
+	JoshuaTranslator:    "translator.joshua.properties",
+	MicrosoftTranslator: "translator.microsoft.properties",
+	YandexTranslator:    "translator.yandex.properties",
+}
+
+// translatorConfig is the configuration of a Translator: either properties
+// or the path of a properties file.
+type translatorConfig struct {
+	props map[string]string
+	file  string
+}
+
+// WithTranslatorProperties returns an Option to configure the Translator t
+// of the Server with the given properties, so Client.Translate works with it.
+// For example, the key of Lingo24Translator is set with:
+//
+//	tika.WithTranslatorProperties(tika.Lingo24Translator, map[string]string{
+//		"translator.user-key": os.Getenv("LINGO24_KEY"),
+//	})
+//
+// The properties replace those bundled in the JAR, so every property the
+// Translator needs must be set. They are written to a private temporary
+// directory when the Server starts, which is removed when it is shut down.
+func WithTranslatorProperties(t Translator, props map[string]string) Option {
+	return func(s *Server) {
+		p := make(map[string]string)
+		for k, v := range props {
+			p[k] = v
+		}
+		s.setTranslator(t, translatorConfig{props: p})
+	}
+}
+
+// WithTranslatorKeyFile returns an Option to configure the Translator t of
+// the Server with the Java properties file at path, like
+// WithTranslatorProperties. The file is read every time the Server starts, so
+// rotated keys are picked up on restart.
+func WithTranslatorKeyFile(t Translator, path string) Option {
+	return func(s *Server) {
+		s.setTranslator(t, translatorConfig{file: path})
+	}
+}
+
+func (s *Server) setTranslator(t Translator, cfg translatorConfig) {
+	if s.translators == nil {
+		s.translators = make(map[Translator]translatorConfig)
+	}
+	s.translators[t] = cfg
+}
+
+// checkTranslators returns an error if a Translator of s cannot be
+// configured.
+func (s *Server) checkTranslators() error {
+	for t, cfg := range s.translators {
+		if _, ok := translatorFiles[t]; !ok {
+			return fmt.Errorf("cannot configure translator %s", t)
+		}
+		if cfg.file != "" {
+			if _, err := os.Stat(cfg.file); err != nil {
+				return fmt.Errorf("translator key file: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeTranslators writes the configuration of the Translators of s to a new
+// temporary directory, laid out as a class path, and returns it. The
+// directory is empty if s has no Translators configured.
+func (s *Server) writeTranslators() (dir string, err error) {
+	if len(s.translators) == 0 {
+		return "", nil
+	}
+	dir, err = ioutil.TempDir("", "tika-translators")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+	for t, cfg := range s.translators {
+		b := []byte(encodeProperties(cfg.props))
+		if cfg.file != "" {
+			if b, err = ioutil.ReadFile(cfg.file); err != nil {
+				return "", fmt.Errorf("translator key file: %w", err)
+			}
+		}
+		pkg := string(t)[:strings.LastIndexByte(string(t), '.')]
+		path := filepath.Join(dir, filepath.FromSlash(strings.Replace(pkg, ".", "/", -1)), translatorFiles[t])
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(path, b, 0600); err != nil {
+			return "", err
+		}
+	}
+	if s.user != nil {
+		// Let the server read its keys when it runs as another user.
+		err = filepath.Walk(dir, func(path string, _ os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Chown(path, int(s.user.uid), int(s.user.gid))
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// encodeProperties encodes props in the format of Java properties files,
+// sorted by key.
+func encodeProperties(props map[string]string) string {
+	var keys []string
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(escapeProperty(k, true))
+		b.WriteByte('=')
+		b.WriteString(escapeProperty(props[k], false))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// escapeProperty escapes s as a key or a value of a Java properties file.
+func escapeProperty(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == ' ' && (key || i == 0):
+			b.WriteString(`\ `)
+		case key && strings.ContainsRune("=:#!", r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r > 0x7e:
+			// Properties files are read as ISO 8859-1.
+			if r > 0xffff {
+				r1, r2 := utf16.EncodeRune(r)
+				fmt.Fprintf(&b, `\u%04x\u%04x`, r1, r2)
+			} else {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
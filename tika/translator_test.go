@@ -0,0 +1,87 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeProperties(t *testing.T) {
+	tests := []struct {
+		props map[string]string
+		want  string
+	}{
+		{props: map[string]string{}, want: ""},
+		{
+			props: map[string]string{"translator.user-key": "abc123", "a": "b"},
+			want:  "a=b\ntranslator.user-key=abc123\n",
+		},
+		{
+			props: map[string]string{"odd key=": " lead\\ing\nnew é"},
+			want:  "odd\\ key\\==\\ lead\\\\ing\\nnew \\u00e9\n",
+		},
+	}
+	for _, test := range tests {
+		if got := encodeProperties(test.props); got != test.want {
+			t.Errorf("encodeProperties(%q) = %q, want %q", test.props, got, test.want)
+		}
+	}
+}
+
+func TestWriteTranslators(t *testing.T) {
+	keyFile := filepath.Join(tempDir(t), "google.properties")
+	if err := ioutil.WriteFile(keyFile, []byte("translator.client-secret=secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewServer("server_test.go",
+		WithTranslatorProperties(Lingo24Translator, map[string]string{"translator.user-key": "key"}),
+		WithTranslatorKeyFile(GoogleTranslator, keyFile))
+	if err != nil {
+		t.Fatalf("NewServer got error: %v", err)
+	}
+	dir, err := s.writeTranslators()
+	if err != nil {
+		t.Fatalf("writeTranslators got error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	pkg := filepath.Join(dir, "org", "apache", "tika", "language", "translate")
+	for file, want := range map[string]string{
+		"translator.lingo24.properties": "translator.user-key=key\n",
+		"translator.google.properties":  "translator.client-secret=secret\n",
+	} {
+		got, err := ioutil.ReadFile(filepath.Join(pkg, file))
+		if err != nil || string(got) != want {
+			t.Errorf("writeTranslators wrote %s = %q, %v, want %q", file, got, err, want)
+		}
+	}
+
+	want := []string{"-cp", dir + string(filepath.ListSeparator) + "server_test.go", serverMainClass, "-h", "localhost", "-p", "9998"}
+	if got := s.args(s.jar, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("args(%q) = %q, want %q", dir, got, want)
+	}
+
+	if _, err := NewServer("server_test.go", WithTranslatorProperties("com.example.Translator", nil)); err == nil {
+		t.Errorf("NewServer with an unknown translator got no error")
+	}
+	if _, err := NewServer("server_test.go", WithTranslatorKeyFile(YandexTranslator, filepath.Join(dir, "missing"))); err == nil {
+		t.Errorf("NewServer with a missing key file got no error")
+	}
+}
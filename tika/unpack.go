@@ -0,0 +1,254 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Names of the entries UnpackAll adds for the container document.
+const (
+	// UnpackTextName is the name of the entry with the text of the container.
+	UnpackTextName = "__TEXT__"
+	// UnpackMetadataName is the name of the entry with the metadata of the
+	// container, as CSV.
+	UnpackMetadataName = "__METADATA__"
+)
+
+// An EmbeddedDocument is a document embedded in an input, such as an email
+// attachment or an archive entry, as returned by an UnpackIterator.
+type EmbeddedDocument struct {
+	// Name is the name of the document given by Tika, usually its original
+	// file name.
+	Name string
+	// ContentType is the MIME type of the document, guessed from its name or
+	// else its first bytes.
+	ContentType string
+	// Size is the size of the document in bytes.
+	Size int64
+	// Content is the content of the document. It is only valid until the
+	// next call to Next or Close.
+	Content io.Reader
+}
+
+// An UnpackIterator iterates over the embedded documents of an input, as they
+// are streamed by the server:
+//
+//	it, err := client.Unpack(ctx, input)
+//	if err != nil {
+//		return err
+//	}
+//	defer it.Close()
+//	for it.Next() {
+//		doc := it.Document()
+//		// Read doc.Content.
+//	}
+//	return it.Err()
+type UnpackIterator struct {
+	body io.ReadCloser
+	tr   *tar.Reader
+	doc  *EmbeddedDocument
+	err  error
+}
+
+// Next advances to the next embedded document, which is then available
+// through Document. It returns false when there are no more documents, or an
+// error occurred; see Err.
+func (it *UnpackIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	for {
+		h, err := it.tr.Next()
+		if err == io.EOF {
+			it.doc = nil
+			return false
+		}
+		if err != nil {
+			it.err, it.doc = err, nil
+			return false
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		it.doc = &EmbeddedDocument{Name: h.Name, Size: h.Size}
+		it.doc.ContentType, it.doc.Content = unpackedType(h.Name, it.tr)
+		return true
+	}
+}
+
+// unpackedType returns the MIME type of the document named name, read from r,
+// and a reader of its content.
+func unpackedType(name string, r io.Reader) (string, io.Reader) {
+	switch name {
+	case UnpackTextName:
+		return "text/plain; charset=UTF-8", r
+	case UnpackMetadataName:
+		return "text/csv; charset=UTF-8", r
+	}
+	if t := typeByExtension(path.Ext(name)); t != "" {
+		return t, r
+	}
+	br := bufio.NewReaderSize(r, 512)
+	head, _ := br.Peek(512)
+	return http.DetectContentType(head), br
+}
+
+// Document returns the current embedded document.
+func (it *UnpackIterator) Document() *EmbeddedDocument {
+	return it.doc
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *UnpackIterator) Err() error {
+	return it.err
+}
+
+// Close ends the call. It must be called once done with the iterator.
+func (it *UnpackIterator) Close() error {
+	it.doc = nil
+	return it.body.Close()
+}
+
+// Unpack parses the given input and returns an iterator over its embedded
+// documents, such as the attachments of an email or the entries of an
+// archive. Embedded documents are not recursed into: unpack them in turn. The
+// caller must close the iterator.
+func (c *Client) Unpack(ctx context.Context, input io.Reader, opts ...RequestOption) (*UnpackIterator, error) {
+	return c.unpack(ctx, input, "/unpack", opts)
+}
+
+// UnpackAll is like Unpack, but also returns the text and metadata of the
+// input, as the documents named UnpackTextName and UnpackMetadataName.
+func (c *Client) UnpackAll(ctx context.Context, input io.Reader, opts ...RequestOption) (*UnpackIterator, error) {
+	return c.unpack(ctx, input, "/unpack/all", opts)
+}
+
+func (c *Client) unpack(ctx context.Context, input io.Reader, path string, opts []RequestOption) (*UnpackIterator, error) {
+	cfg := newCallConfig(opts)
+	// A tar archive can be read as it is streamed, unlike a zip file.
+	cfg.setHeader("Accept", "application/x-tar")
+	// Tika responds with no content to inputs without embedded documents,
+	// which is an empty archive.
+	body, err := c.callStream(ctx, input, "PUT", path, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &UnpackIterator{body: body, tr: tar.NewReader(body)}, nil
+}
+
+// An UnpackedFile is an embedded document written by UnpackToDir.
+type UnpackedFile struct {
+	// Name is the name of the document given by Tika.
+	Name string `json:"name"`
+	// Path is the path of the file the document was written to, relative to
+	// the directory passed to UnpackToDir, with slashes.
+	Path        string `json:"path"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+// UnpackToDir unpacks the embedded documents of the given input, as by Unpack,
+// and writes them to files in dir, which is created if needed. It returns the
+// files written, in order, even if the error is not nil.
+//
+// The names given by Tika come from the input, so they are sanitized: their
+// directories are kept below dir, without "." and ".." elements, and
+// characters which are not valid in file names on common systems are
+// replaced. Existing files are never overwritten: a number is added to the
+// names of files which already exist, as in "report-1.pdf".
+func (c *Client) UnpackToDir(ctx context.Context, input io.Reader, dir string, opts ...RequestOption) ([]UnpackedFile, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	it, err := c.Unpack(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	var files []UnpackedFile
+	for it.Next() {
+		doc := it.Document()
+		rel, err := writeUnpacked(dir, sanitizePath(doc.Name), doc.Content)
+		if err != nil {
+			return files, fmt.Errorf("error writing %s: %w", doc.Name, err)
+		}
+		files = append(files, UnpackedFile{Name: doc.Name, Path: rel, ContentType: doc.ContentType, Size: doc.Size})
+	}
+	return files, it.Err()
+}
+
+// sanitizePath returns name as a relative path with slashes which stays in the
+// directory it is joined to, and is a valid file name on common systems.
+func sanitizePath(name string) string {
+	var elems []string
+	for _, e := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' }) {
+		e = strings.Map(func(r rune) rune {
+			if r < ' ' || r == 0x7F || strings.ContainsRune(`<>:"|?*`, r) {
+				return '_'
+			}
+			return r
+		}, e)
+		// Windows ignores trailing dots and spaces.
+		e = strings.TrimRight(e, ". ")
+		if e != "" {
+			elems = append(elems, e)
+		}
+	}
+	if len(elems) == 0 {
+		return "embedded"
+	}
+	return strings.Join(elems, "/")
+}
+
+// writeUnpacked writes r to a new file at the relative path rel in dir, with a
+// number added to its name if it already exists, and returns the path of the
+// file relative to dir.
+func writeUnpacked(dir, rel string, r io.Reader) (string, error) {
+	if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(path.Dir(rel))), 0755); err != nil {
+		return "", err
+	}
+	ext := path.Ext(rel)
+	stem := strings.TrimSuffix(rel, ext)
+	for i := 0; ; i++ {
+		p := rel
+		if i > 0 {
+			p = fmt.Sprintf("%s-%d%s", stem, i, ext)
+		}
+		f, err := os.OpenFile(filepath.Join(dir, filepath.FromSlash(p)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(f, r)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return p, err
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"archive/tar"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnpack(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != "PUT" || r.Header.Get("Accept") != "application/x-tar" || string(body) == "fail" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		if string(body) == "empty" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		entries := []struct{ name, content string }{
+			{"report.pdf", "%PDF-1.4"},
+			{"photo", "\x89PNG\r\n\x1a\n"},
+		}
+		if r.URL.Path == "/unpack/all" {
+			entries = append(entries, struct{ name, content string }{UnpackTextName, "text"}, struct{ name, content string }{UnpackMetadataName, "k,v"})
+		}
+		tw := tar.NewWriter(w)
+		tw.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755})
+		for _, e := range entries {
+			tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content))})
+			tw.Write([]byte(e.content))
+		}
+		tw.Close()
+	}))
+	defer ts.Close()
+	c := NewClient(nil, ts.URL)
+
+	type doc struct{ Name, ContentType, Content string }
+	unpack := func(name string, call func() (*UnpackIterator, error)) []doc {
+		it, err := call()
+		if err != nil {
+			t.Fatalf("%s got error: %v", name, err)
+		}
+		defer it.Close()
+		var docs []doc
+		for it.Next() {
+			d := it.Document()
+			b, err := ioutil.ReadAll(d.Content)
+			if err != nil || int64(len(b)) != d.Size {
+				t.Errorf("%s read %q, %v from %s, want %d bytes", name, b, err, d.Name, d.Size)
+			}
+			docs = append(docs, doc{d.Name, d.ContentType, string(b)})
+		}
+		if err := it.Err(); err != nil {
+			t.Errorf("%s got error: %v", name, err)
+		}
+		return docs
+	}
+
+	ctx := context.Background()
+	got := unpack("Unpack", func() (*UnpackIterator, error) { return c.Unpack(ctx, strings.NewReader("mail")) })
+	want := []doc{
+		{"report.pdf", "application/pdf", "%PDF-1.4"},
+		{"photo", "image/png", "\x89PNG\r\n\x1a\n"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unpack got %q, want %q", got, want)
+	}
+	got = unpack("UnpackAll", func() (*UnpackIterator, error) { return c.UnpackAll(ctx, strings.NewReader("mail")) })
+	want = append(want, doc{UnpackTextName, "text/plain; charset=UTF-8", "text"}, doc{UnpackMetadataName, "text/csv; charset=UTF-8", "k,v"})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnpackAll got %q, want %q", got, want)
+	}
+	if got := unpack("Unpack without embedded documents", func() (*UnpackIterator, error) { return c.Unpack(ctx, strings.NewReader("empty")) }); len(got) != 0 {
+		t.Errorf("Unpack without embedded documents got %q, want none", got)
+	}
+	if _, err := c.Unpack(ctx, strings.NewReader("fail")); err == nil {
+		t.Errorf("Unpack of an error response got no error")
+	}
+}
+
+func TestUnpackToDir(t *testing.T) {
+	entries := []struct{ name, content string }{
+		{"report.pdf", "a"},
+		{"../../etc/passwd", "b"},
+		{"dir/./a.txt", "c"},
+		{"report.pdf", "d"},
+		{`C:\Users\x\what?.txt`, "e"},
+		{"..", "f"},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := tar.NewWriter(w)
+		for _, e := range entries {
+			tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content))})
+			tw.Write([]byte(e.content))
+		}
+		tw.Close()
+	}))
+	defer ts.Close()
+	dir := filepath.Join(tempDir(t), "out")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "report.pdf"), []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := NewClient(nil, ts.URL).UnpackToDir(context.Background(), nil, dir)
+	if err != nil {
+		t.Fatalf("UnpackToDir got error: %v", err)
+	}
+	wantPaths := []string{"report-1.pdf", "etc/passwd", "dir/a.txt", "report-2.pdf", "C_/Users/x/what_.txt", "embedded"}
+	if len(files) != len(entries) {
+		t.Fatalf("UnpackToDir wrote %+v, want %d files", files, len(entries))
+	}
+	for i, f := range files {
+		if f.Name != entries[i].name || f.Path != wantPaths[i] || f.Size != 1 {
+			t.Errorf("UnpackToDir wrote %+v, want %s at %s", f, entries[i].name, wantPaths[i])
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil || string(b) != entries[i].content {
+			t.Errorf("%s has content %q, %v, want %q", f.Path, b, err, entries[i].content)
+		}
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "report.pdf")); string(b) != "existing" {
+		t.Errorf("UnpackToDir overwrote an existing file")
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"os"
+	"strings"
+)
+
+// processUser is the user and group the Java process runs as.
+type processUser struct {
+	uid, gid uint32
+}
+
+// WithUser returns an Option to run the Java process as the user uid and the
+// group gid, without supplementary groups, so an exploited parser does not get
+// the privileges of the calling process, for example when it runs as root in
+// a container. The calling process must be allowed to switch users. Start
+// fails on systems other than Unix.
+func WithUser(uid, gid uint32) Option {
+	return func(s *Server) {
+		s.user = &processUser{uid: uid, gid: gid}
+	}
+}
+
+// WithEnvironment returns an Option to run the Java process with the
+// environment env, in the form "key=value", instead of the environment of the
+// calling process, which may hold credentials. See MinimalEnvironment.
+func WithEnvironment(env []string) Option {
+	return func(s *Server) {
+		s.env = append([]string{}, env...)
+	}
+}
+
+// minimalEnvironment are the variables Java needs to run.
+var minimalEnvironment = []string{"PATH", "JAVA_HOME", "LANG", "LC_ALL", "TMPDIR", "TZ"}
+
+// MinimalEnvironment returns the variables of the environment Java needs to
+// run, such as PATH and JAVA_HOME, and the variables named by keep, for
+// WithEnvironment.
+func MinimalEnvironment(keep ...string) []string {
+	names := map[string]bool{}
+	for _, name := range minimalEnvironment {
+		names[name] = true
+	}
+	for _, name := range keep {
+		names[name] = true
+	}
+	var env []string
+	for _, kv := range os.Environ() {
+		k := kv
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			k = kv[:i]
+		}
+		if names[k] {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
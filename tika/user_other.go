@@ -0,0 +1,29 @@
+//go:build !unix
+
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// setUser fails: processes cannot be run as another user on this system.
+func setUser(*exec.Cmd, *processUser) error {
+	return errors.New("running the server as another user is not supported on this system")
+}
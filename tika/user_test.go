@@ -0,0 +1,77 @@
+//go:build unix
+
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestServerCommand(t *testing.T) {
+	t.Setenv("JAVA_HOME", "/opt/java")
+	t.Setenv("SECRET_TOKEN", "secret")
+	t.Setenv("KEEP_ME", "kept")
+	tests := []struct {
+		name    string
+		options []Option
+		wantEnv []string
+		// wantUser is whether the command runs as uid 1000 and gid 1001.
+		wantUser bool
+	}{
+		{name: "defaults"},
+		{
+			name:     "restricted",
+			options:  []Option{WithUser(1000, 1001), WithEnvironment([]string{"PATH=/usr/bin"})},
+			wantEnv:  []string{"PATH=/usr/bin"},
+			wantUser: true,
+		},
+	}
+	for _, test := range tests {
+		s, err := NewServer("server_test.go", test.options...)
+		if err != nil {
+			t.Fatalf("NewServer(%s) got error: %v", test.name, err)
+		}
+		cmd, err := s.command(context.Background(), s.jar, "")
+		if err != nil {
+			t.Fatalf("command(%s) got error: %v", test.name, err)
+		}
+		if test.wantEnv != nil && !reflect.DeepEqual(cmd.Env, test.wantEnv) {
+			t.Errorf("command(%s) has environment %q, want %q", test.name, cmd.Env, test.wantEnv)
+		}
+		if !test.wantUser {
+			if cmd.SysProcAttr != nil && cmd.SysProcAttr.Credential != nil {
+				t.Errorf("command(%s) runs as %+v, want the current user", test.name, cmd.SysProcAttr.Credential)
+			}
+			continue
+		}
+		c := cmd.SysProcAttr.Credential
+		if c == nil || c.Uid != 1000 || c.Gid != 1001 || len(c.Groups) != 0 {
+			t.Errorf("command(%s) runs as %+v, want uid 1000, gid 1001 and no groups", test.name, c)
+		}
+	}
+
+	env := map[string]bool{}
+	for _, kv := range MinimalEnvironment("KEEP_ME") {
+		env[kv] = true
+	}
+	if !env["JAVA_HOME=/opt/java"] || !env["KEEP_ME=kept"] || env["SECRET_TOKEN=secret"] {
+		t.Errorf("MinimalEnvironment(KEEP_ME) = %v, want JAVA_HOME and KEEP_ME but not SECRET_TOKEN", env)
+	}
+}
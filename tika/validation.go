@@ -0,0 +1,150 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Metadata keys of the format validation signals of a document.
+const (
+	pdfVersionKey       = "pdf:PDFVersion"
+	pdfaPartKey         = "pdfaid:part"
+	pdfaConformanceKey  = "pdfaid:conformance"
+	pdfaVersionKey      = "pdfa:PDFVersion"
+	pdfuaPartKey        = "pdfuaid:part"
+	pdfEncryptedKey     = "pdf:encrypted"
+	pdfXFAKey           = "pdf:hasXFA"
+	pdfDamagedFontKey   = "pdf:containsDamagedFont"
+	tikaWarningKey      = tikaExceptionPrefix + "warn"
+	tikaExceptionSuffix = "_exception"
+)
+
+// A FormatValidation holds the format validation signals Tika reports for a
+// document and its embedded documents, for example to decide which documents
+// of an archive to migrate. The PDF/A and PDF/UA conformance are the claims
+// of the document, from its XMP metadata: Tika does not validate them.
+type FormatValidation struct {
+	// PDFVersion is the version of a PDF, such as "1.7".
+	PDFVersion string `json:"pdf_version,omitempty"`
+	// PDFA is the PDF/A conformance claimed by a PDF, such as "PDF/A-1b", or
+	// "" if it claims none.
+	PDFA string `json:"pdfa,omitempty"`
+	// PDFUA is the PDF/UA conformance claimed by a PDF, such as "PDF/UA-1".
+	PDFUA string `json:"pdfua,omitempty"`
+	// Encrypted is whether the document is encrypted, even if Tika could
+	// read it without a password.
+	Encrypted bool `json:"encrypted,omitempty"`
+	// XFA is whether a PDF has XFA forms, which PDF/A forbids.
+	XFA bool `json:"xfa,omitempty"`
+	// DamagedFonts is whether a PDF has fonts Tika could not read.
+	DamagedFonts bool `json:"damaged_fonts,omitempty"`
+	// Warnings are the warnings of the parsers, such as the repairs of
+	// malformed structures, sorted.
+	Warnings []string `json:"warnings,omitempty"`
+	// Exceptions are the first lines of the exceptions of the parsers, such
+	// as those of embedded documents they could not parse, sorted.
+	Exceptions []string `json:"exceptions,omitempty"`
+}
+
+// Malformed reports whether v has signs of a malformed document: parser
+// warnings or exceptions, or damaged fonts.
+func (v *FormatValidation) Malformed() bool {
+	return v.DamagedFonts || len(v.Warnings) > 0 || len(v.Exceptions) > 0
+}
+
+// NewFormatValidation returns the format validation signals of a document
+// from its metadata and the metadata of its embedded documents, as returned by
+// MetaRecursive. The conformance and flags are those of the container, the
+// first document; the warnings and exceptions are those of all of them.
+func NewFormatValidation(docs []map[string][]string) *FormatValidation {
+	v := &FormatValidation{}
+	if len(docs) == 0 {
+		return v
+	}
+	m := Metadata(docs[0])
+	v.PDFVersion = m.Get(pdfVersionKey)
+	if part := m.Get(pdfaPartKey); part != "" {
+		v.PDFA = "PDF/A-" + part + strings.ToLower(m.Get(pdfaConformanceKey))
+	} else if version := m.Get(pdfaVersionKey); version != "" {
+		v.PDFA = "PDF/" + strings.TrimPrefix(version, "PDF/")
+	}
+	if part := m.Get(pdfuaPartKey); part != "" {
+		v.PDFUA = "PDF/UA-" + part
+	}
+	v.Encrypted = metaBool(m, pdfEncryptedKey)
+	v.XFA = metaBool(m, pdfXFAKey)
+	v.DamagedFonts = metaBool(m, pdfDamagedFontKey)
+
+	warnings := make(map[string]bool)
+	exceptions := make(map[string]bool)
+	for _, d := range docs {
+		for k, vs := range d {
+			var seen map[string]bool
+			switch {
+			case k == tikaWarningKey:
+				seen = warnings
+			case strings.HasPrefix(k, tikaExceptionPrefix) && strings.HasSuffix(k, tikaExceptionSuffix):
+				seen = exceptions
+			default:
+				continue
+			}
+			for _, s := range vs {
+				if s = firstLine(s); s != "" {
+					seen[s] = true
+				}
+			}
+		}
+	}
+	v.Warnings = sortedKeys(warnings)
+	v.Exceptions = sortedKeys(exceptions)
+	return v
+}
+
+// firstLine returns the first line of s, such as the exception and message of
+// a stack trace, trimmed.
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
+
+// sortedKeys returns the keys of set, sorted, or nil if it is empty.
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Validate returns the format validation signals of the given input, such as
+// a PDF, skipping the content of the documents.
+func (c *Client) Validate(ctx context.Context, input io.Reader, opts ...RequestOption) (*FormatValidation, error) {
+	opts = append([]RequestOption{WithRecursiveHandler(HandlerIgnore)}, opts...)
+	docs, err := c.MetaRecursive(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewFormatValidation(docs), nil
+}
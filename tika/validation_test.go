@@ -0,0 +1,107 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewFormatValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		docs          []map[string][]string
+		want          *FormatValidation
+		wantMalformed bool
+	}{
+		{
+			name: "none",
+			want: &FormatValidation{},
+		},
+		{
+			name: "PDF/A",
+			docs: []map[string][]string{{
+				pdfVersionKey:      {"1.4"},
+				pdfaPartKey:        {"1"},
+				pdfaConformanceKey: {"B"},
+				pdfuaPartKey:       {"1"},
+				pdfEncryptedKey:    {"false"},
+			}},
+			want: &FormatValidation{PDFVersion: "1.4", PDFA: "PDF/A-1b", PDFUA: "PDF/UA-1"},
+		},
+		{
+			name: "PDF/A version only",
+			docs: []map[string][]string{{pdfaVersionKey: {"A-2u"}}},
+			want: &FormatValidation{PDFA: "PDF/A-2u"},
+		},
+		{
+			name: "malformed",
+			docs: []map[string][]string{
+				{
+					pdfVersionKey:     {"1.7"},
+					pdfEncryptedKey:   {"true"},
+					pdfXFAKey:         {"true"},
+					pdfDamagedFontKey: {"true"},
+					tikaWarningKey:    {"org.apache.pdfbox.pdfparser.COSParser: Unexpected XRef\n\tat COSParser.parse", ""},
+					embeddedLimitKey:  {"true"},
+				},
+				{
+					tikaWarningKey: {"org.apache.pdfbox.pdfparser.COSParser: Unexpected XRef"},
+					tikaExceptionPrefix + "embedded_exception": {"java.util.zip.ZipException: invalid entry\n\tat ZipFile"},
+				},
+			},
+			want: &FormatValidation{
+				PDFVersion:   "1.7",
+				Encrypted:    true,
+				XFA:          true,
+				DamagedFonts: true,
+				Warnings:     []string{"org.apache.pdfbox.pdfparser.COSParser: Unexpected XRef"},
+				Exceptions:   []string{"java.util.zip.ZipException: invalid entry"},
+			},
+			wantMalformed: true,
+		},
+	}
+	for _, test := range tests {
+		got := NewFormatValidation(test.docs)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("NewFormatValidation(%s) =\n%+v\nwant\n%+v", test.name, got, test.want)
+		}
+		if got.Malformed() != test.wantMalformed {
+			t.Errorf("NewFormatValidation(%s).Malformed() = %v, want %v", test.name, got.Malformed(), test.wantMalformed)
+		}
+	}
+}
+
+func TestClientValidate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rmeta/ignore" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]string{{pdfaPartKey: "3", pdfaConformanceKey: "A", pdfEncryptedKey: "true"}})
+	}))
+	defer ts.Close()
+	v, err := NewClient(nil, ts.URL).Validate(context.Background(), strings.NewReader("pdf"))
+	if err != nil || v.PDFA != "PDF/A-3a" || !v.Encrypted || v.Malformed() {
+		t.Errorf("Validate = %+v, %v, want an encrypted PDF/A-3a", v, err)
+	}
+}
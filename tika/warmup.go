@@ -0,0 +1,101 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// warmupDocs are tiny documents of common types, used to warm up a Server.
+var warmupDocs = map[string][]byte{
+	"text/plain":      []byte("warm up\n"),
+	"text/html":       []byte("<html><head><title>warm up</title></head><body><p>warm up</p></body></html>"),
+	"text/csv":        []byte("a,b\n1,2\n"),
+	"application/xml": []byte(`<?xml version="1.0"?><doc>warm up</doc>`),
+	"application/rtf": []byte(`{\rtf1\ansi warm up\par}`),
+	"application/pdf": []byte("%PDF-1.1\n" +
+		"1 0 obj<</Type/Catalog/Pages 2 0 R>>endobj\n" +
+		"2 0 obj<</Type/Pages/Kids[3 0 R]/Count 1>>endobj\n" +
+		"3 0 obj<</Type/Page/Parent 2 0 R/MediaBox[0 0 72 72]>>endobj\n" +
+		"trailer<</Root 1 0 R>>\n%%EOF\n"),
+}
+
+// WarmupTypes returns the sorted MIME types which can be passed to WithWarmup.
+func WarmupTypes() []string {
+	var types []string
+	for t := range warmupDocs {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// WithWarmup returns an Option to warm up the Server before Start returns, by
+// parsing a tiny document of each given MIME type, one at a time. This moves
+// the cost of initializing the JVM and the parsers out of the first real
+// requests. Each document is sent with its type as Content-Type. Each document must be parsed within 30 seconds. If no type is
+// given, every type of WarmupTypes is used.
+func WithWarmup(contentTypes ...string) Option {
+	return func(s *Server) {
+		if len(contentTypes) == 0 {
+			contentTypes = WarmupTypes()
+		}
+		s.warmup = contentTypes
+	}
+}
+
+// warmupPause is the pause between warm-up documents, so the warm-up does not
+// compete with the rest of the startup of the Server.
+var warmupPause = 100 * time.Millisecond
+
+// warmupTimeout is the timeout of parsing each warm-up document, so a Server
+// stuck on one fails to start rather than blocking Start.
+var warmupTimeout = 30 * time.Second
+
+// checkWarmup returns an error if a warm-up type of s is not supported.
+func (s *Server) checkWarmup() error {
+	for _, t := range s.warmup {
+		if _, ok := warmupDocs[t]; !ok {
+			return fmt.Errorf("no warm-up document for %q", t)
+		}
+	}
+	return nil
+}
+
+// warmUp parses the warm-up documents of s.
+func (s *Server) warmUp(ctx context.Context) error {
+	c := NewClient(nil, s.URL(), WithDefaultTimeout(warmupTimeout))
+	for i, t := range s.warmup {
+		if i > 0 {
+			select {
+			case <-time.After(warmupPause):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		// Without the type, Tika detects the text samples as text/plain, and
+		// their parsers are not loaded.
+		if _, err := c.Parse(ctx, bytes.NewReader(warmupDocs[t]), WithContentTypeHint(t)); err != nil {
+			return fmt.Errorf("%s: %w", t, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartWarmup(t *testing.T) {
+	path, err := os.Executable() // Use the test executable path as a dummy jar.
+	if err != nil {
+		t.Skip("cannot find current test executable")
+	}
+	var mu sync.Mutex
+	var parsed, types []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tika" {
+			body, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			parsed = append(parsed, string(body))
+			types = append(types, r.Header.Get("Content-Type"))
+			mu.Unlock()
+		}
+		fmt.Fprint(w, "1.14")
+	}))
+	defer ts.Close()
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("error creating test server: %v", err)
+	}
+
+	s, err := NewServer(path, WithHostname(tsURL.Hostname()), WithPort(tsURL.Port()), WithWarmup("text/plain", "text/csv"))
+	if err != nil {
+		t.Fatalf("NewServer got error: %v", err)
+	}
+	cancel, err := s.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start got error: %v", err)
+	}
+	cancel()
+	want := []string{string(warmupDocs["text/plain"]), string(warmupDocs["text/csv"])}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("Start parsed %q, want %q", parsed, want)
+	}
+	if want := []string{"text/plain", "text/csv"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("Start parsed documents of Content-Type %q, want %q", types, want)
+	}
+}
+
+func TestWarmupError(t *testing.T) {
+	path, err := os.Executable()
+	if err != nil {
+		t.Skip("cannot find current test executable")
+	}
+	if _, err := NewServer(path, WithWarmup("application/x-unknown")); err == nil {
+		t.Errorf("NewServer with an unknown warm-up type got no error, want an error")
+	}
+
+	s, err := NewServer(path, WithWarmup())
+	if err != nil {
+		t.Fatalf("NewServer with all warm-up types got error: %v", err)
+	}
+	if !reflect.DeepEqual(s.warmup, WarmupTypes()) {
+		t.Errorf("WithWarmup() set types %v, want %v", s.warmup, WarmupTypes())
+	}
+	s.url = errorServer.URL
+	if err := s.warmUp(context.Background()); err == nil {
+		t.Errorf("warmUp against a failing server got no error, want an error")
+	}
+
+	stuck := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		<-r.Context().Done()
+	}))
+	defer stuck.Close()
+	defer func(d time.Duration) { warmupTimeout = d }(warmupTimeout)
+	warmupTimeout = 50 * time.Millisecond
+	s.url = stuck.URL
+	start := time.Now()
+	if err := s.warmUp(context.Background()); !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Errorf("warmUp against a stuck server got error %v after %v, want a timeout", err, time.Since(start))
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// WebDAVSource is a ChangeSource providing the files of a WebDAV collection and
+// its subcollections. The ID of an Input is its path relative to the
+// collection.
+//
+// WebDAV has no change feed, so Changes lists the files modified after the
+// latest modification time seen by the previous call, and does not report
+// deleted files.
+type WebDAVSource struct {
+	httpClient *http.Client
+	root       *url.URL
+	creds      CredentialsProvider
+}
+
+// NewWebDAVSource creates a Source of the files under the collection at
+// rawurl. If creds is not nil, requests use basic authentication with its
+// Credentials. If httpClient is nil, http.DefaultClient is used.
+func NewWebDAVSource(httpClient *http.Client, rawurl string, creds CredentialsProvider) (*WebDAVSource, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebDAV URL: %w", err)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &WebDAVSource{httpClient: httpClient, root: u, creds: creds}, nil
+}
+
+// davMultistatus is the body of a PROPFIND response.
+type davMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ContentLength int64  `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+				ResourceType  struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+const davPropfind = `<?xml version="1.0" encoding="utf-8"?>
+<propfind xmlns="DAV:"><prop><resourcetype/><getcontentlength/><getlastmodified/></prop></propfind>`
+
+// newRequest creates a request authenticated with s.creds.
+func (s *WebDAVSource) newRequest(ctx context.Context, method string, u *url.URL, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if s.creds != nil {
+		c, err := s.creds.Credentials(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error getting credentials: %w", err)
+		}
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	return req, nil
+}
+
+// Walk implements Source. Inputs are walked in lexical order.
+func (s *WebDAVSource) Walk(ctx context.Context, fn func(Input) error) error {
+	return s.walk(ctx, s.root, fn)
+}
+
+func (s *WebDAVSource) walk(ctx context.Context, dir *url.URL, fn func(Input) error) error {
+	req, err := s.newRequest(ctx, "PROPFIND", dir, strings.NewReader(davPropfind))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml")
+	resp, err := ctxhttp.Do(ctx, s.httpClient, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return fmt.Errorf("error listing %s: response code %v", dir.Path, resp.StatusCode)
+	}
+	var ms davMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return fmt.Errorf("error listing %s: %w", dir.Path, err)
+	}
+	sort.Slice(ms.Responses, func(i, j int) bool { return ms.Responses[i].Href < ms.Responses[j].Href })
+	for _, r := range ms.Responses {
+		href, err := dir.Parse(r.Href)
+		if err != nil || !strings.HasPrefix(href.Path, s.root.Path) {
+			continue
+		}
+		if strings.TrimSuffix(href.Path, "/") == strings.TrimSuffix(dir.Path, "/") {
+			continue // The collection itself.
+		}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			if ps.Prop.ResourceType.Collection != nil {
+				if !strings.HasSuffix(href.Path, "/") {
+					href.Path, href.RawPath = href.Path+"/", ""
+				}
+				if err := s.walk(ctx, href, fn); err != nil {
+					return err
+				}
+				break
+			}
+			id := strings.TrimPrefix(href.Path, s.root.Path)
+			in := Input{ID: id, Name: id[strings.LastIndex(id, "/")+1:], Size: ps.Prop.ContentLength}
+			in.ModTime, _ = http.ParseTime(ps.Prop.LastModified)
+			if err := fn(in); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// Changes implements ChangeSource.
+func (s *WebDAVSource) Changes(ctx context.Context, token string, fn func(Input) error) (string, error) {
+	var since time.Time
+	if token != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, token); err != nil {
+			return "", fmt.Errorf("invalid token %q: %w", token, err)
+		}
+	}
+	latest := since
+	err := s.Walk(ctx, func(in Input) error {
+		if !in.ModTime.After(since) {
+			return nil
+		}
+		if in.ModTime.After(latest) {
+			latest = in.ModTime
+		}
+		return fn(in)
+	})
+	if err != nil {
+		return "", err
+	}
+	return latest.UTC().Format(time.RFC3339), nil
+}
+
+// Open implements Source.
+func (s *WebDAVSource) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	u, err := s.root.Parse("./" + (&url.URL{Path: id}).EscapedPath())
+	if err != nil || !strings.HasPrefix(u.Path, s.root.Path) {
+		return nil, fmt.Errorf("invalid id %q", id)
+	}
+	req, err := s.newRequest(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := openHTTP(ctx, s.httpClient, req)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", id, err)
+	}
+	return rc, nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// davResponse is a PROPFIND response entry of a test WebDAV server.
+func davResponse(href string, collection bool, size int, modified string) string {
+	rt := ""
+	if collection {
+		rt = "<D:collection/>"
+	}
+	return fmt.Sprintf(`<D:response><D:href>%s</D:href><D:propstat><D:prop>
+<D:resourcetype>%s</D:resourcetype><D:getcontentlength>%d</D:getcontentlength>
+<D:getlastmodified>%s</D:getlastmodified></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat></D:response>`,
+		href, rt, size, modified)
+}
+
+func webDAVServer(t *testing.T) *httptest.Server {
+	const (
+		older = "Mon, 02 Jan 2017 15:04:05 GMT"
+		newer = "Tue, 03 Jan 2017 15:04:05 GMT"
+	)
+	listings := map[string][]string{
+		"/dav/": {
+			davResponse("/dav/", true, 0, older),
+			davResponse("/dav/b%20c.txt", false, 3, newer),
+			davResponse("/dav/sub/", true, 0, older),
+		},
+		"/dav/sub/": {
+			davResponse("/dav/sub/", true, 0, older),
+			davResponse("/dav/sub/a.pdf", false, 5, older),
+		},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, _ := r.BasicAuth(); u != "u" || p != "p" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case "PROPFIND":
+			if r.Header.Get("Depth") != "1" {
+				t.Errorf("PROPFIND with Depth %q, want 1", r.Header.Get("Depth"))
+			}
+			w.WriteHeader(http.StatusMultiStatus)
+			fmt.Fprintf(w, `<?xml version="1.0"?><D:multistatus xmlns:D="DAV:">%s</D:multistatus>`,
+				strings.Join(listings[r.URL.Path], ""))
+		case "GET":
+			fmt.Fprint(w, "content of "+r.URL.Path)
+		}
+	}))
+}
+
+func TestWebDAVSource(t *testing.T) {
+	ts := webDAVServer(t)
+	defer ts.Close()
+	s, err := NewWebDAVSource(ts.Client(), ts.URL+"/dav", StaticCredentials{Username: "u", Password: "p"})
+	if err != nil {
+		t.Fatalf("NewWebDAVSource got error: %v", err)
+	}
+
+	want := []string{"b c.txt", "sub/a.pdf"}
+	if got := walkIDs(t, s); !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk got %v, want %v", got, want)
+	}
+
+	var changed []string
+	token, err := s.Changes(context.Background(), "2017-01-02T20:00:00Z", func(in Input) error {
+		changed = append(changed, in.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Changes got error: %v", err)
+	}
+	if want := []string{"b c.txt"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("Changes got %v, want %v", changed, want)
+	}
+	if want := "2017-01-03T15:04:05Z"; token != want {
+		t.Errorf("Changes returned token %q, want %q", token, want)
+	}
+
+	rc, err := s.Open(context.Background(), "b c.txt")
+	if err != nil {
+		t.Fatalf("Open got error: %v", err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != "content of /dav/b c.txt" {
+		t.Errorf("Open read %q", b)
+	}
+	if _, err := s.Open(context.Background(), "../x"); err == nil {
+		t.Errorf("Open outside of the collection got no error")
+	}
+}
+
+func TestWebDAVSourceUnauthorized(t *testing.T) {
+	ts := webDAVServer(t)
+	defer ts.Close()
+	s, _ := NewWebDAVSource(ts.Client(), ts.URL+"/dav/", nil)
+	if err := s.Walk(context.Background(), func(Input) error { return nil }); err == nil {
+		t.Errorf("Walk without credentials got no error")
+	}
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// Headers of webhook requests.
+const (
+	// WebhookEventHeader is the JobEventType of the event.
+	WebhookEventHeader = "X-Tika-Event"
+	// WebhookDeliveryHeader identifies the delivery, and is the same for all
+	// its attempts, so receivers can ignore duplicates.
+	WebhookDeliveryHeader = "X-Tika-Delivery"
+	// WebhookTimestampHeader is the time of the attempt, in seconds since
+	// the Unix epoch.
+	WebhookTimestampHeader = "X-Tika-Timestamp"
+	// WebhookSignatureHeader is "sha256=" followed by the hex encoded
+	// HMAC-SHA256 of the timestamp and the body, keyed with the secret of the
+	// Webhook; see SignWebhook.
+	WebhookSignatureHeader = "X-Tika-Signature"
+)
+
+// ErrWebhookQueueFull is reported to Webhook.OnError for the events dropped
+// by Notify because too many events are waiting for delivery.
+var ErrWebhookQueueFull = errors.New("webhook queue full")
+
+// A Webhook posts JobEvents as JSON to a URL, so that orchestration systems
+// can react to them. Its Notify method can be used as Job.OnEvent, with Close
+// once the Job is done:
+//
+//	hook := &tika.Webhook{URL: "https://example.com/hook", Secret: secret}
+//	job.OnEvent = hook.Notify
+//	err := job.Run(ctx)
+//	hook.Close(ctx)
+type Webhook struct {
+	URL string
+	// Secret, if not empty, signs the requests in WebhookSignatureHeader.
+	Secret []byte
+	// Events are the types of events posted. If empty, all events are posted.
+	Events []JobEventType
+	// MaxAttempts is the number of attempts to deliver an event. Zero means 3.
+	MaxAttempts int
+	// Backoff is the delay before the second attempt, doubled for each
+	// following attempt. Zero means 1s.
+	Backoff time.Duration
+	// MaxRetryAfter bounds the delays requested by the Retry-After header
+	// of 429 and 503 responses, which are waited instead of the backoff.
+	// Zero means 1 minute.
+	MaxRetryAfter time.Duration
+	// Timeout bounds each attempt to deliver an event. Zero means 10s.
+	Timeout time.Duration
+	// QueueSize is the number of events Notify queues for delivery. Events
+	// arriving while the queue is full are dropped. Zero means 64.
+	QueueSize int
+	// HTTPClient sends the requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+	// OnError, if not nil, is called with the errors of the events queued by
+	// Notify, from the goroutine delivering them or from Notify.
+	OnError func(error)
+
+	mu     sync.Mutex
+	queue  chan JobEvent // queue holds the events to deliver, once started.
+	closed bool
+	cancel context.CancelFunc // cancel stops the delivery in progress.
+	done   chan struct{}      // done is closed once the queue is delivered.
+}
+
+// Notify queues ev for delivery, if it is one of the Events of w, and returns
+// without waiting for it, so a slow receiver does not hold up the Job. The
+// events are delivered in order, from a single goroutine. Events arriving
+// while QueueSize events are queued, or after Close, are dropped, and the
+// error reported to OnError.
+func (w *Webhook) Notify(ev JobEvent) {
+	if !w.wants(ev.Type) {
+		return
+	}
+	w.mu.Lock()
+	var err error
+	switch {
+	case w.closed:
+		err = errors.New("webhook closed")
+	default:
+		if w.queue == nil {
+			w.start()
+		}
+		select {
+		case w.queue <- ev:
+		default:
+			err = ErrWebhookQueueFull
+		}
+	}
+	w.mu.Unlock()
+	if err != nil && w.OnError != nil {
+		w.OnError(fmt.Errorf("error sending %s to webhook: %w", ev.Type, err))
+	}
+}
+
+// start starts delivering the queued events. w.mu must be held.
+func (w *Webhook) start() {
+	size := w.QueueSize
+	if size <= 0 {
+		size = 64
+	}
+	queue := make(chan JobEvent, size)
+	ctx, cancel := context.WithCancel(context.Background())
+	w.queue, w.cancel, w.done = queue, cancel, make(chan struct{})
+	go func() {
+		defer close(w.done)
+		for ev := range queue {
+			if err := w.Send(ctx, ev); err != nil && w.OnError != nil {
+				w.OnError(err)
+			}
+		}
+	}()
+}
+
+// Close stops Notify and waits for the queued events to be delivered, or for
+// ctx to be done: the delivery in progress is then canceled, the remaining
+// events dropped, and Close returns the error of ctx.
+func (w *Webhook) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	queue := w.queue
+	w.mu.Unlock()
+	if queue == nil {
+		return nil
+	}
+	close(queue)
+	defer w.cancel()
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		for range queue {
+			// Drop the events the goroutine did not take yet.
+		}
+		<-w.done
+		return ctx.Err()
+	}
+}
+
+func (w *Webhook) wants(typ JobEventType) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, t := range w.Events {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// SignWebhook returns the value of WebhookSignatureHeader for a request with
+// the given body and WebhookTimestampHeader, signed with secret. Receivers
+// compare it to the header with hmac.Equal, and should reject requests whose
+// timestamp is more than a few minutes old, and deliveries already seen, so
+// captured requests cannot be replayed.
+func SignWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send delivers ev and waits for it, retrying after network errors, timeouts,
+// 429 and 5xx responses. Retries wait as long as the Retry-After header asks,
+// bounded by MaxRetryAfter, or else back off exponentially.
+func (w *Webhook) Send(ctx context.Context, ev JobEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return err
+	}
+	attempts, backoff := w.MaxAttempts, w.Backoff
+	if attempts <= 0 {
+		attempts = 3
+	}
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	maxWait := w.MaxRetryAfter
+	if maxWait <= 0 {
+		maxWait = time.Minute
+	}
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	httpClient := w.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	for i := 1; ; i++ {
+		actx, cancel := context.WithTimeout(ctx, timeout)
+		retry, wait, err := w.post(actx, httpClient, ev.Type, hex.EncodeToString(id), body)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if !retry || i == attempts || ctx.Err() != nil {
+			return fmt.Errorf("error sending %s to webhook: %w", ev.Type, err)
+		}
+		if wait <= 0 {
+			wait = backoff
+		} else if wait > maxWait {
+			wait = maxWait
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// post makes one attempt to deliver body, and reports whether to retry and
+// the delay requested by the receiver, if any.
+func (w *Webhook) post(ctx context.Context, httpClient *http.Client, typ JobEventType, id string, body []byte) (bool, time.Duration, error) {
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, string(typ))
+	req.Header.Set(WebhookDeliveryHeader, id)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	if len(w.Secret) > 0 {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(w.Secret, timestamp, body))
+	}
+	resp, err := ctxhttp.Do(ctx, httpClient, req)
+	if err != nil {
+		return true, 0, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, 0, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, retryAfter(resp, time.Now()), fmt.Errorf("response code %v", resp.StatusCode)
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookSend(t *testing.T) {
+	secret := []byte("s3cret")
+	tests := []struct {
+		name         string
+		codes        []int
+		wantErr      bool
+		wantAttempts int
+	}{
+		{name: "ok", codes: []int{200}, wantAttempts: 1},
+		{name: "retried", codes: []int{503, 429, 204}, wantAttempts: 3},
+		{name: "client error", codes: []int{400}, wantErr: true, wantAttempts: 1},
+		{name: "attempts exhausted", codes: []int{500, 500, 500, 200}, wantErr: true, wantAttempts: 3},
+	}
+	for _, test := range tests {
+		var mu sync.Mutex
+		var deliveries []string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			ts := r.Header.Get(WebhookTimestampHeader)
+			if sent, err := strconv.ParseInt(ts, 10, 64); err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+				t.Errorf("%s: got timestamp %q", test.name, ts)
+			}
+			if got, want := r.Header.Get(WebhookSignatureHeader), SignWebhook(secret, ts, body); got != want {
+				t.Errorf("%s: got signature %q, want %q", test.name, got, want)
+			}
+			if got := SignWebhook(secret, "0", body); got == r.Header.Get(WebhookSignatureHeader) {
+				t.Errorf("%s: signature does not cover the timestamp", test.name)
+			}
+			if got := r.Header.Get(WebhookEventHeader); got != string(EventJobFinished) {
+				t.Errorf("%s: got event header %q", test.name, got)
+			}
+			var ev JobEvent
+			if err := json.Unmarshal(body, &ev); err != nil || ev.Status.Name != "nightly" {
+				t.Errorf("%s: got body %s, %v", test.name, body, err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			deliveries = append(deliveries, r.Header.Get(WebhookDeliveryHeader))
+			w.WriteHeader(test.codes[len(deliveries)-1])
+		}))
+		w := &Webhook{URL: ts.URL, Secret: secret, Backoff: time.Millisecond, HTTPClient: ts.Client()}
+		err := w.Send(context.Background(), JobEvent{Type: EventJobFinished, Status: JobStatus{Name: "nightly"}})
+		ts.Close()
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%s: Send got error %v, want error: %v", test.name, err, test.wantErr)
+		}
+		if len(deliveries) != test.wantAttempts {
+			t.Errorf("%s: Send made %d attempts, want %d", test.name, len(deliveries), test.wantAttempts)
+		}
+		for _, id := range deliveries {
+			if id == "" || id != deliveries[0] {
+				t.Errorf("%s: got delivery IDs %v, want the same ID for all attempts", test.name, deliveries)
+				break
+			}
+		}
+	}
+}
+
+func TestJobEvents(t *testing.T) {
+	ts := rmetaServer()
+	defer ts.Close()
+	files := map[string]string{}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		files[name] = name
+	}
+	files["e"] = "fail"
+	files["f"] = "fail"
+	j, _ := testJob(ts, files)
+	j.Workers = 1
+	j.ProgressStep = 50
+	j.FailureThreshold = 2
+
+	var got []JobEventType
+	var percents []int
+	hooked := &Webhook{Events: []JobEventType{EventJobStarted}}
+	j.OnEvent = func(ev JobEvent) {
+		got = append(got, ev.Type)
+		if ev.Type == EventJobProgress {
+			percents = append(percents, ev.Percent)
+		}
+		if hooked.wants(ev.Type) != (ev.Type == EventJobStarted) {
+			t.Errorf("Webhook wants %s: %v", ev.Type, hooked.wants(ev.Type))
+		}
+	}
+	if err := j.Run(context.Background()); err != nil {
+		t.Fatalf("Run got error: %v", err)
+	}
+	want := []JobEventType{EventJobStarted, EventJobProgress, EventJobFailureThreshold, EventJobProgress, EventJobFinished}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Run sent events %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(percents, []int{50, 100}) {
+		t.Errorf("Run sent progress %v, want [50 100]", percents)
+	}
+}
+
+func TestWebhookRetryAfter(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+	w := &Webhook{URL: ts.URL, Backoff: time.Millisecond, MaxRetryAfter: 100 * time.Millisecond}
+	start := time.Now()
+	if err := w.Send(context.Background(), JobEvent{Type: EventJobFinished}); err != nil {
+		t.Fatalf("Send got error: %v", err)
+	}
+	if d := time.Since(start); d < w.MaxRetryAfter || d > 10*time.Second {
+		t.Errorf("Send took %v, want the Retry-After delay bounded to %v", d, w.MaxRetryAfter)
+	}
+	if calls != 2 {
+		t.Errorf("Send made %d calls, want 2", calls)
+	}
+}
+
+func TestWebhookTimeout(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-r.Context().Done()
+		}
+	}))
+	defer ts.Close()
+	w := &Webhook{URL: ts.URL, Backoff: time.Millisecond, Timeout: 50 * time.Millisecond}
+	if err := w.Send(context.Background(), JobEvent{Type: EventJobFinished}); err != nil {
+		t.Fatalf("Send got error: %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("Send made %d calls, want a retry after the timed out one", n)
+	}
+}
+
+func TestWebhookNotify(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var delivered []JobEventType
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		mu.Lock()
+		delivered = append(delivered, JobEventType(r.Header.Get(WebhookEventHeader)))
+		mu.Unlock()
+	}))
+	defer ts.Close()
+	var errs []error
+	w := &Webhook{URL: ts.URL, QueueSize: 1, MaxAttempts: 1, OnError: func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}}
+
+	// A receiver which does not answer holds up neither Notify nor the Job.
+	start := time.Now()
+	w.Notify(JobEvent{Type: EventJobStarted})
+	for deadline := time.Now().Add(5 * time.Second); len(w.queue) != 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the first event was not taken for delivery")
+		}
+	}
+	w.Notify(JobEvent{Type: EventJobProgress})
+	w.Notify(JobEvent{Type: EventJobFinished})
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Notify took %v with a receiver not answering", d)
+	}
+	mu.Lock()
+	if len(errs) != 1 || !errors.Is(errs[0], ErrWebhookQueueFull) {
+		t.Errorf("Notify reported %v, want ErrWebhookQueueFull for the third event", errs)
+	}
+	mu.Unlock()
+
+	close(release)
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("Close got error: %v", err)
+	}
+	if want := []JobEventType{EventJobStarted, EventJobProgress}; !reflect.DeepEqual(delivered, want) {
+		t.Errorf("delivered %v, want %v", delivered, want)
+	}
+	w.Notify(JobEvent{Type: EventJobFinished})
+	if len(errs) != 2 {
+		t.Errorf("Notify after Close reported %v, want an error", errs)
+	}
+}
+
+func TestWebhookCloseCanceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	w := &Webhook{URL: ts.URL, Timeout: time.Hour}
+	w.Notify(JobEvent{Type: EventJobStarted})
+	w.Notify(JobEvent{Type: EventJobFinished})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := w.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close got error %v, want context.DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Close took %v, want the delivery canceled", d)
+	}
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Namespaces used to parse XMP.
+const (
+	rdfNS = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlNS = "http://www.w3.org/XML/1998/namespace"
+)
+
+// ErrNoXMP is returned by ExtractXMP when the input has no XMP packet.
+var ErrNoXMP = errors.New("tika: no XMP packet found")
+
+// XMPKind is the kind of an XMPValue.
+type XMPKind int
+
+// Kinds of XMPValue.
+const (
+	XMPSimple XMPKind = iota // A single value, in Value.
+	XMPStruct                // A structure, in Fields.
+	XMPBag                   // An unordered array, in Items.
+	XMPSeq                   // An ordered array, in Items.
+	XMPAlt                   // An array of alternatives, in Items.
+)
+
+// An XMPValue is the value of an XMP property.
+type XMPValue struct {
+	Kind XMPKind
+	// Value is the value of a simple property.
+	Value string
+	// Lang is the language of the value (xml:lang), if any.
+	Lang string
+	// Fields holds the fields of a structure.
+	Fields map[xml.Name]XMPValue
+	// Items holds the items of an array.
+	Items []XMPValue
+}
+
+// XMP is a parsed XMP packet. Properties are keyed by their namespace URI and
+// local name.
+type XMP struct {
+	Properties map[xml.Name]XMPValue
+}
+
+// Get returns the property with the given namespace URI and local name.
+func (x *XMP) Get(space, local string) (XMPValue, bool) {
+	v, ok := x.Properties[xml.Name{Space: space, Local: local}]
+	return v, ok
+}
+
+// xmpDelimiters are the markers of the start and end of an XMP packet, in
+// order of preference.
+var xmpDelimiters = []struct{ start, end string }{
+	{"<?xpacket begin", "<?xpacket end"},
+	{"<x:xmpmeta", "</x:xmpmeta>"},
+	{"<rdf:RDF", "</rdf:RDF>"},
+}
+
+// ExtractXMP returns the first XMP packet in input, which can be any format
+// embedding XMP without compressing it, such as most PDF, JPEG, PNG and TIFF
+// files. ExtractXMP returns ErrNoXMP if there is no packet. It does not call a
+// Tika Server; see Client.XMPMeta to have Tika convert the metadata of any
+// document to XMP.
+func ExtractXMP(input io.Reader) ([]byte, error) {
+	b, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range xmpDelimiters {
+		start := bytes.Index(b, []byte(d.start))
+		if start < 0 {
+			continue
+		}
+		end := bytes.Index(b[start:], []byte(d.end))
+		if end < 0 {
+			continue
+		}
+		end += start + len(d.end)
+		if strings.HasPrefix(d.end, "<?") {
+			// Include the rest of the processing instruction.
+			close := bytes.Index(b[end:], []byte("?>"))
+			if close < 0 {
+				continue
+			}
+			end += close + len("?>")
+		}
+		return b[start:end], nil
+	}
+	return nil, ErrNoXMP
+}
+
+// XMPMeta returns the metadata of input, as extracted by Tika, serialized as
+// an XMP packet. It requires Tika Server 2.0 or later. If the error is not
+// nil, the packet is undefined.
+func (c *Client) XMPMeta(ctx context.Context, input io.Reader, opts ...RequestOption) ([]byte, error) {
+	return c.call(ctx, input, "PUT", "/xmpmeta", newCallConfig(opts))
+}
+
+// ParseXMP parses an XMP packet, such as the result of ExtractXMP.
+func ParseXMP(packet []byte) (*XMP, error) {
+	x := &XMP{Properties: make(map[xml.Name]XMPValue)}
+	d := xml.NewDecoder(bytes.NewReader(packet))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return x, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XMP: %w", err)
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name != (xml.Name{Space: rdfNS, Local: "Description"}) {
+			continue
+		}
+		v, err := parseXMPResource(d, se)
+		if err != nil {
+			return nil, err
+		}
+		for k, f := range v.Fields {
+			x.Properties[k] = f
+		}
+	}
+}
+
+// parseXMPResource parses the properties of a resource: the attributes and
+// children of an rdf:Description, or of an element with
+// rdf:parseType="Resource".
+func parseXMPResource(d *xml.Decoder, se xml.StartElement) (XMPValue, error) {
+	v := XMPValue{Kind: XMPStruct, Fields: make(map[xml.Name]XMPValue)}
+	for _, a := range se.Attr {
+		if isXMPProperty(a.Name) {
+			v.Fields[a.Name] = XMPValue{Value: a.Value}
+		}
+	}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return v, fmt.Errorf("invalid XMP: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			p, err := parseXMPProperty(d, t)
+			if err != nil {
+				return v, err
+			}
+			v.Fields[t.Name] = p
+		case xml.EndElement:
+			return v, nil
+		}
+	}
+}
+
+// isXMPProperty reports whether an attribute is a property, rather than
+// RDF syntax or a namespace declaration.
+func isXMPProperty(n xml.Name) bool {
+	return n.Space != rdfNS && n.Space != xmlNS && n.Space != "xmlns" && n.Local != "xmlns"
+}
+
+// parseXMPProperty parses a property element and its value.
+func parseXMPProperty(d *xml.Decoder, se xml.StartElement) (XMPValue, error) {
+	var v XMPValue
+	qualifiers := make(map[xml.Name]XMPValue)
+	for _, a := range se.Attr {
+		switch {
+		case a.Name == xml.Name{Space: xmlNS, Local: "lang"}:
+			v.Lang = a.Value
+		case a.Name == xml.Name{Space: rdfNS, Local: "resource"}:
+			v.Value = a.Value
+		case a.Name == xml.Name{Space: rdfNS, Local: "parseType"} && a.Value == "Resource":
+			r, err := parseXMPResource(d, se)
+			r.Lang = v.Lang
+			return r, err
+		case isXMPProperty(a.Name):
+			qualifiers[a.Name] = XMPValue{Value: a.Value}
+		}
+	}
+	if len(qualifiers) > 0 {
+		// Shorthand for a structure with simple fields.
+		v.Kind, v.Fields = XMPStruct, qualifiers
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return v, fmt.Errorf("invalid XMP: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			var child XMPValue
+			var err error
+			switch t.Name {
+			case xml.Name{Space: rdfNS, Local: "Bag"}, xml.Name{Space: rdfNS, Local: "Seq"}, xml.Name{Space: rdfNS, Local: "Alt"}:
+				child, err = parseXMPArray(d, t)
+			case xml.Name{Space: rdfNS, Local: "Description"}:
+				child, err = parseXMPResource(d, t)
+			default:
+				err = fmt.Errorf("invalid XMP: unexpected element %s in property %s", t.Name.Local, se.Name.Local)
+			}
+			if err != nil {
+				return v, err
+			}
+			child.Lang = v.Lang
+			v = child
+		case xml.EndElement:
+			if v.Kind == XMPSimple && v.Value == "" {
+				v.Value = strings.TrimSpace(text.String())
+			}
+			return v, nil
+		}
+	}
+}
+
+// parseXMPArray parses an rdf:Bag, rdf:Seq or rdf:Alt element.
+func parseXMPArray(d *xml.Decoder, se xml.StartElement) (XMPValue, error) {
+	v := XMPValue{Kind: map[string]XMPKind{"Bag": XMPBag, "Seq": XMPSeq, "Alt": XMPAlt}[se.Name.Local]}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return v, fmt.Errorf("invalid XMP: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			item, err := parseXMPProperty(d, t)
+			if err != nil {
+				return v, err
+			}
+			v.Items = append(v.Items, item)
+		case xml.EndElement:
+			return v, nil
+		}
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tika
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	dcNS    = "http://purl.org/dc/elements/1.1/"
+	xmpNS   = "http://ns.adobe.com/xap/1.0/"
+	stDimNS = "http://ns.adobe.com/xap/1.0/sType/Dimensions#"
+	tpgNS   = "http://ns.adobe.com/xap/1.0/t/pg/"
+)
+
+const testXMP = "<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>" + `
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:xmpTPg="http://ns.adobe.com/xap/1.0/t/pg/"
+    xmlns:stDim="http://ns.adobe.com/xap/1.0/sType/Dimensions#"
+    xmp:CreateDate="2017-01-01T00:00:00Z">
+   <dc:title><rdf:Alt><rdf:li xml:lang="x-default">Sample</rdf:li></rdf:Alt></dc:title>
+   <dc:creator><rdf:Seq><rdf:li>Ann</rdf:li><rdf:li>Bob</rdf:li></rdf:Seq></dc:creator>
+   <dc:source rdf:resource="http://example.com/"/>
+   <xmpTPg:MaxPageSize rdf:parseType="Resource">
+    <stDim:w>8.5</stDim:w>
+    <stDim:h>11</stDim:h>
+   </xmpTPg:MaxPageSize>
+   <xmpTPg:MinPageSize stDim:w="1" stDim:h="2"/>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+func TestExtractXMP(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"packet", "%PDF-1.4 binary" + testXMP + " more binary", testXMP},
+		{"no xpacket", "JFIF <x:xmpmeta>meta</x:xmpmeta> tail", "<x:xmpmeta>meta</x:xmpmeta>"},
+		{"bare rdf", "PNG <rdf:RDF>rdf</rdf:RDF>", "<rdf:RDF>rdf</rdf:RDF>"},
+	}
+	for _, test := range tests {
+		got, err := ExtractXMP(strings.NewReader(test.input))
+		if err != nil {
+			t.Errorf("ExtractXMP(%s) got error: %v", test.name, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("ExtractXMP(%s) = %q, want %q", test.name, got, test.want)
+		}
+	}
+	if _, err := ExtractXMP(strings.NewReader("no packet <?xpacket begin")); err != ErrNoXMP {
+		t.Errorf("ExtractXMP with no packet got error %v, want ErrNoXMP", err)
+	}
+}
+
+func TestParseXMP(t *testing.T) {
+	x, err := ParseXMP([]byte(testXMP))
+	if err != nil {
+		t.Fatalf("ParseXMP got error: %v", err)
+	}
+	want := map[xml.Name]XMPValue{
+		{Space: xmpNS, Local: "CreateDate"}: {Value: "2017-01-01T00:00:00Z"},
+		{Space: dcNS, Local: "title"}: {
+			Kind:  XMPAlt,
+			Items: []XMPValue{{Value: "Sample", Lang: "x-default"}},
+		},
+		{Space: dcNS, Local: "creator"}: {
+			Kind:  XMPSeq,
+			Items: []XMPValue{{Value: "Ann"}, {Value: "Bob"}},
+		},
+		{Space: dcNS, Local: "source"}: {Value: "http://example.com/"},
+		{Space: tpgNS, Local: "MaxPageSize"}: {
+			Kind: XMPStruct,
+			Fields: map[xml.Name]XMPValue{
+				{Space: stDimNS, Local: "w"}: {Value: "8.5"},
+				{Space: stDimNS, Local: "h"}: {Value: "11"},
+			},
+		},
+		{Space: tpgNS, Local: "MinPageSize"}: {
+			Kind: XMPStruct,
+			Fields: map[xml.Name]XMPValue{
+				{Space: stDimNS, Local: "w"}: {Value: "1"},
+				{Space: stDimNS, Local: "h"}: {Value: "2"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(x.Properties, want) {
+		t.Errorf("ParseXMP got %+v, want %+v", x.Properties, want)
+	}
+	if v, ok := x.Get(dcNS, "creator"); !ok || len(v.Items) != 2 {
+		t.Errorf("Get(dc, creator) = %+v, %t, want the creators", v, ok)
+	}
+}
+
+func TestParseXMPError(t *testing.T) {
+	for _, packet := range []string{
+		`<rdf:RDF xmlns:rdf="` + rdfNS + `"><rdf:Description><p>unterminated`,
+		`<rdf:RDF xmlns:rdf="` + rdfNS + `"><rdf:Description><p><unexpected/></p></rdf:Description></rdf:RDF>`,
+	} {
+		if _, err := ParseXMP([]byte(packet)); err == nil {
+			t.Errorf("ParseXMP(%q) got no error, want an error", packet)
+		}
+	}
+}
+
+func TestXMPMeta(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/xmpmeta" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, testXMP)
+	}))
+	defer ts.Close()
+	got, err := NewClient(nil, ts.URL).XMPMeta(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("XMPMeta returned an error: %v", err)
+	}
+	if string(got) != testXMP {
+		t.Errorf("XMPMeta got %q, want %q", got, testXMP)
+	}
+}